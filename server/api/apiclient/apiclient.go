@@ -0,0 +1,97 @@
+package apiclient
+
+import (
+	"server/service"
+	apiClientService "server/service/apiclient"
+	"server/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIClient 创建服务间调用客户端（管理员）
+// 响应中的密钥只返回这一次，请妥善保存
+func CreateAPIClient(c *gin.Context) {
+	var req apiClientService.CreateAPIClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	result, err := service.APIClientService.CreateAPIClient(&req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(result, "创建成功", c)
+}
+
+// UpdateAPIClient 更新服务间调用客户端（管理员）
+func UpdateAPIClient(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	var req apiClientService.UpdateAPIClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := service.APIClientService.UpdateAPIClient(id, &req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("更新成功", c)
+}
+
+// RotateAPIClientSecret 重置服务间调用客户端密钥（管理员）
+func RotateAPIClientSecret(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	result, err := service.APIClientService.RotateSecret(id)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(result, "密钥已重置", c)
+}
+
+// DeleteAPIClient 删除服务间调用客户端（管理员）
+func DeleteAPIClient(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的ID", c)
+		return
+	}
+
+	if err := service.APIClientService.DeleteAPIClient(id); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("删除成功", c)
+}
+
+// ListAPIClients 获取服务间调用客户端列表（管理员）
+func ListAPIClients(c *gin.Context) {
+	clients, err := service.APIClientService.ListAPIClients()
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(gin.H{
+		"list":   clients,
+		"scopes": apiClientService.AllScopes,
+	}, c)
+}
@@ -1,8 +1,11 @@
 package app
 
 import (
+	"errors"
+
 	"server/service"
 	appService "server/service/app"
+	billingService "server/service/billing"
 	"server/utils"
 
 	"github.com/gin-gonic/gin"
@@ -160,7 +163,7 @@ func DeleteWorkflow(c *gin.Context) {
 	utils.OkWithMessage("删除成功", c)
 }
 
-// 可以指定 block 或 streaming 响应方式
+// 可以指定 blocking、streaming 或 async 响应方式，async 返回任务信息，通过 GET /api/jobs/:id 查询结果
 // POST /api/workflow/v2/:name/execute
 func ExecuteWorkflowByName(c *gin.Context) {
 	workflowName := c.Param("name")
@@ -175,14 +178,29 @@ func ExecuteWorkflowByName(c *gin.Context) {
 		req.ResponseMode = "blocking"
 	}
 
+	if req.ResponseMode == "async" {
+		job, err := service.AppService.SubmitWorkflowJobByName(workflowName, userID, req.Inputs)
+		if err != nil {
+			failWithExecuteError(err, c)
+			return
+		}
+		utils.OkWithDetailed(job, "任务已提交", c)
+		return
+	}
+
 	// 调用服务层
 	result, err := service.AppService.ExecuteWorkflowByName(c, workflowName, userID, req.Inputs, req.ResponseMode)
 	if err != nil {
-		utils.FailWithMessage(err.Error(), c)
+		// 流式执行开始后的错误已经在服务层发送给客户端
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "application/json; charset=utf-8")
+			failWithExecuteError(err, c)
+		}
 		return
 	}
 
 	if req.ResponseMode == "blocking" {
+		utils.SetHeaders(result.QuotaStatus.Headers(), c)
 		utils.OkWithData(result, c)
 	} // streaming 类型不用在这里响应
 }
@@ -201,18 +219,31 @@ func ExecuteWorkflow(c *gin.Context) {
 	switch req.ResponseMode {
 	case "blocking":
 		// 调用服务层
-		result, err := service.AppService.ExecuteWorkflow(workflowID, userID, req.Inputs)
+		result, err := service.AppService.ExecuteWorkflow(c.Request.Context(), workflowID, userID, req.Inputs)
 		if err != nil {
-			utils.FailWithMessage(err.Error(), c)
+			failWithExecuteError(err, c)
 			return
 		}
+		utils.SetHeaders(result.QuotaStatus.Headers(), c)
 		utils.OkWithData(result, c)
 	case "streaming":
 		// 调用服务层流式执行
 		if err := service.AppService.ExecuteWorkflowStream(c, workflowID, userID, req.Inputs); err != nil {
-			// 错误已经在服务层处理并发送给客户端
+			// 流开始前的错误（如积分不足）以普通响应返回，流开始后的错误已经在服务层处理并发送给客户端
+			if !c.Writer.Written() {
+				c.Header("Content-Type", "application/json; charset=utf-8")
+				failWithExecuteError(err, c)
+			}
+			return
+		}
+	case "async":
+		// 提交异步任务，通过 GET /api/jobs/:id 查询进度和结果
+		job, err := service.AppService.SubmitWorkflowJob(workflowID, userID, req.Inputs)
+		if err != nil {
+			failWithExecuteError(err, c)
 			return
 		}
+		utils.OkWithDetailed(job, "任务已提交", c)
 	default:
 		utils.FailWithMessage("响应模式不支持", c)
 		return
@@ -244,7 +275,7 @@ func UpdateWorkflow(c *gin.Context) {
 	}
 
 	// 调用服务层
-	if err := service.AppService.UpdateWorkflow(workflowID, req); err != nil {
+	if err := service.AppService.UpdateWorkflow(workflowID, req, c.GetString("userID")); err != nil {
 		utils.FailWithMessage(err.Error(), c)
 		return
 	}
@@ -252,6 +283,18 @@ func UpdateWorkflow(c *gin.Context) {
 	utils.OkWithMessage("更新成功", c)
 }
 
+// RotateWorkflowAPIKeys 使用当前主密钥重新加密所有工作流API密钥（管理员）
+// POST /api/workflow/keys/rotate
+func RotateWorkflowAPIKeys(c *gin.Context) {
+	result, err := service.AppService.RotateWorkflowAPIKeys()
+	if err != nil {
+		utils.FailWithMessage("重新加密失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(result, "重新加密完成", c)
+}
+
 // ExecuteWorkflowStream 流式执行工作流
 // func ExecuteWorkflowStream(c *gin.Context) {
 // 	workflowID := c.Param("id")
@@ -268,3 +311,24 @@ func UpdateWorkflow(c *gin.Context) {
 // 		return
 // 	}
 // }
+
+// failWithExecuteError 返回工作流执行错误，输入校验失败时返回400及字段级错误，积分不足时返回402及所需积分信息，超出使用配额时返回429及配额信息
+func failWithExecuteError(err error, c *gin.Context) {
+	var insufficient *billingService.InsufficientCreditsError
+	if errors.As(err, &insufficient) {
+		utils.FailWithPaymentRequired(insufficient, insufficient.Error(), c)
+		return
+	}
+	var invalid *appService.InputValidationError
+	if errors.As(err, &invalid) {
+		utils.FailWithBadRequest(invalid, invalid.Error(), c)
+		return
+	}
+	var exceeded *billingService.QuotaExceededError
+	if errors.As(err, &exceeded) {
+		utils.SetHeaders(exceeded.Headers(), c)
+		utils.FailWithTooManyRequests(exceeded, exceeded.Error(), c)
+		return
+	}
+	utils.FailWithMessage(err.Error(), c)
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"strconv"
+
+	"server/service"
+	appService "server/service/app"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWorkflowCacheStats 查询工作流响应缓存统计（管理员）
+// GET /api/workflow/cache/stats?days=7
+func GetWorkflowCacheStats(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+
+	stats, err := service.AppService.GetWorkflowCacheStats(days)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(stats, c)
+}
+
+// PurgeWorkflowCache 清除工作流的响应缓存（管理员），可通过 user_id 只清除指定用户的缓存
+// DELETE /api/workflow/:id/cache
+func PurgeWorkflowCache(c *gin.Context) {
+	removed, err := service.AppService.PurgeWorkflowCache(c.Param("id"), c.Query("user_id"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(appService.PurgeWorkflowCacheResponse{Removed: removed}, "缓存已清除", c)
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"errors"
+
+	"server/model"
+	"server/service"
+	appService "server/service/app"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAllPipelines 获取所有流水线（管理员）
+// GET /api/workflow/pipelines
+func GetAllPipelines(c *gin.Context) {
+	pipelines, err := service.AppService.GetAllPipelines()
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(pipelines, c)
+}
+
+// CreatePipeline 创建流水线（管理员）
+// POST /api/workflow/pipelines
+func CreatePipeline(c *gin.Context) {
+	var req appService.CreatePipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithBadRequest(nil, "请求参数错误", c)
+		return
+	}
+
+	pipeline, err := service.AppService.CreatePipeline(req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(pipeline, "创建成功", c)
+}
+
+// UpdatePipeline 更新流水线（管理员）
+// PUT /api/workflow/pipelines/:id
+func UpdatePipeline(c *gin.Context) {
+	var req appService.UpdatePipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithBadRequest(nil, "请求参数错误", c)
+		return
+	}
+
+	if err := service.AppService.UpdatePipeline(c.Param("id"), req, c.GetString("userID")); err != nil {
+		if errors.Is(err, appService.ErrPipelineNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("更新成功", c)
+}
+
+// DeletePipeline 删除流水线（管理员）
+// DELETE /api/workflow/pipelines/:id
+func DeletePipeline(c *gin.Context) {
+	if err := service.AppService.DeletePipeline(c.Param("id")); err != nil {
+		if errors.Is(err, appService.ErrPipelineNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("删除成功", c)
+}
+
+// RunPipeline 按名称运行流水线，response_mode 为 blocking（默认）或 streaming
+// POST /api/workflow/pipelines/:name/run
+func RunPipeline(c *gin.Context) {
+	var req appService.RunPipelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithBadRequest(nil, "请求参数错误", c)
+		return
+	}
+
+	run, err := service.AppService.RunPipelineByName(c, c.Param("name"), c.GetString("userID"), req.Inputs, req.ResponseMode)
+	if err != nil {
+		// 流开始前的错误以普通响应返回
+		if c.Writer.Written() {
+			return
+		}
+		if errors.Is(err, appService.ErrPipelineNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+	if run == nil {
+		// 流式运行的事件已写入响应
+		return
+	}
+
+	if run.Status != model.PipelineRunStatusSucceeded {
+		utils.FailWithDetailed(run, run.ErrorMessage, c)
+		return
+	}
+	utils.OkWithDetailed(run, "流水线执行成功", c)
+}
+
+// GetPipelineRun 查询流水线运行记录（仅限本人）
+// GET /api/workflow/pipelines/runs/:id
+func GetPipelineRun(c *gin.Context) {
+	run, err := service.AppService.GetPipelineRun(c.Param("id"), c.GetString("userID"))
+	if err != nil {
+		if errors.Is(err, appService.ErrPipelineRunNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(run, c)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+
+	"server/service"
+	appService "server/service/app"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResumeWorkflowStream 重连流式执行，补发 Last-Event-ID 之后的事件并继续接收
+// 流ID 来自流式执行响应头 X-Stream-ID，Last-Event-ID 也可通过 last_event_id 查询参数传递
+// GET /api/workflow/streams/:stream_id
+func ResumeWorkflowStream(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var eventID int64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || parsed < 0 {
+			utils.FailWithBadRequest(nil, "无效的 Last-Event-ID", c)
+			return
+		}
+		eventID = parsed
+	}
+
+	if err := service.AppService.ResumeWorkflowStream(c, c.Param("stream_id"), c.GetString("userID"), eventID); err != nil {
+		if errors.Is(err, appService.ErrStreamNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+	}
+}
+
+// CancelWorkflowStream 取消流式执行，停止上游任务并释放预扣积分
+// POST /api/workflow/streams/:stream_id/cancel
+func CancelWorkflowStream(c *gin.Context) {
+	if err := service.AppService.CancelWorkflowStream(c.Param("stream_id"), c.GetString("userID")); err != nil {
+		if errors.Is(err, appService.ErrStreamNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+	utils.OkWithMessage("已取消", c)
+}
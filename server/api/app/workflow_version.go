@@ -0,0 +1,65 @@
+package app
+
+import (
+	"strconv"
+
+	"server/service"
+	appService "server/service/app"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWorkflowVersions 查询工作流配置版本历史（管理员）
+// GET /api/workflow/:id/versions
+func ListWorkflowVersions(c *gin.Context) {
+	versions, err := service.AppService.ListWorkflowVersions(c.Param("id"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(versions, c)
+}
+
+// DiffWorkflowVersions 比较工作流两个配置版本（管理员）
+// GET /api/workflow/:id/versions/diff?from=1&to=2
+func DiffWorkflowVersions(c *gin.Context) {
+	var req appService.WorkflowVersionDiffRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	diff, err := service.AppService.DiffWorkflowVersions(c.Param("id"), req.From, req.To)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(diff, c)
+}
+
+// RollbackWorkflow 将工作流配置回滚到指定版本（管理员）
+// POST /api/workflow/:id/versions/:version/rollback
+func RollbackWorkflow(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		utils.FailWithMessage("无效的版本号", c)
+		return
+	}
+
+	var req appService.RollbackWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	rolledBack, err := service.AppService.RollbackWorkflow(c.Param("id"), version, c.GetString("userID"), req.Notes)
+	if err != nil {
+		utils.FailWithMessage("回滚失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(rolledBack, "回滚成功", c)
+}
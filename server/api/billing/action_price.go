@@ -1,6 +1,8 @@
 package billing
 
 import (
+	"strconv"
+
 	billingService "server/service/billing"
 	"server/utils"
 
@@ -34,3 +36,72 @@ func GetActiveActionPrices(c *gin.Context) {
 
 	utils.OkWithData(actionPrices, c)
 }
+
+// ListActionPriceVersions 查询动作价格版本历史（管理员）
+// GET /api/admin/billing/action-prices/:actionKey/versions
+func ListActionPriceVersions(c *gin.Context) {
+	service := &billingService.ActionPriceService{}
+	versions, err := service.ListPriceVersions(c.Param("actionKey"))
+	if err != nil {
+		utils.FailWithMessage("查询失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(versions, c)
+}
+
+// ScheduleActionPrice 排期动作价格版本（管理员）
+// POST /api/admin/billing/action-prices/:actionKey/versions
+func ScheduleActionPrice(c *gin.Context) {
+	var req billingService.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	service := &billingService.ActionPriceService{}
+	version, err := service.SchedulePriceVersion(c.Param("actionKey"), &req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(version, c)
+}
+
+// CancelActionPriceVersion 取消价格版本（管理员），未生效的版本删除，生效中的版本立即结束
+// DELETE /api/admin/billing/action-price-versions/:id
+func CancelActionPriceVersion(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的价格版本ID", c)
+		return
+	}
+
+	service := &billingService.ActionPriceService{}
+	if err := service.CancelPriceVersion(id); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("价格版本已取消", c)
+}
+
+// UpdateActionPrice 更新动作的展示信息与计费方式（管理员）
+// PUT /api/admin/billing/action-prices/:actionKey
+func UpdateActionPrice(c *gin.Context) {
+	var req billingService.UpdateActionPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	service := &billingService.ActionPriceService{}
+	actionPrice, err := service.UpdateActionConfig(c.Param("actionKey"), &req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(actionPrice, c)
+}
@@ -0,0 +1,50 @@
+package billing
+
+import (
+	billingService "server/service/billing"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdjustUserCredits 手动调整用户积分（管理员）
+// POST /api/admin/billing/credit-adjustments
+func AdjustUserCredits(c *gin.Context) {
+	var req billingService.AdjustCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	service := &billingService.AdjustmentService{}
+	adjustment, err := service.AdjustCredits(&req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage("调整积分失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(adjustment, "积分调整成功", c)
+}
+
+// ListCreditAdjustments 查询积分调整记录（管理员）
+// GET /api/admin/billing/credit-adjustments
+// GET /api/admin/billing/users/:userId/credit-adjustments
+func ListCreditAdjustments(c *gin.Context) {
+	var req billingService.AdjustmentQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+	if userID := c.Param("userId"); userID != "" {
+		req.UserID = userID
+	}
+
+	service := &billingService.AdjustmentService{}
+	result, err := service.ListAdjustments(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
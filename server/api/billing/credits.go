@@ -1,6 +1,9 @@
 package billing
 
 import (
+	"strconv"
+	"time"
+
 	billingService "server/service/billing"
 	"server/utils"
 
@@ -15,10 +18,19 @@ type CheckCreditsRequest struct {
 
 // DeductCreditsRequest 扣减积分请求
 type DeductCreditsRequest struct {
-	UserID       string `json:"user_id" binding:"required"`
-	ActionKey    string `json:"action_key" binding:"required"`
-	ResourceType string `json:"resource_type"`
-	ResourceID   string `json:"resource_id"`
+	UserID         string `json:"user_id" binding:"required"`
+	ActionKey      string `json:"action_key" binding:"required"`
+	ResourceType   string `json:"resource_type"`
+	ResourceID     string `json:"resource_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// RefundCreditsRequest 退还积分请求
+type RefundCreditsRequest struct {
+	UserID         string `json:"user_id" binding:"required"`
+	DeductionID    int64  `json:"deduction_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Reason         string `json:"reason"`
 }
 
 // CheckCredits 检查积分（内部API）
@@ -51,10 +63,11 @@ func DeductCredits(c *gin.Context) {
 
 	service := &billingService.UserPackageService{}
 	serviceReq := &billingService.DeductCreditsRequest{
-		UserID:       req.UserID,
-		ActionKey:    billingService.ActionKey(req.ActionKey),
-		ResourceType: req.ResourceType,
-		ResourceID:   req.ResourceID,
+		UserID:         req.UserID,
+		ActionKey:      billingService.ActionKey(req.ActionKey),
+		ResourceType:   req.ResourceType,
+		ResourceID:     req.ResourceID,
+		IdempotencyKey: req.IdempotencyKey,
 	}
 
 	result, err := service.DeductCredits(serviceReq)
@@ -70,3 +83,122 @@ func DeductCredits(c *gin.Context) {
 
 	utils.OkWithData(result, c)
 }
+
+// RefundCredits 退还一次扣减的积分（内部API）
+// POST /api/internal/billing/credits/refund
+func RefundCredits(c *gin.Context) {
+	var req RefundCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	service := &billingService.UserPackageService{}
+	result, err := service.RefundDeduction(&billingService.RefundCreditsRequest{
+		UserID:         req.UserID,
+		DeductionID:    req.DeductionID,
+		IdempotencyKey: req.IdempotencyKey,
+		Reason:         req.Reason,
+	})
+	if err != nil {
+		utils.FailWithMessage("退还积分失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// ReserveCreditsRequest 预扣积分请求
+type ReserveCreditsRequest struct {
+	UserID         string `json:"user_id" binding:"required"`
+	ActionKey      string `json:"action_key" binding:"required"`
+	ResourceType   string `json:"resource_type"`
+	ResourceID     string `json:"resource_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	TTLSeconds     int    `json:"ttl_seconds" binding:"min=0"` // 预扣有效期（秒），0使用默认值
+}
+
+// SettleHoldRequest 结算/释放预扣请求
+type SettleHoldRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// ReserveCredits 预扣积分（内部API）
+// POST /api/internal/billing/credits/reserve
+func ReserveCredits(c *gin.Context) {
+	var req ReserveCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	service := &billingService.HoldService{}
+	result, err := service.ReserveCredits(&billingService.ReserveCreditsRequest{
+		UserID:         req.UserID,
+		ActionKey:      billingService.ActionKey(req.ActionKey),
+		ResourceType:   req.ResourceType,
+		ResourceID:     req.ResourceID,
+		IdempotencyKey: req.IdempotencyKey,
+		TTL:            time.Duration(req.TTLSeconds) * time.Second,
+	})
+	if err != nil {
+		utils.FailWithMessage("预扣积分失败: "+err.Error(), c)
+		return
+	}
+
+	if !result.Success {
+		utils.FailWithMessage(result.Message, c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// CommitHold 结算预扣（内部API）
+// POST /api/internal/billing/credits/holds/:id/commit
+func CommitHold(c *gin.Context) {
+	holdID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的预扣ID", c)
+		return
+	}
+
+	var req SettleHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	service := &billingService.HoldService{}
+	if err := service.CommitHold(req.UserID, holdID); err != nil {
+		utils.FailWithMessage("结算预扣失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("结算成功", c)
+}
+
+// ReleaseHold 释放预扣（内部API）
+// POST /api/internal/billing/credits/holds/:id/release
+func ReleaseHold(c *gin.Context) {
+	holdID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的预扣ID", c)
+		return
+	}
+
+	var req SettleHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	service := &billingService.HoldService{}
+	if err := service.ReleaseHold(req.UserID, holdID, req.Reason); err != nil {
+		utils.FailWithMessage("释放预扣失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("释放成功", c)
+}
@@ -17,12 +17,15 @@ type CreateBillingPackageRequest struct {
 	PackageType   string  `json:"package_type" binding:"required"`
 	Price         float64 `json:"price"`
 	OriginalPrice float64 `json:"original_price"`
-	CreditsAmount int     `json:"credits_amount" binding:"required,min=1"`
+	CreditsAmount int     `json:"credits_amount" binding:"min=0"`
 	ValidityDays  int     `json:"validity_days" binding:"min=0"`
 	IsActive      bool    `json:"is_active"`
 	IsVisible     bool    `json:"is_visible"`
 	SortOrder     int     `json:"sort_order"`
 	DisplayOrder  int     `json:"display_order"`
+
+	CoveredActions   []string `json:"covered_actions"`                    // 不限次使用的动作key（时长型/混合型/永久型）
+	DailyActionLimit int      `json:"daily_action_limit" binding:"min=0"` // 每个动作每日合理使用上限，0=不限
 }
 
 // CreateBillingPackage 创建套餐（管理员）
@@ -46,9 +49,15 @@ func CreateBillingPackage(c *gin.Context) {
 		IsVisible:     req.IsVisible,
 		SortOrder:     req.SortOrder,
 		DisplayOrder:  req.DisplayOrder,
+
+		DailyActionLimit: req.DailyActionLimit,
 	}
 
 	service := &billingService.PackageService{}
+	if err := service.ValidateBillingPackage(pkg, req.CoveredActions); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
 	if err := service.CreateBillingPackage(pkg); err != nil {
 		utils.FailWithMessage("创建套餐失败: "+err.Error(), c)
 		return
@@ -128,7 +137,12 @@ func UpdateBillingPackage(c *gin.Context) {
 	pkg.IsVisible = req.IsVisible
 	pkg.SortOrder = req.SortOrder
 	pkg.DisplayOrder = req.DisplayOrder
+	pkg.DailyActionLimit = req.DailyActionLimit
 
+	if err := service.ValidateBillingPackage(pkg, req.CoveredActions); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
 	if err := service.UpdateBillingPackage(pkg); err != nil {
 		utils.FailWithMessage("更新套餐失败: "+err.Error(), c)
 		return
@@ -0,0 +1,123 @@
+package billing
+
+import (
+	"strconv"
+
+	billingService "server/service/billing"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMyQuotas 查询我的动作使用配额
+// GET /api/user/billing/quotas
+func GetMyQuotas(c *gin.Context) {
+	service := &billingService.QuotaService{}
+	statuses, err := service.ListUserQuotaStatus(c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(statuses, c)
+}
+
+// ListQuotaRules 查询配额规则（管理员）
+// GET /api/admin/billing/quotas
+func ListQuotaRules(c *gin.Context) {
+	var req billingService.QuotaRuleQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	service := &billingService.QuotaService{}
+	rules, err := service.ListQuotaRules(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(rules, c)
+}
+
+// SaveQuotaRule 创建或更新配额规则（管理员）
+// PUT /api/admin/billing/quotas
+func SaveQuotaRule(c *gin.Context) {
+	var req billingService.QuotaRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	service := &billingService.QuotaService{}
+	rule, err := service.SaveQuotaRule(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(rule, "配额规则已保存", c)
+}
+
+// DeleteQuotaRule 删除配额规则（管理员）
+// DELETE /api/admin/billing/quotas/:id
+func DeleteQuotaRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的配额规则ID", c)
+		return
+	}
+
+	service := &billingService.QuotaService{}
+	if err := service.DeleteQuotaRule(id); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("配额规则已删除", c)
+}
+
+// GetUserQuotas 查询用户配额覆盖与使用情况（管理员）
+// GET /api/admin/billing/users/:userId/quotas
+func GetUserQuotas(c *gin.Context) {
+	service := &billingService.QuotaService{}
+	result, err := service.GetUserQuotas(c.Param("userId"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// SetUserQuotaOverride 设置用户配额覆盖（管理员）
+// PUT /api/admin/billing/users/:userId/quotas
+func SetUserQuotaOverride(c *gin.Context) {
+	var req billingService.QuotaOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	service := &billingService.QuotaService{}
+	override, err := service.SetQuotaOverride(c.Param("userId"), &req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(override, "配额覆盖已保存", c)
+}
+
+// DeleteUserQuotaOverride 删除用户配额覆盖（管理员）
+// DELETE /api/admin/billing/users/:userId/quotas/:actionKey
+func DeleteUserQuotaOverride(c *gin.Context) {
+	service := &billingService.QuotaService{}
+	if err := service.DeleteQuotaOverride(c.Param("userId"), c.Param("actionKey")); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("配额覆盖已删除", c)
+}
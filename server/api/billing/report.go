@@ -0,0 +1,145 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"time"
+
+	billingService "server/service/billing"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// csvReport 支持导出 CSV 的报表
+type csvReport interface {
+	CSV() [][]string
+}
+
+// respondReport 返回报表，format=csv 时以 CSV 附件下载
+func respondReport(c *gin.Context, name string, report csvReport) {
+	if c.Query("format") != "csv" {
+		utils.OkWithData(report, c)
+		return
+	}
+
+	// 先在内存中生成完整的 CSV，生成失败时仍可返回错误响应，避免下载到被截断的文件
+	var buf bytes.Buffer
+	// 写入 UTF-8 BOM，保证 Excel 正确识别中文
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+	// WriteAll 写入后会 Flush 并返回 writer.Error()
+	if err := writer.WriteAll(report.CSV()); err != nil {
+		utils.FailWithMessage("生成CSV失败: "+err.Error(), c)
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s.csv", name, time.Now().Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if _, err := c.Writer.Write(buf.Bytes()); err != nil {
+		// 响应已开始写入，只能记录错误
+		fmt.Printf("导出报表失败: report=%s, err=%v\n", name, err)
+	}
+}
+
+// bindReportRequest 绑定报表查询条件
+func bindReportRequest(c *gin.Context) (*billingService.ReportRequest, bool) {
+	var req billingService.ReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return nil, false
+	}
+	return &req, true
+}
+
+// GetRevenueReport 收入报表（管理员）
+// GET /api/admin/billing/reports/revenue
+func GetRevenueReport(c *gin.Context) {
+	req, ok := bindReportRequest(c)
+	if !ok {
+		return
+	}
+
+	service := &billingService.ReportService{}
+	report, err := service.GetRevenueReport(req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	respondReport(c, "revenue", report)
+}
+
+// GetCreditsReport 积分发放与消耗报表（管理员）
+// GET /api/admin/billing/reports/credits
+func GetCreditsReport(c *gin.Context) {
+	req, ok := bindReportRequest(c)
+	if !ok {
+		return
+	}
+
+	service := &billingService.ReportService{}
+	report, err := service.GetCreditsReport(req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	respondReport(c, "credits", report)
+}
+
+// GetBreakageReport 积分沉淀（过期未使用）报表（管理员）
+// GET /api/admin/billing/reports/breakage
+func GetBreakageReport(c *gin.Context) {
+	req, ok := bindReportRequest(c)
+	if !ok {
+		return
+	}
+
+	service := &billingService.ReportService{}
+	report, err := service.GetBreakageReport(req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	respondReport(c, "breakage", report)
+}
+
+// GetTopConsumersReport 积分消耗排行报表（管理员）
+// GET /api/admin/billing/reports/top-consumers
+func GetTopConsumersReport(c *gin.Context) {
+	req, ok := bindReportRequest(c)
+	if !ok {
+		return
+	}
+
+	service := &billingService.ReportService{}
+	report, err := service.GetTopConsumersReport(req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	respondReport(c, "top_consumers", report)
+}
+
+// GetConversionReport 赠送→购买转化报表（管理员）
+// GET /api/admin/billing/reports/conversion
+func GetConversionReport(c *gin.Context) {
+	req, ok := bindReportRequest(c)
+	if !ok {
+		return
+	}
+
+	service := &billingService.ReportService{}
+	report, err := service.GetConversionReport(req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	respondReport(c, "conversion", report)
+}
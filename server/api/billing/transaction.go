@@ -0,0 +1,54 @@
+package billing
+
+import (
+	billingService "server/service/billing"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMyCreditTransactions 查询我的积分流水（用户）
+// GET /api/user/billing/transactions
+func ListMyCreditTransactions(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		utils.FailWithMessage("未登录", c)
+		return
+	}
+
+	var req billingService.TransactionQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+	// 用户只能查询自己的流水
+	req.UserID = userID
+
+	service := &billingService.TransactionService{}
+	result, err := service.ListTransactions(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// ListCreditTransactions 查询积分流水（管理员）
+// GET /api/admin/billing/transactions
+func ListCreditTransactions(c *gin.Context) {
+	var req billingService.TransactionQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	service := &billingService.TransactionService{}
+	result, err := service.ListTransactions(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
@@ -98,9 +98,16 @@ func GetMyCredits(c *gin.Context) {
 		return
 	}
 
+	entitlements, err := service.GetUserEntitlements(userID)
+	if err != nil {
+		utils.FailWithMessage("查询失败: "+err.Error(), c)
+		return
+	}
+
 	utils.OkWithData(gin.H{
 		"total_credits": totalCredits,
 		"user_id":       userID,
+		"entitlements":  entitlements,
 	}, c)
 }
 
@@ -1,6 +1,8 @@
 package interview
 
 import (
+	"errors"
+	billingService "server/service/billing"
 	"server/service/interview"
 	"server/utils"
 	"strconv"
@@ -149,6 +151,17 @@ func TriggerAnalysis(c *gin.Context) {
 	// 触发分析
 	review, err := interview.InterviewService.TriggerAnalysis(reviewID, userID)
 	if err != nil {
+		var insufficient *billingService.InsufficientCreditsError
+		if errors.As(err, &insufficient) {
+			utils.FailWithPaymentRequired(insufficient, err.Error(), c)
+			return
+		}
+		var exceeded *billingService.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			utils.SetHeaders(exceeded.Headers(), c)
+			utils.FailWithTooManyRequests(exceeded, err.Error(), c)
+			return
+		}
 		utils.FailWithMessage(err.Error(), c)
 		return
 	}
@@ -0,0 +1,57 @@
+package jobqueue
+
+import (
+	"errors"
+	"strconv"
+
+	jobqueueService "server/service/jobqueue"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJob 查询当前用户的任务进度和结果
+// GET /api/jobs/:id
+func GetJob(c *gin.Context) {
+	job, err := jobqueueService.JobQueueService.GetUserJob(c.Param("id"), c.GetString("userID"))
+	if err != nil {
+		if errors.Is(err, jobqueueService.ErrJobNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(job, c)
+}
+
+// ListJobs 查询当前用户的任务列表，可按 type 和 resource_id 筛选
+// GET /api/jobs
+func ListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	jobs, err := jobqueueService.JobQueueService.ListUserJobs(c.GetString("userID"), c.Query("type"), c.Query("resource_id"), page, pageSize)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(jobs, c)
+}
+
+// AdminListJobs 查询所有任务（管理员），可按 status 和 type 筛选
+// GET /api/admin/jobs
+func AdminListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	jobs, err := jobqueueService.JobQueueService.ListJobs(c.Query("status"), c.Query("type"), page, pageSize)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(jobs, c)
+}
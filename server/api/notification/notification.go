@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"strconv"
+
+	notificationService "server/service/notification"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotifications 查询我的通知
+// GET /api/user/notifications
+func ListNotifications(c *gin.Context) {
+	var req notificationService.NotificationQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+	req.UserID = c.GetString("userID")
+
+	result, err := notificationService.NotificationService.ListNotifications(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// MarkNotificationRead 标记通知为已读
+// POST /api/user/notifications/:id/read
+func MarkNotificationRead(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.FailWithMessage("无效的通知ID", c)
+		return
+	}
+
+	if err := notificationService.NotificationService.MarkRead(c.GetString("userID"), id); err != nil {
+		utils.FailWithMessage("标记已读失败", c)
+		return
+	}
+
+	utils.OkWithMessage("已标记为已读", c)
+}
+
+// MarkAllNotificationsRead 标记全部通知为已读
+// POST /api/user/notifications/read-all
+func MarkAllNotificationsRead(c *gin.Context) {
+	if err := notificationService.NotificationService.MarkRead(c.GetString("userID"), 0); err != nil {
+		utils.FailWithMessage("标记已读失败", c)
+		return
+	}
+
+	utils.OkWithMessage("已全部标记为已读", c)
+}
+
+// GetNotificationPreference 获取通知偏好
+// GET /api/user/notifications/preferences
+func GetNotificationPreference(c *gin.Context) {
+	pref, err := notificationService.NotificationService.GetPreference(c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(pref, c)
+}
+
+// UpdateNotificationPreference 更新通知偏好
+// PUT /api/user/notifications/preferences
+func UpdateNotificationPreference(c *gin.Context) {
+	var req notificationService.UpdatePreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	pref, err := notificationService.NotificationService.UpdatePreference(c.GetString("userID"), &req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(pref, "通知偏好已更新", c)
+}
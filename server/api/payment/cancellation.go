@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"strconv"
+
+	paymentService "server/service/payment"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewPackageCancellation 试算取消用户套餐的退款金额（管理员）
+// GET /api/admin/billing/user-packages/:id/cancellation-quote
+func PreviewPackageCancellation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的用户套餐ID", c)
+		return
+	}
+
+	quote, err := paymentService.CancellationService.PreviewCancellation(id)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(quote, c)
+}
+
+// CancelUserPackage 取消用户套餐并按比例退款（管理员）
+// POST /api/admin/billing/user-packages/:id/cancel
+func CancelUserPackage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的用户套餐ID", c)
+		return
+	}
+
+	var req paymentService.CancelPackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	cancellation, err := paymentService.CancellationService.CancelPackage(id, &req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage("取消套餐失败: "+err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(cancellation, "套餐已取消", c)
+}
+
+// ListPackageCancellations 查询套餐取消记录（管理员）
+// GET /api/admin/billing/package-cancellations
+func ListPackageCancellations(c *gin.Context) {
+	var req paymentService.CancellationQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	result, err := paymentService.CancellationService.ListCancellations(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
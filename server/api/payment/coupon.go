@@ -0,0 +1,150 @@
+package payment
+
+import (
+	"strconv"
+
+	paymentService "server/service/payment"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewCoupon 试算优惠券在套餐上的优惠
+// POST /api/coupons/preview
+func PreviewCoupon(c *gin.Context) {
+	var req paymentService.PreviewCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	quote, err := paymentService.CouponService.PreviewCoupon(c.GetString("userID"), &req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(quote, c)
+}
+
+// CreateCoupon 创建优惠券（管理员）
+// POST /api/admin/coupons
+func CreateCoupon(c *gin.Context) {
+	var req paymentService.CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	coupon, err := paymentService.CouponService.CreateCoupon(&req, c.GetString("userID"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(coupon, "优惠券创建成功", c)
+}
+
+// ListCoupons 查询优惠券（管理员）
+// GET /api/admin/coupons
+func ListCoupons(c *gin.Context) {
+	var req paymentService.CouponQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	result, err := paymentService.CouponService.ListCoupons(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// UpdateCoupon 更新优惠券（管理员）
+// PUT /api/admin/coupons/:id
+func UpdateCoupon(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的优惠券ID", c)
+		return
+	}
+
+	var req paymentService.CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	coupon, err := paymentService.CouponService.UpdateCoupon(id, &req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(coupon, "优惠券更新成功", c)
+}
+
+// DeleteCoupon 删除未被使用过的优惠券（管理员）
+// DELETE /api/admin/coupons/:id
+func DeleteCoupon(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的优惠券ID", c)
+		return
+	}
+
+	if err := paymentService.CouponService.DeleteCoupon(id); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("优惠券已删除", c)
+}
+
+// GetCouponStats 查询优惠券使用统计（管理员）
+// GET /api/admin/coupons/:id/stats
+func GetCouponStats(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.FailWithMessage("无效的优惠券ID", c)
+		return
+	}
+
+	stats, err := paymentService.CouponService.GetCouponStats(id)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(stats, c)
+}
+
+// ListCouponRedemptions 查询优惠券使用记录（管理员）
+// GET /api/admin/coupons/:id/redemptions
+// GET /api/admin/coupon-redemptions
+func ListCouponRedemptions(c *gin.Context) {
+	var req paymentService.CouponRedemptionQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			utils.FailWithMessage("无效的优惠券ID", c)
+			return
+		}
+		req.CouponID = id
+	}
+
+	result, err := paymentService.CouponService.ListRedemptions(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
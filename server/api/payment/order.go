@@ -0,0 +1,120 @@
+package payment
+
+import (
+	"io"
+	"net/http"
+
+	paymentService "server/service/payment"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateOrder 创建套餐购买订单
+// POST /api/orders
+func CreateOrder(c *gin.Context) {
+	var req paymentService.CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	result, err := paymentService.OrderService.CreateOrder(c.GetString("userID"), &req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(result, "订单创建成功", c)
+}
+
+// ListMyOrders 查询我的订单
+// GET /api/orders
+func ListMyOrders(c *gin.Context) {
+	var req paymentService.OrderQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+	// 用户只能查询自己的订单
+	req.UserID = c.GetString("userID")
+
+	result, err := paymentService.OrderService.ListOrders(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
+
+// GetOrder 查询订单详情
+// GET /api/orders/:id
+func GetOrder(c *gin.Context) {
+	order, err := paymentService.OrderService.GetOrder(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(order, c)
+}
+
+// CancelOrder 取消未支付的订单
+// POST /api/orders/:id/cancel
+func CancelOrder(c *gin.Context) {
+	if err := paymentService.OrderService.CancelOrder(c.GetString("userID"), c.Param("id")); err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("订单已取消", c)
+}
+
+// MockPayOrder 模拟支付订单（仅在启用模拟支付时可用）
+// POST /api/orders/:id/mock-pay
+func MockPayOrder(c *gin.Context) {
+	order, err := paymentService.OrderService.SimulateMockPayment(c.GetString("userID"), c.Param("id"))
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(order, "支付成功", c)
+}
+
+// PaymentWebhook 支付渠道回调
+// POST /api/payment/webhook/:provider
+func PaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": "读取回调报文失败"})
+		return
+	}
+
+	if err := paymentService.OrderService.HandleWebhook(c.Param("provider"), c.Request.Header, body); err != nil {
+		// 返回非2xx状态，渠道会稍后重新投递
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "msg": err.Error()})
+		return
+	}
+
+	utils.OkWithMessage("success", c)
+}
+
+// ListOrders 查询订单（管理员）
+// GET /api/admin/orders
+func ListOrders(c *gin.Context) {
+	var req paymentService.OrderQueryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		utils.FailWithMessage("请求参数错误: "+err.Error(), c)
+		return
+	}
+
+	result, err := paymentService.OrderService.ListOrders(&req)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(result, c)
+}
@@ -1,9 +1,12 @@
 package resume
 
 import (
+	"errors"
 	"net/http"
 
+	billingService "server/service/billing"
 	"server/service/pdfexport"
+	"server/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -37,6 +40,25 @@ func CreateExportTask(c *gin.Context) {
 	// 3. 调用服务层创建任务（传递简历数据快照）
 	taskID, err := pdfexport.CreateExportTask(userID, req.ResumeID, req.ResumeData)
 	if err != nil {
+		var insufficient *billingService.InsufficientCreditsError
+		if errors.As(err, &insufficient) {
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"code": 402,
+				"data": insufficient,
+				"msg":  err.Error(),
+			})
+			return
+		}
+		var exceeded *billingService.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			utils.SetHeaders(exceeded.Headers(), c)
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code": 429,
+				"data": exceeded,
+				"msg":  err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"code": 500,
 			"msg":  err.Error(),
@@ -44,7 +66,8 @@ func CreateExportTask(c *gin.Context) {
 		return
 	}
 
-	// 4. 返回任务ID
+	// 4. 返回任务ID，附带导出配额使用情况
+	utils.SetHeaders(billingService.GetActionQuotaStatus(userID, billingService.ActionPDFExport).Headers(), c)
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"data": gin.H{
@@ -116,30 +116,49 @@ func UploadResume(c *gin.Context) {
 	utils.OkWithData(response, c)
 }
 
+// ResumeFileToText 提交简历文件转文本任务，返回任务信息，通过 GET /api/jobs/:id 查询进度
 // POST /api/user/resumes/file_to_text/:id
 func ResumeFileToText(c *gin.Context) {
 	userID := c.GetString("userID")
 	resumeID := c.Param("id")
 
-	if err := resume.ResumeService.ResumeFileToText(userID, resumeID); err != nil {
+	job, err := resume.ResumeService.SubmitFileToText(userID, resumeID)
+	if err != nil {
 		utils.FailWithMessage(err.Error(), c)
 		return
 	}
 
-	utils.OkWithMessage("文本提取成功", c)
+	utils.OkWithDetailed(job, "文本提取任务已提交", c)
 }
 
+// StructureTextToJSON 提交简历文本结构化任务，返回任务信息，通过 GET /api/jobs/:id 查询进度
 // POST /api/user/resumes/structure_data/:id
 func StructureTextToJSON(c *gin.Context) {
 	userID := c.GetString("userID")
 	resumeID := c.Param("id")
 
-	if err := resume.ResumeService.StructureTextToJSON(userID, resumeID); err != nil {
+	job, err := resume.ResumeService.SubmitStructure(userID, resumeID)
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithDetailed(job, "文本结构化任务已提交", c)
+}
+
+// IngestResume 提交简历解析任务（提取文本并结构化），返回任务信息，通过 GET /api/jobs/:id 查询进度
+// POST /api/user/resumes/ingest/:id
+func IngestResume(c *gin.Context) {
+	userID := c.GetString("userID")
+	resumeID := c.Param("id")
+
+	job, err := resume.ResumeService.SubmitIngest(userID, resumeID)
+	if err != nil {
 		utils.FailWithMessage(err.Error(), c)
 		return
 	}
 
-	utils.OkWithMessage("文本结构化成功", c)
+	utils.OkWithDetailed(job, "简历解析任务已提交", c)
 }
 
 // CreateTextResume 创建纯文本简历
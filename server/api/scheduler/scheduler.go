@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"errors"
+
+	schedulerService "server/service/scheduler"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListScheduledJobs 获取定时任务列表（管理员）
+// GET /api/admin/scheduled-jobs
+func ListScheduledJobs(c *gin.Context) {
+	jobs, err := schedulerService.SchedulerService.ListJobs()
+	if err != nil {
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithData(jobs, c)
+}
+
+// TriggerScheduledJob 立即执行定时任务（管理员）
+// POST /api/admin/scheduled-jobs/:name/trigger
+func TriggerScheduledJob(c *gin.Context) {
+	if err := schedulerService.SchedulerService.TriggerJob(c.Param("name")); err != nil {
+		if errors.Is(err, schedulerService.ErrJobNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("任务已触发", c)
+}
+
+// PauseScheduledJob 暂停定时任务（管理员）
+// POST /api/admin/scheduled-jobs/:name/pause
+func PauseScheduledJob(c *gin.Context) {
+	if err := schedulerService.SchedulerService.PauseJob(c.Param("name")); err != nil {
+		if errors.Is(err, schedulerService.ErrJobNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("任务已暂停", c)
+}
+
+// ResumeScheduledJob 恢复定时任务（管理员）
+// POST /api/admin/scheduled-jobs/:name/resume
+func ResumeScheduledJob(c *gin.Context) {
+	if err := schedulerService.SchedulerService.ResumeJob(c.Param("name")); err != nil {
+		if errors.Is(err, schedulerService.ErrJobNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("任务已恢复", c)
+}
@@ -0,0 +1,37 @@
+package upstream
+
+import (
+	"errors"
+
+	upstreamService "server/service/upstream"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBreakers 查询当前实例各上游的熔断器状态（管理员）
+// GET /api/admin/upstream/breakers
+func ListBreakers(c *gin.Context) {
+	utils.OkWithData(upstreamService.ListBreakers(), c)
+}
+
+// ResetBreaker 手动恢复熔断器（管理员）
+// POST /api/admin/upstream/breakers/reset
+func ResetBreaker(c *gin.Context) {
+	var req upstreamService.ResetBreakerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.FailWithMessage("请求参数错误", c)
+		return
+	}
+
+	if err := upstreamService.ResetBreaker(req.Key); err != nil {
+		if errors.Is(err, upstreamService.ErrBreakerNotFound) {
+			utils.FailWithNotFound(err.Error(), c)
+			return
+		}
+		utils.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	utils.OkWithMessage("熔断器已恢复", c)
+}
@@ -115,6 +115,48 @@ type PdfExportConfig struct {
 	RenderBaseURL  string `mapstructure:"render_base_url" json:"render_base_url" yaml:"render_base_url"`    // 前端渲染页面基础URL
 }
 
+type PaymentConfig struct {
+	DefaultProvider    string `mapstructure:"default_provider" json:"default_provider" yaml:"default_provider"`             // 默认支付渠道
+	OrderExpireMinutes int    `mapstructure:"order_expire_minutes" json:"order_expire_minutes" yaml:"order_expire_minutes"` // 未支付订单自动取消时间（分钟）
+	Mock               struct {
+		Enabled bool   `mapstructure:"enabled" json:"enabled" yaml:"enabled"` // 是否启用模拟支付（仅用于本地测试）
+		Secret  string `mapstructure:"secret" json:"secret" yaml:"secret"`    // 模拟支付回调签名密钥
+	} `mapstructure:"mock" json:"mock" yaml:"mock"`
+}
+
+// MasterKey 应用主密钥，用于加密敏感字段的数据密钥
+type MasterKey struct {
+	ID  string `mapstructure:"id" json:"id" yaml:"id"`    // 密钥ID，记录在密文中，不能包含冒号
+	Key string `mapstructure:"key" json:"key" yaml:"key"` // base64编码的32字节AES-256密钥
+}
+
+// SecretEncryptionConfig 敏感字段（如工作流API密钥）加密配置
+// 轮换时新增一个主密钥并设为 active_key_id，保留旧密钥直到所有数据重新加密完成
+type SecretEncryptionConfig struct {
+	ActiveKeyID string      `mapstructure:"active_key_id" json:"active_key_id" yaml:"active_key_id"` // 新数据使用的主密钥ID
+	Keys        []MasterKey `mapstructure:"keys" json:"keys" yaml:"keys"`                            // 所有可用于解密的主密钥
+}
+
+// JobQueueConfig 异步任务队列配置
+type JobQueueConfig struct {
+	Workers             int `mapstructure:"workers" json:"workers" yaml:"workers"`                                        // 每个实例同时执行的任务数，默认8
+	WorkflowConcurrency int `mapstructure:"workflow_concurrency" json:"workflow_concurrency" yaml:"workflow_concurrency"` // 单个工作流默认的最大并发任务数（所有实例合计），默认4，可在工作流上单独设置
+}
+
+// UpstreamConfig 上游服务（Dify、ASR）调用的重试与熔断配置
+type UpstreamConfig struct {
+	MaxAttempts            int `mapstructure:"max_attempts" json:"max_attempts" yaml:"max_attempts"`                                     // 最大尝试次数（含首次），默认3
+	BaseDelayMs            int `mapstructure:"base_delay_ms" json:"base_delay_ms" yaml:"base_delay_ms"`                                  // 首次重试前的等待时间（毫秒），之后按指数增长，默认500
+	MaxDelayMs             int `mapstructure:"max_delay_ms" json:"max_delay_ms" yaml:"max_delay_ms"`                                     // 单次重试等待上限（毫秒），默认5000
+	BreakerThreshold       int `mapstructure:"breaker_threshold" json:"breaker_threshold" yaml:"breaker_threshold"`                      // 连续失败多少次后熔断，默认5
+	BreakerCooldownSeconds int `mapstructure:"breaker_cooldown_seconds" json:"breaker_cooldown_seconds" yaml:"breaker_cooldown_seconds"` // 熔断后多久放行试探请求（秒），默认30
+}
+
+// WorkflowCacheConfig 工作流响应缓存配置
+type WorkflowCacheConfig struct {
+	DefaultTTLHours int `mapstructure:"default_ttl_hours" json:"default_ttl_hours" yaml:"default_ttl_hours"` // 工作流未单独设置时的缓存有效期（小时），默认24
+}
+
 type Config struct {
 	Server    Server          `mapstructure:"server" json:"server" yaml:"server"`
 	CORS      CORS            `mapstructure:"cors" json:"cors" yaml:"cors"`
@@ -128,4 +170,10 @@ type Config struct {
 	TOS       TOSConfig       `mapstructure:"tos" json:"tos" yaml:"tos"`
 	ASR       ASRConfig       `mapstructure:"asr" json:"asr" yaml:"asr"`
 	PdfExport PdfExportConfig `mapstructure:"pdf_export" json:"pdf_export" yaml:"pdf_export"`
+	Payment   PaymentConfig   `mapstructure:"payment" json:"payment" yaml:"payment"`
+
+	SecretEncryption SecretEncryptionConfig `mapstructure:"secret_encryption" json:"secret_encryption" yaml:"secret_encryption"`
+	JobQueue         JobQueueConfig         `mapstructure:"job_queue" json:"job_queue" yaml:"job_queue"`
+	Upstream         UpstreamConfig         `mapstructure:"upstream" json:"upstream" yaml:"upstream"`
+	WorkflowCache    WorkflowCacheConfig    `mapstructure:"workflow_cache" json:"workflow_cache" yaml:"workflow_cache"`
 }
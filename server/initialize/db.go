@@ -61,16 +61,37 @@ func InitDB() {
 		&model.Conversation{},
 		&model.ChatMessage{},
 		&model.Workflow{},
+		&model.WorkflowVersion{},
 		&model.ResumeRecord{},
 		&model.WorkflowExecution{},
+		&model.WorkflowJob{},
+		&model.WorkflowCacheEntry{},
+		&model.WorkflowPipeline{},
+		&model.WorkflowPipelineRun{},
 		&model.File{},
 		&model.InvitationCode{},
 		&model.InvitationUse{},
 		&model.SiteVariable{},
 		&model.EventLog{},
+		&model.APIClient{},
+		&model.APIClientNonce{},
+		&model.ScheduledJob{},
 		&model.BillingActionPrice{},
+		&model.BillingActionPriceVersion{},
 		&model.BillingPackage{},
 		&model.UserBillingPackage{},
+		&model.CreditTransaction{},
+		&model.CreditDeduction{},
+		&model.CreditAdjustment{},
+		&model.ActionQuota{},
+		&model.ActionQuotaOverride{},
+		&model.ActionUsage{},
+		&model.PackageCancellation{},
+		&model.Notification{},
+		&model.NotificationPreference{},
+		&model.Order{},
+		&model.Coupon{},
+		&model.CouponRedemption{},
 		&model.TOSUpload{},
 		&model.ASRTask{},
 		&model.PdfExportTask{},
@@ -0,0 +1,46 @@
+package initialize
+
+import (
+	"fmt"
+
+	"server/service/app"
+	"server/service/interview"
+	"server/service/jobqueue"
+	"server/service/resume"
+)
+
+// InitJobQueue 注册异步任务类型并启动工作协程池
+func InitJobQueue() {
+	jobqueue.JobQueueService.Register(&jobqueue.JobType{
+		Name:    app.JobTypeWorkflow,
+		Handler: app.AppService.RunWorkflowJob,
+	})
+
+	jobqueue.JobQueueService.Register(&jobqueue.JobType{
+		Name:    resume.JobTypeFileToText,
+		Handler: resume.ResumeService.RunFileToTextJob,
+	})
+
+	jobqueue.JobQueueService.Register(&jobqueue.JobType{
+		Name:    resume.JobTypeStructure,
+		Handler: resume.ResumeService.RunStructureJob,
+	})
+
+	jobqueue.JobQueueService.Register(&jobqueue.JobType{
+		Name:    resume.JobTypeIngest,
+		Handler: resume.ResumeService.RunIngestJob,
+	})
+
+	jobqueue.JobQueueService.Register(&jobqueue.JobType{
+		Name:     interview.JobTypeAnalysis,
+		Timeout:  interview.AnalysisJobTimeout,
+		Handler:  interview.InterviewService.RunAnalysisJob,
+		OnFailed: interview.InterviewService.OnAnalysisFailed,
+	})
+
+	if err := jobqueue.JobQueueService.Start(); err != nil {
+		fmt.Printf("Warning: Failed to start job queue: %v\n", err)
+		return
+	}
+	fmt.Println("异步任务队列启动成功")
+}
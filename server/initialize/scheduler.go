@@ -0,0 +1,140 @@
+package initialize
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"server/service/apiclient"
+	"server/service/app"
+	"server/service/asr"
+	"server/service/billing"
+	"server/service/jobqueue"
+	"server/service/notification"
+	"server/service/payment"
+	"server/service/pdfexport"
+	"server/service/scheduler"
+)
+
+// pdfRetentionDays 导出的PDF文件保留天数
+const pdfRetentionDays = 7
+
+// InitScheduler 注册定时任务并启动调度器
+func InitScheduler() {
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "billing_clean_expired_packages",
+		Description: "将已过期的用户套餐标记为过期并记录积分流水",
+		CronExpr:    "*/10 * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			service := &billing.UserPackageService{}
+			count, err := service.CleanExpiredBillingPackages()
+			return fmt.Sprintf("已过期 %d 个套餐", count), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "billing_sweep_expired_holds",
+		Description: "释放超时未结算的积分预扣",
+		CronExpr:    "* * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			service := &billing.HoldService{}
+			released, err := service.SweepExpiredHolds()
+			return fmt.Sprintf("已释放 %d 个超时预扣", released), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "billing_clean_action_usages",
+		Description: "删除超过最长配额窗口的动作使用记录",
+		CronExpr:    "15 4 * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			service := &billing.QuotaService{}
+			removed, err := service.CleanActionUsages()
+			return fmt.Sprintf("已删除 %d 条使用记录", removed), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "pdf_clean_old_files",
+		Description: fmt.Sprintf("删除 uploads/pdf 下超过 %d 天的PDF文件", pdfRetentionDays),
+		CronExpr:    "30 3 * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			removed, err := pdfexport.CleanOldPdfFiles(pdfRetentionDays)
+			return fmt.Sprintf("已删除 %d 个日期目录", removed), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "asr_repoll_stuck_tasks",
+		Description: "重新轮询超过10分钟未更新的ASR识别任务",
+		CronExpr:    "*/5 * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			polled, err := asr.RepollStuckTasks(ctx, 10*time.Minute)
+			return fmt.Sprintf("已轮询 %d 个任务", polled), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "payment_order_maintenance",
+		Description: "取消超时未支付的订单，并为已支付但未履约的订单补发套餐",
+		CronExpr:    "*/5 * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			cancelled, err := payment.OrderService.CancelExpiredOrders()
+			if err != nil {
+				return "", err
+			}
+			fulfilled, err := payment.OrderService.FulfillPaidOrders()
+			return fmt.Sprintf("已取消 %d 个订单，补偿履约 %d 个订单", cancelled, fulfilled), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "billing_notifications",
+		Description: "发送套餐即将到期与积分余额不足提醒",
+		CronExpr:    "0 * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			expiring, err := notification.NotificationService.SendPackageExpiryNotices()
+			if err != nil {
+				return "", err
+			}
+			lowBalance, err := notification.NotificationService.SendLowBalanceNotices()
+			return fmt.Sprintf("发送到期提醒 %d 条，余额不足提醒 %d 条", expiring, lowBalance), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "jobqueue_recover_stale_jobs",
+		Description: "重新排队心跳超时（执行实例已退出）的异步任务，超过最大执行次数的标记为失败",
+		CronExpr:    "* * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			requeued, failed, err := jobqueue.JobQueueService.RecoverStaleJobs()
+			return fmt.Sprintf("重新排队 %d 个任务，失败 %d 个任务", requeued, failed), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "workflow_clean_expired_cache",
+		Description: "删除已过期的工作流响应缓存",
+		CronExpr:    "45 * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			removed, err := app.CleanExpiredWorkflowCache()
+			return fmt.Sprintf("已删除 %d 条缓存", removed), err
+		},
+	})
+
+	scheduler.SchedulerService.Register(&scheduler.Job{
+		Name:        "apiclient_clean_expired_nonces",
+		Description: "删除已过期的服务间请求nonce记录",
+		CronExpr:    "*/10 * * * *",
+		Handler: func(ctx context.Context) (string, error) {
+			removed, err := apiclient.CleanExpiredNonces()
+			return fmt.Sprintf("已删除 %d 条nonce记录", removed), err
+		},
+	})
+
+	if err := scheduler.SchedulerService.Start(); err != nil {
+		fmt.Printf("Warning: Failed to start scheduler: %v\n", err)
+		return
+	}
+	fmt.Println("定时任务调度器启动成功")
+}
@@ -2,9 +2,11 @@ package initialize
 
 import (
 	"fmt"
+
 	"server/global"
 	"server/service/asr"
 	"server/service/eventlog"
+	"server/service/payment"
 	"server/service/tos"
 )
 
@@ -34,4 +36,14 @@ func InitServices() {
 		global.ASRService = asrService
 		fmt.Println("ASR服务初始化成功")
 	}
+
+	// 注册支付渠道
+	if global.CONFIG.Payment.Mock.Enabled {
+		if global.CONFIG.Payment.Mock.Secret == "" {
+			fmt.Println("Warning: 模拟支付已启用但未配置签名密钥，已跳过")
+		} else {
+			payment.RegisterProvider(&payment.MockProvider{Secret: global.CONFIG.Payment.Mock.Secret})
+			fmt.Println("模拟支付渠道已启用（仅用于测试）")
+		}
+	}
 }
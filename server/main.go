@@ -26,6 +26,12 @@ func main() {
 	// 初始化全局服务
 	initialize.InitServices()
 
+	// 启动异步任务队列
+	initialize.InitJobQueue()
+
+	// 启动定时任务
+	initialize.InitScheduler()
+
 	// 初始化路由
 	r := initialize.InitRouter()
 
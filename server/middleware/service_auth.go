@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"server/service/apiclient"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSignedBodySize 签名请求体大小上限
+const maxSignedBodySize = 1 << 20
+
+// ServiceAuth 服务间调用鉴权中间件
+// 校验请求的HMAC签名、时间戳和nonce，并要求客户端拥有指定的授权范围
+func ServiceAuth(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodySize))
+		if err != nil {
+			utils.FailWithMessage("读取请求体失败", c)
+			c.Abort()
+			return
+		}
+		// 恢复请求体，供后续处理函数绑定参数
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		req := &apiclient.VerifyRequest{
+			AccessKey: c.GetHeader(apiclient.HeaderAccessKey),
+			Timestamp: c.GetHeader(apiclient.HeaderTimestamp),
+			Nonce:     c.GetHeader(apiclient.HeaderNonce),
+			Signature: c.GetHeader(apiclient.HeaderSignature),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.RequestURI(),
+			Body:      body,
+			Scope:     scope,
+		}
+
+		client, err := apiclient.APIClientService.Verify(req)
+		if err != nil {
+			apiclient.APIClientService.LogRejected(req, client, c.ClientIP(), c.Request.UserAgent(), err.Error())
+			if errors.Is(err, apiclient.ErrScopeDenied) {
+				utils.FailWithForbidden(err.Error(), c)
+			} else {
+				utils.FailWithUnauthorized(err.Error(), c)
+			}
+			c.Abort()
+			return
+		}
+
+		// 将客户端信息存入上下文
+		c.Set("apiClientID", client.ID)
+		c.Set("apiClientName", client.Name)
+		c.Next()
+	}
+}
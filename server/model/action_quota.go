@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+)
+
+// ActionQuota 动作使用配额规则表（合理使用限制，与积分无关）
+// 按套餐档位配置，BillingPackageID 为0表示默认规则，适用于没有匹配档位规则的用户
+// 各窗口上限为0表示不限，窗口按滑动时间计算（分钟=60秒，天=24小时，月=30天）
+type ActionQuota struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	BillingPackageID int64  `gorm:"not null;default:0;uniqueIndex:idx_action_quotas_tier" json:"billing_package_id"`
+	ActionKey        string `gorm:"size:50;not null;uniqueIndex:idx_action_quotas_tier" json:"action_key"`
+
+	PerMinute int `gorm:"not null;default:0" json:"per_minute"`
+	PerDay    int `gorm:"not null;default:0" json:"per_day"`
+	PerMonth  int `gorm:"not null;default:0" json:"per_month"`
+
+	Notes string `gorm:"type:text" json:"notes"`
+}
+
+// TableName 设置表名
+func (ActionQuota) TableName() string {
+	return "action_quotas"
+}
+
+// ActionQuotaOverride 用户动作配额覆盖表，由管理员为单个用户设置，生效期间取代套餐档位规则
+type ActionQuotaOverride struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID    string `gorm:"size:20;not null;uniqueIndex:idx_action_quota_overrides_user" json:"user_id"`
+	ActionKey string `gorm:"size:50;not null;uniqueIndex:idx_action_quota_overrides_user" json:"action_key"`
+
+	PerMinute int `gorm:"not null;default:0" json:"per_minute"`
+	PerDay    int `gorm:"not null;default:0" json:"per_day"`
+	PerMonth  int `gorm:"not null;default:0" json:"per_month"`
+
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 为空表示长期有效
+	OperatorID string     `gorm:"size:20;not null" json:"operator_id"`
+	Notes      string     `gorm:"type:text" json:"notes"`
+}
+
+// TableName 设置表名
+func (ActionQuotaOverride) TableName() string {
+	return "action_quota_overrides"
+}
+
+// ActionUsage 动作使用记录表，用于滑动窗口配额计数，超过最长窗口的记录由定时任务清理
+type ActionUsage struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_action_usages_user_action,priority:3;index:idx_action_usages_created" json:"created_at"`
+
+	UserID    string `gorm:"size:20;not null;index:idx_action_usages_user_action,priority:1" json:"user_id"`
+	ActionKey string `gorm:"size:50;not null;index:idx_action_usages_user_action,priority:2" json:"action_key"`
+}
+
+// TableName 设置表名
+func (ActionUsage) TableName() string {
+	return "action_usages"
+}
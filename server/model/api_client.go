@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// APIClient 服务间调用的API客户端（如PDF导出服务、Dify工具）
+// 客户端使用 AccessKey + Secret 对请求进行HMAC签名，仅能访问授权范围（Scopes）内的内部接口
+type APIClient struct {
+	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement;comment:主键ID"`
+	CreatedAt time.Time `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"comment:更新时间"`
+
+	Name        string `json:"name" gorm:"type:varchar(100);uniqueIndex:idx_api_clients_name;not null;comment:客户端名称"`
+	Description string `json:"description" gorm:"type:varchar(500);default:'';comment:客户端描述"`
+	AccessKey   string `json:"access_key" gorm:"type:varchar(64);uniqueIndex:idx_api_clients_access_key;not null;comment:访问标识"`
+	Secret      string `json:"-" gorm:"type:text;not null;comment:签名密钥"` // 信封加密存储（utils.EncryptSecret），历史明文在首次校验通过时迁移
+	Scopes      JSON   `json:"scopes" gorm:"type:jsonb;comment:授权范围列表"`
+	Enabled     bool   `json:"enabled" gorm:"default:true;comment:是否启用"`
+
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"comment:最近一次成功调用时间"`
+	CreatedBy  string     `json:"created_by" gorm:"type:varchar(20);comment:创建人"`
+}
+
+// TableName 指定表名
+func (APIClient) TableName() string {
+	return "api_clients"
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// APIClientNonce 服务间请求已使用的nonce，用于多实例间共享的重放检查
+// 同一客户端的nonce在有效期内只能使用一次，过期记录由定时任务清理
+type APIClientNonce struct {
+	AccessKey string    `json:"access_key" gorm:"primaryKey;type:varchar(64);comment:访问标识"`
+	Nonce     string    `json:"nonce" gorm:"primaryKey;type:varchar(64);comment:请求nonce"`
+	CreatedAt time.Time `json:"created_at" gorm:"comment:创建时间"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null;comment:过期时间，过期后可清理"`
+}
+
+// TableName 指定表名
+func (APIClientNonce) TableName() string {
+	return "api_client_nonces"
+}
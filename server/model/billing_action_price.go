@@ -16,10 +16,27 @@ type BillingActionPrice struct {
 
 	CreditsCost int `gorm:"not null;default:1" json:"credits_cost"`
 
+	// 按量计费（可选）：实际积分 = CreditsCost + 每千token积分 × token数 / 1000（向上取整），不超过单次上限
+	PricingMode        string `gorm:"size:20;not null;default:'fixed'" json:"pricing_mode"` // fixed/metered
+	CreditsPer1KTokens int    `gorm:"default:0" json:"credits_per_1k_tokens"`
+	MaxCreditsPerCall  int    `gorm:"default:0" json:"max_credits_per_call"` // 按量计费时的单次上限，执行前按此预扣
+
 	IsActive  bool `gorm:"default:true" json:"is_active"`
 	SortOrder int  `gorm:"default:0" json:"sort_order"`
 
 	Metadata JSON `gorm:"type:jsonb" json:"metadata,omitempty"`
+
+	// 以下字段由价格版本计算得出，不落库
+	PriceVersionID *int64             `gorm:"-" json:"price_version_id,omitempty"` // 当前生效的价格版本，为空表示使用基础价格
+	UpcomingChange *ActionPriceChange `gorm:"-" json:"upcoming_change,omitempty"`  // 即将发生的价格变动
+}
+
+// ActionPriceChange 动作价格变动预告
+type ActionPriceChange struct {
+	CreditsCost    int       `json:"credits_cost"`
+	EffectiveAt    time.Time `json:"effective_at"`
+	PriceVersionID *int64    `json:"price_version_id,omitempty"` // 为空表示恢复基础价格
+	Notes          string    `json:"notes,omitempty"`
 }
 
 // TableName 设置表名
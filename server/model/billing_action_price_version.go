@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// BillingActionPriceVersion 动作价格版本表
+// 同一动作可预先排期多个价格版本（调价、促销、限时折扣），某一时刻生效的是
+// EffectiveFrom 最晚且尚未到 EffectiveTo 的版本；没有生效版本时使用动作的基础价格
+type BillingActionPriceVersion struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ActionKey   string `gorm:"size:50;not null;index:idx_action_price_versions_effective" json:"action_key"`
+	CreditsCost int    `gorm:"not null" json:"credits_cost"`
+
+	EffectiveFrom time.Time  `gorm:"not null;index:idx_action_price_versions_effective" json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"` // 为空表示长期有效
+
+	Notes     string `gorm:"type:text" json:"notes,omitempty"` // 调价说明，如"春节限时折扣"
+	CreatedBy string `gorm:"size:20" json:"created_by,omitempty"`
+}
+
+// TableName 设置表名
+func (BillingActionPriceVersion) TableName() string {
+	return "billing_action_price_versions"
+}
+
+// ActiveAt 判断版本在指定时刻是否生效
+func (v *BillingActionPriceVersion) ActiveAt(t time.Time) bool {
+	if v.EffectiveFrom.After(t) {
+		return false
+	}
+	return v.EffectiveTo == nil || v.EffectiveTo.After(t)
+}
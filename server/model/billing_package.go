@@ -20,6 +20,9 @@ type BillingPackage struct {
 	CreditsAmount int `gorm:"not null" json:"credits_amount"`
 	ValidityDays  int `gorm:"default:0" json:"validity_days"` // 0=permanent
 
+	CoveredActions   JSON `gorm:"type:jsonb" json:"covered_actions,omitempty"` // 不限次使用的动作key列表（时长型/混合型/永久型）
+	DailyActionLimit int  `gorm:"default:0" json:"daily_action_limit"`         // 每个动作每日合理使用上限，0=不限
+
 	IsActive     bool `gorm:"default:true" json:"is_active"`
 	IsVisible    bool `gorm:"default:true" json:"is_visible"`
 	SortOrder    int  `gorm:"default:0" json:"sort_order"`
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+)
+
+// Coupon 优惠券表
+// 下单时使用，可按比例或固定金额减免订单金额，并可在履约时额外赠送积分
+type Coupon struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Code        string `gorm:"size:32;not null;uniqueIndex:idx_coupons_code" json:"code"` // 优惠码（大写）
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Description string `gorm:"type:text" json:"description,omitempty"`
+
+	DiscountType  string `gorm:"size:20;not null" json:"discount_type"`    // percent/fixed/none
+	DiscountValue int64  `gorm:"not null;default:0" json:"discount_value"` // percent 为折扣百分比（1-100），fixed 为减免金额（分）
+	MaxDiscount   int64  `gorm:"not null;default:0" json:"max_discount"`   // 百分比折扣的最高减免金额（分），0=不限
+	MinAmount     int64  `gorm:"not null;default:0" json:"min_amount"`     // 订单最低金额（分），0=不限
+	BonusCredits  int    `gorm:"not null;default:0" json:"bonus_credits"`  // 履约时额外赠送的积分
+	PackageIDs    JSON   `gorm:"type:jsonb" json:"package_ids,omitempty"`  // 限定可用的套餐ID列表，为空表示全部套餐
+
+	MaxRedemptions int `gorm:"not null;default:0" json:"max_redemptions"` // 总使用次数上限，0=不限
+	PerUserLimit   int `gorm:"not null;default:1" json:"per_user_limit"`  // 每个用户使用次数上限，0=不限
+	RedeemedCount  int `gorm:"not null;default:0" json:"redeemed_count"`  // 已占用次数（待支付与已使用的订单）
+
+	StartsAt *time.Time `json:"starts_at,omitempty"` // 为空表示立即生效
+	EndsAt   *time.Time `json:"ends_at,omitempty"`   // 为空表示长期有效
+
+	IsActive  bool   `gorm:"not null;default:true" json:"is_active"`
+	CreatedBy string `gorm:"size:20" json:"created_by,omitempty"`
+}
+
+// TableName 设置表名
+func (Coupon) TableName() string {
+	return "coupons"
+}
+
+// Coupon 折扣类型
+const (
+	CouponDiscountPercent = "percent" // 按比例减免
+	CouponDiscountFixed   = "fixed"   // 固定金额减免
+	CouponDiscountNone    = "none"    // 不减免，仅赠送积分
+)
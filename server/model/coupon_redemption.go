@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+// CouponRedemption 优惠券使用记录表
+// 下单时创建（pending），订单支付后变为 redeemed，订单取消或超时后变为 released 并归还使用次数
+type CouponRedemption struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_coupon_redemptions_created" json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	CouponID int64  `gorm:"not null;index:idx_coupon_redemptions_coupon_user" json:"coupon_id"`
+	Code     string `gorm:"size:32;not null" json:"code"`
+	UserID   string `gorm:"size:20;not null;index:idx_coupon_redemptions_coupon_user;index:idx_coupon_redemptions_user" json:"user_id"`
+	OrderID  string `gorm:"size:20;not null;uniqueIndex:idx_coupon_redemptions_order" json:"order_id"` // 每个订单只能使用一张优惠券
+
+	OriginalAmount int64 `gorm:"not null" json:"original_amount"` // 订单原价（分）
+	DiscountAmount int64 `gorm:"not null" json:"discount_amount"` // 减免金额（分）
+	BonusCredits   int   `gorm:"not null" json:"bonus_credits"`   // 赠送积分
+
+	Status     string     `gorm:"size:20;not null;index:idx_coupon_redemptions_status" json:"status"` // pending/redeemed/released
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// TableName 设置表名
+func (CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
+
+// CouponRedemption 状态
+const (
+	CouponRedemptionPending  = "pending"
+	CouponRedemptionRedeemed = "redeemed"
+	CouponRedemptionReleased = "released"
+)
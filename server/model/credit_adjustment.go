@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// CreditAdjustment 管理员积分调整记录表（只追加，不修改）
+// 发放时生成一个系统来源的用户套餐，扣回时按扣减优先级从用户现有套餐中扣除，对应积分流水类型为 adjust
+type CreditAdjustment struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_credit_adjustments_created" json:"created_at"`
+
+	UserID     string `gorm:"size:20;not null;index:idx_credit_adjustments_user" json:"user_id"`
+	OperatorID string `gorm:"size:20;not null;index:idx_credit_adjustments_operator" json:"operator_id"` // 操作管理员
+
+	Credits      int `gorm:"not null" json:"credits"`       // 调整积分（发放为正，扣回为负）
+	BalanceAfter int `gorm:"not null" json:"balance_after"` // 调整后用户可用总积分
+
+	ReasonCode string `gorm:"size:30;not null;index:idx_credit_adjustments_reason" json:"reason_code"` // 调整原因代码
+	Notes      string `gorm:"type:text;not null" json:"notes"`                                         // 调整说明
+
+	UserBillingPackageID *int64     `json:"user_billing_package_id,omitempty"` // 发放时生成的系统套餐
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`              // 发放积分的过期时间，为空表示永久有效
+}
+
+// TableName 设置表名
+func (CreditAdjustment) TableName() string {
+	return "credit_adjustments"
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+// CreditDeduction 积分扣减记录表
+// 一次扣减可能跨多个用户套餐，对应的多条积分流水通过 DeductionID 关联到该记录
+type CreditDeduction struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID         string  `gorm:"size:20;not null;index;uniqueIndex:idx_credit_deductions_idempotency" json:"user_id"`
+	IdempotencyKey *string `gorm:"size:100;uniqueIndex:idx_credit_deductions_idempotency" json:"idempotency_key,omitempty"` // 幂等键（同一用户下唯一）
+
+	ActionKey    string `gorm:"size:50;not null" json:"action_key"`
+	ResourceType string `gorm:"size:50" json:"resource_type,omitempty"`
+	ResourceID   string `gorm:"size:50" json:"resource_id,omitempty"`
+
+	Credits int    `gorm:"not null" json:"credits"`                        // 扣减积分总数
+	Status  string `gorm:"size:20;default:'deducted';index" json:"status"` // reserved/deducted/released/refunded
+
+	EntitlementPackageID *int64 `gorm:"index" json:"entitlement_package_id,omitempty"` // 由套餐权益覆盖时的用户套餐ID（此时积分为0）
+	PriceVersionID       *int64 `gorm:"index" json:"price_version_id,omitempty"`       // 扣减时生效的价格版本，为空表示按基础价格
+	TotalTokens          int    `gorm:"default:0" json:"total_tokens,omitempty"`       // 按量计费时结算所用的token数
+
+	HoldExpiresAt *time.Time `gorm:"index" json:"hold_expires_at,omitempty"` // 预扣过期时间（仅预扣）
+	SettledAt     *time.Time `json:"settled_at,omitempty"`                   // 预扣结算或释放时间
+
+	RefundedAt   *time.Time `json:"refunded_at,omitempty"`
+	RefundReason string     `gorm:"type:text" json:"refund_reason,omitempty"`
+}
+
+// TableName 设置表名
+func (CreditDeduction) TableName() string {
+	return "credit_deductions"
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// CreditTransaction 积分流水表（只追加，不修改）
+// 每一次积分变动（发放/扣减/退还/过期/调整）都会针对具体的用户套餐记录一行
+type CreditTransaction struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_credit_transactions_created" json:"created_at"`
+
+	UserID               string `gorm:"size:20;not null;index:idx_credit_transactions_user" json:"user_id"`
+	UserBillingPackageID int64  `gorm:"not null;index:idx_credit_transactions_package" json:"user_billing_package_id"`
+
+	Type         string `gorm:"size:20;not null;index:idx_credit_transactions_type" json:"type"` // grant/deduct/refund/expire/adjust
+	Credits      int    `gorm:"not null" json:"credits"`                                         // 变动积分（增加为正，减少为负）
+	BalanceAfter int    `gorm:"not null" json:"balance_after"`                                   // 变动后用户可用总积分
+
+	DeductionID *int64 `gorm:"index:idx_credit_transactions_deduction" json:"deduction_id,omitempty"` // 关联的扣减记录（deduct/refund）
+
+	ActionKey    string `gorm:"size:50;index:idx_credit_transactions_action" json:"action_key,omitempty"`
+	ResourceType string `gorm:"size:50" json:"resource_type,omitempty"`
+	ResourceID   string `gorm:"size:50" json:"resource_id,omitempty"`
+
+	OperatorID string `gorm:"size:20" json:"operator_id,omitempty"` // 操作人（管理员操作时记录）
+	Notes      string `gorm:"type:text" json:"notes,omitempty"`
+
+	Metadata JSON `gorm:"type:jsonb" json:"metadata,omitempty"`
+}
+
+// TableName 设置表名
+func (CreditTransaction) TableName() string {
+	return "credit_transactions"
+}
@@ -18,6 +18,7 @@ type InterviewReview struct {
 	//   "workflow_id": "workflow_xxx",        // 工作流ID，从site_variables获取
 	//   "status": "pending|transcribing|analyzing|completed|failed", // 处理状态
 	//   "asr_result": {...},                  // 缓存的ASR识别结果
+	//   "analysis_job_id": "job_xxx",         // 最近一次分析的异步任务ID（workflow_jobs.id）
 	//   "error_message": "错误信息"            // 失败时的错误详情（可选）
 	// }
 	Metadata JSON `gorm:"type:jsonb;not null;comment:工作流状态和引用信息" json:"metadata"`
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+// Notification 站内通知表
+// DedupKey 唯一，保证同一事件（如某个套餐的到期提醒）只通知一次
+type Notification struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_notifications_user" json:"created_at"`
+
+	UserID   string `gorm:"size:20;not null;index:idx_notifications_user" json:"user_id"`
+	Type     string `gorm:"size:50;not null" json:"type"` // package_expiring/low_balance
+	DedupKey string `gorm:"size:100;not null;uniqueIndex:idx_notifications_dedup" json:"-"`
+
+	Title   string `gorm:"size:200;not null" json:"title"`
+	Content string `gorm:"type:text" json:"content"`
+
+	ResourceType string `gorm:"size:50" json:"resource_type,omitempty"`
+	ResourceID   string `gorm:"size:50" json:"resource_id,omitempty"`
+
+	Deliveries JSON       `gorm:"type:jsonb" json:"deliveries,omitempty"` // 外部渠道发送结果，渠道名 → sent 或错误信息
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+}
+
+// TableName 设置表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationPreference 用户通知偏好表，没有记录时所有通知默认开启
+type NotificationPreference struct {
+	UserID    string    `gorm:"primaryKey;size:20" json:"user_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PackageExpiry    bool `gorm:"not null;default:true" json:"package_expiry"`    // 套餐到期提醒
+	LowBalance       bool `gorm:"not null;default:true" json:"low_balance"`       // 积分余额不足提醒
+	ExternalChannels bool `gorm:"not null;default:true" json:"external_channels"` // 是否同时通过短信/邮件发送
+}
+
+// TableName 设置表名
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+)
+
+// Order 套餐购买订单表
+// 状态流转：created → paid → fulfilled，created 可取消（cancelled），paid/fulfilled 可退款（refunded，取消套餐时可部分退款）
+type Order struct {
+	ID        string    `gorm:"primaryKey;type:varchar(20)" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_orders_created" json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID           string `gorm:"type:varchar(20);not null;index:idx_orders_user" json:"user_id"`
+	BillingPackageID int64  `gorm:"not null" json:"billing_package_id"`
+	PackageName      string `gorm:"size:100;not null" json:"package_name"`
+
+	Amount int64  `gorm:"not null" json:"amount"`                                 // 订单金额（分）
+	Status string `gorm:"size:20;not null;index:idx_orders_status" json:"status"` // created/paid/fulfilled/cancelled/refunded
+
+	Provider        string  `gorm:"size:20;not null" json:"provider"`                                                  // 支付渠道
+	ProviderTradeNo *string `gorm:"size:100;uniqueIndex:idx_orders_provider_trade" json:"provider_trade_no,omitempty"` // 支付渠道交易号
+
+	UserBillingPackageID *int64 `json:"user_billing_package_id,omitempty"` // 履约生成的用户套餐
+
+	ExpiresAt   time.Time  `gorm:"index:idx_orders_expires" json:"expires_at"` // 未支付自动取消时间
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+	FulfilledAt *time.Time `json:"fulfilled_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	RefundedAt  *time.Time `json:"refunded_at,omitempty"`
+
+	Notes string `gorm:"type:text" json:"notes,omitempty"`
+
+	CouponCode     string `gorm:"size:32" json:"coupon_code,omitempty"`      // 使用的优惠码
+	OriginalAmount int64  `gorm:"not null;default:0" json:"original_amount"` // 优惠前金额（分）
+	DiscountAmount int64  `gorm:"not null;default:0" json:"discount_amount"` // 优惠减免金额（分）
+	BonusCredits   int    `gorm:"not null;default:0" json:"bonus_credits"`   // 优惠券赠送积分，履约时发放
+
+	RefundAmount int64 `gorm:"not null;default:0" json:"refund_amount"` // 退款金额（分），取消套餐按比例退款时小于订单金额
+}
+
+// TableName 设置表名
+func (Order) TableName() string {
+	return "orders"
+}
+
+// Order 状态
+const (
+	OrderStatusCreated   = "created"
+	OrderStatusPaid      = "paid"
+	OrderStatusFulfilled = "fulfilled"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
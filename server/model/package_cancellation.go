@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+// PackageCancellation 用户套餐取消记录表（只追加，不修改）
+// 购买的套餐按未使用积分与剩余有效期折算退款金额，关联原订单；非购买来源或折算金额为0时只收回积分不退款
+type PackageCancellation struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_package_cancellations_created" json:"created_at"`
+
+	UserID               string  `gorm:"size:20;not null;index:idx_package_cancellations_user" json:"user_id"`
+	UserBillingPackageID int64   `gorm:"not null;uniqueIndex:idx_package_cancellations_package" json:"user_billing_package_id"` // 唯一，保证套餐只取消一次
+	OrderID              *string `gorm:"size:50;index:idx_package_cancellations_order" json:"order_id,omitempty"`               // 原购买订单
+	OperatorID           string  `gorm:"size:20;not null" json:"operator_id"`                                                   // 操作管理员
+
+	PackageName      string `gorm:"size:100;not null" json:"package_name"`
+	TotalCredits     int    `gorm:"not null" json:"total_credits"`
+	RemainingCredits int    `gorm:"not null" json:"remaining_credits"` // 取消时收回的剩余积分
+
+	CreditsRatio float64 `gorm:"not null" json:"credits_ratio"` // 未使用积分比例
+	TimeRatio    float64 `gorm:"not null" json:"time_ratio"`    // 剩余有效期比例
+	RefundRatio  float64 `gorm:"not null" json:"refund_ratio"`  // 实际采用的退款比例
+
+	PaidAmount     int64  `gorm:"not null;default:0" json:"paid_amount"`     // 原订单实付金额（分）
+	ProratedAmount int64  `gorm:"not null;default:0" json:"prorated_amount"` // 按比例折算的退款金额（分）
+	RefundAmount   int64  `gorm:"not null;default:0" json:"refund_amount"`   // 实际退款金额（分），可由管理员在折算金额内调整
+	Status         string `gorm:"size:20;not null" json:"status"`            // 取消后的套餐状态：cancelled/refunded
+
+	Reason string `gorm:"type:text;not null" json:"reason"`
+}
+
+// TableName 设置表名
+func (PackageCancellation) TableName() string {
+	return "package_cancellations"
+}
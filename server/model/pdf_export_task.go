@@ -21,6 +21,7 @@ type PdfExportTask struct {
 	TokenUsed    bool       `gorm:"default:false" json:"token_used"`          // token是否已使用
 	PdfFilePath  string     `gorm:"size:512" json:"pdf_file_path"`
 	ErrorMessage string     `gorm:"type:text" json:"error_message"`
+	CreditHoldID int64      `gorm:"default:0" json:"-"` // 导出预扣积分ID（0表示不计费）
 	CreatedAt    time.Time  `json:"created_at"`
 	CompletedAt  *time.Time `json:"completed_at"`
 }
@@ -37,13 +37,26 @@ type WorkflowExecution struct {
 	UserID        string    `gorm:"type:varchar(20);index;not null" json:"user_id"`
 	Inputs        JSON      `gorm:"type:jsonb" json:"inputs"`       // 输入参数
 	Outputs       JSON      `gorm:"type:jsonb" json:"outputs"`      // 输出结果
-	Status        string    `gorm:"size:20" json:"status"`          // 执行状态 (running/success/failed)
+	Status        string    `gorm:"size:20" json:"status"`          // 执行状态 (running/success/failed/cancelled)
 	ErrorMessage  string    `gorm:"type:text" json:"error_message"` // 错误信息
 	ExecutionTime int       `json:"execution_time"`                 // 执行时间(ms)
 	CreatedAt     time.Time `json:"created_at"`
 	User          User      `gorm:"foreignKey:UserID" json:"-"`
 	Workflow      Workflow  `gorm:"foreignKey:WorkflowID" json:"-"`
 	// Resume        *ResumeRecord `gorm:"foreignKey:ResumeID" json:"-"` // 移除外键约束，避免空字符串违反约束
+
+	// 上游（Dify）报告的用量，用于按量计费对账
+	TotalTokens int     `gorm:"default:0" json:"total_tokens"`
+	TotalSteps  int     `gorm:"default:0" json:"total_steps"`
+	ElapsedTime float64 `gorm:"default:0" json:"elapsed_time"`       // 上游执行耗时(s)
+	DeductionID *int64  `gorm:"index" json:"deduction_id,omitempty"` // 关联的积分扣减（预扣）记录
+
+	ConfigVersion int  `gorm:"default:0" json:"config_version"`          // 实际执行的工作流配置版本，0表示版本化之前的执行
+	SchemaDrift   JSON `gorm:"type:jsonb" json:"schema_drift,omitempty"` // 输出与声明字段不一致的字段列表，为空表示一致
+	HasDrift      bool `gorm:"default:false;index" json:"has_drift"`     // 是否存在输出字段漂移，便于筛选
+
+	FailoverWorkflowID string `gorm:"type:varchar(20)" json:"failover_workflow_id,omitempty"` // 上游不可用时实际执行的备用工作流，为空表示未切换
+	CacheHit           bool   `gorm:"default:false;index" json:"cache_hit"`                   // 是否命中响应缓存（未调用上游）
 }
 
 // TableName 设置表名
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+// ScheduledJob 定时任务表
+// 任务的执行逻辑在代码中注册，表中保存调度配置和最近一次执行情况
+type ScheduledJob struct {
+	ID        int64     `json:"id" gorm:"primaryKey;autoIncrement;comment:主键ID"`
+	CreatedAt time.Time `json:"created_at" gorm:"comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"comment:更新时间"`
+
+	Name        string `json:"name" gorm:"type:varchar(100);uniqueIndex:idx_scheduled_jobs_name;not null;comment:任务名称"`
+	Description string `json:"description" gorm:"type:varchar(500);default:'';comment:任务描述"`
+	CronExpr    string `json:"cron_expr" gorm:"type:varchar(100);not null;comment:Cron表达式（分 时 日 月 周）"`
+	Paused      bool   `json:"paused" gorm:"default:false;comment:是否暂停"`
+
+	NextRunAt      *time.Time `json:"next_run_at" gorm:"index:idx_scheduled_jobs_next_run;comment:下次执行时间"`
+	LastRunAt      *time.Time `json:"last_run_at" gorm:"comment:最近一次开始执行时间"`
+	LastStatus     string     `json:"last_status" gorm:"type:varchar(20);default:'';comment:最近一次执行状态：running/success/failed"`
+	LastError      string     `json:"last_error" gorm:"type:text;comment:最近一次执行错误"`
+	LastResult     string     `json:"last_result" gorm:"type:text;comment:最近一次执行结果摘要"`
+	LastDurationMs int64      `json:"last_duration_ms" gorm:"default:0;comment:最近一次执行耗时（毫秒）"`
+	RunCount       int64      `json:"run_count" gorm:"default:0;comment:累计执行次数"`
+}
+
+// TableName 指定表名
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
+
+// ScheduledJob 执行状态
+const (
+	ScheduledJobStatusRunning = "running"
+	ScheduledJobStatusSuccess = "success"
+	ScheduledJobStatusFailed  = "failed"
+)
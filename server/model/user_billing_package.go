@@ -19,17 +19,24 @@ type UserBillingPackage struct {
 	TotalCredits     int `gorm:"not null" json:"total_credits"`
 	UsedCredits      int `gorm:"default:0" json:"used_credits"`
 	RemainingCredits int `gorm:"not null" json:"remaining_credits"`
+	ReservedCredits  int `gorm:"default:0" json:"reserved_credits"` // 预扣中的积分，不计入剩余积分
+	
+	CoveredActions   JSON `gorm:"type:jsonb" json:"covered_actions,omitempty"` // 分配时的套餐权益快照：不限次使用的动作key列表
+	DailyActionLimit int  `gorm:"default:0" json:"daily_action_limit"`         // 每个动作每日合理使用上限，0=不限
 	
 	ActivatedAt *time.Time `json:"activated_at"`
 	ExpiresAt   *time.Time `gorm:"index:idx_user_billing_packages_expires" json:"expires_at"`
 	
-	Status   string `gorm:"size:20;default:'pending';index:idx_user_billing_packages_user" json:"status"` // pending/active/expired/depleted
+	Status   string `gorm:"size:20;default:'pending';index:idx_user_billing_packages_user" json:"status"` // pending/active/expired/depleted/cancelled/refunded
 	Priority int    `gorm:"default:0;index:idx_user_billing_packages_user" json:"priority"`
 	
 	Source  string  `gorm:"size:50;default:'purchase'" json:"source"` // purchase/gift/promotion/system
-	OrderID *string `gorm:"size:50" json:"order_id,omitempty"`
+	OrderID *string `gorm:"size:50;uniqueIndex:idx_user_billing_packages_order" json:"order_id,omitempty"` // 购买订单（唯一，保证订单只履约一次）
 	
 	Notes string `gorm:"type:text" json:"notes,omitempty"`
+
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`                  // 取消或退款时间
+	RefundAmount int64      `gorm:"not null;default:0" json:"refund_amount"` // 退款金额（分）
 }
 
 // TableName 设置表名
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// WorkflowCacheEntry 工作流响应缓存
+// 开启缓存的工作流以相同输入（文件按内容哈希比较）再次执行时直接返回缓存的结果，不调用上游
+// 缓存键包含工作流配置版本和用户，配置变更后旧缓存自然失效，不同用户之间不共享
+type WorkflowCacheEntry struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	WorkflowID    string `gorm:"type:varchar(20);not null;uniqueIndex:idx_workflow_cache_key" json:"workflow_id"`
+	CacheKey      string `gorm:"type:varchar(64);not null;uniqueIndex:idx_workflow_cache_key" json:"cache_key"` // 输入的规范化哈希（SHA256）
+	UserID        string `gorm:"type:varchar(20);not null;index" json:"user_id"`
+	ConfigVersion int    `gorm:"default:0" json:"config_version"` // 产生缓存时的工作流配置版本
+
+	Data        JSON    `gorm:"type:jsonb" json:"data"`        // 缓存的执行结果（ExecuteWorkflowResponse.Data）
+	TotalTokens int     `gorm:"default:0" json:"total_tokens"` // 产生缓存时上游报告的token用量
+	ElapsedTime float64 `gorm:"default:0" json:"elapsed_time"` // 产生缓存时上游执行耗时(s)
+
+	HitCount  int64      `gorm:"default:0" json:"hit_count"`
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+}
+
+// TableName 设置表名
+func (WorkflowCacheEntry) TableName() string {
+	return "workflow_cache_entries"
+}
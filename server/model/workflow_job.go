@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+)
+
+// WorkflowJob 状态常量
+const (
+	WorkflowJobStatusPending   = "pending"
+	WorkflowJobStatusRunning   = "running"
+	WorkflowJobStatusSucceeded = "succeeded"
+	WorkflowJobStatusFailed    = "failed"
+)
+
+// WorkflowJob 异步任务表
+// 任务的执行逻辑按类型在代码中注册（service/jobqueue），表中保存任务参数、进度和结果，服务重启后未完成的任务会重新执行
+type WorkflowJob struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(20);comment:任务ID"`
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_workflow_jobs_user;comment:创建时间"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"comment:更新时间"`
+
+	Type       string `json:"type" gorm:"type:varchar(50);not null;comment:任务类型"`
+	UserID     string `json:"user_id" gorm:"type:varchar(20);not null;index:idx_workflow_jobs_user,priority:1;comment:提交用户ID"`
+	WorkflowID string `json:"workflow_id" gorm:"type:varchar(20);default:'';index:idx_workflow_jobs_workflow;comment:执行的工作流ID，用于按工作流限制并发"`
+	ResourceID string `json:"resource_id" gorm:"type:varchar(50);default:'';comment:关联的业务记录ID（如简历ID、面试复盘ID）"`
+	Payload    JSON   `json:"-" gorm:"type:jsonb;comment:任务参数"`
+
+	Status       string `json:"status" gorm:"type:varchar(20);not null;default:'pending';index:idx_workflow_jobs_status;index:idx_workflow_jobs_workflow;comment:状态：pending/running/succeeded/failed"`
+	Progress     int    `json:"progress" gorm:"default:0;comment:进度（0-100）"`
+	Message      string `json:"message" gorm:"type:varchar(200);default:'';comment:当前进度说明"`
+	Result       JSON   `json:"result" gorm:"type:jsonb;comment:执行结果"`
+	ErrorMessage string `json:"error_message" gorm:"type:text;comment:失败原因"`
+
+	Attempts    int        `json:"attempts" gorm:"default:0;comment:已开始执行的次数"`
+	MaxAttempts int        `json:"max_attempts" gorm:"default:1;comment:最大执行次数（执行被中断时重新排队）"`
+	WorkerID    string     `json:"-" gorm:"type:varchar(100);default:'';comment:执行中的实例标识"`
+	HeartbeatAt *time.Time `json:"-" gorm:"comment:执行中最近一次心跳时间"`
+	StartedAt   *time.Time `json:"started_at" gorm:"comment:最近一次开始执行时间"`
+	FinishedAt  *time.Time `json:"finished_at" gorm:"comment:完成时间"`
+}
+
+// TableName 指定表名
+func (WorkflowJob) TableName() string {
+	return "workflow_jobs"
+}
+
+// IsFinished 任务是否已结束（成功或失败）
+func (j *WorkflowJob) IsFinished() bool {
+	return j.Status == WorkflowJobStatusSucceeded || j.Status == WorkflowJobStatusFailed
+}
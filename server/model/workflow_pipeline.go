@@ -0,0 +1,105 @@
+package model
+
+import (
+	"time"
+)
+
+// 流水线运行状态
+const (
+	PipelineRunStatusRunning   = "running"
+	PipelineRunStatusSucceeded = "succeeded"
+	PipelineRunStatusFailed    = "failed"
+	PipelineRunStatusCancelled = "cancelled"
+)
+
+// 流水线步骤状态
+const (
+	PipelineStepStatusSucceeded = "succeeded"
+	PipelineStepStatusFailed    = "failed"
+	PipelineStepStatusSkipped   = "skipped"
+)
+
+// WorkflowPipeline 工作流流水线表
+// 按顺序执行多个工作流（按名称引用），后续步骤的输入可以引用流水线输入和之前步骤的输出
+type WorkflowPipeline struct {
+	ID          string    `gorm:"primaryKey;type:varchar(20)" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Name        string    `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"size:500" json:"description"`
+	Steps       JSON      `gorm:"type:jsonb" json:"steps"`   // []PipelineStep
+	Outputs     JSON      `gorm:"type:jsonb" json:"outputs"` // 流水线输出：输出字段 -> 取值表达式，为空表示最后一个成功步骤的输出
+	Enabled     bool      `gorm:"default:true" json:"enabled"`
+	AuthorID    string    `gorm:"type:varchar(20)" json:"author_id"`
+}
+
+// TableName 设置表名
+func (WorkflowPipeline) TableName() string {
+	return "workflow_pipelines"
+}
+
+// PipelineStep 流水线步骤
+// 输入映射的字符串值可以使用 {{表达式}} 引用流水线输入（input.字段）和之前步骤的结果（steps.步骤名.outputs.字段、steps.步骤名.status），
+// 整个值为单个 {{表达式}} 时保留原始类型，表达式可用 || 连接多个路径，取第一个非空值
+type PipelineStep struct {
+	Name       string                 `json:"name"`                 // 步骤名称，流水线内唯一
+	Workflow   string                 `json:"workflow"`             // 工作流名称
+	Inputs     map[string]interface{} `json:"inputs"`               // 工作流输入映射
+	Transforms []PipelineTransform    `json:"transforms,omitempty"` // 输出转换，按顺序执行
+	When       *PipelineCondition     `json:"when,omitempty"`       // 执行条件，不满足时跳过，为空表示总是执行
+	OnError    string                 `json:"on_error,omitempty"`   // 失败处理：fail（默认，终止流水线）/continue（记录失败后继续）
+}
+
+// PipelineTransform 步骤输出转换
+type PipelineTransform struct {
+	Type  string `json:"type"`            // strip_think（去除 <think> 标签）/extract_json（提取并解析JSON）/trim（去除首尾空白）
+	Field string `json:"field,omitempty"` // 输出字段，为空表示所有字符串输出
+}
+
+// PipelineCondition 步骤执行条件
+type PipelineCondition struct {
+	Path  string      `json:"path"`            // 取值路径，如 input.doc_file、steps.extract.status
+	Op    string      `json:"op,omitempty"`    // not_empty（默认）/empty/equals/not_equals
+	Value interface{} `json:"value,omitempty"` // equals/not_equals 的比较值
+}
+
+// WorkflowPipelineRun 流水线运行记录表
+// 每次运行记录一条，各步骤的工作流执行仍按单个工作流计费并写入 workflow_executions
+type WorkflowPipelineRun struct {
+	ID        string    `gorm:"primaryKey;type:varchar(20)" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_workflow_pipeline_runs_user,priority:2" json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PipelineID   string `gorm:"type:varchar(20);index" json:"pipeline_id"` // 使用内置定义运行时为空
+	PipelineName string `gorm:"size:100;not null" json:"pipeline_name"`
+	UserID       string `gorm:"type:varchar(20);not null;index:idx_workflow_pipeline_runs_user,priority:1" json:"user_id"`
+	ResponseMode string `gorm:"size:20" json:"response_mode"` // blocking/streaming
+
+	Status       string `gorm:"size:20;not null;default:'running'" json:"status"` // running/succeeded/failed/cancelled
+	Inputs       JSON   `gorm:"type:jsonb" json:"inputs"`
+	Outputs      JSON   `gorm:"type:jsonb" json:"outputs"`
+	Steps        JSON   `gorm:"type:jsonb" json:"steps"` // []PipelineStepResult
+	FailedStep   string `gorm:"size:100" json:"failed_step,omitempty"`
+	ErrorMessage string `gorm:"type:text" json:"error_message,omitempty"`
+
+	ExecutionTime int        `json:"execution_time"` // 总耗时（毫秒）
+	FinishedAt    *time.Time `json:"finished_at"`
+}
+
+// TableName 设置表名
+func (WorkflowPipelineRun) TableName() string {
+	return "workflow_pipeline_runs"
+}
+
+// PipelineStepResult 流水线步骤执行结果
+type PipelineStepResult struct {
+	Name          string                 `json:"name"`
+	Workflow      string                 `json:"workflow"`
+	WorkflowID    string                 `json:"workflow_id,omitempty"`
+	Status        string                 `json:"status"` // succeeded/failed/skipped
+	Outputs       map[string]interface{} `json:"outputs,omitempty"`
+	ErrorMessage  string                 `json:"error_message,omitempty"`
+	WorkflowRunID string                 `json:"workflow_run_id,omitempty"` // 上游工作流运行ID，用于对照 workflow_executions
+	CacheHit      bool                   `json:"cache_hit,omitempty"`
+	ExecutionTime int                    `json:"execution_time"`
+}
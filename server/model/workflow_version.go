@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+// WorkflowVersion 工作流配置版本表（只追加，不修改）
+// 每次修改调用配置（API地址、密钥、输入输出字段、计费动作）都会追加一个版本，回滚同样追加一个新版本
+type WorkflowVersion struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	WorkflowID string `gorm:"type:varchar(20);not null;uniqueIndex:idx_workflow_versions_version" json:"workflow_id"`
+	Version    int    `gorm:"not null;uniqueIndex:idx_workflow_versions_version" json:"version"` // 从1开始递增
+
+	ApiURL           string `gorm:"size:500;not null" json:"api_url"`
+	ApiKey           string `gorm:"type:text;not null" json:"api_key"` // 与工作流相同的加密格式
+	Inputs           JSON   `gorm:"type:jsonb" json:"inputs"`
+	Outputs          JSON   `gorm:"type:jsonb" json:"outputs"`
+	BillingActionKey string `gorm:"size:50" json:"billing_action_key"`
+
+	AuthorID       string `gorm:"type:varchar(20)" json:"author_id"`
+	RolledBackFrom *int   `json:"rolled_back_from,omitempty"` // 回滚产生的版本记录来源版本号
+	Notes          string `gorm:"type:text" json:"notes,omitempty"`
+}
+
+// TableName 设置表名
+func (WorkflowVersion) TableName() string {
+	return "workflow_versions"
+}
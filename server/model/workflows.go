@@ -8,12 +8,17 @@ type Field struct {
 	FieldName string `json:"field_name"`
 	FieldType string `json:"field_type"` // string/number/boolean/file
 	Required  bool   `json:"required"`
+
+	MaxLength int           `json:"max_length,omitempty"` // string 的最大字符数，file 的最大文件数
+	Min       *float64      `json:"min,omitempty"`        // number 的最小值
+	Max       *float64      `json:"max,omitempty"`        // number 的最大值
+	Enum      []interface{} `json:"enum,omitempty"`       // 可选值，为空表示不限制
 }
 
 type Workflow struct {
 	ID          string    `gorm:"primaryKey;type:varchar(20)" json:"id"`
 	ApiURL      string    `gorm:"size:500;not null" json:"api_url"`
-	ApiKey      string    `gorm:"size:255;not null" json:"api_key"`
+	ApiKey      string    `gorm:"type:text;not null" json:"api_key"` // 信封加密存储（utils.EncryptSecret），历史明文在首次读取时迁移
 	Name        string    `gorm:"size:100;not null" json:"name"`
 	Description string    `gorm:"size:500" json:"description"`
 	CreatorID   string    `gorm:"type:varchar(20);index" json:"creator_id"`
@@ -25,6 +30,17 @@ type Workflow struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Creator     User      `gorm:"foreignKey:CreatorID" json:"-"`
+
+	BillingActionKey string `gorm:"size:50;index" json:"billing_action_key"` // 计费动作（关联 billing_action_prices.action_key），为空表示免费
+	ConfigVersion    int    `gorm:"default:0" json:"config_version"`         // 当前配置版本（workflow_versions.version），0表示尚无版本记录
+	MaxConcurrency   int    `gorm:"default:0" json:"max_concurrency"`        // 异步任务的最大并发数（所有实例合计），0表示使用 job_queue.workflow_concurrency
+
+	FallbackWorkflowID string `gorm:"type:varchar(20)" json:"fallback_workflow_id"` // 备用工作流，本工作流上游不可用（熔断、网络错误、5xx）时切换执行，为空表示不切换
+
+	// 响应缓存，仅适用于相同输入总是产生相同结果的工作流（如文本提取、结构化），ChatFlow 不缓存
+	CacheEnabled    bool   `gorm:"default:false" json:"cache_enabled"`
+	CacheTTLSeconds int    `gorm:"default:0" json:"cache_ttl_seconds"` // 缓存有效期，0表示使用 workflow_cache.default_ttl_hours
+	CacheActionKey  string `gorm:"size:50" json:"cache_action_key"`    // 命中缓存时的计费动作（通常为折扣价），为空表示命中缓存免费
 }
 
 // TableName 设置表名
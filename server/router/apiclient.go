@@ -0,0 +1,20 @@
+package router
+
+import (
+	"server/api/apiclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitAPIClientRouter 初始化服务间调用客户端相关路由
+func InitAPIClientRouter(adminGroup *gin.RouterGroup) {
+	// 管理员路由 - 服务间调用客户端管理
+	AdminAPIClientRouter := adminGroup.Group("/api/admin/api-clients")
+	{
+		AdminAPIClientRouter.POST("", apiclient.CreateAPIClient)                         // 创建客户端
+		AdminAPIClientRouter.GET("", apiclient.ListAPIClients)                           // 获取客户端列表
+		AdminAPIClientRouter.PUT("/:id", apiclient.UpdateAPIClient)                      // 更新客户端
+		AdminAPIClientRouter.POST("/:id/rotate-secret", apiclient.RotateAPIClientSecret) // 重置密钥
+		AdminAPIClientRouter.DELETE("/:id", apiclient.DeleteAPIClient)                   // 删除客户端
+	}
+}
@@ -2,6 +2,8 @@ package router
 
 import (
 	"server/api/billing"
+	"server/middleware"
+	"server/service/apiclient"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,8 +21,10 @@ func InitBillingRouter(privateGroup *gin.RouterGroup, publicGroup *gin.RouterGro
 	// 用户路由 - 我的套餐和积分
 	BillingUserRouter := privateGroup.Group("/api/user/billing")
 	{
-		BillingUserRouter.GET("/packages", billing.GetMyBillingPackages) // 我的套餐
-		BillingUserRouter.GET("/credits", billing.GetMyCredits)          // 我的积分
+		BillingUserRouter.GET("/packages", billing.GetMyBillingPackages)         // 我的套餐
+		BillingUserRouter.GET("/credits", billing.GetMyCredits)                  // 我的积分
+		BillingUserRouter.GET("/transactions", billing.ListMyCreditTransactions) // 我的积分流水
+		BillingUserRouter.GET("/quotas", billing.GetMyQuotas)                    // 我的使用配额
 	}
 
 	// 管理员路由 - 套餐管理
@@ -36,12 +40,51 @@ func InitBillingRouter(privateGroup *gin.RouterGroup, publicGroup *gin.RouterGro
 		AdminBillingRouter.POST("/user-packages", billing.AssignBillingPackage)                   // 分配套餐
 		AdminBillingRouter.GET("/users/:userId/billing-packages", billing.GetUserBillingPackages) // 查询用户套餐
 		AdminBillingRouter.POST("/user-packages/:id/activate", billing.ActivateBillingPackage)    // 激活套餐
+
+		// 积分调整
+		AdminBillingRouter.POST("/credit-adjustments", billing.AdjustUserCredits)                  // 手动调整积分
+		AdminBillingRouter.GET("/credit-adjustments", billing.ListCreditAdjustments)               // 查询调整记录
+		AdminBillingRouter.GET("/users/:userId/credit-adjustments", billing.ListCreditAdjustments) // 查询用户调整记录
+
+		// 动作使用配额
+		AdminBillingRouter.GET("/quotas", billing.ListQuotaRules)                                      // 查询配额规则
+		AdminBillingRouter.PUT("/quotas", billing.SaveQuotaRule)                                       // 创建或更新配额规则
+		AdminBillingRouter.DELETE("/quotas/:id", billing.DeleteQuotaRule)                              // 删除配额规则
+		AdminBillingRouter.GET("/users/:userId/quotas", billing.GetUserQuotas)                         // 查询用户配额
+		AdminBillingRouter.PUT("/users/:userId/quotas", billing.SetUserQuotaOverride)                  // 设置用户配额覆盖
+		AdminBillingRouter.DELETE("/users/:userId/quotas/:actionKey", billing.DeleteUserQuotaOverride) // 删除用户配额覆盖
+
+		// 动作价格与价格版本
+		AdminBillingRouter.PUT("/action-prices/:actionKey", billing.UpdateActionPrice)                // 更新动作计费方式
+		AdminBillingRouter.GET("/action-prices/:actionKey/versions", billing.ListActionPriceVersions) // 查询价格版本历史
+		AdminBillingRouter.POST("/action-prices/:actionKey/versions", billing.ScheduleActionPrice)    // 排期价格版本
+		AdminBillingRouter.DELETE("/action-price-versions/:id", billing.CancelActionPriceVersion)     // 取消价格版本
+
+		// 积分流水
+		AdminBillingRouter.GET("/transactions", billing.ListCreditTransactions) // 查询积分流水
+
+		// 计费报表（format=csv 时导出 CSV）
+		AdminBillingRouter.GET("/reports/revenue", billing.GetRevenueReport)            // 收入报表
+		AdminBillingRouter.GET("/reports/credits", billing.GetCreditsReport)            // 积分发放与消耗报表
+		AdminBillingRouter.GET("/reports/breakage", billing.GetBreakageReport)          // 积分沉淀报表
+		AdminBillingRouter.GET("/reports/top-consumers", billing.GetTopConsumersReport) // 积分消耗排行
+		AdminBillingRouter.GET("/reports/conversion", billing.GetConversionReport)      // 赠送转化报表
 	}
 
-	// 内部路由 - 积分扣减
+	// 内部路由 - 积分扣减（服务间调用，需HMAC签名鉴权）
+	// 内部路由只能通过 internalPOST 注册，每个路由都必须指定授权范围，避免未鉴权的路由暴露在公共路由组
 	BillingInternalRouter := publicGroup.Group("/api/internal/billing")
+	internalPOST := func(path, scope string, handler gin.HandlerFunc) {
+		BillingInternalRouter.POST(path, middleware.ServiceAuth(scope), handler)
+	}
 	{
-		BillingInternalRouter.POST("/credits/check", billing.CheckCredits)   // 检查积分
-		BillingInternalRouter.POST("/credits/deduct", billing.DeductCredits) // 扣减积分
+		internalPOST("/credits/check", apiclient.ScopeBillingCheck, billing.CheckCredits)    // 检查积分
+		internalPOST("/credits/deduct", apiclient.ScopeBillingDeduct, billing.DeductCredits) // 扣减积分
+		internalPOST("/credits/refund", apiclient.ScopeBillingRefund, billing.RefundCredits) // 退还积分
+
+		// 两阶段扣减
+		internalPOST("/credits/reserve", apiclient.ScopeBillingReserve, billing.ReserveCredits)        // 预扣积分
+		internalPOST("/credits/holds/:id/commit", apiclient.ScopeBillingReserve, billing.CommitHold)   // 结算预扣
+		internalPOST("/credits/holds/:id/release", apiclient.ScopeBillingReserve, billing.ReleaseHold) // 释放预扣
 	}
 }
@@ -30,7 +30,13 @@ func InitRoutes(r *gin.Engine) {
 	InitInvitationRouter(PrivateGroup, PublicGroup, AdminGroup)
 	InitSiteVariableRouter(PrivateGroup, PublicGroup, AdminGroup)
 	InitEventLogRouter(AdminGroup)
+	InitAPIClientRouter(AdminGroup)
+	InitSchedulerRouter(AdminGroup)
+	InitJobQueueRouter(PrivateGroup, AdminGroup)
+	InitUpstreamRouter(AdminGroup)
 	InitBillingRouter(PrivateGroup, PublicGroup, AdminGroup)
+	InitPaymentRouter(PrivateGroup, PublicGroup, AdminGroup)
+	InitNotificationRouter(PrivateGroup, PublicGroup, AdminGroup)
 	InitTOSRouter(PrivateGroup, PublicGroup, AdminGroup)
 	InitASRRouter(PrivateGroup, PublicGroup, AdminGroup)
 	InitInterviewRouter(PrivateGroup, PublicGroup, AdminGroup)
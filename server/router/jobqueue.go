@@ -0,0 +1,23 @@
+package router
+
+import (
+	"server/api/jobqueue"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitJobQueueRouter 初始化异步任务相关路由
+func InitJobQueueRouter(privateGroup *gin.RouterGroup, adminGroup *gin.RouterGroup) {
+	// 私有路由 - 查询自己提交的任务
+	JobRouter := privateGroup.Group("/api/jobs")
+	{
+		JobRouter.GET("", jobqueue.ListJobs)   // 获取任务列表
+		JobRouter.GET("/:id", jobqueue.GetJob) // 查询任务进度和结果
+	}
+
+	// 管理员路由 - 任务监控
+	AdminJobRouter := adminGroup.Group("/api/admin/jobs")
+	{
+		AdminJobRouter.GET("", jobqueue.AdminListJobs) // 获取所有任务
+	}
+}
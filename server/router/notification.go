@@ -0,0 +1,20 @@
+package router
+
+import (
+	"server/api/notification"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitNotificationRouter 初始化用户通知相关路由
+func InitNotificationRouter(privateGroup *gin.RouterGroup, publicGroup *gin.RouterGroup, adminGroup *gin.RouterGroup) {
+	// 用户路由 - 站内通知
+	NotificationRouter := privateGroup.Group("/api/user/notifications")
+	{
+		NotificationRouter.GET("", notification.ListNotifications)                        // 我的通知
+		NotificationRouter.POST("/read-all", notification.MarkAllNotificationsRead)       // 全部标记已读
+		NotificationRouter.POST("/:id/read", notification.MarkNotificationRead)           // 标记已读
+		NotificationRouter.GET("/preferences", notification.GetNotificationPreference)    // 获取通知偏好
+		NotificationRouter.PUT("/preferences", notification.UpdateNotificationPreference) // 更新通知偏好
+	}
+}
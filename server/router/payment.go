@@ -0,0 +1,58 @@
+package router
+
+import (
+	"server/api/payment"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitPaymentRouter 初始化订单支付相关路由
+func InitPaymentRouter(privateGroup *gin.RouterGroup, publicGroup *gin.RouterGroup, adminGroup *gin.RouterGroup) {
+	// 用户路由 - 订单
+	OrderRouter := privateGroup.Group("/api/orders")
+	{
+		OrderRouter.POST("", payment.CreateOrder)               // 创建订单
+		OrderRouter.GET("", payment.ListMyOrders)               // 我的订单
+		OrderRouter.GET("/:id", payment.GetOrder)               // 订单详情
+		OrderRouter.POST("/:id/cancel", payment.CancelOrder)    // 取消订单
+		OrderRouter.POST("/:id/mock-pay", payment.MockPayOrder) // 模拟支付（仅测试环境）
+	}
+
+	// 用户路由 - 优惠券
+	CouponRouter := privateGroup.Group("/api/coupons")
+	{
+		CouponRouter.POST("/preview", payment.PreviewCoupon) // 试算优惠券
+	}
+
+	// 公共路由 - 支付渠道回调（通过渠道签名校验）
+	PaymentPublicRouter := publicGroup.Group("/api/payment")
+	{
+		PaymentPublicRouter.POST("/webhook/:provider", payment.PaymentWebhook) // 支付回调
+	}
+
+	// 管理员路由 - 订单管理
+	AdminOrderRouter := adminGroup.Group("/api/admin/orders")
+	{
+		AdminOrderRouter.GET("", payment.ListOrders) // 查询订单
+	}
+
+	// 管理员路由 - 优惠券管理
+	AdminCouponRouter := adminGroup.Group("/api/admin/coupons")
+	{
+		AdminCouponRouter.POST("", payment.CreateCoupon)                         // 创建优惠券
+		AdminCouponRouter.GET("", payment.ListCoupons)                           // 查询优惠券
+		AdminCouponRouter.PUT("/:id", payment.UpdateCoupon)                      // 更新优惠券
+		AdminCouponRouter.DELETE("/:id", payment.DeleteCoupon)                   // 删除优惠券
+		AdminCouponRouter.GET("/:id/stats", payment.GetCouponStats)              // 使用统计
+		AdminCouponRouter.GET("/:id/redemptions", payment.ListCouponRedemptions) // 使用记录
+	}
+	adminGroup.GET("/api/admin/coupon-redemptions", payment.ListCouponRedemptions) // 按用户查询优惠券使用记录
+
+	// 管理员路由 - 套餐取消与退款
+	AdminCancellationRouter := adminGroup.Group("/api/admin/billing")
+	{
+		AdminCancellationRouter.GET("/user-packages/:id/cancellation-quote", payment.PreviewPackageCancellation) // 试算退款金额
+		AdminCancellationRouter.POST("/user-packages/:id/cancel", payment.CancelUserPackage)                     // 取消套餐
+		AdminCancellationRouter.GET("/package-cancellations", payment.ListPackageCancellations)                  // 查询取消记录
+	}
+}
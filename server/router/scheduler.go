@@ -0,0 +1,19 @@
+package router
+
+import (
+	"server/api/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitSchedulerRouter 初始化定时任务相关路由
+func InitSchedulerRouter(adminGroup *gin.RouterGroup) {
+	// 管理员路由 - 定时任务管理
+	AdminSchedulerRouter := adminGroup.Group("/api/admin/scheduled-jobs")
+	{
+		AdminSchedulerRouter.GET("", scheduler.ListScheduledJobs)                  // 获取定时任务列表
+		AdminSchedulerRouter.POST("/:name/trigger", scheduler.TriggerScheduledJob) // 立即执行
+		AdminSchedulerRouter.POST("/:name/pause", scheduler.PauseScheduledJob)     // 暂停
+		AdminSchedulerRouter.POST("/:name/resume", scheduler.ResumeScheduledJob)   // 恢复
+	}
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"server/api/upstream"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InitUpstreamRouter 初始化上游服务监控路由
+func InitUpstreamRouter(adminGroup *gin.RouterGroup) {
+	// 管理员路由 - 熔断器状态
+	AdminUpstreamRouter := adminGroup.Group("/api/admin/upstream")
+	{
+		AdminUpstreamRouter.GET("/breakers", upstream.ListBreakers)        // 获取熔断器状态
+		AdminUpstreamRouter.POST("/breakers/reset", upstream.ResetBreaker) // 手动恢复熔断器
+	}
+}
@@ -11,18 +11,35 @@ func InitWorkflowRouter(privateGroup *gin.RouterGroup, publicGroup *gin.RouterGr
 	// 私有路由 - 用户工作流操作
 	WorkflowRouter := privateGroup.Group("/api/workflow")
 	{
-		WorkflowRouter.GET("", app.GetWorkflows)                            // 获取工作流列表
-		WorkflowRouter.GET("/:id", app.GetWorkflow)                         // 获取特定工作流
-		WorkflowRouter.POST("", app.CreateWorkflow)                         // 创建工作流
-		WorkflowRouter.DELETE("/:id", app.DeleteWorkflow)                   // 删除工作流
-		WorkflowRouter.POST("/v1/:id/execute", app.ExecuteWorkflow)         // 执行工作流/流式执行工作流
-		WorkflowRouter.POST("/v2/:name/execute", app.ExecuteWorkflowByName) // 执行工作流/流式执行工作流
+		WorkflowRouter.GET("", app.GetWorkflows)                                    // 获取工作流列表
+		WorkflowRouter.GET("/:id", app.GetWorkflow)                                 // 获取特定工作流
+		WorkflowRouter.POST("", app.CreateWorkflow)                                 // 创建工作流
+		WorkflowRouter.DELETE("/:id", app.DeleteWorkflow)                           // 删除工作流
+		WorkflowRouter.POST("/v1/:id/execute", app.ExecuteWorkflow)                 // 执行工作流/流式执行工作流
+		WorkflowRouter.POST("/v2/:name/execute", app.ExecuteWorkflowByName)         // 执行工作流/流式执行工作流
+		WorkflowRouter.GET("/streams/:stream_id", app.ResumeWorkflowStream)         // 按 Last-Event-ID 重连流式执行
+		WorkflowRouter.POST("/streams/:stream_id/cancel", app.CancelWorkflowStream) // 取消流式执行
+		WorkflowRouter.POST("/pipelines/:name/run", app.RunPipeline)                // 运行流水线
+		WorkflowRouter.GET("/pipelines/runs/:id", app.GetPipelineRun)               // 查询流水线运行记录
 	}
 
 	// 管理员路由 - 工作流管理
 	AdminWorkflowRouter := adminGroup.Group("/api/workflow")
 	{
-		AdminWorkflowRouter.GET("/all", app.GetAllWorkflows) // 获取所有工作流
-		AdminWorkflowRouter.PUT("/:id", app.UpdateWorkflow)  // 管理员更新工作流
+		AdminWorkflowRouter.GET("/all", app.GetAllWorkflows)                // 获取所有工作流
+		AdminWorkflowRouter.PUT("/:id", app.UpdateWorkflow)                 // 管理员更新工作流
+		AdminWorkflowRouter.POST("/keys/rotate", app.RotateWorkflowAPIKeys) // 使用当前主密钥重新加密API密钥
+
+		AdminWorkflowRouter.GET("/:id/versions", app.ListWorkflowVersions)                // 配置版本历史
+		AdminWorkflowRouter.GET("/:id/versions/diff", app.DiffWorkflowVersions)           // 版本差异
+		AdminWorkflowRouter.POST("/:id/versions/:version/rollback", app.RollbackWorkflow) // 回滚到指定版本
+
+		AdminWorkflowRouter.GET("/cache/stats", app.GetWorkflowCacheStats) // 响应缓存统计
+		AdminWorkflowRouter.DELETE("/:id/cache", app.PurgeWorkflowCache)   // 清除工作流的响应缓存
+
+		AdminWorkflowRouter.GET("/pipelines", app.GetAllPipelines)       // 获取所有流水线
+		AdminWorkflowRouter.POST("/pipelines", app.CreatePipeline)       // 创建流水线
+		AdminWorkflowRouter.PUT("/pipelines/:id", app.UpdatePipeline)    // 更新流水线
+		AdminWorkflowRouter.DELETE("/pipelines/:id", app.DeletePipeline) // 删除流水线
 	}
 }
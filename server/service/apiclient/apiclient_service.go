@@ -0,0 +1,358 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+	"server/service/eventlog"
+	"server/utils"
+)
+
+type apiClientService struct{}
+
+var APIClientService = &apiClientService{}
+
+// ErrScopeDenied 客户端未被授予接口所需的范围
+var ErrScopeDenied = errors.New("客户端无权访问该接口")
+
+// CreateAPIClient 创建API客户端，返回的密钥只展示这一次
+func (s *apiClientService) CreateAPIClient(req *CreateAPIClientRequest, operatorID string) (*APIClientSecretResponse, error) {
+	scopesJSON, err := marshalScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := global.DB.Model(&model.APIClient{}).Where("name = ?", req.Name).Count(&count).Error; err != nil {
+		return nil, errors.New("查询客户端失败")
+	}
+	if count > 0 {
+		return nil, errors.New("客户端名称已存在")
+	}
+
+	accessKey, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &model.APIClient{
+		Name:        req.Name,
+		Description: req.Description,
+		AccessKey:   accessKey,
+		Secret:      encrypted,
+		Scopes:      scopesJSON,
+		Enabled:     true,
+		CreatedBy:   operatorID,
+	}
+	if err := global.DB.Create(client).Error; err != nil {
+		return nil, errors.New("创建客户端失败")
+	}
+
+	return &APIClientSecretResponse{
+		ID:        client.ID,
+		Name:      client.Name,
+		AccessKey: client.AccessKey,
+		Secret:    secret,
+	}, nil
+}
+
+// UpdateAPIClient 更新API客户端的描述、授权范围和启用状态
+func (s *apiClientService) UpdateAPIClient(id int64, req *UpdateAPIClientRequest) error {
+	var client model.APIClient
+	if err := global.DB.First(&client, id).Error; err != nil {
+		return errors.New("客户端不存在")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Scopes != nil {
+		scopesJSON, err := marshalScopes(req.Scopes)
+		if err != nil {
+			return err
+		}
+		updates["scopes"] = scopesJSON
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := global.DB.Model(&client).Updates(updates).Error; err != nil {
+		return errors.New("更新客户端失败")
+	}
+	return nil
+}
+
+// RotateSecret 重置API客户端密钥，旧密钥立即失效
+func (s *apiClientService) RotateSecret(id int64) (*APIClientSecretResponse, error) {
+	var client model.APIClient
+	if err := global.DB.First(&client, id).Error; err != nil {
+		return nil, errors.New("客户端不存在")
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := global.DB.Model(&client).Update("secret", encrypted).Error; err != nil {
+		return nil, errors.New("重置密钥失败")
+	}
+
+	return &APIClientSecretResponse{
+		ID:        client.ID,
+		Name:      client.Name,
+		AccessKey: client.AccessKey,
+		Secret:    secret,
+	}, nil
+}
+
+// DeleteAPIClient 删除API客户端
+func (s *apiClientService) DeleteAPIClient(id int64) error {
+	result := global.DB.Delete(&model.APIClient{}, id)
+	if result.Error != nil {
+		return errors.New("删除客户端失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("客户端不存在")
+	}
+	return nil
+}
+
+// ListAPIClients 获取API客户端列表（不包含密钥）
+func (s *apiClientService) ListAPIClients() ([]model.APIClient, error) {
+	var clients []model.APIClient
+	if err := global.DB.Order("id ASC").Find(&clients).Error; err != nil {
+		return nil, errors.New("查询客户端失败")
+	}
+	return clients, nil
+}
+
+// Verify 验证服务间请求的签名、时间戳、nonce和授权范围
+//
+// 签名算法：HMAC-SHA256(secret, METHOD + "\n" + PATH + "\n" + TIMESTAMP + "\n" + NONCE + "\n" + SHA256_HEX(BODY))，结果为十六进制
+func (s *apiClientService) Verify(req *VerifyRequest) (*model.APIClient, error) {
+	if req.AccessKey == "" || req.Timestamp == "" || req.Nonce == "" || req.Signature == "" {
+		return nil, errors.New("缺少签名信息")
+	}
+	if len(req.Nonce) < 8 || len(req.Nonce) > 64 {
+		return nil, errors.New("nonce长度应为8-64个字符")
+	}
+
+	ts, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("时间戳格式错误")
+	}
+	skew := time.Since(time.Unix(ts, 0))
+	if skew > TimestampTolerance || skew < -TimestampTolerance {
+		return nil, errors.New("请求已过期")
+	}
+
+	var client model.APIClient
+	if err := global.DB.Where("access_key = ?", req.AccessKey).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("客户端不存在")
+		}
+		return nil, errors.New("查询客户端失败")
+	}
+	if !client.Enabled {
+		return &client, errors.New("客户端已停用")
+	}
+
+	secret, err := utils.DecryptSecret(client.Secret)
+	if err != nil {
+		fmt.Printf("解密API客户端密钥失败: client_id=%d, err=%v\n", client.ID, err)
+		return &client, errors.New("客户端密钥不可用")
+	}
+	expected := Sign(secret, req.Method, req.Path, req.Timestamp, req.Nonce, req.Body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(req.Signature))) {
+		return &client, errors.New("签名错误")
+	}
+	rewrapClientSecret(&client)
+
+	// 签名通过后再登记nonce，避免伪造请求占用合法客户端的nonce
+	fresh, err := registerNonce(client.AccessKey, req.Nonce)
+	if err != nil {
+		return &client, errors.New("登记nonce失败")
+	}
+	if !fresh {
+		return &client, errors.New("重复的请求")
+	}
+
+	if !hasScope(client.Scopes, req.Scope) {
+		return &client, ErrScopeDenied
+	}
+
+	now := time.Now()
+	global.DB.Model(&client).UpdateColumn("last_used_at", now)
+
+	return &client, nil
+}
+
+// encryptSecret 加密新生成的客户端密钥
+func encryptSecret(secret string) (string, error) {
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("加密密钥失败: %w", err)
+	}
+	return encrypted, nil
+}
+
+// rewrapClientSecret 将历史明文密钥或使用旧主密钥加密的密钥用当前主密钥重新加密并写回
+// 仅当数据库中的密钥未被并发重置时写入；未配置主密钥或写入失败时保留原值并继续使用
+func rewrapClientSecret(client *model.APIClient) {
+	rewrapped, changed, err := utils.RewrapSecret(client.Secret)
+	if err != nil || !changed {
+		return
+	}
+	if err := global.DB.Model(&model.APIClient{}).
+		Where("id = ? AND secret = ?", client.ID, client.Secret).
+		UpdateColumn("secret", rewrapped).Error; err != nil {
+		fmt.Printf("重新加密API客户端密钥失败: client_id=%d, err=%v\n", client.ID, err)
+		return
+	}
+	client.Secret = rewrapped
+}
+
+// registerNonce 在数据库中登记nonce，有效期内已登记过（其他实例也算）时返回 false
+// 有效期为时间戳允许偏差的两倍，超出后请求本身会因时间戳过期被拒绝；已过期的旧记录直接覆盖
+func registerNonce(accessKey, nonce string) (bool, error) {
+	now := time.Now()
+	result := global.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "access_key"}, {Name: "nonce"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "api_client_nonces.expires_at <= ?", Vars: []interface{}{now}},
+		}},
+	}).Create(&model.APIClientNonce{
+		AccessKey: accessKey,
+		Nonce:     nonce,
+		ExpiresAt: now.Add(2 * TimestampTolerance),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CleanExpiredNonces 删除已过期的nonce记录，返回删除数量
+func CleanExpiredNonces() (int64, error) {
+	result := global.DB.Where("expires_at <= ?", time.Now()).Delete(&model.APIClientNonce{})
+	return result.RowsAffected, result.Error
+}
+
+// LogRejected 记录被拒绝的服务间请求
+func (s *apiClientService) LogRejected(req *VerifyRequest, client *model.APIClient, ip, userAgent, reason string) {
+	details := map[string]interface{}{
+		"access_key": req.AccessKey,
+		"method":     req.Method,
+		"path":       req.Path,
+		"scope":      req.Scope,
+	}
+	if client != nil {
+		details["client_id"] = client.ID
+		details["client_name"] = client.Name
+	}
+	detailsJSON, _ := json.Marshal(details)
+
+	global.EventLog.Log(context.Background(), &model.EventLog{
+		EventType:     eventlog.EventServiceAuthFailed,
+		EventCategory: eventlog.CategorySystem,
+		IPAddress:     ip,
+		UserAgent:     userAgent,
+		ResourceType:  "api_client",
+		ErrorMessage:  reason,
+		Status:        eventlog.StatusFailed,
+		Details:       model.JSON(detailsJSON),
+	})
+}
+
+// Sign 计算请求签名，供调用方和服务端共用
+func Sign(secret, method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	payload := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// marshalScopes 校验并序列化授权范围
+func marshalScopes(scopes []string) (model.JSON, error) {
+	for _, scope := range scopes {
+		valid := false
+		for _, allowed := range AllScopes {
+			if scope == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("不支持的授权范围: %s", scope)
+		}
+	}
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, errors.New("授权范围格式错误")
+	}
+	return model.JSON(scopesJSON), nil
+}
+
+// hasScope 判断客户端是否拥有指定授权范围
+func hasScope(scopesJSON model.JSON, scope string) bool {
+	var scopes []string
+	if err := json.Unmarshal(scopesJSON, &scopes); err != nil {
+		return false
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New("生成随机密钥失败")
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,65 @@
+package apiclient
+
+import "time"
+
+// 授权范围
+const (
+	ScopeBillingCheck   = "billing:check"   // 检查积分
+	ScopeBillingDeduct  = "billing:deduct"  // 扣减积分
+	ScopeBillingRefund  = "billing:refund"  // 退还积分
+	ScopeBillingReserve = "billing:reserve" // 预扣、结算、释放积分
+)
+
+// AllScopes 所有可授权的范围
+var AllScopes = []string{
+	ScopeBillingCheck,
+	ScopeBillingDeduct,
+	ScopeBillingRefund,
+	ScopeBillingReserve,
+}
+
+// 签名请求头
+const (
+	HeaderAccessKey = "X-Client-Key" // 客户端访问标识
+	HeaderTimestamp = "X-Timestamp"  // Unix时间戳（秒）
+	HeaderNonce     = "X-Nonce"      // 随机串，同一客户端在有效期内不可重复
+	HeaderSignature = "X-Signature"  // HMAC-SHA256签名（十六进制）
+)
+
+// TimestampTolerance 请求时间戳允许的最大偏差，超出视为过期请求
+// 已使用的nonce保留两倍时长，覆盖整个可接受的时间窗口
+const TimestampTolerance = 5 * time.Minute
+
+// CreateAPIClientRequest 创建API客户端请求
+type CreateAPIClientRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes" binding:"required,min=1"`
+}
+
+// UpdateAPIClientRequest 更新API客户端请求
+type UpdateAPIClientRequest struct {
+	Description *string  `json:"description"`
+	Scopes      []string `json:"scopes"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// APIClientSecretResponse 包含密钥的响应，仅在创建和重置密钥时返回一次
+type APIClientSecretResponse struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	AccessKey string `json:"access_key"`
+	Secret    string `json:"secret"`
+}
+
+// VerifyRequest 待验证的签名请求
+type VerifyRequest struct {
+	AccessKey string
+	Timestamp string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string // 包含查询参数的请求路径
+	Body      []byte
+	Scope     string // 接口要求的授权范围
+}
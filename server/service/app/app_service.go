@@ -2,7 +2,6 @@ package app
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -15,6 +14,8 @@ import (
 
 	"server/global"
 	"server/model"
+	"server/service/billing"
+	"server/service/upstream"
 	"server/utils"
 
 	"github.com/gin-gonic/gin"
@@ -230,10 +231,15 @@ func (s *appService) CreateWorkflow(userID string, req CreateWorkflowRequest) (*
 		return nil, errors.New("输出参数格式错误")
 	}
 
+	apiKey, err := encryptAPIKey(req.ApiKey)
+	if err != nil {
+		return nil, err
+	}
+
 	workflow := model.Workflow{
 		ID:          utils.GenerateTLID(),
 		ApiURL:      req.ApiURL,
-		ApiKey:      req.ApiKey,
+		ApiKey:      apiKey,
 		Name:        req.Name,
 		Description: req.Description,
 		CreatorID:   userID,
@@ -243,8 +249,14 @@ func (s *appService) CreateWorkflow(userID string, req CreateWorkflowRequest) (*
 		Used:        0,
 	}
 
-	if err := global.DB.Create(&workflow).Error; err != nil {
-		return nil, errors.New("创建工作流失败")
+	if err := global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&workflow).Error; err != nil {
+			return errors.New("创建工作流失败")
+		}
+		_, err := recordWorkflowVersionInTx(tx, &workflow, userID, nil, "初始配置")
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	response := &WorkflowResponse{
@@ -276,10 +288,53 @@ func (s *appService) DeleteWorkflow(workflowID, userID string) error {
 }
 
 // ExecuteWorkflow 执行工作流
-func (s *appService) ExecuteWorkflow(workflowID, userID string, inputs map[string]interface{}) (*ExecuteWorkflowResponse, error) {
-	startTime := time.Now()
+// 按工作流关联的计费动作预扣积分，成功后按实际token用量结算，执行失败时释放
+// 开启响应缓存的工作流命中缓存时直接返回缓存结果，按缓存计费动作扣费
+// ctx 取消时中止上游调用，按执行失败释放预扣
+func (s *appService) ExecuteWorkflow(ctx context.Context, workflowID, userID string, inputs map[string]interface{}) (*ExecuteWorkflowResponse, error) {
+	workflow, err := s.getWorkflowByID(workflowID)
+	if err != nil {
+		return nil, err
+	}
 
-	// 获取工作流信息
+	// 先校验输入，避免无效请求预扣积分和调用上游
+	inputs, err = validateWorkflowInputs(workflow, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := workflowCacheKey(workflow, userID, inputs)
+	if entry := s.lookupWorkflowCache(workflow, cacheKey); entry != nil {
+		return s.serveFromCache(workflow, userID, inputs, entry)
+	}
+
+	// 预扣积分（不计费的工作流返回0），按量计费的动作预扣单次上限
+	holdID, err := billing.ReserveForWorkflow(userID, workflow, billing.DefaultHoldTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	response, usage, status, errorMessage := s.executeBlocking(ctx, workflow, userID, inputs, holdID)
+
+	if status == "failed" {
+		if err := billing.ReleaseCreditHold(userID, holdID, "工作流执行失败: "+errorMessage); err != nil {
+			fmt.Printf("释放工作流预扣失败: user_id=%s, hold_id=%d, err=%v\n", userID, holdID, err)
+		}
+	} else {
+		if err := billing.SettleCreditHold(userID, holdID, usage.TotalTokens); err != nil {
+			fmt.Printf("结算工作流预扣失败: user_id=%s, hold_id=%d, err=%v\n", userID, holdID, err)
+		}
+		s.storeWorkflowCache(workflow, userID, cacheKey, response, usage)
+	}
+
+	if response != nil {
+		response.QuotaStatus = billing.GetWorkflowQuotaStatus(userID, workflow)
+	}
+	return response, nil
+}
+
+// getWorkflowByID 根据ID查询工作流
+func (s *appService) getWorkflowByID(workflowID string) (*model.Workflow, error) {
 	var workflow model.Workflow
 	if err := global.DB.Where("id = ?", workflowID).First(&workflow).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -287,14 +342,22 @@ func (s *appService) ExecuteWorkflow(workflowID, userID string, inputs map[strin
 		}
 		return nil, errors.New("查询工作流失败")
 	}
+	return &workflow, nil
+}
+
+// executeBlocking 以阻塞模式调用工作流并记录执行日志，不涉及计费
+// holdID 为调用方的预扣ID，仅用于关联执行日志
+// 返回响应、上游报告的用量、执行状态（success/failed）和错误信息
+func (s *appService) executeBlocking(ctx context.Context, workflow *model.Workflow, userID string, inputs map[string]interface{}, holdID int64) (*ExecuteWorkflowResponse, WorkflowUsage, string, string) {
+	startTime := time.Now()
 
-	// 实现实际的工作流执行逻辑
 	var response *ExecuteWorkflowResponse
+	var usage WorkflowUsage
 	var status string
 	var errorMessage string
 
-	// 调用远程工作流API
-	apiResponse, err := s.callWorkflowAPI(workflow.ApiURL, workflow.ApiKey, userID, inputs)
+	// 调用远程工作流API，上游不可用时切换到备用工作流
+	apiResponse, servedBy, err := s.callWorkflowWithFailover(ctx, workflow, userID, inputs)
 	if err != nil {
 		errorMessage = err.Error()
 		status = "failed"
@@ -304,6 +367,12 @@ func (s *appService) ExecuteWorkflow(workflowID, userID string, inputs map[strin
 			Message: fmt.Sprintf("工作流执行失败: %s", err.Error()),
 		}
 	} else {
+		usage = WorkflowUsage{
+			TotalTokens: apiResponse.Data.TotalTokens,
+			TotalSteps:  apiResponse.Data.TotalSteps,
+			ElapsedTime: apiResponse.Data.ElapsedTime,
+		}
+
 		// 根据API响应状态判断结果
 		if apiResponse.Data.Status == "succeeded" {
 			status = "success"
@@ -316,7 +385,7 @@ func (s *appService) ExecuteWorkflow(workflowID, userID string, inputs map[strin
 				"total_steps":     apiResponse.Data.TotalSteps,
 			}
 			// 如果是ChatFlow，添加conversation_id到返回数据
-			if apiResponse.Data.WorkflowID != "" && strings.HasSuffix(strings.TrimRight(workflow.ApiURL, "/"), "chat-messages") {
+			if apiResponse.Data.WorkflowID != "" && isChatFlowURL(servedBy.ApiURL) {
 				responseData["conversation_id"] = apiResponse.Data.WorkflowID
 			}
 			response = &ExecuteWorkflowResponse{
@@ -343,13 +412,16 @@ func (s *appService) ExecuteWorkflow(workflowID, userID string, inputs map[strin
 		}
 	}
 
-	// 计算执行时间
-	executionTime := int(time.Since(startTime).Milliseconds())
+	// 切换到备用工作流时在返回数据中标明实际执行的工作流
+	if servedBy != workflow {
+		response.Data["failover_workflow_id"] = servedBy.ID
+	}
 
-	// 记录工作流执行日志
-	s.LogWorkflowExecution(workflowID, userID, inputs, response, status, errorMessage, executionTime)
+	// 计算执行时间并记录日志
+	executionTime := int(time.Since(startTime).Milliseconds())
+	s.LogWorkflowExecution(workflow, userID, inputs, response, status, errorMessage, executionTime, usage, holdID)
 
-	return response, nil
+	return response, usage, status, errorMessage
 }
 
 // GetAllWorkflows 获取所有工作流（管理员）
@@ -360,7 +432,13 @@ func (s *appService) GetAllWorkflows() ([]AdminWorkflowResponse, error) {
 	}
 
 	var responses []AdminWorkflowResponse
-	for _, workflow := range workflows {
+	for i := range workflows {
+		workflow := &workflows[i]
+		// 首次读取时迁移历史明文密钥
+		if _, err := ResolveWorkflowAPIKey(workflow); err != nil {
+			fmt.Printf("读取工作流API密钥失败: workflow_id=%s, err=%v\n", workflow.ID, err)
+		}
+
 		var inputs, outputs interface{}
 		if len(workflow.Inputs) > 0 {
 			json.Unmarshal(workflow.Inputs, &inputs)
@@ -372,7 +450,7 @@ func (s *appService) GetAllWorkflows() ([]AdminWorkflowResponse, error) {
 		response := AdminWorkflowResponse{
 			ID:          workflow.ID,
 			ApiURL:      workflow.ApiURL,
-			ApiKey:      workflow.ApiKey,
+			ApiKey:      maskAPIKey(workflow.ApiKey),
 			Name:        workflow.Name,
 			Description: workflow.Description,
 			CreatorID:   workflow.CreatorID,
@@ -383,6 +461,16 @@ func (s *appService) GetAllWorkflows() ([]AdminWorkflowResponse, error) {
 			IsPublic:    workflow.IsPublic,
 			CreatedAt:   workflow.CreatedAt,
 			UpdatedAt:   workflow.UpdatedAt,
+
+			BillingActionKey: workflow.BillingActionKey,
+			ConfigVersion:    workflow.ConfigVersion,
+			MaxConcurrency:   workflow.MaxConcurrency,
+
+			FallbackWorkflowID: workflow.FallbackWorkflowID,
+
+			CacheEnabled:    workflow.CacheEnabled,
+			CacheTTLSeconds: workflow.CacheTTLSeconds,
+			CacheActionKey:  workflow.CacheActionKey,
 		}
 		responses = append(responses, response)
 	}
@@ -390,23 +478,20 @@ func (s *appService) GetAllWorkflows() ([]AdminWorkflowResponse, error) {
 	return responses, nil
 }
 
-// AdminUpdateWorkflow 管理员更新工作流
-func (s *appService) UpdateWorkflow(workflowID string, req UpdateWorkflowRequest) error {
-	// 检查工作流是否存在
-	var workflow model.Workflow
-	if err := global.DB.Where("id = ?", workflowID).First(&workflow).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("工作流不存在")
-		}
-		return errors.New("查询工作流失败")
-	}
-
+// UpdateWorkflow 管理员更新工作流
+// 调用配置（API地址、密钥、输入输出字段、计费动作）发生变化时追加一个配置版本，authorID 记为版本作者
+func (s *appService) UpdateWorkflow(workflowID string, req UpdateWorkflowRequest, authorID string) error {
 	updates := make(map[string]interface{})
 	if req.ApiURL != "" {
 		updates["api_url"] = req.ApiURL
 	}
-	if req.ApiKey != "" {
-		updates["api_key"] = req.ApiKey
+	// 回传的脱敏值表示不修改密钥
+	if req.ApiKey != "" && !utils.IsMaskedSecret(req.ApiKey) {
+		apiKey, err := encryptAPIKey(req.ApiKey)
+		if err != nil {
+			return err
+		}
+		updates["api_key"] = apiKey
 	}
 	if req.Name != "" {
 		updates["name"] = req.Name
@@ -428,151 +513,176 @@ func (s *appService) UpdateWorkflow(workflowID string, req UpdateWorkflowRequest
 		}
 		updates["outputs"] = model.JSON(outputsJSON)
 	}
+	if req.BillingActionKey != nil {
+		if err := validateBillingActionKey(*req.BillingActionKey); err != nil {
+			return err
+		}
+		updates["billing_action_key"] = *req.BillingActionKey
+	}
+	if req.MaxConcurrency != nil {
+		if *req.MaxConcurrency < 0 {
+			return errors.New("最大并发数不能小于0")
+		}
+		updates["max_concurrency"] = *req.MaxConcurrency
+	}
+	if req.FallbackWorkflowID != nil {
+		if err := validateFallbackWorkflow(workflowID, *req.FallbackWorkflowID); err != nil {
+			return err
+		}
+		updates["fallback_workflow_id"] = *req.FallbackWorkflowID
+	}
+	if req.CacheEnabled != nil {
+		updates["cache_enabled"] = *req.CacheEnabled
+	}
+	if req.CacheTTLSeconds != nil {
+		if *req.CacheTTLSeconds < 0 {
+			return errors.New("缓存有效期不能小于0")
+		}
+		updates["cache_ttl_seconds"] = *req.CacheTTLSeconds
+	}
+	if req.CacheActionKey != nil {
+		if err := validateBillingActionKey(*req.CacheActionKey); err != nil {
+			return err
+		}
+		updates["cache_action_key"] = *req.CacheActionKey
+	}
 	updates["enabled"] = req.Enabled
 	updates["is_public"] = req.IsPublic
 
-	if err := global.DB.Model(&workflow).Updates(updates).Error; err != nil {
-		return errors.New("更新工作流失败")
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		// 检查工作流是否存在，并锁定以保证版本号连续
+		workflow, err := lockWorkflow(tx, workflowID)
+		if err != nil {
+			return err
+		}
+		if err := ensureBaselineVersionInTx(tx, workflow); err != nil {
+			return err
+		}
+		current := model.WorkflowVersion{
+			ApiURL:           workflow.ApiURL,
+			ApiKey:           workflow.ApiKey,
+			Inputs:           workflow.Inputs,
+			Outputs:          workflow.Outputs,
+			BillingActionKey: workflow.BillingActionKey,
+		}
+
+		if err := tx.Model(workflow).Updates(updates).Error; err != nil {
+			return errors.New("更新工作流失败")
+		}
+		if err := tx.Where("id = ?", workflowID).First(workflow).Error; err != nil {
+			return errors.New("查询工作流失败")
+		}
+
+		if sameWorkflowConfig(workflow, &current) {
+			return nil
+		}
+		_, err = recordWorkflowVersionInTx(tx, workflow, authorID, nil, req.Notes)
+		return err
+	})
+}
+
+// validateBillingActionKey 校验计费动作是否存在，空字符串表示免费
+func validateBillingActionKey(actionKey string) error {
+	if actionKey == "" {
+		return nil
 	}
 
+	var count int64
+	if err := global.DB.Model(&model.BillingActionPrice{}).Where("action_key = ?", actionKey).Count(&count).Error; err != nil {
+		return errors.New("查询计费动作失败")
+	}
+	if count == 0 {
+		return fmt.Errorf("计费动作不存在: %s", actionKey)
+	}
 	return nil
 }
 
 // LogWorkflowExecution 记录工作流执行日志
-func (s *appService) LogWorkflowExecution(workflowID, userID string, inputs map[string]interface{}, response *ExecuteWorkflowResponse, status string, errorMessage string, executionTime int) {
+// workflow 为执行时的工作流配置，用于记录配置版本并按声明的输出字段检查输出是否漂移
+// usage 为上游报告的用量，deductionID 为关联的积分扣减（预扣）记录，0表示不计费
+func (s *appService) LogWorkflowExecution(workflow *model.Workflow, userID string, inputs map[string]interface{}, response *ExecuteWorkflowResponse, status string, errorMessage string, executionTime int, usage WorkflowUsage, deductionID int64) {
 	go func() {
-		// 获取工作流信息用于更新使用次数
-		var workflow model.Workflow
-		global.DB.Where("id = ?", workflowID).First(&workflow)
-
 		inputsJSON, _ := json.Marshal(inputs)
 		outputsJSON, _ := json.Marshal(response.Data)
 
 		execution := model.WorkflowExecution{
 			ID:            utils.GenerateTLID(),
-			WorkflowID:    workflowID,
+			WorkflowID:    workflow.ID,
 			UserID:        userID,
 			Inputs:        model.JSON(inputsJSON),
 			Outputs:       model.JSON(outputsJSON),
 			Status:        status,
 			ErrorMessage:  errorMessage,
 			ExecutionTime: executionTime,
+
+			TotalTokens: usage.TotalTokens,
+			TotalSteps:  usage.TotalSteps,
+			ElapsedTime: usage.ElapsedTime,
+
+			ConfigVersion: workflow.ConfigVersion,
+		}
+		if failoverWorkflowID, ok := response.Data["failover_workflow_id"].(string); ok {
+			execution.FailoverWorkflowID = failoverWorkflowID
+		}
+		if cacheHit, ok := response.Data["cache_hit"].(bool); ok {
+			execution.CacheHit = cacheHit
+		}
+		if deductionID != 0 {
+			execution.DeductionID = &deductionID
+		}
+
+		// 仅检查成功执行的输出
+		if response.Success {
+			outputs, _ := response.Data["outputs"].(map[string]interface{})
+			if drift := checkOutputSchema(workflow, outputs); len(drift) > 0 {
+				driftJSON, _ := json.Marshal(drift)
+				execution.SchemaDrift = model.JSON(driftJSON)
+				execution.HasDrift = true
+				fmt.Printf("工作流输出与声明字段不一致: workflow_id=%s, config_version=%d, drift=%s\n", workflow.ID, workflow.ConfigVersion, driftJSON)
+			}
 		}
 
 		global.DB.Create(&execution)
 
 		// 只有成功时才增加使用次数
 		if status == "success" {
-			global.DB.Model(&workflow).UpdateColumn("used", gorm.Expr("used + ?", 1))
+			global.DB.Model(&model.Workflow{}).Where("id = ?", workflow.ID).UpdateColumn("used", gorm.Expr("used + ?", 1))
 		}
 	}()
 }
 
 // ExecuteWorkflowAPI 执行工作流API并自动记录日志 (公开方法)
+// 供其他服务调用，计费规则与 ExecuteWorkflow 一致
 func (s *appService) ExecuteWorkflowAPI(workflowID, userID string, inputs map[string]interface{}) (*ExecuteWorkflowResponse, error) {
-	startTime := time.Now()
+	return s.ExecuteWorkflow(context.Background(), workflowID, userID, inputs)
+}
 
-	// 获取工作流信息
-	var workflow model.Workflow
-	if err := global.DB.Where("id = ?", workflowID).First(&workflow).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("工作流不存在")
-		}
-		return nil, errors.New("查询工作流失败")
+// ExecuteWorkflowWithoutBilling 执行工作流并记录日志，但不扣减积分
+// 仅供已自行预扣积分的调用方使用（如面试分析），调用方负责结算（可按 response.Data["total_tokens"] 按量结算）或释放预扣
+// holdID 为调用方的预扣ID，仅用于关联执行日志
+func (s *appService) ExecuteWorkflowWithoutBilling(ctx context.Context, workflowID, userID string, inputs map[string]interface{}, holdID int64) (*ExecuteWorkflowResponse, error) {
+	workflow, err := s.getWorkflowByID(workflowID)
+	if err != nil {
+		return nil, err
 	}
-
-	// 调用私有的工作流API方法
-	var response *ExecuteWorkflowResponse
-	var status string
-	var errorMessage string
-
-	apiResponse, err := s.callWorkflowAPI(workflow.ApiURL, workflow.ApiKey, userID, inputs)
+	inputs, err = validateWorkflowInputs(workflow, inputs)
 	if err != nil {
-		errorMessage = err.Error()
-		status = "failed"
-		response = &ExecuteWorkflowResponse{
-			Success: false,
-			Data:    map[string]interface{}{},
-			Message: fmt.Sprintf("工作流执行失败: %s", err.Error()),
-		}
-	} else {
-		// 根据API响应状态判断结果
-		if apiResponse.Data.Status == "succeeded" {
-			status = "success"
-			responseData := map[string]interface{}{
-				"workflow_run_id": apiResponse.WorkflowRunID,
-				"task_id":         apiResponse.TaskID,
-				"outputs":         apiResponse.Data.Outputs,
-				"elapsed_time":    apiResponse.Data.ElapsedTime,
-				"total_tokens":    apiResponse.Data.TotalTokens,
-				"total_steps":     apiResponse.Data.TotalSteps,
-			}
-			// 如果是ChatFlow，添加conversation_id到返回数据
-			if apiResponse.Data.WorkflowID != "" && strings.HasSuffix(strings.TrimRight(workflow.ApiURL, "/"), "chat-messages") {
-				responseData["conversation_id"] = apiResponse.Data.WorkflowID
-			}
-			response = &ExecuteWorkflowResponse{
-				Success: true,
-				Data:    responseData,
-				Message: "工作流执行成功",
-			}
-		} else {
-			status = "failed"
-			errorMsg := "未知错误"
-			if apiResponse.Data.Error != "" {
-				errorMsg = apiResponse.Data.Error
-			}
-			errorMessage = errorMsg
-			response = &ExecuteWorkflowResponse{
-				Success: false,
-				Data: map[string]interface{}{
-					"workflow_run_id": apiResponse.WorkflowRunID,
-					"task_id":         apiResponse.TaskID,
-					"status":          apiResponse.Data.Status,
-				},
-				Message: fmt.Sprintf("工作流执行失败: %s", errorMsg),
-			}
-		}
+		return nil, err
 	}
 
-	// 计算执行时间并记录日志
-	executionTime := int(time.Since(startTime).Milliseconds())
-	s.LogWorkflowExecution(workflowID, userID, inputs, response, status, errorMessage, executionTime)
-
+	response, _, _, _ := s.executeBlocking(ctx, workflow, userID, inputs, holdID)
 	return response, nil
 }
 
 // callWorkflowAPI 调用远程工作流API (私有方法)
 // 根据API URL类型自动判断返回格式（WorkflowAPIResponse 或 ChatFlowAPIResponse）
-func (s *appService) callWorkflowAPI(apiURL, apiKey, userID string, inputs map[string]interface{}) (*WorkflowAPIResponse, error) {
+// 通过共享的上游客户端发送，按 workflowID 熔断
+func (s *appService) callWorkflowAPI(ctx context.Context, workflowID, apiURL, apiKey, userID string, inputs map[string]interface{}) (*WorkflowAPIResponse, error) {
 	// 判断是否为ChatFlow API
-	isChatFlow := strings.HasSuffix(strings.TrimRight(apiURL, "/"), "chat-messages")
+	isChatFlow := isChatFlowURL(apiURL)
 
 	// 构建请求体
-	requestBody := WorkflowAPIRequest{
-		Inputs:       inputs,
-		ResponseMode: "blocking", // 只支持blocking模式
-		User:         userID,
-	}
-
-	// inputs 中的 __query pop 出来，赋给 requestBody.Query
-	// __conversation_id 用于 ChatFlow API 的会话上下文
-	// __xx 为特殊变量，单独处理
-	query, ok := inputs["__query"]
-	if ok {
-		requestBody.Query = query.(string)
-		delete(inputs, "__query")
-	}
-
-	// 如果是 ChatFlow API，处理 conversation_id
-	if isChatFlow {
-		if conversationID, ok := inputs["__conversation_id"]; ok {
-			if conversationID.(string) != "" {
-				requestBody.ConversationID = conversationID.(string)
-			}
-			delete(inputs, "__conversation_id")
-		}
-	}
+	requestBody := buildWorkflowRequest(apiURL, userID, "blocking", inputs) // 只支持blocking模式
 
 	// 序列化请求体
 	jsonData, err := json.Marshal(requestBody)
@@ -580,21 +690,18 @@ func (s *appService) callWorkflowAPI(apiURL, apiKey, userID string, inputs map[s
 		return nil, fmt.Errorf("序列化请求数据失败: %w", err)
 	}
 
-	// 创建HTTP请求
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
-	}
-
 	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-
-	// 创建HTTP客户端并发送请求
-	client := &http.Client{Timeout: 300 * time.Second} // 设置300秒超时
-	fmt.Println("[request workflow]", req)
-
-	resp, err := client.Do(req)
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+
+	resp, err := workflowClient.Do(ctx, &upstream.Request{
+		Method:  "POST",
+		URL:     apiURL,
+		Header:  header,
+		Body:    jsonData,
+		Breaker: WorkflowBreakerKey(workflowID),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("发送HTTP请求失败: %w", err)
 	}
@@ -605,11 +712,12 @@ func (s *appService) callWorkflowAPI(apiURL, apiKey, userID string, inputs map[s
 	if err != nil {
 		return nil, fmt.Errorf("读取响应体失败: %w", err)
 	}
-	fmt.Println("[response workflow] ", string(body))
+	// 请求和响应内容包含用户的简历、面试文本，只记录地址和状态码
+	fmt.Printf("[workflow] POST %s: status=%d\n", apiURL, resp.StatusCode)
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))
+		return nil, &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// 根据API响应类型解析数据（isChatFlow 已在函数开头定义）
@@ -663,6 +771,9 @@ var (
 )
 
 // ExecuteWorkflowStream 流式执行工作流
+// 上游执行在后台协程中进行，事件缓存在流ID下（响应头 X-Stream-ID，也作为第一个 stream_started 事件发送）；
+// 客户端短暂断开不会中断执行，可通过 ResumeWorkflowStream 携带 Last-Event-ID 重连，补发遗漏的事件后继续接收，
+// 或通过 CancelWorkflowStream 取消
 func (s *appService) ExecuteWorkflowStream(c *gin.Context, workflowID, userID string, inputs map[string]interface{}) error {
 	// 获取工作流信息
 	var workflow model.Workflow
@@ -673,12 +784,22 @@ func (s *appService) ExecuteWorkflowStream(c *gin.Context, workflowID, userID st
 		return errors.New("查询工作流失败")
 	}
 
-	// 设置SSE响应头
-	s.setSSEHeaders(c)
+	// 先校验输入，避免无效请求预扣积分和调用上游
+	inputs, err := validateWorkflowInputs(&workflow, inputs)
+	if err != nil {
+		return err
+	}
+
+	// 预扣积分，执行结果在流结束后才能确定
+	holdID, err := billing.ReserveForWorkflow(userID, &workflow, billing.DefaultHoldTTL)
+	if err != nil {
+		return err
+	}
 
-	// 创建流式执行上下文
-	ctx, cancel := context.WithCancel(c.Request.Context())
+	// 创建流式执行上下文，上游执行不绑定当前请求，由所有客户端断开超时或主动取消时结束
+	ctx, cancel := context.WithCancel(context.Background())
 	streamCtx := &StreamContext{
+		StreamID:   fmt.Sprintf("%s_%s_%d", workflowID, userID, time.Now().UnixNano()),
 		WorkflowID: workflowID,
 		UserID:     userID,
 		Inputs:     inputs,
@@ -686,123 +807,160 @@ func (s *appService) ExecuteWorkflowStream(c *gin.Context, workflowID, userID st
 		Done:       make(chan struct{}),
 		Error:      make(chan error, 1),
 		StartTime:  time.Now(),
+		HoldID:     holdID,
+		Workflow:   &workflow,
+		Buffer:     newStreamBuffer(),
 	}
 
-	// 注册流式上下文
-	streamID := fmt.Sprintf("%s_%s_%d", workflowID, userID, time.Now().UnixNano())
-	streamMutex.Lock()
-	streamContexts[streamID] = streamCtx
-	streamMutex.Unlock()
+	// 建立上游流，失败时释放预扣并以普通响应返回错误
+	resp, err := s.openWorkflowStreamWithFailover(ctx, streamCtx, &workflow)
+	if err != nil {
+		cancel()
+		s.settleStreamHold(streamCtx, err)
+		return err
+	}
 
-	// 清理函数
-	defer func() {
-		streamMutex.Lock()
-		delete(streamContexts, streamID)
-		streamMutex.Unlock()
-		close(streamCtx.Done)
-		close(streamCtx.Error)
-	}()
+	// 第一个事件告知客户端流ID，用于重连和取消
+	startedEvent, _ := json.Marshal(map[string]interface{}{
+		"event": "stream_started",
+		"data":  map[string]string{"stream_id": streamCtx.StreamID},
+	})
+	streamCtx.Buffer.append("", string(startedEvent))
 
-	// 直接在当前goroutine中处理流式请求
-	return s.handleWorkflowStreamDirect(ctx, c, streamCtx, workflow)
+	// 注册流式上下文，在后台处理上游事件
+	registerStream(streamCtx)
+	go s.runWorkflowStream(ctx, streamCtx, resp)
+
+	// 设置SSE响应头，附带动作使用配额和流ID
+	utils.SetHeaders(billing.GetWorkflowQuotaStatus(userID, &workflow).Headers(), c)
+	c.Header(StreamIDHeader, streamCtx.StreamID)
+	s.setSSEHeaders(c)
+
+	s.serveStream(c, streamCtx, 0)
+	return nil
 }
 
-// handleWorkflowStreamDirect 直接处理工作流流式执行
-func (s *appService) handleWorkflowStreamDirect(ctx context.Context, c *gin.Context, streamCtx *StreamContext, workflow model.Workflow) error {
+// runWorkflowStream 读取上游SSE流写入事件缓存，结束后结算预扣并在保留期后移除流
+// 处理过程中发生panic时同样结束流并释放预扣，避免客户端一直等待和流记录泄漏
+func (s *appService) runWorkflowStream(ctx context.Context, streamCtx *StreamContext, resp *http.Response) {
+	var err error
 	defer func() {
-		streamCtx.ExecutionTime = int(time.Since(streamCtx.StartTime).Milliseconds())
+		if r := recover(); r != nil {
+			fmt.Printf("流式执行异常: stream_id=%s, err=%v\n", streamCtx.StreamID, r)
+			err = fmt.Errorf("流式执行异常: %v", r)
+		}
+		s.settleStreamHold(streamCtx, err)
+
+		streamCtx.CancelFunc()
+		streamCtx.Buffer.finish()
+		close(streamCtx.Done)
+		close(streamCtx.Error)
+		releaseStream(streamCtx.StreamID)
 	}()
+	defer resp.Body.Close()
 
-	// 调用远程工作流流式API
-	return s.callWorkflowStreamAPIDirect(ctx, c, streamCtx, workflow.ApiURL, workflow.ApiKey)
+	err = s.processSSEStreamDirect(ctx, streamCtx, resp.Body)
 }
 
-// callWorkflowStreamAPIDirect 直接调用远程工作流流式API
-func (s *appService) callWorkflowStreamAPIDirect(ctx context.Context, c *gin.Context, streamCtx *StreamContext, apiURL, apiKey string) error {
-	// 判断是否为ChatFlow API
-	isChatFlow := strings.HasSuffix(strings.TrimRight(apiURL, "/"), "chat-messages")
+// settleStreamHold 根据流式执行结果结算或释放预扣积分
+// 仅当 workflow_finished 报告 succeeded 时按实际token用量结算，出错、客户端断开、超时均释放
+func (s *appService) settleStreamHold(streamCtx *StreamContext, streamErr error) {
+	if streamCtx.HoldID == 0 {
+		return
+	}
 
-	// 构建请求体
-	requestBody := WorkflowAPIRequest{
-		Inputs:       streamCtx.Inputs,
-		ResponseMode: "streaming",
-		User:         streamCtx.UserID,
+	if streamErr == nil && streamCtx.FinalStatus == "succeeded" {
+		if err := billing.SettleCreditHold(streamCtx.UserID, streamCtx.HoldID, streamCtx.Usage.TotalTokens); err != nil {
+			fmt.Printf("结算流式执行预扣失败: hold_id=%d, err=%v\n", streamCtx.HoldID, err)
+		}
+		return
 	}
 
-	// inputs 中的 __query pop 出来，赋给 requestBody.Query
-	// __conversation_id 用于 ChatFlow API 的会话上下文
-	// __xx 为特殊变量，单独处理
-	query, ok := streamCtx.Inputs["__query"]
-	if ok {
-		requestBody.Query = query.(string)
-		delete(streamCtx.Inputs, "__query")
+	reason := "工作流未成功完成"
+	if streamErr != nil {
+		reason = "流式执行中断: " + streamErr.Error()
+	} else if streamCtx.FinalStatus != "" {
+		reason = "工作流执行状态: " + streamCtx.FinalStatus
+	}
+	if err := billing.ReleaseCreditHold(streamCtx.UserID, streamCtx.HoldID, reason); err != nil {
+		fmt.Printf("释放流式执行预扣失败: hold_id=%d, err=%v\n", streamCtx.HoldID, err)
 	}
+}
 
-	// 如果是 ChatFlow API，处理 conversation_id
-	if isChatFlow {
-		if conversationID, ok := streamCtx.Inputs["__conversation_id"]; ok {
-			requestBody.ConversationID = conversationID.(string)
-			delete(streamCtx.Inputs, "__conversation_id")
+// openWorkflowStreamWithFailover 建立工作流上游流
+// 上游不可用且配置了备用工作流时切换到备用工作流，流开始后不再切换
+func (s *appService) openWorkflowStreamWithFailover(ctx context.Context, streamCtx *StreamContext, workflow *model.Workflow) (*http.Response, error) {
+	streamCtx.upstreamWorkflow = workflow
+	resp, err := s.openWorkflowStream(ctx, streamCtx, workflow)
+	if err != nil && upstream.IsUnavailable(err) && workflow.FallbackWorkflowID != "" {
+		if fallback, fallbackErr := s.getFallbackWorkflow(workflow); fallbackErr == nil {
+			fmt.Printf("工作流上游不可用，切换到备用工作流: workflow_id=%s, fallback_workflow_id=%s, err=%v\n", workflow.ID, fallback.ID, err)
+			streamCtx.FailoverWorkflowID = fallback.ID
+			streamCtx.upstreamWorkflow = fallback
+			if resp, err = s.openWorkflowStream(ctx, streamCtx, fallback); err != nil {
+				err = fmt.Errorf("备用工作流执行失败: %w", err)
+			}
 		}
 	}
+	return resp, err
+}
 
-	// 序列化请求体
-	jsonData, err := json.Marshal(requestBody)
+// openWorkflowStream 调用远程工作流流式API，返回状态码为200的SSE响应
+func (s *appService) openWorkflowStream(ctx context.Context, streamCtx *StreamContext, workflow *model.Workflow) (*http.Response, error) {
+	apiKey, err := ResolveWorkflowAPIKey(workflow)
 	if err != nil {
-		return fmt.Errorf("序列化请求数据失败: %w", err)
+		return nil, err
 	}
 
-	fmt.Println("apiURL", apiURL)
-	fmt.Println("apiKey", apiKey)
-	fmt.Println("[request workflow stream]", string(jsonData))
+	// 构建请求体
+	requestBody := buildWorkflowRequest(workflow.ApiURL, streamCtx.UserID, "streaming", streamCtx.Inputs)
 
-	// 创建HTTP请求
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	// 序列化请求体
+	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
-		return fmt.Errorf("创建HTTP请求失败: %w", err)
+		return nil, fmt.Errorf("序列化请求数据失败: %w", err)
 	}
 
 	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
-	req.Header.Set("Accept", "text/event-stream")
-
-	// 创建HTTP客户端并发送请求
-	// 注意：不设置 http.Client.Timeout，因为它会限制整个请求生命周期（包括读取流式响应体）
-	// 对于 SSE 流式响应，超时应由 context 和 Transport 控制
-	client := &http.Client{
-		Transport: &http.Transport{
-			ResponseHeaderTimeout: 60 * time.Second,  // 等待响应头的超时
-			IdleConnTimeout:       600 * time.Second,  // 空闲连接超时
-		},
-	}
-	resp, err := client.Do(req)
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	header.Set("Accept", "text/event-stream")
+
+	resp, err := workflowStreamClient.Do(ctx, &upstream.Request{
+		Method:  "POST",
+		URL:     workflow.ApiURL,
+		Header:  header,
+		Body:    jsonData,
+		Breaker: WorkflowBreakerKey(workflow.ID),
+	})
 	if err != nil {
-		return fmt.Errorf("发送HTTP请求失败: %w", err)
+		return nil, fmt.Errorf("发送HTTP请求失败: %w", err)
 	}
-	defer resp.Body.Close()
+	fmt.Printf("[workflow stream] POST %s: status=%d\n", workflow.ApiURL, resp.StatusCode)
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API请求失败，状态码: %d, 响应: %s", resp.StatusCode, string(body))
+		resp.Body.Close()
+		return nil, &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
-
-	// 处理SSE流
-	return s.processSSEStreamDirect(ctx, c, streamCtx, resp.Body)
+	return resp, nil
 }
 
-// processSSEStreamDirect 直接处理SSE流并转发到gin.Context
-func (s *appService) processSSEStreamDirect(ctx context.Context, c *gin.Context, streamCtx *StreamContext, body io.ReadCloser) error {
+// processSSEStreamDirect 读取上游SSE流并写入事件缓存
+// event 行作为下一条 data 的事件类型，空行结束一条事件（没有 data 的事件如 ping 不转发）
+func (s *appService) processSSEStreamDirect(ctx context.Context, streamCtx *StreamContext, body io.ReadCloser) error {
 	var finalOutputs map[string]interface{}
 	var finalStatus string
 	var errorMessage string
+	var pendingEvent string
+	var taskID string // 上游任务ID，取消时用于停止上游执行
 
 	// 用 channel 将上游 SSE 数据从 scanner goroutine 传递到主循环
 	type scanResult struct {
 		line string
 		err  error
-		done bool
 	}
 	lineCh := make(chan scanResult, 10)
 
@@ -810,127 +968,140 @@ func (s *appService) processSSEStreamDirect(ctx context.Context, c *gin.Context,
 	go func() {
 		defer close(lineCh)
 		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
 		scanner.Split(bufio.ScanLines)
 		for scanner.Scan() {
-			lineCh <- scanResult{line: scanner.Text()}
+			select {
+			case lineCh <- scanResult{line: scanner.Text()}:
+			case <-ctx.Done():
+				return
+			}
 		}
 		if err := scanner.Err(); err != nil {
-			lineCh <- scanResult{err: err}
+			select {
+			case lineCh <- scanResult{err: err}:
+			case <-ctx.Done():
+			}
 		}
-		lineCh <- scanResult{done: true}
 	}()
 
-	// 心跳定时器：每 15 秒发送一个 SSE 注释行保持连接活跃
-	heartbeat := time.NewTicker(15 * time.Second)
-	defer heartbeat.Stop()
-
 	streamDone := false
 	for !streamDone {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-
-		case <-heartbeat.C:
-			// SSE 注释行（以 : 开头），客户端会忽略，但能保持连接不被中间层掐断
-			fmt.Fprintf(c.Writer, ": heartbeat\n\n")
-			c.Writer.Flush()
+			return s.finishCancelledStream(streamCtx, taskID)
 
 		case result, ok := <-lineCh:
 			if !ok {
-				// channel 已关闭
-				streamDone = true
-				break
-			}
-			if result.done {
+				// channel 已关闭，上游流结束
 				streamDone = true
 				break
 			}
 			if result.err != nil {
+				if ctx.Err() != nil {
+					return s.finishCancelledStream(streamCtx, taskID)
+				}
 				errorMessage = result.err.Error()
 				finalStatus = "failed"
 				// 向前端发送 error 事件，而不是直接断连
 				errEvent := fmt.Sprintf(`{"event": "error", "data": {"message": "上游连接中断: %s"}}`, result.err.Error())
-				fmt.Fprintf(c.Writer, "data: %s\n\n", errEvent)
-				c.Writer.Flush()
+				streamCtx.Buffer.append("", errEvent)
 				streamDone = true
 				break
 			}
 
-			line := result.line
+			line := strings.TrimSuffix(result.line, "\r")
 			if len(line) == 0 {
+				pendingEvent = ""
 				continue
 			}
 
 			// 处理SSE数据行
 			if strings.HasPrefix(line, "data: ") {
 				data := strings.TrimPrefix(line, "data: ")
-				data = strings.TrimSuffix(data, "\r")
 
 				if data == "[DONE]" {
 					streamDone = true
 					break
 				}
 
-				// 转发给客户端
-				fmt.Fprintf(c.Writer, "data: %s\n\n", data)
-				c.Writer.Flush()
+				// 写入事件缓存，由客户端连接转发
+				streamCtx.Buffer.append(pendingEvent, data)
+
+				if taskID == "" {
+					var event struct {
+						TaskID string `json:"task_id"`
+					}
+					if json.Unmarshal([]byte(data), &event) == nil {
+						taskID = event.TaskID
+					}
+				}
 
 				// 检查是否为workflow_finished事件
 				if strings.HasPrefix(data, `{"event": "workflow_finished"`) {
-					outputs, status, err := s.parseWorkflowFinishedEvent(data)
+					finished, err := s.parseWorkflowFinishedEvent(data)
 					if err == nil {
-						finalOutputs = outputs
-						finalStatus = status
+						finalOutputs = finished.Outputs
+						finalStatus = finished.Status
+						streamCtx.Usage = WorkflowUsage{
+							TotalTokens: int(finished.TotalTokens),
+							TotalSteps:  finished.TotalSteps,
+							ElapsedTime: finished.ElapsedTime,
+						}
 					}
 				}
 			} else if strings.HasPrefix(line, "event: ") {
-				// 转发事件行
-				fmt.Fprintf(c.Writer, "%s\n", line)
-				c.Writer.Flush()
+				pendingEvent = strings.TrimPrefix(line, "event: ")
 			}
 		}
 	}
 
+	streamCtx.FinalStatus = finalStatus
+	streamCtx.ExecutionTime = int(time.Since(streamCtx.StartTime).Milliseconds())
+
 	// 记录执行日志
 	response := &ExecuteWorkflowResponse{
 		Success: finalStatus == "succeeded",
 		Data:    map[string]interface{}{"outputs": finalOutputs},
 		Message: "工作流执行完成",
 	}
+	if streamCtx.FailoverWorkflowID != "" {
+		response.Data["failover_workflow_id"] = streamCtx.FailoverWorkflowID
+	}
 
 	if finalStatus == "failed" {
 		response.Success = false
 		response.Message = fmt.Sprintf("工作流执行失败: %s", errorMessage)
 	}
 
-	s.LogWorkflowExecution(streamCtx.WorkflowID, streamCtx.UserID, streamCtx.Inputs, response, finalStatus, errorMessage, streamCtx.ExecutionTime)
+	s.LogWorkflowExecution(streamCtx.Workflow, streamCtx.UserID, streamCtx.Inputs, response, finalStatus, errorMessage, streamCtx.ExecutionTime, streamCtx.Usage, streamCtx.HoldID)
 
 	return nil
 }
 
 // parseWorkflowFinishedEvent 解析workflow_finished事件
-func (s *appService) parseWorkflowFinishedEvent(data string) (map[string]interface{}, string, error) {
+func (s *appService) parseWorkflowFinishedEvent(data string) (*WorkflowFinishedEventData, error) {
 	var event WorkflowStreamEvent
 	if err := json.Unmarshal([]byte(data), &event); err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	if event.Event != "workflow_finished" {
-		return nil, "", errors.New("不是workflow_finished事件")
+		return nil, errors.New("不是workflow_finished事件")
 	}
 
 	// 解析事件数据
 	dataBytes, err := json.Marshal(event.Data)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	var finishedData WorkflowFinishedEventData
 	if err := json.Unmarshal(dataBytes, &finishedData); err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return finishedData.Outputs, finishedData.Status, nil
+	return &finishedData, nil
 }
 
 // setSSEHeaders 设置SSE响应头
@@ -954,19 +1125,6 @@ func (s *appService) setSSEHeaders(c *gin.Context) {
 // 	c.Writer.Flush()
 // }
 
-// CancelWorkflowStream 取消流式执行
-func (s *appService) CancelWorkflowStream(streamID string) error {
-	streamMutex.RLock()
-	streamCtx, exists := streamContexts[streamID]
-	streamMutex.RUnlock()
-
-	if !exists {
-		return errors.New("流式执行不存在")
-	}
-
-	streamCtx.CancelFunc()
-	return nil
-}
 
 func (s *appService) ExecuteWorkflowByName(c *gin.Context, workflowName, userID string, inputs map[string]interface{}, responseMode string) (*ExecuteWorkflowResponse, error) {
 
@@ -980,9 +1138,8 @@ func (s *appService) ExecuteWorkflowByName(c *gin.Context, workflowName, userID
 	}
 
 	if responseMode == "blocking" {
-		return s.ExecuteWorkflow(workflow.ID, userID, inputs)
+		return s.ExecuteWorkflow(c.Request.Context(), workflow.ID, userID, inputs)
 	} else {
-		s.ExecuteWorkflowStream(c, workflow.ID, userID, inputs)
-		return nil, nil
+		return nil, s.ExecuteWorkflowStream(c, workflow.ID, userID, inputs)
 	}
 }
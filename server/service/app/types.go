@@ -1,6 +1,25 @@
 package app
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"server/model"
+	"server/service/billing"
+)
+
+var (
+	// ErrStreamNotFound 流式执行不存在、已过期或不属于当前用户
+	ErrStreamNotFound = errors.New("流式执行不存在或已过期")
+	// ErrStreamFinished 流式执行已结束，无法取消
+	ErrStreamFinished = errors.New("流式执行已结束")
+	// ErrPipelineNotFound 流水线不存在或未启用
+	ErrPipelineNotFound = errors.New("流水线不存在")
+	// ErrPipelineRunNotFound 流水线运行记录不存在或不属于当前用户
+	ErrPipelineRunNotFound = errors.New("流水线运行记录不存在")
+)
 
 // CreateConversationRequest 创建对话请求
 type CreateConversationRequest struct {
@@ -35,6 +54,49 @@ type UpdateWorkflowRequest struct {
 	Outputs     interface{} `json:"outputs"`
 	Enabled     bool        `json:"enabled"`
 	IsPublic    bool        `json:"is_public"`
+
+	BillingActionKey *string `json:"billing_action_key"` // 计费动作，nil 表示不修改，空字符串表示设为免费
+	Notes            string  `json:"notes"`              // 配置变更说明，记录在新版本中
+	MaxConcurrency   *int    `json:"max_concurrency"`    // 异步任务最大并发数，nil 表示不修改，0 表示使用全局默认值
+
+	FallbackWorkflowID *string `json:"fallback_workflow_id"` // 备用工作流ID，nil 表示不修改，空字符串表示取消
+
+	CacheEnabled    *bool   `json:"cache_enabled"`     // 是否开启响应缓存，nil 表示不修改
+	CacheTTLSeconds *int    `json:"cache_ttl_seconds"` // 缓存有效期，nil 表示不修改，0 表示使用全局默认值
+	CacheActionKey  *string `json:"cache_action_key"`  // 命中缓存时的计费动作，nil 表示不修改，空字符串表示命中缓存免费
+}
+
+// RollbackWorkflowRequest 回滚工作流配置请求
+type RollbackWorkflowRequest struct {
+	Notes string `json:"notes"` // 回滚说明，为空时自动生成
+}
+
+// RotateAPIKeysResponse 重新加密工作流API密钥的结果
+type RotateAPIKeysResponse struct {
+	KeyID     string `json:"key_id"`    // 当前主密钥ID
+	Workflows int    `json:"workflows"` // 重新加密的工作流数
+	Versions  int    `json:"versions"`  // 重新加密的版本记录数
+}
+
+// WorkflowVersionDiffRequest 工作流版本差异查询请求
+type WorkflowVersionDiffRequest struct {
+	From int `form:"from" binding:"required,min=1"`
+	To   int `form:"to" binding:"required,min=1"`
+}
+
+// WorkflowConfigChange 工作流配置项的变更，新增字段 From 为空，删除字段 To 为空
+type WorkflowConfigChange struct {
+	Field string      `json:"field"` // 配置项，输入输出字段为 inputs.<field_name> / outputs.<field_name>
+	From  interface{} `json:"from,omitempty"`
+	To    interface{} `json:"to,omitempty"`
+}
+
+// WorkflowVersionDiff 工作流两个配置版本的差异
+type WorkflowVersionDiff struct {
+	WorkflowID  string                 `json:"workflow_id"`
+	FromVersion int                    `json:"from_version"`
+	ToVersion   int                    `json:"to_version"`
+	Changes     []WorkflowConfigChange `json:"changes"`
 }
 
 // ExecuteWorkflowRequest 执行工作流请求
@@ -80,6 +142,90 @@ type AdminWorkflowResponse struct {
 	IsPublic    bool        `json:"is_public"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
+
+	BillingActionKey string `json:"billing_action_key"` // 计费动作，为空表示免费
+	ConfigVersion    int    `json:"config_version"`     // 当前配置版本
+	MaxConcurrency   int    `json:"max_concurrency"`    // 异步任务最大并发数，0表示使用全局默认值
+
+	FallbackWorkflowID string `json:"fallback_workflow_id"` // 备用工作流ID，为空表示不切换
+
+	CacheEnabled    bool   `json:"cache_enabled"`     // 是否开启响应缓存
+	CacheTTLSeconds int    `json:"cache_ttl_seconds"` // 缓存有效期，0表示使用全局默认值
+	CacheActionKey  string `json:"cache_action_key"`  // 命中缓存时的计费动作，为空表示命中缓存免费
+}
+
+// WorkflowCacheStats 工作流响应缓存统计
+type WorkflowCacheStats struct {
+	WorkflowID     string `json:"workflow_id"`
+	WorkflowName   string `json:"workflow_name"`
+	CacheEnabled   bool   `json:"cache_enabled"`
+	TTLSeconds     int    `json:"ttl_seconds"`      // 实际生效的缓存有效期
+	CacheActionKey string `json:"cache_action_key"` // 命中缓存时的计费动作
+
+	Entries       int64 `json:"entries"`        // 缓存记录数
+	ActiveEntries int64 `json:"active_entries"` // 未过期的缓存记录数
+	StoredHits    int64 `json:"stored_hits"`    // 现存缓存记录的累计命中次数
+
+	Days       int     `json:"days"`       // 执行统计的天数
+	Executions int64   `json:"executions"` // 统计期内的执行次数
+	CacheHits  int64   `json:"cache_hits"` // 统计期内命中缓存的执行次数
+	HitRate    float64 `json:"hit_rate"`   // 命中率
+}
+
+// PurgeWorkflowCacheResponse 清除工作流响应缓存的结果
+type PurgeWorkflowCacheResponse struct {
+	Removed int64 `json:"removed"` // 删除的缓存记录数
+}
+
+// Pipeline 解析后的流水线定义
+type Pipeline struct {
+	ID      string               // 使用内置定义时为空
+	Name    string               // 流水线名称
+	Steps   []model.PipelineStep // 按顺序执行的步骤
+	Outputs map[string]string    // 输出字段 -> 取值表达式，为空表示最后一个成功步骤的输出
+}
+
+// CreatePipelineRequest 创建流水线请求（管理员）
+type CreatePipelineRequest struct {
+	Name        string               `json:"name" binding:"required"`
+	Description string               `json:"description"`
+	Steps       []model.PipelineStep `json:"steps" binding:"required"`
+	Outputs     map[string]string    `json:"outputs"`
+	Enabled     *bool                `json:"enabled"`
+}
+
+// UpdatePipelineRequest 更新流水线请求（管理员），未提供的字段保持不变
+type UpdatePipelineRequest struct {
+	Name        *string              `json:"name"`
+	Description *string              `json:"description"`
+	Steps       []model.PipelineStep `json:"steps"`
+	Outputs     map[string]string    `json:"outputs"`
+	Enabled     *bool                `json:"enabled"`
+}
+
+// RunPipelineRequest 运行流水线请求
+type RunPipelineRequest struct {
+	Inputs       map[string]interface{} `json:"inputs"`
+	ResponseMode string                 `json:"response_mode"` // blocking（默认）/streaming
+}
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// InputValidationError 工作流输入不符合声明的字段，API层据此返回400及字段级错误
+type InputValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *InputValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldError := range e.Errors {
+		messages[i] = fieldError.Field + ": " + fieldError.Message
+	}
+	return "输入参数校验失败: " + strings.Join(messages, "; ")
 }
 
 // ExecuteWorkflowResponse 执行工作流响应
@@ -87,12 +233,14 @@ type ExecuteWorkflowResponse struct {
 	Success bool                   `json:"success"`
 	Data    map[string]interface{} `json:"data"`
 	Message string                 `json:"message"`
+
+	QuotaStatus *billing.QuotaStatus `json:"-"` // 执行后的动作使用配额，由API层写入响应头
 }
 
 // WorkflowAPIRequest 工作流API请求结构体
 type WorkflowAPIRequest struct {
 	Inputs         map[string]interface{} `json:"inputs"`
-	ResponseMode   string                 `json:"response_mode"`
+	ResponseMode   string                 `json:"response_mode"` // blocking/streaming/async
 	User           string                 `json:"user"`
 	Query          string                 `json:"query,omitempty"`           // ChatFlow API 的查询参数
 	ConversationID string                 `json:"conversation_id,omitempty"` // ChatFlow API 的会话ID（用于保持上下文）
@@ -155,13 +303,21 @@ type WorkflowFinishedEventData struct {
 	Status      string                 `json:"status"`
 	ElapsedTime float64                `json:"elapsed_time"`
 	TotalTokens int64                  `json:"total_tokens"`
-	TotalSteps  string                 `json:"total_steps"`
+	TotalSteps  int                    `json:"total_steps"`
 	CreatedAt   int64                  `json:"created_at"`
 	FinishedAt  int64                  `json:"finished_at"`
 }
 
+// WorkflowUsage 上游（Dify）报告的工作流执行用量
+type WorkflowUsage struct {
+	TotalTokens int
+	TotalSteps  int
+	ElapsedTime float64 // 上游执行耗时(s)
+}
+
 // StreamContext 流式执行上下文
 type StreamContext struct {
+	StreamID      string
 	WorkflowID    string
 	UserID        string
 	Inputs        map[string]interface{}
@@ -170,4 +326,16 @@ type StreamContext struct {
 	Error         chan error
 	StartTime     time.Time
 	ExecutionTime int
+	HoldID        int64           // 预扣积分ID（0表示不计费）
+	Workflow      *model.Workflow // 执行时的工作流配置，用于记录配置版本和检查输出字段
+	FinalStatus   string          // workflow_finished 事件报告的最终状态
+	Usage         WorkflowUsage   // workflow_finished 事件报告的用量，用于按量结算
+
+	FailoverWorkflowID string // 上游不可用时实际执行的备用工作流，为空表示未切换
+
+	Buffer *streamBuffer // 事件缓存，客户端连接和重连从这里读取
+
+	upstreamWorkflow *model.Workflow // 实际建立上游流的工作流（切换到备用工作流时为备用工作流），用于停止上游任务
+	cancelOnce       sync.Once
+	cancelReason     string // 取消原因，在 CancelFunc 调用前写入
 }
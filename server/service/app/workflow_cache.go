@@ -0,0 +1,294 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+	"server/service/billing"
+)
+
+// defaultCacheTTL 工作流和配置都未设置缓存有效期时的默认值
+const defaultCacheTTL = 24 * time.Hour
+
+// workflowCacheKey 计算工作流响应缓存键，返回空表示本次执行不使用缓存
+// 缓存键为工作流ID、配置版本、用户和规范化输入的 SHA256；文件输入按文件内容哈希（model.File.Hash）计算，
+// 同一文件重复上传得到不同的 Dify 文件ID 时仍能命中
+func workflowCacheKey(workflow *model.Workflow, userID string, inputs map[string]interface{}) string {
+	// ChatFlow 的结果依赖会话上下文，不缓存
+	if !workflow.CacheEnabled || isChatFlowURL(workflow.ApiURL) {
+		return ""
+	}
+
+	canonical, ok := canonicalCacheValue(inputs)
+	if !ok {
+		return ""
+	}
+
+	// map 序列化时按键排序，相同输入得到相同的字节序列
+	data, err := json.Marshal(map[string]interface{}{
+		"workflow_id":    workflow.ID,
+		"config_version": workflow.ConfigVersion,
+		"user_id":        userID,
+		"inputs":         canonical,
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalCacheValue 规范化输入值，将本地文件替换为文件内容哈希
+// 文件不存在于 files 表（无法确定内容）时返回 false，本次执行不使用缓存
+func canonicalCacheValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if uploadFileID, ok := v["upload_file_id"].(string); ok && v["transfer_method"] == "local_file" {
+			var file model.File
+			if err := global.DB.Select("hash").Where("dify_id = ?", uploadFileID).First(&file).Error; err != nil || file.Hash == "" {
+				return nil, false
+			}
+			return map[string]interface{}{
+				"transfer_method": "local_file",
+				"type":            v["type"],
+				"file_hash":       file.Hash,
+			}, true
+		}
+
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			canonical, ok := canonicalCacheValue(item)
+			if !ok {
+				return nil, false
+			}
+			result[key] = canonical
+		}
+		return result, true
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			canonical, ok := canonicalCacheValue(item)
+			if !ok {
+				return nil, false
+			}
+			result[i] = canonical
+		}
+		return result, true
+	default:
+		return v, true
+	}
+}
+
+// workflowCacheTTL 工作流响应缓存有效期
+func workflowCacheTTL(workflow *model.Workflow) time.Duration {
+	if workflow.CacheTTLSeconds > 0 {
+		return time.Duration(workflow.CacheTTLSeconds) * time.Second
+	}
+	if global.CONFIG.WorkflowCache.DefaultTTLHours > 0 {
+		return time.Duration(global.CONFIG.WorkflowCache.DefaultTTLHours) * time.Hour
+	}
+	return defaultCacheTTL
+}
+
+// lookupWorkflowCache 查询未过期的缓存，未命中返回 nil
+func (s *appService) lookupWorkflowCache(workflow *model.Workflow, cacheKey string) *model.WorkflowCacheEntry {
+	if cacheKey == "" {
+		return nil
+	}
+
+	var entry model.WorkflowCacheEntry
+	if err := global.DB.Where("workflow_id = ? AND cache_key = ? AND expires_at > ?", workflow.ID, cacheKey, time.Now()).
+		First(&entry).Error; err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// serveFromCache 以缓存结果响应执行请求，按缓存计费动作扣费并记录执行日志
+func (s *appService) serveFromCache(workflow *model.Workflow, userID string, inputs map[string]interface{}, entry *model.WorkflowCacheEntry) (*ExecuteWorkflowResponse, error) {
+	startTime := time.Now()
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(entry.Data, &data); err != nil || data == nil {
+		return nil, errors.New("解析缓存结果失败")
+	}
+
+	deductionID, err := billing.DeductForWorkflowCacheHit(userID, workflow, "")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	global.DB.Model(&model.WorkflowCacheEntry{}).Where("id = ?", entry.ID).UpdateColumns(map[string]interface{}{
+		"hit_count":   gorm.Expr("hit_count + ?", 1),
+		"last_hit_at": now,
+	})
+
+	data["cache_hit"] = true
+	response := &ExecuteWorkflowResponse{
+		Success: true,
+		Data:    data,
+		Message: "工作流执行成功（命中缓存）",
+	}
+
+	executionTime := int(time.Since(startTime).Milliseconds())
+	s.LogWorkflowExecution(workflow, userID, inputs, response, "success", "", executionTime, WorkflowUsage{}, deductionID)
+
+	response.QuotaStatus = billing.GetWorkflowQuotaStatus(userID, workflow)
+	return response, nil
+}
+
+// storeWorkflowCache 保存成功执行的结果，切换到备用工作流产生的结果不缓存
+func (s *appService) storeWorkflowCache(workflow *model.Workflow, userID, cacheKey string, response *ExecuteWorkflowResponse, usage WorkflowUsage) {
+	if cacheKey == "" || response == nil || !response.Success {
+		return
+	}
+	if _, failover := response.Data["failover_workflow_id"]; failover {
+		return
+	}
+
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		return
+	}
+
+	entry := model.WorkflowCacheEntry{
+		WorkflowID:    workflow.ID,
+		CacheKey:      cacheKey,
+		UserID:        userID,
+		ConfigVersion: workflow.ConfigVersion,
+		Data:          model.JSON(data),
+		TotalTokens:   usage.TotalTokens,
+		ElapsedTime:   usage.ElapsedTime,
+		ExpiresAt:     time.Now().Add(workflowCacheTTL(workflow)),
+	}
+	// 已有的缓存（已过期才会重新执行）被新结果覆盖
+	if err := global.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "workflow_id"}, {Name: "cache_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"data":           entry.Data,
+			"total_tokens":   entry.TotalTokens,
+			"elapsed_time":   entry.ElapsedTime,
+			"config_version": entry.ConfigVersion,
+			"expires_at":     entry.ExpiresAt,
+			"hit_count":      0,
+			"last_hit_at":    nil,
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&entry).Error; err != nil {
+		fmt.Printf("保存工作流缓存失败: workflow_id=%s, err=%v\n", workflow.ID, err)
+	}
+}
+
+// PurgeWorkflowCache 清除工作流的响应缓存（管理员），userID 不为空时只清除该用户的缓存
+func (s *appService) PurgeWorkflowCache(workflowID, userID string) (int64, error) {
+	if _, err := s.getWorkflowByID(workflowID); err != nil {
+		return 0, err
+	}
+
+	query := global.DB.Where("workflow_id = ?", workflowID)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	result := query.Delete(&model.WorkflowCacheEntry{})
+	if result.Error != nil {
+		return 0, errors.New("清除缓存失败")
+	}
+	return result.RowsAffected, nil
+}
+
+// GetWorkflowCacheStats 统计各工作流的缓存情况（管理员），包括开启缓存或仍有缓存记录的工作流
+// days 为执行记录的统计天数
+func (s *appService) GetWorkflowCacheStats(days int) ([]WorkflowCacheStats, error) {
+	if days <= 0 {
+		days = 7
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	var entryStats []struct {
+		WorkflowID    string
+		Entries       int64
+		ActiveEntries int64
+		StoredHits    int64
+	}
+	if err := global.DB.Model(&model.WorkflowCacheEntry{}).
+		Select("workflow_id, COUNT(*) AS entries, "+
+			"COALESCE(SUM(CASE WHEN expires_at > ? THEN 1 ELSE 0 END), 0) AS active_entries, "+
+			"COALESCE(SUM(hit_count), 0) AS stored_hits", time.Now()).
+		Group("workflow_id").
+		Scan(&entryStats).Error; err != nil {
+		return nil, errors.New("查询缓存统计失败")
+	}
+
+	var executionStats []struct {
+		WorkflowID string
+		Executions int64
+		CacheHits  int64
+	}
+	if err := global.DB.Model(&model.WorkflowExecution{}).
+		Select("workflow_id, COUNT(*) AS executions, "+
+			"COALESCE(SUM(CASE WHEN cache_hit THEN 1 ELSE 0 END), 0) AS cache_hits").
+		Where("created_at >= ?", since).
+		Group("workflow_id").
+		Scan(&executionStats).Error; err != nil {
+		return nil, errors.New("查询执行统计失败")
+	}
+
+	var workflows []model.Workflow
+	if err := global.DB.Select("id", "name", "cache_enabled", "cache_ttl_seconds", "cache_action_key").
+		Order("name").Find(&workflows).Error; err != nil {
+		return nil, errors.New("查询工作流失败")
+	}
+
+	statsByWorkflow := make(map[string]*WorkflowCacheStats, len(workflows))
+	for _, workflow := range workflows {
+		statsByWorkflow[workflow.ID] = &WorkflowCacheStats{
+			WorkflowID:     workflow.ID,
+			WorkflowName:   workflow.Name,
+			CacheEnabled:   workflow.CacheEnabled,
+			TTLSeconds:     int(workflowCacheTTL(&workflow).Seconds()),
+			CacheActionKey: workflow.CacheActionKey,
+			Days:           days,
+		}
+	}
+	for _, row := range entryStats {
+		if stats, ok := statsByWorkflow[row.WorkflowID]; ok {
+			stats.Entries = row.Entries
+			stats.ActiveEntries = row.ActiveEntries
+			stats.StoredHits = row.StoredHits
+		}
+	}
+	for _, row := range executionStats {
+		if stats, ok := statsByWorkflow[row.WorkflowID]; ok {
+			stats.Executions = row.Executions
+			stats.CacheHits = row.CacheHits
+			if row.Executions > 0 {
+				stats.HitRate = float64(row.CacheHits) / float64(row.Executions)
+			}
+		}
+	}
+
+	results := make([]WorkflowCacheStats, 0)
+	for _, workflow := range workflows {
+		stats := statsByWorkflow[workflow.ID]
+		if stats.CacheEnabled || stats.Entries > 0 {
+			results = append(results, *stats)
+		}
+	}
+	return results, nil
+}
+
+// CleanExpiredWorkflowCache 删除已过期的工作流响应缓存，返回删除数量
+func CleanExpiredWorkflowCache() (int64, error) {
+	result := global.DB.Where("expires_at <= ?", time.Now()).Delete(&model.WorkflowCacheEntry{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+	"server/service/upstream"
+)
+
+var (
+	// workflowClient 阻塞模式调用工作流的客户端
+	// 工作流执行会消耗上游用量，请求发出后失败（500、504、读取超时）不重试，只重试确认未被处理的请求
+	workflowClient = upstream.NewClient(&http.Client{Timeout: 300 * time.Second}, upstream.RetryPolicy{})
+
+	// workflowStreamClient 流式调用工作流的客户端，重试只发生在收到响应头之前
+	// 注意：不设置 http.Client.Timeout，因为它会限制整个请求生命周期（包括读取流式响应体）
+	// 对于 SSE 流式响应，超时应由 context 和 Transport 控制
+	workflowStreamClient = upstream.NewClient(&http.Client{
+		Transport: &http.Transport{
+			ResponseHeaderTimeout: 60 * time.Second,  // 等待响应头的超时
+			IdleConnTimeout:       600 * time.Second, // 空闲连接超时
+		},
+	}, upstream.RetryPolicy{})
+)
+
+// WorkflowBreakerKey 工作流上游的熔断器键
+func WorkflowBreakerKey(workflowID string) string {
+	return "workflow:" + workflowID
+}
+
+// isChatFlowURL 判断是否为ChatFlow API
+func isChatFlowURL(apiURL string) bool {
+	return strings.HasSuffix(strings.TrimRight(apiURL, "/"), "chat-messages")
+}
+
+// buildWorkflowRequest 构建工作流API请求体，不修改 inputs（切换备用工作流时需要再次使用）
+// inputs 中的 __query 赋给 Query，__conversation_id 用于 ChatFlow API 的会话上下文，
+// __xx 为特殊变量，不作为工作流输入发送
+func buildWorkflowRequest(apiURL, userID, responseMode string, inputs map[string]interface{}) WorkflowAPIRequest {
+	requestBody := WorkflowAPIRequest{
+		Inputs:       make(map[string]interface{}, len(inputs)),
+		ResponseMode: responseMode,
+		User:         userID,
+	}
+	for key, value := range inputs {
+		requestBody.Inputs[key] = value
+	}
+
+	if query, ok := inputs["__query"].(string); ok {
+		requestBody.Query = query
+	}
+	delete(requestBody.Inputs, "__query")
+
+	// 如果是 ChatFlow API，处理 conversation_id
+	if isChatFlowURL(apiURL) {
+		if conversationID, ok := inputs["__conversation_id"].(string); ok && conversationID != "" {
+			requestBody.ConversationID = conversationID
+		}
+		delete(requestBody.Inputs, "__conversation_id")
+	}
+	return requestBody
+}
+
+// callWorkflowWithFailover 阻塞调用工作流，上游不可用且配置了备用工作流时切换到备用工作流执行一次
+// 返回实际执行的工作流（未切换时为 workflow 本身）；ctx 已取消时不切换
+func (s *appService) callWorkflowWithFailover(ctx context.Context, workflow *model.Workflow, userID string, inputs map[string]interface{}) (*WorkflowAPIResponse, *model.Workflow, error) {
+	apiResponse, err := s.callWorkflow(ctx, workflow, userID, inputs)
+	if err == nil || ctx.Err() != nil || !upstream.IsUnavailable(err) || workflow.FallbackWorkflowID == "" {
+		return apiResponse, workflow, err
+	}
+
+	fallback, fallbackErr := s.getFallbackWorkflow(workflow)
+	if fallbackErr != nil {
+		return nil, workflow, err
+	}
+	fmt.Printf("工作流上游不可用，切换到备用工作流: workflow_id=%s, fallback_workflow_id=%s, err=%v\n", workflow.ID, fallback.ID, err)
+
+	apiResponse, fallbackErr = s.callWorkflow(ctx, fallback, userID, inputs)
+	if fallbackErr != nil {
+		return nil, fallback, fmt.Errorf("%v；备用工作流执行失败: %w", err, fallbackErr)
+	}
+	return apiResponse, fallback, nil
+}
+
+// callWorkflow 解析API密钥并阻塞调用工作流
+func (s *appService) callWorkflow(ctx context.Context, workflow *model.Workflow, userID string, inputs map[string]interface{}) (*WorkflowAPIResponse, error) {
+	apiKey, err := ResolveWorkflowAPIKey(workflow)
+	if err != nil {
+		return nil, err
+	}
+	return s.callWorkflowAPI(ctx, workflow.ID, workflow.ApiURL, apiKey, userID, inputs)
+}
+
+// getFallbackWorkflow 查询可用的备用工作流
+func (s *appService) getFallbackWorkflow(workflow *model.Workflow) (*model.Workflow, error) {
+	var fallback model.Workflow
+	if err := global.DB.Where("id = ? AND enabled = ?", workflow.FallbackWorkflowID, true).First(&fallback).Error; err != nil {
+		return nil, errors.New("备用工作流不可用")
+	}
+	if fallback.ID == workflow.ID {
+		return nil, errors.New("备用工作流不能是自身")
+	}
+	return &fallback, nil
+}
+
+// validateFallbackWorkflow 校验备用工作流，空字符串表示取消
+func validateFallbackWorkflow(workflowID, fallbackWorkflowID string) error {
+	if fallbackWorkflowID == "" {
+		return nil
+	}
+	if fallbackWorkflowID == workflowID {
+		return errors.New("备用工作流不能是自身")
+	}
+
+	var fallback model.Workflow
+	if err := global.DB.Where("id = ?", fallbackWorkflowID).First(&fallback).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("备用工作流不存在")
+		}
+		return errors.New("查询工作流失败")
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"server/global"
+	"server/model"
+	"server/service/jobqueue"
+
+	"gorm.io/gorm"
+)
+
+// JobTypeWorkflow 异步执行工作流的任务类型
+const JobTypeWorkflow = "workflow_execute"
+
+// workflowJobPayload 异步执行工作流的任务参数
+type workflowJobPayload struct {
+	Inputs map[string]interface{} `json:"inputs"`
+}
+
+// SubmitWorkflowJob 提交异步执行工作流任务，返回任务记录，可通过任务ID查询进度和结果
+// 提交时即校验输入，执行时按 ExecuteWorkflow 的规则预扣和结算积分
+func (s *appService) SubmitWorkflowJob(workflowID, userID string, inputs map[string]interface{}) (*model.WorkflowJob, error) {
+	workflow, err := s.getWorkflowByID(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err = validateWorkflowInputs(workflow, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobqueue.JobQueueService.Submit(jobqueue.SubmitRequest{
+		Type:       JobTypeWorkflow,
+		UserID:     userID,
+		WorkflowID: workflow.ID,
+		Payload:    workflowJobPayload{Inputs: inputs},
+	})
+}
+
+// SubmitWorkflowJobByName 按工作流名称提交异步执行任务
+func (s *appService) SubmitWorkflowJobByName(workflowName, userID string, inputs map[string]interface{}) (*model.WorkflowJob, error) {
+	var workflow model.Workflow
+	if err := global.DB.Where("name = ?", workflowName).First(&workflow).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("工作流不存在")
+		}
+		return nil, errors.New("查询工作流失败")
+	}
+	return s.SubmitWorkflowJob(workflow.ID, userID, inputs)
+}
+
+// RunWorkflowJob 执行异步工作流任务，任务结果为 ExecuteWorkflow 的响应
+// 任务超时（ctx 取消）时中止上游调用
+func (s *appService) RunWorkflowJob(ctx context.Context, job *jobqueue.Job) (interface{}, error) {
+	var payload workflowJobPayload
+	if err := job.Bind(&payload); err != nil {
+		return nil, err
+	}
+
+	job.Progress(10, "正在执行工作流")
+	response, err := s.ExecuteWorkflow(ctx, job.WorkflowID, job.UserID, payload.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, errors.New(response.Message)
+	}
+	return response, nil
+}
@@ -0,0 +1,482 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+	"server/utils"
+)
+
+var (
+	// pipelineExprPattern 输入映射中的 {{表达式}}
+	pipelineExprPattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+	// pipelineStepNamePattern 步骤名称用于表达式路径，只允许字母、数字、下划线和连字符
+	pipelineStepNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// thinkTagPattern 推理模型输出的 <think> 段落，(?s) 使 . 匹配换行符
+	thinkTagPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+)
+
+// GetAllPipelines 获取所有流水线（管理员）
+func (s *appService) GetAllPipelines() ([]model.WorkflowPipeline, error) {
+	var pipelines []model.WorkflowPipeline
+	if err := global.DB.Order("name").Find(&pipelines).Error; err != nil {
+		return nil, errors.New("查询流水线失败")
+	}
+	return pipelines, nil
+}
+
+// CreatePipeline 创建流水线（管理员），保存前校验步骤定义
+func (s *appService) CreatePipeline(req CreatePipelineRequest, authorID string) (*model.WorkflowPipeline, error) {
+	pipeline := &Pipeline{Name: strings.TrimSpace(req.Name), Steps: req.Steps, Outputs: req.Outputs}
+	if err := validatePipeline(pipeline); err != nil {
+		return nil, err
+	}
+	if err := checkPipelineNameAvailable(pipeline.Name, ""); err != nil {
+		return nil, err
+	}
+
+	stepsJSON, _ := json.Marshal(pipeline.Steps)
+	outputsJSON, _ := json.Marshal(pipeline.Outputs)
+	record := &model.WorkflowPipeline{
+		ID:          utils.GenerateTLID(),
+		Name:        pipeline.Name,
+		Description: req.Description,
+		Steps:       model.JSON(stepsJSON),
+		Outputs:     model.JSON(outputsJSON),
+		Enabled:     true,
+		AuthorID:    authorID,
+	}
+	if req.Enabled != nil {
+		record.Enabled = *req.Enabled
+	}
+	// Enabled 的数据库默认值为 true，显式选择列避免 false 被忽略
+	if err := global.DB.Select("*").Create(record).Error; err != nil {
+		return nil, errors.New("创建流水线失败")
+	}
+	return record, nil
+}
+
+// UpdatePipeline 更新流水线（管理员），修改步骤或输出时重新校验
+func (s *appService) UpdatePipeline(pipelineID string, req UpdatePipelineRequest, authorID string) error {
+	var record model.WorkflowPipeline
+	if err := global.DB.Where("id = ?", pipelineID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPipelineNotFound
+		}
+		return errors.New("查询流水线失败")
+	}
+
+	pipeline, err := parsePipeline(&record)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"author_id": authorID}
+	if req.Name != nil {
+		pipeline.Name = strings.TrimSpace(*req.Name)
+		if err := checkPipelineNameAvailable(pipeline.Name, record.ID); err != nil {
+			return err
+		}
+		updates["name"] = pipeline.Name
+	}
+	if req.Steps != nil {
+		pipeline.Steps = req.Steps
+		stepsJSON, _ := json.Marshal(req.Steps)
+		updates["steps"] = model.JSON(stepsJSON)
+	}
+	if req.Outputs != nil {
+		pipeline.Outputs = req.Outputs
+		outputsJSON, _ := json.Marshal(req.Outputs)
+		updates["outputs"] = model.JSON(outputsJSON)
+	}
+	if err := validatePipeline(pipeline); err != nil {
+		return err
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	if err := global.DB.Model(&record).Updates(updates).Error; err != nil {
+		return errors.New("更新流水线失败")
+	}
+	return nil
+}
+
+// DeletePipeline 删除流水线（管理员），运行记录保留
+func (s *appService) DeletePipeline(pipelineID string) error {
+	result := global.DB.Where("id = ?", pipelineID).Delete(&model.WorkflowPipeline{})
+	if result.Error != nil {
+		return errors.New("删除流水线失败")
+	}
+	if result.RowsAffected == 0 {
+		return ErrPipelineNotFound
+	}
+	return nil
+}
+
+// GetPipelineByName 按名称查询已启用的流水线，不存在时返回 ErrPipelineNotFound
+func (s *appService) GetPipelineByName(name string) (*Pipeline, error) {
+	var record model.WorkflowPipeline
+	if err := global.DB.Where("name = ? AND enabled = ?", name, true).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPipelineNotFound
+		}
+		return nil, errors.New("查询流水线失败")
+	}
+	return parsePipeline(&record)
+}
+
+// parsePipeline 解析流水线记录中的步骤和输出定义
+func parsePipeline(record *model.WorkflowPipeline) (*Pipeline, error) {
+	pipeline := &Pipeline{ID: record.ID, Name: record.Name}
+	if len(record.Steps) > 0 {
+		if err := json.Unmarshal(record.Steps, &pipeline.Steps); err != nil {
+			return nil, errors.New("流水线步骤格式错误")
+		}
+	}
+	if len(record.Outputs) > 0 && string(record.Outputs) != "null" {
+		if err := json.Unmarshal(record.Outputs, &pipeline.Outputs); err != nil {
+			return nil, errors.New("流水线输出格式错误")
+		}
+	}
+	return pipeline, nil
+}
+
+// checkPipelineNameAvailable 检查流水线名称是否已被其他流水线使用
+func checkPipelineNameAvailable(name, excludeID string) error {
+	if name == "" {
+		return errors.New("流水线名称不能为空")
+	}
+	var count int64
+	query := global.DB.Model(&model.WorkflowPipeline{}).Where("name = ?", name)
+	if excludeID != "" {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return errors.New("查询流水线失败")
+	}
+	if count > 0 {
+		return errors.New("流水线名称已存在")
+	}
+	return nil
+}
+
+// validatePipeline 校验流水线定义：步骤名称唯一、工作流存在、表达式只引用流水线输入和之前的步骤
+func validatePipeline(pipeline *Pipeline) error {
+	if pipeline.Name == "" {
+		return errors.New("流水线名称不能为空")
+	}
+	if len(pipeline.Steps) == 0 {
+		return errors.New("流水线至少需要一个步骤")
+	}
+
+	defined := make(map[string]bool, len(pipeline.Steps))
+	for i, step := range pipeline.Steps {
+		if !pipelineStepNamePattern.MatchString(step.Name) {
+			return fmt.Errorf("第 %d 个步骤的名称无效，只能包含字母、数字、下划线和连字符", i+1)
+		}
+		if defined[step.Name] {
+			return fmt.Errorf("步骤名称重复: %s", step.Name)
+		}
+		if step.Workflow == "" {
+			return fmt.Errorf("步骤 %s 未指定工作流", step.Name)
+		}
+		var count int64
+		if err := global.DB.Model(&model.Workflow{}).Where("name = ?", step.Workflow).Count(&count).Error; err != nil {
+			return errors.New("查询工作流失败")
+		}
+		if count == 0 {
+			return fmt.Errorf("步骤 %s 的工作流不存在: %s", step.Name, step.Workflow)
+		}
+
+		switch step.OnError {
+		case "", "fail", "continue":
+		default:
+			return fmt.Errorf("步骤 %s 的失败处理无效: %s", step.Name, step.OnError)
+		}
+		for _, transform := range step.Transforms {
+			switch transform.Type {
+			case "strip_think", "extract_json", "trim":
+			default:
+				return fmt.Errorf("步骤 %s 的输出转换无效: %s", step.Name, transform.Type)
+			}
+		}
+
+		var paths []string
+		collectPipelinePaths(step.Inputs, &paths)
+		if step.When != nil {
+			switch step.When.Op {
+			case "", "not_empty", "empty", "equals", "not_equals":
+			default:
+				return fmt.Errorf("步骤 %s 的执行条件无效: %s", step.Name, step.When.Op)
+			}
+			if strings.TrimSpace(step.When.Path) == "" {
+				return fmt.Errorf("步骤 %s 的执行条件缺少取值路径", step.Name)
+			}
+			paths = append(paths, splitPipelineExpr(step.When.Path)...)
+		}
+		for _, path := range paths {
+			if err := checkPipelinePath(path, defined); err != nil {
+				return fmt.Errorf("步骤 %s: %v", step.Name, err)
+			}
+		}
+		defined[step.Name] = true
+	}
+
+	for key, expr := range pipeline.Outputs {
+		var paths []string
+		collectPipelinePaths(expr, &paths)
+		if len(paths) == 0 {
+			return fmt.Errorf("流水线输出 %s 缺少取值表达式", key)
+		}
+		for _, path := range paths {
+			if err := checkPipelinePath(path, defined); err != nil {
+				return fmt.Errorf("流水线输出 %s: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPipelinePath 校验取值路径，steps 只能引用已定义（之前）的步骤
+func checkPipelinePath(path string, defined map[string]bool) error {
+	segments := strings.Split(path, ".")
+	switch segments[0] {
+	case "input":
+		return nil
+	case "steps":
+		if len(segments) < 2 || !defined[segments[1]] {
+			return fmt.Errorf("引用了不存在或之后的步骤: %s", path)
+		}
+		return nil
+	}
+	return fmt.Errorf("取值路径必须以 input. 或 steps. 开头: %s", path)
+}
+
+// collectPipelinePaths 收集值中所有 {{表达式}} 引用的路径
+func collectPipelinePaths(value interface{}, paths *[]string) {
+	switch v := value.(type) {
+	case string:
+		for _, match := range pipelineExprPattern.FindAllStringSubmatch(v, -1) {
+			*paths = append(*paths, splitPipelineExpr(match[1])...)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectPipelinePaths(item, paths)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectPipelinePaths(item, paths)
+		}
+	}
+}
+
+// splitPipelineExpr 拆分用 || 连接的多个路径
+func splitPipelineExpr(expr string) []string {
+	var paths []string
+	for _, path := range strings.Split(expr, "||") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// resolvePipelineValue 将值中的 {{表达式}} 替换为 scope 中的取值
+// 整个字符串为单个表达式时返回原始类型的值，否则按字符串拼接
+func resolvePipelineValue(value interface{}, scope map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if loc := pipelineExprPattern.FindStringSubmatchIndex(v); loc != nil && loc[0] == 0 && loc[1] == len(v) {
+			return evalPipelineExpr(v[loc[2]:loc[3]], scope)
+		}
+		return pipelineExprPattern.ReplaceAllStringFunc(v, func(match string) string {
+			expr := pipelineExprPattern.FindStringSubmatch(match)[1]
+			return stringifyPipelineValue(evalPipelineExpr(expr, scope))
+		})
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = resolvePipelineValue(item, scope)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = resolvePipelineValue(item, scope)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// evalPipelineExpr 依次取 || 连接的路径，返回第一个非空值
+func evalPipelineExpr(expr string, scope map[string]interface{}) interface{} {
+	for _, path := range splitPipelineExpr(expr) {
+		if value := lookupPipelinePath(scope, path); !isEmptyPipelineValue(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// lookupPipelinePath 按 . 分隔的路径取值，数组使用数字下标，路径不存在时返回 nil
+func lookupPipelinePath(scope map[string]interface{}, path string) interface{} {
+	var current interface{} = scope
+	for _, segment := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			current = v[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// isEmptyPipelineValue 判断值是否为空（nil、空白字符串、false、空数组或空对象）
+func isEmptyPipelineValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case bool:
+		return !v
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// stringifyPipelineValue 拼接到字符串中的值，非字符串按 JSON 序列化
+func stringifyPipelineValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// pipelineConditionMet 判断步骤执行条件是否满足
+func pipelineConditionMet(condition *model.PipelineCondition, scope map[string]interface{}) bool {
+	if condition == nil {
+		return true
+	}
+
+	value := evalPipelineExpr(condition.Path, scope)
+	switch condition.Op {
+	case "", "not_empty":
+		return !isEmptyPipelineValue(value)
+	case "empty":
+		return isEmptyPipelineValue(value)
+	case "equals":
+		return samePipelineValue(value, condition.Value)
+	case "not_equals":
+		return !samePipelineValue(value, condition.Value)
+	}
+	return false
+}
+
+// samePipelineValue 按 JSON 表示比较两个值，避免数字类型不同（int 与 float64）导致不相等
+func samePipelineValue(a, b interface{}) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(dataA) == string(dataB)
+}
+
+// applyPipelineTransforms 按顺序对步骤输出执行转换，不修改原输出
+func applyPipelineTransforms(outputs map[string]interface{}, transforms []model.PipelineTransform) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(outputs))
+	for key, value := range outputs {
+		result[key] = value
+	}
+
+	for _, transform := range transforms {
+		fields := []string{transform.Field}
+		if transform.Field == "" {
+			fields = fields[:0]
+			for key, value := range result {
+				if _, ok := value.(string); ok {
+					fields = append(fields, key)
+				}
+			}
+		}
+
+		for _, field := range fields {
+			value, exists := result[field]
+			if !exists {
+				return nil, fmt.Errorf("输出字段不存在: %s", field)
+			}
+			text, ok := value.(string)
+			if !ok {
+				// 已经是结构化数据（如之前的 extract_json 已解析），无需转换
+				continue
+			}
+
+			switch transform.Type {
+			case "strip_think":
+				result[field] = strings.TrimSpace(thinkTagPattern.ReplaceAllString(text, ""))
+			case "trim":
+				result[field] = strings.TrimSpace(text)
+			case "extract_json":
+				candidate := extractCompleteJSON(text)
+				if candidate == "" {
+					return nil, fmt.Errorf("输出字段 %s 中没有有效的JSON", field)
+				}
+				var parsed interface{}
+				if err := json.Unmarshal([]byte(candidate), &parsed); err != nil {
+					return nil, fmt.Errorf("输出字段 %s 的JSON解析失败", field)
+				}
+				result[field] = parsed
+			}
+		}
+	}
+	return result, nil
+}
+
+// extractCompleteJSON 从字符串中提取第一个完整的JSON对象或数组
+func extractCompleteJSON(s string) string {
+	// 查找第一个 '{' 或 '['
+	start := strings.IndexAny(s, "{[")
+	if start == -1 {
+		return ""
+	}
+
+	// 从开始位置解码一个JSON值，解码器读到值结束即停止，忽略其后的内容
+	decoder := json.NewDecoder(strings.NewReader(s[start:]))
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return ""
+	}
+	return s[start : start+int(decoder.InputOffset())]
+}
@@ -0,0 +1,311 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+	"server/utils"
+)
+
+// RunPipelineByName 按名称运行已启用的流水线
+// blocking 模式返回运行记录；streaming 模式以SSE推送步骤事件，返回 nil 记录
+func (s *appService) RunPipelineByName(c *gin.Context, name, userID string, inputs map[string]interface{}, responseMode string) (*model.WorkflowPipelineRun, error) {
+	pipeline, err := s.GetPipelineByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	switch responseMode {
+	case "", "blocking":
+		return s.RunPipeline(c.Request.Context(), pipeline, userID, inputs)
+	case "streaming":
+		return nil, s.RunPipelineStream(c, pipeline, userID, inputs)
+	}
+	return nil, errors.New("响应模式不支持")
+}
+
+// RunPipeline 阻塞运行流水线，所有步骤结束后返回运行记录
+// 各步骤按 ExecuteWorkflow 执行（输入校验、计费、缓存、备用工作流切换均与单独执行相同），
+// 步骤失败时运行记录状态为 failed，ctx 取消时中止当前步骤并记录为 cancelled，均不作为错误返回
+func (s *appService) RunPipeline(ctx context.Context, pipeline *Pipeline, userID string, inputs map[string]interface{}) (*model.WorkflowPipelineRun, error) {
+	run, err := createPipelineRun(pipeline, userID, inputs, "blocking")
+	if err != nil {
+		return nil, err
+	}
+
+	s.executePipeline(ctx, pipeline, run, inputs, nil)
+	return run, nil
+}
+
+// RunPipelineStream 流式运行流水线
+// 步骤事件缓存在流ID下，与工作流流式执行一样支持 ResumeWorkflowStream 重连和 CancelWorkflowStream 取消；
+// 步骤以阻塞模式执行，取消时中止当前步骤的上游调用
+func (s *appService) RunPipelineStream(c *gin.Context, pipeline *Pipeline, userID string, inputs map[string]interface{}) error {
+	run, err := createPipelineRun(pipeline, userID, inputs, "streaming")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	streamCtx := &StreamContext{
+		StreamID:   "pipeline_" + run.ID,
+		UserID:     userID,
+		Inputs:     inputs,
+		CancelFunc: cancel,
+		Done:       make(chan struct{}),
+		Error:      make(chan error, 1),
+		StartTime:  time.Now(),
+		Buffer:     newStreamBuffer(),
+	}
+	emitPipelineEvent(streamCtx, "stream_started", map[string]string{"stream_id": streamCtx.StreamID})
+
+	registerStream(streamCtx)
+	go s.runPipelineStream(ctx, pipeline, run, inputs, streamCtx)
+
+	c.Header(StreamIDHeader, streamCtx.StreamID)
+	s.setSSEHeaders(c)
+	s.serveStream(c, streamCtx, 0)
+	return nil
+}
+
+// runPipelineStream 在后台运行流水线，结束后在保留期后移除流
+// 执行过程中发生panic时将运行记录标记为失败并同样结束流
+func (s *appService) runPipelineStream(ctx context.Context, pipeline *Pipeline, run *model.WorkflowPipelineRun, inputs map[string]interface{}, streamCtx *StreamContext) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("流水线执行异常: run_id=%s, err=%v\n", run.ID, r)
+			failPipelineRun(run, fmt.Sprintf("流水线执行异常: %v", r))
+			emitPipelineEvent(streamCtx, "pipeline_finished", map[string]interface{}{
+				"run_id":        run.ID,
+				"status":        run.Status,
+				"error_message": run.ErrorMessage,
+			})
+		}
+
+		streamCtx.CancelFunc()
+		streamCtx.Buffer.finish()
+		close(streamCtx.Done)
+		close(streamCtx.Error)
+		releaseStream(streamCtx.StreamID)
+	}()
+
+	s.executePipeline(ctx, pipeline, run, inputs, streamCtx)
+}
+
+// failPipelineRun 将仍在运行的流水线记录标记为失败（如执行异常中断）
+func failPipelineRun(run *model.WorkflowPipelineRun, message string) {
+	finishedAt := time.Now()
+	run.Status = model.PipelineRunStatusFailed
+	run.ErrorMessage = message
+	run.FinishedAt = &finishedAt
+	if err := global.DB.Model(&model.WorkflowPipelineRun{}).
+		Where("id = ? AND status = ?", run.ID, model.PipelineRunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":        run.Status,
+			"error_message": run.ErrorMessage,
+			"finished_at":   finishedAt,
+		}).Error; err != nil {
+		fmt.Printf("保存流水线运行记录失败: run_id=%s, err=%v\n", run.ID, err)
+	}
+}
+
+// GetPipelineRun 查询用户自己的流水线运行记录
+func (s *appService) GetPipelineRun(runID, userID string) (*model.WorkflowPipelineRun, error) {
+	var run model.WorkflowPipelineRun
+	if err := global.DB.Where("id = ? AND user_id = ?", runID, userID).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPipelineRunNotFound
+		}
+		return nil, errors.New("查询流水线运行记录失败")
+	}
+	return &run, nil
+}
+
+// createPipelineRun 创建状态为 running 的运行记录
+func createPipelineRun(pipeline *Pipeline, userID string, inputs map[string]interface{}, responseMode string) (*model.WorkflowPipelineRun, error) {
+	if inputs == nil {
+		inputs = map[string]interface{}{}
+	}
+	inputsJSON, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, errors.New("输入参数格式错误")
+	}
+
+	run := &model.WorkflowPipelineRun{
+		ID:           utils.GenerateTLID(),
+		PipelineID:   pipeline.ID,
+		PipelineName: pipeline.Name,
+		UserID:       userID,
+		ResponseMode: responseMode,
+		Status:       model.PipelineRunStatusRunning,
+		Inputs:       model.JSON(inputsJSON),
+	}
+	if err := global.DB.Create(run).Error; err != nil {
+		return nil, errors.New("创建流水线运行记录失败")
+	}
+	return run, nil
+}
+
+// executePipeline 按顺序执行流水线步骤并保存运行结果，streamCtx 不为空时推送步骤事件
+// 不满足执行条件的步骤跳过；失败的步骤按 on_error 终止流水线（fail）或继续执行后续步骤（continue）
+func (s *appService) executePipeline(ctx context.Context, pipeline *Pipeline, run *model.WorkflowPipelineRun, inputs map[string]interface{}, streamCtx *StreamContext) {
+	startTime := time.Now()
+	emitPipelineEvent(streamCtx, "pipeline_started", map[string]string{"run_id": run.ID, "pipeline": pipeline.Name})
+
+	stepScope := make(map[string]interface{}, len(pipeline.Steps))
+	scope := map[string]interface{}{"input": inputs, "steps": stepScope}
+
+	results := make([]model.PipelineStepResult, 0, len(pipeline.Steps))
+	var lastOutputs map[string]interface{}
+	run.Status = model.PipelineRunStatusSucceeded
+
+	markCancelled := func() {
+		run.Status = model.PipelineRunStatusCancelled
+		run.ErrorMessage = "流水线已取消"
+		if streamCtx != nil {
+			if streamCtx.cancelReason != "" {
+				run.ErrorMessage = streamCtx.cancelReason
+			}
+			emitPipelineEvent(streamCtx, "stream_cancelled", map[string]string{"stream_id": streamCtx.StreamID, "message": run.ErrorMessage})
+		}
+	}
+
+	for _, step := range pipeline.Steps {
+		if ctx.Err() != nil {
+			markCancelled()
+			break
+		}
+
+		result := model.PipelineStepResult{Name: step.Name, Workflow: step.Workflow}
+		if !pipelineConditionMet(step.When, scope) {
+			result.Status = model.PipelineStepStatusSkipped
+			results = append(results, result)
+			stepScope[step.Name] = map[string]interface{}{"status": result.Status}
+			emitPipelineEvent(streamCtx, "step_finished", result)
+			continue
+		}
+
+		emitPipelineEvent(streamCtx, "step_started", map[string]string{"step": step.Name, "workflow": step.Workflow})
+		s.runPipelineStep(ctx, step, run.UserID, scope, &result)
+		results = append(results, result)
+		stepScope[step.Name] = map[string]interface{}{"status": result.Status, "outputs": result.Outputs}
+		emitPipelineEvent(streamCtx, "step_finished", result)
+
+		if result.Status == model.PipelineStepStatusFailed {
+			// 取消导致的步骤失败不按 on_error 处理，流水线记录为已取消
+			if ctx.Err() != nil {
+				markCancelled()
+				break
+			}
+			if step.OnError == "continue" {
+				continue
+			}
+			run.Status = model.PipelineRunStatusFailed
+			run.FailedStep = step.Name
+			run.ErrorMessage = fmt.Sprintf("步骤 %s 执行失败: %s", step.Name, result.ErrorMessage)
+			break
+		}
+		lastOutputs = result.Outputs
+	}
+
+	outputs := lastOutputs
+	if run.Status == model.PipelineRunStatusSucceeded && len(pipeline.Outputs) > 0 {
+		outputs = make(map[string]interface{}, len(pipeline.Outputs))
+		for key, expr := range pipeline.Outputs {
+			outputs[key] = resolvePipelineValue(expr, scope)
+		}
+	}
+	if outputs == nil {
+		outputs = map[string]interface{}{}
+	}
+
+	outputsJSON, _ := json.Marshal(outputs)
+	stepsJSON, _ := json.Marshal(results)
+	finishedAt := time.Now()
+	run.Outputs = model.JSON(outputsJSON)
+	run.Steps = model.JSON(stepsJSON)
+	run.ExecutionTime = int(time.Since(startTime).Milliseconds())
+	run.FinishedAt = &finishedAt
+	if err := global.DB.Model(run).Select("status", "outputs", "steps", "failed_step", "error_message", "execution_time", "finished_at").
+		Updates(run).Error; err != nil {
+		fmt.Printf("保存流水线运行记录失败: run_id=%s, err=%v\n", run.ID, err)
+	}
+
+	emitPipelineEvent(streamCtx, "pipeline_finished", map[string]interface{}{
+		"run_id":        run.ID,
+		"status":        run.Status,
+		"outputs":       outputs,
+		"failed_step":   run.FailedStep,
+		"error_message": run.ErrorMessage,
+	})
+}
+
+// runPipelineStep 解析输入映射并执行步骤的工作流，结果写入 result
+func (s *appService) runPipelineStep(ctx context.Context, step model.PipelineStep, userID string, scope map[string]interface{}, result *model.PipelineStepResult) {
+	startTime := time.Now()
+	defer func() {
+		result.ExecutionTime = int(time.Since(startTime).Milliseconds())
+	}()
+
+	fail := func(message string) {
+		result.Status = model.PipelineStepStatusFailed
+		result.ErrorMessage = message
+	}
+
+	var workflow model.Workflow
+	if err := global.DB.Where("name = ?", step.Workflow).First(&workflow).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fail("工作流不存在")
+		} else {
+			fail("查询工作流失败")
+		}
+		return
+	}
+	result.WorkflowID = workflow.ID
+
+	inputs := make(map[string]interface{}, len(step.Inputs))
+	for key, value := range step.Inputs {
+		inputs[key] = resolvePipelineValue(value, scope)
+	}
+
+	response, err := s.ExecuteWorkflow(ctx, workflow.ID, userID, inputs)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	result.WorkflowRunID, _ = response.Data["workflow_run_id"].(string)
+	result.CacheHit, _ = response.Data["cache_hit"].(bool)
+	if !response.Success {
+		fail(response.Message)
+		return
+	}
+
+	outputs, _ := response.Data["outputs"].(map[string]interface{})
+	outputs, err = applyPipelineTransforms(outputs, step.Transforms)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	result.Status = model.PipelineStepStatusSucceeded
+	result.Outputs = outputs
+}
+
+// emitPipelineEvent 向流水线的事件缓存写入一个事件，非流式运行时忽略
+func emitPipelineEvent(streamCtx *StreamContext, event string, data interface{}) {
+	if streamCtx == nil {
+		return
+	}
+	payload, err := json.Marshal(map[string]interface{}{"event": event, "data": data})
+	if err != nil {
+		return
+	}
+	streamCtx.Buffer.append("", string(payload))
+}
@@ -0,0 +1,303 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"server/model"
+)
+
+// 声明的字段类型，其他类型（如 array/object）不做校验
+const (
+	FieldTypeString  = "string"
+	FieldTypeNumber  = "number"
+	FieldTypeBoolean = "boolean"
+	FieldTypeFile    = "file"
+)
+
+// parseFieldSchema 解析工作流的输入或输出字段声明
+// 支持字段列表 [{"field_name": ..., "field_type": ...}] 和以字段名为键的对象 {"name": {"field_type": ...}} 两种格式
+// 未声明字段时返回 false，调用方不做校验
+func parseFieldSchema(data model.JSON) ([]model.Field, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+
+	var list []model.Field
+	if err := json.Unmarshal(data, &list); err == nil {
+		for _, field := range list {
+			if field.FieldName == "" {
+				return nil, false
+			}
+		}
+		return list, len(list) > 0
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, false
+	}
+	fields := make([]model.Field, 0, len(object))
+	for name, raw := range object {
+		var field model.Field
+		// 值不是字段声明（如直接写了默认值）时视为未声明类型
+		_ = json.Unmarshal(raw, &field)
+		field.FieldName = name
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].FieldName < fields[j].FieldName })
+	return fields, len(fields) > 0
+}
+
+// validateWorkflowInputs 按工作流声明的输入字段校验并转换输入，未声明字段的输入原样透传
+// 校验失败返回 InputValidationError，包含每个字段的错误
+func validateWorkflowInputs(workflow *model.Workflow, inputs map[string]interface{}) (map[string]interface{}, error) {
+	fields, ok := parseFieldSchema(workflow.Inputs)
+	if !ok {
+		return inputs, nil
+	}
+
+	coerced := make(map[string]interface{}, len(inputs))
+	for key, value := range inputs {
+		coerced[key] = value
+	}
+
+	var fieldErrors []FieldError
+	for _, field := range fields {
+		value := inputs[field.FieldName]
+		if isEmptyInput(value) {
+			if field.Required {
+				fieldErrors = append(fieldErrors, FieldError{Field: field.FieldName, Message: "必填字段"})
+			}
+			continue
+		}
+		converted, message := coerceFieldValue(field, value)
+		if message != "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.FieldName, Message: message})
+			continue
+		}
+		coerced[field.FieldName] = converted
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, &InputValidationError{Errors: fieldErrors}
+	}
+	return coerced, nil
+}
+
+// checkOutputSchema 按工作流声明的输出字段检查上游返回的输出，返回与声明不一致的字段（schema drift）
+// 缺少必填字段、类型不符以及未声明的字段都会被记录，但不影响执行结果
+func checkOutputSchema(workflow *model.Workflow, outputs map[string]interface{}) []FieldError {
+	fields, ok := parseFieldSchema(workflow.Outputs)
+	if !ok {
+		return nil
+	}
+
+	var drift []FieldError
+	declared := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		declared[field.FieldName] = true
+		value := outputs[field.FieldName]
+		if isEmptyInput(value) {
+			if field.Required {
+				drift = append(drift, FieldError{Field: field.FieldName, Message: "缺少输出字段"})
+			}
+			continue
+		}
+		if _, message := coerceFieldValue(field, value); message != "" {
+			drift = append(drift, FieldError{Field: field.FieldName, Message: message})
+		}
+	}
+
+	var undeclared []string
+	for key := range outputs {
+		if !declared[key] {
+			undeclared = append(undeclared, key)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, key := range undeclared {
+		drift = append(drift, FieldError{Field: key, Message: "未声明的输出字段"})
+	}
+	return drift
+}
+
+// isEmptyInput 判断输入值是否为空（未提供、null 或空字符串）
+func isEmptyInput(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+// coerceFieldValue 按字段类型校验并转换值，失败时返回错误信息
+func coerceFieldValue(field model.Field, value interface{}) (interface{}, string) {
+	var converted interface{}
+	var message string
+	switch field.FieldType {
+	case FieldTypeString:
+		converted, message = coerceString(field, value)
+	case FieldTypeNumber:
+		converted, message = coerceNumber(field, value)
+	case FieldTypeBoolean:
+		converted, message = coerceBoolean(value)
+	case FieldTypeFile:
+		converted, message = checkFiles(field, value)
+	default:
+		converted = value
+	}
+	if message != "" {
+		return nil, message
+	}
+
+	if len(field.Enum) > 0 && !enumContains(field.Enum, converted) {
+		return nil, fmt.Sprintf("取值必须是 %s 之一", formatEnum(field.Enum))
+	}
+	return converted, ""
+}
+
+// coerceString 校验字符串，数字和布尔值转换为字符串
+func coerceString(field model.Field, value interface{}) (interface{}, string) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case bool, float64, float32, int, int64, json.Number:
+		s = fmt.Sprint(v)
+	default:
+		return nil, "应为字符串"
+	}
+	if field.MaxLength > 0 && utf8.RuneCountInString(s) > field.MaxLength {
+		return nil, fmt.Sprintf("长度不能超过 %d 个字符", field.MaxLength)
+	}
+	return s, ""
+}
+
+// coerceNumber 校验数字，数字字符串转换为数字
+func coerceNumber(field model.Field, value interface{}) (interface{}, string) {
+	var n float64
+	switch v := value.(type) {
+	case float64:
+		n = v
+	case float32:
+		n = float64(v)
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	case json.Number:
+		parsed, err := v.Float64()
+		if err != nil {
+			return nil, "应为数字"
+		}
+		n = parsed
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, "应为数字"
+		}
+		n = parsed
+	default:
+		return nil, "应为数字"
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, "应为数字"
+	}
+	if field.Min != nil && n < *field.Min {
+		return nil, fmt.Sprintf("不能小于 %v", *field.Min)
+	}
+	if field.Max != nil && n > *field.Max {
+		return nil, fmt.Sprintf("不能大于 %v", *field.Max)
+	}
+	return n, ""
+}
+
+// coerceBoolean 校验布尔值，"true"/"false"/"1"/"0" 及数字 0/1 转换为布尔值
+func coerceBoolean(value interface{}) (interface{}, string) {
+	switch v := value.(type) {
+	case bool:
+		return v, ""
+	case string:
+		if parsed, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return parsed, ""
+		}
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, ""
+		}
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, ""
+		}
+	}
+	return nil, "应为布尔值"
+}
+
+// checkFiles 校验 Dify 文件参数，支持单个文件对象或文件列表，MaxLength 限制文件数
+func checkFiles(field model.Field, value interface{}) (interface{}, string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if message := checkFile(v); message != "" {
+			return nil, message
+		}
+		return v, ""
+	case []interface{}:
+		if field.MaxLength > 0 && len(v) > field.MaxLength {
+			return nil, fmt.Sprintf("最多上传 %d 个文件", field.MaxLength)
+		}
+		for i, item := range v {
+			file, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Sprintf("第 %d 个文件格式错误", i+1)
+			}
+			if message := checkFile(file); message != "" {
+				return nil, fmt.Sprintf("第 %d 个文件%s", i+1, message)
+			}
+		}
+		return v, ""
+	}
+	return nil, "应为文件对象（transfer_method + upload_file_id 或 url）"
+}
+
+// checkFile 校验单个 Dify 文件对象
+func checkFile(file map[string]interface{}) string {
+	method, _ := file["transfer_method"].(string)
+	switch method {
+	case "local_file":
+		if id, _ := file["upload_file_id"].(string); id == "" {
+			return "缺少 upload_file_id"
+		}
+	case "remote_url":
+		if url, _ := file["url"].(string); url == "" {
+			return "缺少 url"
+		}
+	default:
+		return "transfer_method 必须是 local_file 或 remote_url"
+	}
+	return ""
+}
+
+// enumContains 判断值是否在可选值中，数字按数值比较
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, option := range enum {
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnum 格式化可选值用于错误信息
+func formatEnum(enum []interface{}) string {
+	options := make([]string, len(enum))
+	for i, option := range enum {
+		options[i] = fmt.Sprint(option)
+	}
+	return strings.Join(options, "/")
+}
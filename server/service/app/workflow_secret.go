@@ -0,0 +1,154 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"server/global"
+	"server/model"
+	"server/utils"
+
+	"gorm.io/gorm"
+)
+
+// rotateBatchSize 重新加密时每批处理的行数
+const rotateBatchSize = 100
+
+// ResolveWorkflowAPIKey 解密工作流API密钥用于调用上游
+// 历史明文密钥会被加密后写回（工作流及其版本记录），未配置主密钥时保留明文并继续使用
+func ResolveWorkflowAPIKey(workflow *model.Workflow) (string, error) {
+	apiKey, err := utils.DecryptSecret(workflow.ApiKey)
+	if err != nil {
+		return "", fmt.Errorf("解密工作流API密钥失败: %w", err)
+	}
+	if !utils.IsEncryptedSecret(workflow.ApiKey) {
+		if err := encryptPlaintextAPIKey(global.DB, workflow); err != nil {
+			fmt.Printf("迁移工作流明文API密钥失败: workflow_id=%s, err=%v\n", workflow.ID, err)
+		}
+	}
+	return apiKey, nil
+}
+
+// encryptPlaintextAPIKey 加密工作流的明文API密钥并写回，同时更新使用相同明文的版本记录
+// 仅当数据库中仍为该明文时才写入，避免覆盖并发的修改
+func encryptPlaintextAPIKey(db *gorm.DB, workflow *model.Workflow) error {
+	if utils.IsEncryptedSecret(workflow.ApiKey) {
+		return nil
+	}
+	plaintext := workflow.ApiKey
+	encrypted, err := utils.EncryptSecret(plaintext)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(&model.Workflow{}).
+		Where("id = ? AND api_key = ?", workflow.ID, plaintext).
+		UpdateColumn("api_key", encrypted).Error; err != nil {
+		return fmt.Errorf("更新工作流API密钥失败: %w", err)
+	}
+	if err := db.Model(&model.WorkflowVersion{}).
+		Where("workflow_id = ? AND api_key = ?", workflow.ID, plaintext).
+		UpdateColumn("api_key", encrypted).Error; err != nil {
+		return fmt.Errorf("更新工作流版本API密钥失败: %w", err)
+	}
+	workflow.ApiKey = encrypted
+	return nil
+}
+
+// maskAPIKey 返回加密或明文API密钥的脱敏显示值
+func maskAPIKey(stored string) string {
+	apiKey, err := utils.DecryptSecret(stored)
+	if err != nil {
+		return "****"
+	}
+	return utils.MaskSecret(apiKey)
+}
+
+// sameAPIKey 比较两个API密钥的明文是否相同（每次加密结果不同，不能直接比较密文）
+func sameAPIKey(a, b string) bool {
+	if a == b {
+		return true
+	}
+	plainA, errA := utils.DecryptSecret(a)
+	plainB, errB := utils.DecryptSecret(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return plainA == plainB
+}
+
+// encryptAPIKey 加密管理员提交的API密钥
+func encryptAPIKey(apiKey string) (string, error) {
+	if utils.IsMaskedSecret(apiKey) {
+		return "", errors.New("API密钥不能是脱敏后的值，请重新填写")
+	}
+	encrypted, err := utils.EncryptSecret(apiKey)
+	if err != nil {
+		return "", fmt.Errorf("加密API密钥失败: %w", err)
+	}
+	return encrypted, nil
+}
+
+// RotateWorkflowAPIKeys 使用当前主密钥重新加密所有工作流及版本记录的API密钥（管理员）
+// 历史明文同时完成加密；已使用当前主密钥的行跳过，可重复执行
+func (s *appService) RotateWorkflowAPIKeys() (*RotateAPIKeysResponse, error) {
+	keyID, err := utils.ActiveSecretKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RotateAPIKeysResponse{KeyID: keyID}
+	if result.Workflows, err = rewrapAPIKeys(&model.Workflow{}, "workflows", keyID); err != nil {
+		return result, err
+	}
+	if result.Versions, err = rewrapAPIKeys(&model.WorkflowVersion{}, "workflow_versions", keyID); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// rewrapAPIKeys 分批重新加密指定表中未使用当前主密钥的 api_key，返回更新的行数
+func rewrapAPIKeys(table interface{}, tableName, keyID string) (int, error) {
+	type row struct {
+		ID     string
+		ApiKey string
+	}
+
+	updated := 0
+	for {
+		var rows []row
+		if err := global.DB.Model(table).
+			Select("id, api_key").
+			Where("api_key NOT LIKE ?", utils.SecretKeyPrefix(keyID)+"%").
+			Limit(rotateBatchSize).
+			Find(&rows).Error; err != nil {
+			return updated, fmt.Errorf("查询%s失败: %w", tableName, err)
+		}
+		if len(rows) == 0 {
+			return updated, nil
+		}
+
+		batchUpdated := 0
+		for _, r := range rows {
+			rewrapped, changed, err := utils.RewrapSecret(r.ApiKey)
+			if err != nil {
+				return updated, fmt.Errorf("重新加密%s(id=%s)失败: %w", tableName, r.ID, err)
+			}
+			if !changed {
+				continue
+			}
+			// 仅当密钥未被并发修改时写入
+			result := global.DB.Model(table).
+				Where("id = ? AND api_key = ?", r.ID, r.ApiKey).
+				UpdateColumn("api_key", rewrapped)
+			if result.Error != nil {
+				return updated, fmt.Errorf("更新%s(id=%s)失败: %w", tableName, r.ID, result.Error)
+			}
+			batchUpdated += int(result.RowsAffected)
+		}
+		updated += batchUpdated
+		if batchUpdated == 0 {
+			return updated, nil
+		}
+	}
+}
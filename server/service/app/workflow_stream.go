@@ -0,0 +1,324 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"server/model"
+	"server/service/upstream"
+)
+
+// StreamIDHeader 流式执行响应头，值为流ID，客户端断开后用于重连
+const StreamIDHeader = "X-Stream-ID"
+
+const (
+	// streamDetachGrace 所有客户端断开后继续执行上游的时长，超过后取消执行
+	streamDetachGrace = 2 * time.Minute
+	// streamRetention 流结束后保留事件的时长，期间仍可重连读取剩余事件
+	streamRetention = 5 * time.Minute
+	// streamHeartbeatInterval 向客户端发送心跳的间隔
+	streamHeartbeatInterval = 15 * time.Second
+	// streamStopTimeout 调用上游停止接口的超时
+	streamStopTimeout = 10 * time.Second
+)
+
+// workflowStopClient 调用上游停止接口的客户端，停止是幂等操作，可以重试；不记录熔断器，避免影响执行请求
+var workflowStopClient = upstream.NewClient(&http.Client{Timeout: streamStopTimeout}, upstream.RetryPolicy{MaxAttempts: 2, Idempotent: true})
+
+// streamEvent 缓存的SSE事件，ID 从1开始递增，作为 SSE 的 id 字段
+type streamEvent struct {
+	ID    int64
+	Event string // 上游 event 字段，为空表示默认事件
+	Data  string
+}
+
+// streamBuffer 流式执行的事件缓存
+// 上游事件由执行协程写入，客户端连接（包括按 Last-Event-ID 重连）从缓存读取，上游执行不依赖客户端连接
+// 缓存保存在实例内存中，多实例部署时重连需要路由到同一实例
+type streamBuffer struct {
+	mu          sync.Mutex
+	events      []streamEvent
+	notify      chan struct{} // 有新事件或流结束时关闭并替换
+	done        bool
+	subscribers int
+	detachedAt  time.Time
+}
+
+// newStreamBuffer 创建事件缓存
+func newStreamBuffer() *streamBuffer {
+	return &streamBuffer{notify: make(chan struct{})}
+}
+
+// append 追加事件并通知等待的客户端
+func (b *streamBuffer) append(event, data string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done {
+		return
+	}
+	b.events = append(b.events, streamEvent{ID: int64(len(b.events) + 1), Event: event, Data: data})
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// finish 标记流结束，不再追加事件
+func (b *streamBuffer) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.done {
+		return
+	}
+	b.done = true
+	close(b.notify)
+}
+
+// isDone 流是否已结束
+func (b *streamBuffer) isDone() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+// since 返回 ID 大于 lastEventID 的事件、流是否已结束，以及等待新事件的 channel
+func (b *streamBuffer) since(lastEventID int64) ([]streamEvent, bool, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lastEventID < 0 {
+		lastEventID = 0
+	}
+	var events []streamEvent
+	if lastEventID < int64(len(b.events)) {
+		events = append(events, b.events[lastEventID:]...)
+	}
+	return events, b.done, b.notify
+}
+
+// attach 客户端连接
+func (b *streamBuffer) attach() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers++
+}
+
+// detach 客户端断开，所有客户端断开超过 streamDetachGrace 且流未结束时调用 onIdle
+func (b *streamBuffer) detach(onIdle func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers--
+	if b.subscribers > 0 || b.done {
+		return
+	}
+	b.detachedAt = time.Now()
+	time.AfterFunc(streamDetachGrace, func() {
+		b.mu.Lock()
+		idle := b.subscribers == 0 && !b.done && time.Since(b.detachedAt) >= streamDetachGrace
+		b.mu.Unlock()
+		if idle {
+			onIdle()
+		}
+	})
+}
+
+// registerStream 注册流式执行上下文
+func registerStream(streamCtx *StreamContext) {
+	streamMutex.Lock()
+	streamContexts[streamCtx.StreamID] = streamCtx
+	streamMutex.Unlock()
+}
+
+// getStream 查询流式执行上下文
+func getStream(streamID string) (*StreamContext, bool) {
+	streamMutex.RLock()
+	defer streamMutex.RUnlock()
+	streamCtx, exists := streamContexts[streamID]
+	return streamCtx, exists
+}
+
+// releaseStream 流结束后保留 streamRetention 供重连读取剩余事件，然后移除
+func releaseStream(streamID string) {
+	time.AfterFunc(streamRetention, func() {
+		streamMutex.Lock()
+		delete(streamContexts, streamID)
+		streamMutex.Unlock()
+	})
+}
+
+// ResumeWorkflowStream 重连流式执行，先补发 lastEventID 之后的事件，再继续接收新事件
+// 只有发起执行的用户可以重连
+func (s *appService) ResumeWorkflowStream(c *gin.Context, streamID, userID string, lastEventID int64) error {
+	streamCtx, exists := getStream(streamID)
+	if !exists || streamCtx.UserID != userID {
+		return ErrStreamNotFound
+	}
+
+	c.Header(StreamIDHeader, streamID)
+	s.setSSEHeaders(c)
+	s.serveStream(c, streamCtx, lastEventID)
+	return nil
+}
+
+// CancelWorkflowStream 取消流式执行，只有发起执行的用户可以取消
+// 取消后由执行协程停止上游任务、将执行记录为 cancelled 并释放预扣积分
+func (s *appService) CancelWorkflowStream(streamID, userID string) error {
+	streamCtx, exists := getStream(streamID)
+	if !exists || streamCtx.UserID != userID {
+		return ErrStreamNotFound
+	}
+	if streamCtx.Buffer.isDone() {
+		return ErrStreamFinished
+	}
+
+	streamCtx.cancel("用户取消")
+	return nil
+}
+
+// cancel 取消流式执行，只有第一次调用的原因会被记录
+func (sc *StreamContext) cancel(reason string) {
+	sc.cancelOnce.Do(func() {
+		sc.cancelReason = reason
+		sc.CancelFunc()
+	})
+}
+
+// finishCancelledStream 处理被取消的流式执行：停止上游任务，通知客户端，并将执行记录为 cancelled
+// 返回的错误使预扣积分被释放
+func (s *appService) finishCancelledStream(streamCtx *StreamContext, taskID string) error {
+	reason := streamCtx.cancelReason
+	if reason == "" {
+		reason = "流式执行已取消"
+	}
+
+	if taskID != "" && streamCtx.upstreamWorkflow != nil {
+		if err := s.stopUpstreamTask(streamCtx.upstreamWorkflow, streamCtx.UserID, taskID); err != nil {
+			fmt.Printf("停止上游任务失败: stream_id=%s, task_id=%s, err=%v\n", streamCtx.StreamID, taskID, err)
+		}
+	}
+
+	cancelledEvent, _ := json.Marshal(map[string]interface{}{
+		"event": "stream_cancelled",
+		"data":  map[string]string{"stream_id": streamCtx.StreamID, "message": reason},
+	})
+	streamCtx.Buffer.append("", string(cancelledEvent))
+
+	streamCtx.FinalStatus = "cancelled"
+	streamCtx.ExecutionTime = int(time.Since(streamCtx.StartTime).Milliseconds())
+
+	response := &ExecuteWorkflowResponse{
+		Success: false,
+		Data:    map[string]interface{}{"outputs": nil},
+		Message: "工作流执行已取消: " + reason,
+	}
+	if streamCtx.FailoverWorkflowID != "" {
+		response.Data["failover_workflow_id"] = streamCtx.FailoverWorkflowID
+	}
+	s.LogWorkflowExecution(streamCtx.Workflow, streamCtx.UserID, streamCtx.Inputs, response, "cancelled", reason, streamCtx.ExecutionTime, streamCtx.Usage, streamCtx.HoldID)
+
+	return fmt.Errorf("已取消: %s", reason)
+}
+
+// stopUpstreamTask 调用 Dify 停止接口停止上游任务，task_id 取自流式事件
+func (s *appService) stopUpstreamTask(workflow *model.Workflow, userID, taskID string) error {
+	stopURL, ok := difyStopURL(workflow.ApiURL, taskID)
+	if !ok {
+		return fmt.Errorf("无法根据接口地址确定停止接口: %s", workflow.ApiURL)
+	}
+	apiKey, err := ResolveWorkflowAPIKey(workflow)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]string{"user": userID})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", "Bearer "+apiKey)
+
+	ctx, cancel := context.WithTimeout(context.Background(), streamStopTimeout)
+	defer cancel()
+	resp, err := workflowStopClient.Do(ctx, &upstream.Request{
+		Method: http.MethodPost,
+		URL:    stopURL,
+		Header: header,
+		Body:   body,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &upstream.StatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return nil
+}
+
+// difyStopURL 根据执行接口地址推导停止接口地址
+// 工作流 .../workflows/run 对应 .../workflows/tasks/{task_id}/stop，ChatFlow .../chat-messages 对应 .../chat-messages/{task_id}/stop
+func difyStopURL(apiURL, taskID string) (string, bool) {
+	trimmed := strings.TrimRight(apiURL, "/")
+	switch {
+	case strings.HasSuffix(trimmed, "/workflows/run"):
+		return strings.TrimSuffix(trimmed, "/run") + "/tasks/" + url.PathEscape(taskID) + "/stop", true
+	case isChatFlowURL(trimmed):
+		return trimmed + "/" + url.PathEscape(taskID) + "/stop", true
+	}
+	return "", false
+}
+
+// serveStream 将缓存的事件写入客户端连接，直到流结束或客户端断开
+func (s *appService) serveStream(c *gin.Context, streamCtx *StreamContext, lastEventID int64) {
+	buffer := streamCtx.Buffer
+	buffer.attach()
+	defer buffer.detach(func() {
+		fmt.Printf("流式执行的客户端断开超过 %v，取消执行: stream_id=%s\n", streamDetachGrace, streamCtx.StreamID)
+		streamCtx.cancel(fmt.Sprintf("客户端断开超过 %v", streamDetachGrace))
+	})
+
+	// 心跳定时器：定期发送一个 SSE 注释行保持连接活跃
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		events, done, wait := buffer.since(lastEventID)
+		for _, event := range events {
+			fmt.Fprintf(c.Writer, "id: %d\n", event.ID)
+			if event.Event != "" {
+				fmt.Fprintf(c.Writer, "event: %s\n", event.Event)
+			}
+			fmt.Fprintf(c.Writer, "data: %s\n\n", event.Data)
+			lastEventID = event.ID
+		}
+		if len(events) > 0 {
+			c.Writer.Flush()
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			// SSE 注释行（以 : 开头），客户端会忽略，但能保持连接不被中间层掐断
+			fmt.Fprintf(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case <-wait:
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"server/global"
+	"server/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockWorkflow 锁定工作流行
+func lockWorkflow(tx *gorm.DB, workflowID string) (*model.Workflow, error) {
+	var workflow model.Workflow
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", workflowID).
+		First(&workflow).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("工作流不存在")
+		}
+		return nil, errors.New("查询工作流失败")
+	}
+	return &workflow, nil
+}
+
+// sameWorkflowConfig 判断工作流当前调用配置是否与版本一致
+func sameWorkflowConfig(workflow *model.Workflow, version *model.WorkflowVersion) bool {
+	return workflow.ApiURL == version.ApiURL &&
+		sameAPIKey(workflow.ApiKey, version.ApiKey) &&
+		workflow.BillingActionKey == version.BillingActionKey &&
+		sameJSON(workflow.Inputs, version.Inputs) &&
+		sameJSON(workflow.Outputs, version.Outputs)
+}
+
+// sameJSON 按语义比较两个JSON值，忽略格式与键顺序差异
+func sameJSON(a, b model.JSON) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	return reflect.DeepEqual(decodeJSON(a), decodeJSON(b))
+}
+
+// decodeJSON 解析JSON值，为空或无法解析时返回 nil
+func decodeJSON(data model.JSON) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// recordWorkflowVersionInTx 在给定事务中为已锁定的工作流追加一个配置版本，并更新工作流的当前版本号
+func recordWorkflowVersionInTx(tx *gorm.DB, workflow *model.Workflow, authorID string, rolledBackFrom *int, notes string) (*model.WorkflowVersion, error) {
+	version := &model.WorkflowVersion{
+		WorkflowID:       workflow.ID,
+		Version:          workflow.ConfigVersion + 1,
+		ApiURL:           workflow.ApiURL,
+		ApiKey:           workflow.ApiKey,
+		Inputs:           workflow.Inputs,
+		Outputs:          workflow.Outputs,
+		BillingActionKey: workflow.BillingActionKey,
+		AuthorID:         authorID,
+		RolledBackFrom:   rolledBackFrom,
+		Notes:            notes,
+	}
+	if err := tx.Create(version).Error; err != nil {
+		return nil, fmt.Errorf("记录工作流版本失败: %w", err)
+	}
+
+	if err := tx.Model(workflow).UpdateColumn("config_version", version.Version).Error; err != nil {
+		return nil, fmt.Errorf("更新工作流版本失败: %w", err)
+	}
+	workflow.ConfigVersion = version.Version
+	return version, nil
+}
+
+// ensureBaselineVersionInTx 为版本化之前创建的工作流补记当前配置作为第1版，作者记为创建者
+// 历史明文密钥先加密，避免明文写入版本记录
+func ensureBaselineVersionInTx(tx *gorm.DB, workflow *model.Workflow) error {
+	if err := encryptPlaintextAPIKey(tx, workflow); err != nil {
+		fmt.Printf("迁移工作流明文API密钥失败: workflow_id=%s, err=%v\n", workflow.ID, err)
+	}
+	if workflow.ConfigVersion > 0 {
+		return nil
+	}
+	_, err := recordWorkflowVersionInTx(tx, workflow, workflow.CreatorID, nil, "初始配置")
+	return err
+}
+
+// getWorkflowVersion 查询工作流的指定版本
+func getWorkflowVersion(db *gorm.DB, workflowID string, version int) (*model.WorkflowVersion, error) {
+	var workflowVersion model.WorkflowVersion
+	if err := db.Where("workflow_id = ? AND version = ?", workflowID, version).
+		First(&workflowVersion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("工作流版本不存在: v%d", version)
+		}
+		return nil, errors.New("查询工作流版本失败")
+	}
+	return &workflowVersion, nil
+}
+
+// ListWorkflowVersions 查询工作流的配置版本历史，按版本号倒序（管理员）
+func (s *appService) ListWorkflowVersions(workflowID string) ([]model.WorkflowVersion, error) {
+	if _, err := s.getWorkflowByID(workflowID); err != nil {
+		return nil, err
+	}
+
+	var versions []model.WorkflowVersion
+	if err := global.DB.Where("workflow_id = ?", workflowID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, errors.New("查询工作流版本失败")
+	}
+	for i := range versions {
+		versions[i].ApiKey = maskAPIKey(versions[i].ApiKey)
+	}
+	return versions, nil
+}
+
+// DiffWorkflowVersions 比较工作流两个配置版本的差异（管理员）
+// 输入输出字段按 field_name 逐项比较，其余配置项整体比较
+func (s *appService) DiffWorkflowVersions(workflowID string, from, to int) (*WorkflowVersionDiff, error) {
+	fromVersion, err := getWorkflowVersion(global.DB, workflowID, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := getWorkflowVersion(global.DB, workflowID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &WorkflowVersionDiff{
+		WorkflowID:  workflowID,
+		FromVersion: from,
+		ToVersion:   to,
+		Changes:     []WorkflowConfigChange{},
+	}
+	if fromVersion.ApiURL != toVersion.ApiURL {
+		diff.Changes = append(diff.Changes, WorkflowConfigChange{Field: "api_url", From: fromVersion.ApiURL, To: toVersion.ApiURL})
+	}
+	if !sameAPIKey(fromVersion.ApiKey, toVersion.ApiKey) {
+		diff.Changes = append(diff.Changes, WorkflowConfigChange{Field: "api_key", From: maskAPIKey(fromVersion.ApiKey), To: maskAPIKey(toVersion.ApiKey)})
+	}
+	if fromVersion.BillingActionKey != toVersion.BillingActionKey {
+		diff.Changes = append(diff.Changes, WorkflowConfigChange{Field: "billing_action_key", From: fromVersion.BillingActionKey, To: toVersion.BillingActionKey})
+	}
+	diff.Changes = append(diff.Changes, diffFieldList("inputs", fromVersion.Inputs, toVersion.Inputs)...)
+	diff.Changes = append(diff.Changes, diffFieldList("outputs", fromVersion.Outputs, toVersion.Outputs)...)
+	return diff, nil
+}
+
+// diffFieldList 比较输入或输出字段定义
+// 两侧都能解析为字段声明时按 field_name 给出新增、删除、修改的字段，否则整体比较
+func diffFieldList(name string, from, to model.JSON) []WorkflowConfigChange {
+	if sameJSON(from, to) {
+		return nil
+	}
+
+	fromFields, fromOK := parseFieldSchema(from)
+	toFields, toOK := parseFieldSchema(to)
+	if (!fromOK && !isEmptySchema(from)) || (!toOK && !isEmptySchema(to)) {
+		return []WorkflowConfigChange{{Field: name, From: decodeJSON(from), To: decodeJSON(to)}}
+	}
+
+	var changes []WorkflowConfigChange
+	for _, field := range fromFields {
+		path := name + "." + field.FieldName
+		target, ok := findField(toFields, field.FieldName)
+		if !ok {
+			changes = append(changes, WorkflowConfigChange{Field: path, From: field})
+		} else if !reflect.DeepEqual(*target, field) {
+			changes = append(changes, WorkflowConfigChange{Field: path, From: field, To: *target})
+		}
+	}
+	for _, field := range toFields {
+		if _, ok := findField(fromFields, field.FieldName); !ok {
+			changes = append(changes, WorkflowConfigChange{Field: name + "." + field.FieldName, To: field})
+		}
+	}
+	if len(changes) == 0 {
+		// 字段相同仅顺序或格式不同
+		changes = append(changes, WorkflowConfigChange{Field: name, From: decodeJSON(from), To: decodeJSON(to)})
+	}
+	return changes
+}
+
+// isEmptySchema 判断字段声明是否为空（未设置、null、空列表或空对象）
+func isEmptySchema(data model.JSON) bool {
+	switch value := decodeJSON(data).(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+// findField 按字段名查找字段定义
+func findField(fields []model.Field, name string) (*model.Field, bool) {
+	for i := range fields {
+		if fields[i].FieldName == name {
+			return &fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// RollbackWorkflow 将工作流配置回滚到指定版本（管理员）
+// 回滚不会删除历史，而是以目标版本的配置追加一个新版本
+func (s *appService) RollbackWorkflow(workflowID string, version int, authorID, notes string) (*model.WorkflowVersion, error) {
+	var rolledBack *model.WorkflowVersion
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		workflow, err := lockWorkflow(tx, workflowID)
+		if err != nil {
+			return err
+		}
+		if err := ensureBaselineVersionInTx(tx, workflow); err != nil {
+			return err
+		}
+
+		target, err := getWorkflowVersion(tx, workflowID, version)
+		if err != nil {
+			return err
+		}
+		if sameWorkflowConfig(workflow, target) {
+			return fmt.Errorf("当前配置与 v%d 相同，无需回滚", version)
+		}
+		if err := validateBillingActionKey(target.BillingActionKey); err != nil {
+			return err
+		}
+
+		if err := tx.Model(workflow).Updates(map[string]interface{}{
+			"api_url":            target.ApiURL,
+			"api_key":            target.ApiKey,
+			"inputs":             target.Inputs,
+			"outputs":            target.Outputs,
+			"billing_action_key": target.BillingActionKey,
+		}).Error; err != nil {
+			return errors.New("回滚工作流失败")
+		}
+		workflow.ApiURL = target.ApiURL
+		workflow.ApiKey = target.ApiKey
+		workflow.Inputs = target.Inputs
+		workflow.Outputs = target.Outputs
+		workflow.BillingActionKey = target.BillingActionKey
+
+		if notes == "" {
+			notes = fmt.Sprintf("回滚到 v%d", version)
+		}
+		rolledBack, err = recordWorkflowVersionInTx(tx, workflow, authorID, &target.Version, notes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	rolledBack.ApiKey = maskAPIKey(rolledBack.ApiKey)
+	return rolledBack, nil
+}
@@ -1,7 +1,6 @@
 package asr
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,6 +10,7 @@ import (
 
 	"server/global"
 	"server/model"
+	"server/service/upstream"
 
 	"github.com/google/uuid"
 )
@@ -38,13 +38,16 @@ type ASRServiceInterface interface {
 
 // asrService ASR服务实现
 type asrService struct {
-	client     *http.Client
+	client     *upstream.Client
 	baseURL    string
 	appKey     string
 	accessKey  string
 	resourceID string
 }
 
+// asrBreakerKey ASR上游的熔断器键
+const asrBreakerKey = "asr"
+
 // NewASRService 创建ASR服务实例
 func NewASRService() (ASRServiceInterface, error) {
 	config := global.CONFIG.ASR
@@ -56,9 +59,10 @@ func NewASRService() (ASRServiceInterface, error) {
 	}
 
 	return &asrService{
-		client: &http.Client{
+		// 提交和查询都以任务ID作为 X-Api-Request-Id，重复发送不会产生重复任务，可以安全重试
+		client: upstream.NewClient(&http.Client{
 			Timeout: time.Duration(timeout) * time.Second,
-		},
+		}, upstream.RetryPolicy{Idempotent: true}),
 		baseURL:    config.BaseURL,
 		appKey:     config.AppKey,
 		accessKey:  config.AccessKey,
@@ -138,22 +142,18 @@ func (s *asrService) submitToASRAPI(taskID, audioURL, audioFormat string, option
 		return
 	}
 
-	// 发送HTTP请求
-	req, err := http.NewRequest("POST", s.baseURL+"/submit", bytes.NewBuffer(jsonData))
-	if err != nil {
-		s.updateTaskError(taskID, fmt.Sprintf("failed to create request: %v", err))
-		return
-	}
-
 	// 设置字节跳动ASR API要求的请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-App-Key", s.appKey)
-	req.Header.Set("X-Api-Access-Key", s.accessKey)
-	req.Header.Set("X-Api-Resource-Id", s.resourceID)
-	req.Header.Set("X-Api-Request-Id", taskID)
-	req.Header.Set("X-Api-Sequence", "-1")
-
-	resp, err := s.client.Do(req)
+	header := s.apiHeader(taskID)
+	header.Set("X-Api-Sequence", "-1")
+
+	// 发送HTTP请求
+	resp, err := s.client.Do(context.Background(), &upstream.Request{
+		Method:  "POST",
+		URL:     s.baseURL + "/submit",
+		Header:  header,
+		Body:    jsonData,
+		Breaker: asrBreakerKey,
+	})
 	if err != nil {
 		s.updateTaskError(taskID, fmt.Sprintf("failed to submit task: %v", err))
 		return
@@ -207,19 +207,13 @@ func (s *asrService) PollTask(ctx context.Context, taskID string) (*model.ASRTas
 	}
 
 	// 查询ASR API获取最新状态
-	req, err := http.NewRequest("POST", s.baseURL+"/query", bytes.NewReader([]byte("{}")))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// 设置字节跳动ASR API要求的请求头
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-App-Key", s.appKey)
-	req.Header.Set("X-Api-Access-Key", s.accessKey)
-	req.Header.Set("X-Api-Resource-Id", s.resourceID)
-	req.Header.Set("X-Api-Request-Id", taskID)
-
-	resp, err := s.client.Do(req)
+	resp, err := s.client.Do(ctx, &upstream.Request{
+		Method:  "POST",
+		URL:     s.baseURL + "/query",
+		Header:  s.apiHeader(taskID),
+		Body:    []byte("{}"),
+		Breaker: asrBreakerKey,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query task: %w", err)
 	}
@@ -361,6 +355,17 @@ func (s *asrService) RetryTask(ctx context.Context, taskID string) (*model.ASRTa
 	return s.GetTask(ctx, taskID)
 }
 
+// apiHeader 字节跳动ASR API要求的请求头
+func (s *asrService) apiHeader(taskID string) http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Api-App-Key", s.appKey)
+	header.Set("X-Api-Access-Key", s.accessKey)
+	header.Set("X-Api-Resource-Id", s.resourceID)
+	header.Set("X-Api-Request-Id", taskID)
+	return header
+}
+
 // updateTaskError 更新任务错误状态
 func (s *asrService) updateTaskError(taskID, errorMsg string) {
 	global.DB.Model(&model.ASRTask{}).
@@ -370,3 +375,34 @@ func (s *asrService) updateTaskError(taskID, errorMsg string) {
 			"error_message": errorMsg,
 		})
 }
+
+// RepollStuckTasks 重新轮询长时间未更新的进行中任务
+// 用户关闭页面后前端不再轮询，任务会一直停留在pending/processing状态，由定时任务调用补偿
+func RepollStuckTasks(ctx context.Context, staleAfter time.Duration) (int, error) {
+	if global.ASRService == nil {
+		return 0, nil
+	}
+
+	var tasks []model.ASRTask
+	if err := global.DB.
+		Where("status IN ? AND updated_at < ?",
+			[]string{model.ASRTaskStatusPending, model.ASRTaskStatusProcessing}, time.Now().Add(-staleAfter)).
+		Order("updated_at ASC").
+		Limit(100).
+		Find(&tasks).Error; err != nil {
+		return 0, fmt.Errorf("查询待轮询任务失败: %w", err)
+	}
+
+	polled := 0
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, err := global.ASRService.PollTask(ctx, task.ID); err != nil {
+			fmt.Printf("重新轮询ASR任务失败: task_id=%s, err=%v\n", task.ID, err)
+			continue
+		}
+		polled++
+	}
+	return polled, nil
+}
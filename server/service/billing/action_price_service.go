@@ -1,6 +1,13 @@
 package billing
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
 	"server/global"
 	"server/model"
 )
@@ -8,16 +15,24 @@ import (
 type ActionPriceService struct{}
 
 // GetActionPrice 根据action_key获取动作价格
+// 返回的 CreditsCost 为当前生效的价格，PriceVersionID 为对应的价格版本
 func (s *ActionPriceService) GetActionPrice(actionKey string) (*model.BillingActionPrice, error) {
-	var actionPrice model.BillingActionPrice
-	err := global.DB.Where("action_key = ? AND is_active = ?", actionKey, true).First(&actionPrice).Error
+	var actionPrices []model.BillingActionPrice
+	err := global.DB.Where("action_key = ? AND is_active = ?", actionKey, true).Limit(1).Find(&actionPrices).Error
 	if err != nil {
 		return nil, err
 	}
-	return &actionPrice, nil
+	if len(actionPrices) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := applyPriceVersions(global.DB, actionPrices, time.Now()); err != nil {
+		return nil, err
+	}
+	return &actionPrices[0], nil
 }
 
 // ListActionPrices 查询动作价格列表
+// 返回当前生效的价格，并附带即将发生的价格变动
 func (s *ActionPriceService) ListActionPrices(activeOnly bool) ([]model.BillingActionPrice, error) {
 	var actionPrices []model.BillingActionPrice
 	query := global.DB.Order("sort_order ASC, id ASC")
@@ -26,8 +41,13 @@ func (s *ActionPriceService) ListActionPrices(activeOnly bool) ([]model.BillingA
 		query = query.Where("is_active = ?", true)
 	}
 
-	err := query.Find(&actionPrices).Error
-	return actionPrices, err
+	if err := query.Find(&actionPrices).Error; err != nil {
+		return nil, err
+	}
+	if err := applyPriceVersions(global.DB, actionPrices, time.Now()); err != nil {
+		return nil, err
+	}
+	return actionPrices, nil
 }
 
 // CreateActionPrice 创建动作价格
@@ -36,6 +56,252 @@ func (s *ActionPriceService) CreateActionPrice(actionPrice *model.BillingActionP
 }
 
 // UpdateActionPrice 更新动作价格
+// CreditsCost 为基础价格，仅在没有生效中的价格版本时使用；调价请使用 SchedulePriceVersion
 func (s *ActionPriceService) UpdateActionPrice(actionPrice *model.BillingActionPrice) error {
 	return global.DB.Save(actionPrice).Error
 }
+
+// ListPriceVersions 查询动作的价格版本历史（含已排期的未来版本），按生效时间倒序
+func (s *ActionPriceService) ListPriceVersions(actionKey string) ([]model.BillingActionPriceVersion, error) {
+	var versions []model.BillingActionPriceVersion
+	err := global.DB.Where("action_key = ?", actionKey).
+		Order("effective_from DESC, id DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// SchedulePriceVersion 为动作排期一个价格版本
+// 不指定生效时间时立即生效；不指定结束时间时长期有效，直到被更晚生效的版本覆盖
+func (s *ActionPriceService) SchedulePriceVersion(actionKey string, req *SchedulePriceRequest, createdBy string) (*model.BillingActionPriceVersion, error) {
+	var count int64
+	if err := global.DB.Model(&model.BillingActionPrice{}).Where("action_key = ?", actionKey).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("动作不存在: %s", actionKey)
+	}
+
+	now := time.Now()
+	effectiveFrom := now
+	if req.EffectiveFrom != nil {
+		// 允许少量时钟误差，但不允许追溯修改历史价格
+		if req.EffectiveFrom.Before(now.Add(-time.Minute)) {
+			return nil, errors.New("生效时间不能早于当前时间")
+		}
+		if req.EffectiveFrom.After(now) {
+			effectiveFrom = *req.EffectiveFrom
+		}
+	}
+	if req.EffectiveTo != nil && !req.EffectiveTo.After(effectiveFrom) {
+		return nil, errors.New("结束时间必须晚于生效时间")
+	}
+
+	version := &model.BillingActionPriceVersion{
+		ActionKey:     actionKey,
+		CreditsCost:   req.CreditsCost,
+		EffectiveFrom: effectiveFrom,
+		EffectiveTo:   req.EffectiveTo,
+		Notes:         req.Notes,
+		CreatedBy:     createdBy,
+	}
+	if err := global.DB.Create(version).Error; err != nil {
+		return nil, fmt.Errorf("创建价格版本失败: %w", err)
+	}
+	return version, nil
+}
+
+// CancelPriceVersion 取消价格版本
+// 尚未生效的版本直接删除；生效中的版本立即结束；已结束的版本作为历史记录保留，不可取消
+func (s *ActionPriceService) CancelPriceVersion(id int64) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		var version model.BillingActionPriceVersion
+		if err := tx.First(&version, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("价格版本不存在")
+			}
+			return err
+		}
+
+		now := time.Now()
+		switch {
+		case version.EffectiveFrom.After(now):
+			return tx.Delete(&version).Error
+		case version.ActiveAt(now):
+			return tx.Model(&version).Update("effective_to", now).Error
+		default:
+			return errors.New("价格版本已结束，无法取消")
+		}
+	})
+}
+
+// applyPriceVersions 按价格版本计算动作在指定时刻的价格及即将发生的变动
+func applyPriceVersions(db *gorm.DB, actionPrices []model.BillingActionPrice, at time.Time) error {
+	if len(actionPrices) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(actionPrices))
+	for _, price := range actionPrices {
+		keys = append(keys, price.ActionKey)
+	}
+
+	// 只需要当前生效及未来的版本
+	var versions []model.BillingActionPriceVersion
+	if err := db.Where("action_key IN ? AND (effective_to IS NULL OR effective_to > ?)", keys, at).
+		Order("effective_from DESC, id DESC").
+		Find(&versions).Error; err != nil {
+		return fmt.Errorf("查询价格版本失败: %w", err)
+	}
+
+	byKey := make(map[string][]model.BillingActionPriceVersion)
+	for _, version := range versions {
+		byKey[version.ActionKey] = append(byKey[version.ActionKey], version)
+	}
+
+	for i := range actionPrices {
+		price := &actionPrices[i]
+		keyVersions := byKey[price.ActionKey]
+		baseCost := price.CreditsCost
+
+		current := resolvePriceVersion(keyVersions, at)
+		if current != nil {
+			price.CreditsCost = current.CreditsCost
+			price.PriceVersionID = &current.ID
+		}
+		price.UpcomingChange = nextPriceChange(keyVersions, baseCost, price.CreditsCost, at)
+	}
+	return nil
+}
+
+// resolvePriceVersion 返回指定时刻生效的版本（versions 需按生效时间倒序），没有时返回 nil
+func resolvePriceVersion(versions []model.BillingActionPriceVersion, at time.Time) *model.BillingActionPriceVersion {
+	for i := range versions {
+		if versions[i].ActiveAt(at) {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+// nextPriceChange 计算当前时刻之后第一次价格变动（新版本生效或限时版本结束）
+func nextPriceChange(versions []model.BillingActionPriceVersion, baseCost, currentCost int, at time.Time) *model.ActionPriceChange {
+	var points []time.Time
+	for _, version := range versions {
+		if version.EffectiveFrom.After(at) {
+			points = append(points, version.EffectiveFrom)
+		}
+		if version.EffectiveTo != nil && version.EffectiveTo.After(at) {
+			points = append(points, *version.EffectiveTo)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+
+	for _, point := range points {
+		change := &model.ActionPriceChange{CreditsCost: baseCost, EffectiveAt: point}
+		if version := resolvePriceVersion(versions, point); version != nil {
+			change.CreditsCost = version.CreditsCost
+			change.PriceVersionID = &version.ID
+			change.Notes = version.Notes
+		}
+		if change.CreditsCost != currentCost {
+			return change
+		}
+	}
+	return nil
+}
+
+// UpdateActionConfig 更新动作的展示信息与计费方式，基础价格请使用 SchedulePriceVersion 调整
+func (s *ActionPriceService) UpdateActionConfig(actionKey string, req *UpdateActionPriceRequest) (*model.BillingActionPrice, error) {
+	var actionPrice model.BillingActionPrice
+	if err := global.DB.Where("action_key = ?", actionKey).First(&actionPrice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("动作不存在: %s", actionKey)
+		}
+		return nil, err
+	}
+
+	// 未提交的计费字段保留当前值，按修改后的完整配置校验
+	mode := PricingMode(actionPrice.PricingMode)
+	if req.PricingMode != nil {
+		mode = *req.PricingMode
+	}
+	if mode == "" {
+		mode = PricingModeFixed
+	}
+	creditsPer1KTokens := actionPrice.CreditsPer1KTokens
+	if req.CreditsPer1KTokens != nil {
+		creditsPer1KTokens = *req.CreditsPer1KTokens
+	}
+	maxCreditsPerCall := actionPrice.MaxCreditsPerCall
+	if req.MaxCreditsPerCall != nil {
+		maxCreditsPerCall = *req.MaxCreditsPerCall
+	}
+	switch mode {
+	case PricingModeFixed:
+	case PricingModeMetered:
+		if creditsPer1KTokens <= 0 {
+			return nil, errors.New("按量计费必须设置每千token积分")
+		}
+		if maxCreditsPerCall <= 0 {
+			return nil, errors.New("按量计费必须设置单次上限")
+		}
+	default:
+		return nil, fmt.Errorf("无效的计费方式: %s", mode)
+	}
+
+	updates := map[string]interface{}{
+		"pricing_mode":          mode,
+		"credits_per_1k_tokens": creditsPer1KTokens,
+		"max_credits_per_call":  maxCreditsPerCall,
+	}
+	if req.ActionName != "" {
+		updates["action_name"] = req.ActionName
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+	if err := global.DB.Model(&actionPrice).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("更新动作失败: %w", err)
+	}
+
+	actionPrices := []model.BillingActionPrice{actionPrice}
+	if err := applyPriceVersions(global.DB, actionPrices, time.Now()); err != nil {
+		return nil, err
+	}
+	return &actionPrices[0], nil
+}
+
+// maxActionCredits 单次调用最多需要的积分，预扣和余额检查按此计算
+// 按量计费为单次上限（不低于基础价格），固定价格为当前价格
+func maxActionCredits(actionPrice *model.BillingActionPrice) int {
+	if PricingMode(actionPrice.PricingMode) != PricingModeMetered {
+		return actionPrice.CreditsCost
+	}
+	if actionPrice.MaxCreditsPerCall > actionPrice.CreditsCost {
+		return actionPrice.MaxCreditsPerCall
+	}
+	return actionPrice.CreditsCost
+}
+
+// meteredCredits 按token用量计算实际积分，baseCost 为扣减时生效的基础价格，结果不超过 maxCredits
+// 固定价格的动作直接返回 baseCost
+func meteredCredits(actionPrice *model.BillingActionPrice, baseCost, totalTokens, maxCredits int) int {
+	if PricingMode(actionPrice.PricingMode) != PricingModeMetered {
+		return baseCost
+	}
+
+	credits := baseCost
+	if totalTokens > 0 && actionPrice.CreditsPer1KTokens > 0 {
+		credits += (totalTokens*actionPrice.CreditsPer1KTokens + 999) / 1000
+	}
+	if maxCredits > 0 && credits > maxCredits {
+		credits = maxCredits
+	}
+	return credits
+}
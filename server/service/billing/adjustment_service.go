@@ -0,0 +1,245 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+	"server/service/eventlog"
+)
+
+type AdjustmentService struct{}
+
+// adjustmentPackageName 积分发放调整生成的系统套餐名称
+const adjustmentPackageName = "积分调整"
+
+// AdjustCredits 管理员手动调整用户积分
+// 发放时生成一个系统来源的积分包，扣回时按扣减优先级从用户有效套餐中扣除，可用积分不足时整体失败
+// 每次调整都记录调整记录与 adjust 流水，并关联操作管理员
+func (s *AdjustmentService) AdjustCredits(req *AdjustCreditsRequest, operatorID string) (*model.CreditAdjustment, error) {
+	if req.Credits == 0 {
+		return nil, errors.New("调整积分不能为0")
+	}
+	if !req.ReasonCode.IsValid() {
+		return nil, fmt.Errorf("无效的调整原因: %s", req.ReasonCode)
+	}
+	if req.Notes == "" {
+		return nil, errors.New("必须填写调整说明")
+	}
+
+	var userCount int64
+	if err := global.DB.Model(&model.User{}).Where("id = ?", req.UserID).Count(&userCount).Error; err != nil {
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+	if userCount == 0 {
+		return nil, errors.New("用户不存在")
+	}
+
+	adjustment := &model.CreditAdjustment{
+		UserID:     req.UserID,
+		OperatorID: operatorID,
+		Credits:    req.Credits,
+		ReasonCode: string(req.ReasonCode),
+		Notes:      req.Notes,
+	}
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 先写入调整记录，流水通过 resource_id 关联
+		if err := tx.Create(adjustment).Error; err != nil {
+			return fmt.Errorf("创建调整记录失败: %w", err)
+		}
+
+		var err error
+		if req.Credits > 0 {
+			err = grantAdjustment(tx, adjustment, req.ValidityDays)
+		} else {
+			err = debitAdjustment(tx, adjustment)
+		}
+		if err != nil {
+			return err
+		}
+
+		balance, err := sumUserCredits(tx, req.UserID)
+		if err != nil {
+			return err
+		}
+		adjustment.BalanceAfter = balance
+		return tx.Model(adjustment).Updates(map[string]interface{}{
+			"balance_after":           adjustment.BalanceAfter,
+			"user_billing_package_id": adjustment.UserBillingPackageID,
+			"expires_at":              adjustment.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logAdjustmentEvent(adjustment)
+	return adjustment, nil
+}
+
+// grantAdjustment 为发放调整生成系统来源的积分包，并记录 adjust 流水
+func grantAdjustment(tx *gorm.DB, adjustment *model.CreditAdjustment, validityDays int) error {
+	now := time.Now()
+	userPackage := &model.UserBillingPackage{
+		UserID:           adjustment.UserID,
+		PackageName:      adjustmentPackageName,
+		PackageType:      string(PackageTypeCredits),
+		TotalCredits:     adjustment.Credits,
+		RemainingCredits: adjustment.Credits,
+		Status:           string(PackageStatusActive),
+		Source:           string(PackageSourceSystem),
+		ActivatedAt:      &now,
+		Notes:            adjustment.Notes,
+	}
+	if validityDays > 0 {
+		expiresAt := now.AddDate(0, 0, validityDays)
+		userPackage.ExpiresAt = &expiresAt
+	}
+	if err := tx.Create(userPackage).Error; err != nil {
+		return fmt.Errorf("创建用户套餐失败: %w", err)
+	}
+	adjustment.UserBillingPackageID = &userPackage.ID
+	adjustment.ExpiresAt = userPackage.ExpiresAt
+
+	return recordAdjustmentTransaction(tx, adjustment, userPackage.ID, adjustment.Credits)
+}
+
+// debitAdjustment 按扣减优先级从用户有效套餐中扣回积分，每个被扣减的套餐记录一条 adjust 流水
+func debitAdjustment(tx *gorm.DB, adjustment *model.CreditAdjustment) error {
+	userPackages, err := lockActivePackages(tx, adjustment.UserID)
+	if err != nil {
+		return fmt.Errorf("查询用户套餐失败: %w", err)
+	}
+
+	required := -adjustment.Credits
+	available := 0
+	for _, pkg := range userPackages {
+		available += pkg.RemainingCredits
+	}
+	if available < required {
+		return fmt.Errorf("用户可用积分不足，当前 %d，需扣回 %d", available, required)
+	}
+
+	for i := range userPackages {
+		if required <= 0 {
+			break
+		}
+
+		pkg := &userPackages[i]
+		debit := required
+		if pkg.RemainingCredits < debit {
+			debit = pkg.RemainingCredits
+		}
+
+		pkg.RemainingCredits -= debit
+		if pkg.RemainingCredits == 0 && pkg.ReservedCredits == 0 {
+			pkg.Status = string(PackageStatusDepleted)
+		}
+		if err := tx.Save(pkg).Error; err != nil {
+			return fmt.Errorf("更新套餐积分失败: %w", err)
+		}
+
+		if err := recordAdjustmentTransaction(tx, adjustment, pkg.ID, -debit); err != nil {
+			return err
+		}
+		required -= debit
+	}
+	return nil
+}
+
+// recordAdjustmentTransaction 记录积分调整流水
+func recordAdjustmentTransaction(tx *gorm.DB, adjustment *model.CreditAdjustment, userPackageID int64, credits int) error {
+	metadata, _ := json.Marshal(map[string]interface{}{"reason_code": adjustment.ReasonCode})
+	if err := recordTransaction(tx, &model.CreditTransaction{
+		UserID:               adjustment.UserID,
+		UserBillingPackageID: userPackageID,
+		Type:                 string(TransactionAdjust),
+		Credits:              credits,
+		ResourceType:         "credit_adjustment",
+		ResourceID:           strconv.FormatInt(adjustment.ID, 10),
+		OperatorID:           adjustment.OperatorID,
+		Notes:                adjustment.Notes,
+		Metadata:             model.JSON(metadata),
+	}); err != nil {
+		return fmt.Errorf("记录积分流水失败: %w", err)
+	}
+	return nil
+}
+
+// logAdjustmentEvent 记录积分调整事件
+func logAdjustmentEvent(adjustment *model.CreditAdjustment) {
+	details, _ := json.Marshal(map[string]interface{}{
+		"credits":       adjustment.Credits,
+		"balance_after": adjustment.BalanceAfter,
+		"reason_code":   adjustment.ReasonCode,
+		"operator_id":   adjustment.OperatorID,
+	})
+
+	global.EventLog.Log(context.Background(), &model.EventLog{
+		UserID:        adjustment.UserID,
+		EventType:     eventlog.EventBalanceChange,
+		EventCategory: eventlog.CategoryPayment,
+		ResourceType:  "credit_adjustment",
+		ResourceID:    strconv.FormatInt(adjustment.ID, 10),
+		Status:        eventlog.StatusSuccess,
+		Details:       model.JSON(details),
+	})
+}
+
+// ListAdjustments 分页查询积分调整记录
+func (s *AdjustmentService) ListAdjustments(req *AdjustmentQueryRequest) (*AdjustmentQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.CreditAdjustment{})
+	if req.UserID != "" {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.OperatorID != "" {
+		query = query.Where("operator_id = ?", req.OperatorID)
+	}
+	if req.ReasonCode != "" {
+		query = query.Where("reason_code = ?", req.ReasonCode)
+	}
+	if !req.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", req.StartTime)
+	}
+	if !req.EndTime.IsZero() {
+		query = query.Where("created_at <= ?", req.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计调整记录失败: " + err.Error())
+	}
+
+	var adjustments []model.CreditAdjustment
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&adjustments).Error; err != nil {
+		return nil, errors.New("查询调整记录失败: " + err.Error())
+	}
+
+	return &AdjustmentQueryResponse{
+		List:     adjustments,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"server/model"
+)
+
+// RefundProration 套餐取消时按比例折算的退款
+type RefundProration struct {
+	CreditsRatio   float64 `json:"credits_ratio"`   // 未使用积分比例，不发放积分的套餐为1
+	TimeRatio      float64 `json:"time_ratio"`      // 剩余有效期比例，永久或未激活的套餐为1
+	RefundRatio    float64 `json:"refund_ratio"`    // 退款比例，取两者中较小的一个
+	PaidAmount     int64   `json:"paid_amount"`     // 实付金额（分）
+	ProratedAmount int64   `json:"prorated_amount"` // 折算退款金额（分），向下取整
+}
+
+// ProrateRefund 根据未使用积分与剩余有效期折算套餐退款金额
+// 同时发放积分并设置有效期的套餐取两个比例中较小的一个，避免用完积分或临近到期的套餐获得高额退款
+func ProrateRefund(pkg *model.UserBillingPackage, paidAmount int64, now time.Time) *RefundProration {
+	proration := &RefundProration{CreditsRatio: 1, TimeRatio: 1, PaidAmount: paidAmount}
+
+	if pkg.TotalCredits > 0 {
+		proration.CreditsRatio = clampRatio(float64(pkg.RemainingCredits) / float64(pkg.TotalCredits))
+	}
+	if pkg.ActivatedAt != nil && pkg.ExpiresAt != nil {
+		total := pkg.ExpiresAt.Sub(*pkg.ActivatedAt)
+		if total > 0 {
+			proration.TimeRatio = clampRatio(float64(pkg.ExpiresAt.Sub(now)) / float64(total))
+		} else {
+			proration.TimeRatio = 0
+		}
+	}
+
+	proration.RefundRatio = math.Min(proration.CreditsRatio, proration.TimeRatio)
+	proration.ProratedAmount = int64(math.Floor(float64(paidAmount) * proration.RefundRatio))
+
+	// 比例保留四位小数，便于展示和记录
+	proration.CreditsRatio = math.Round(proration.CreditsRatio*10000) / 10000
+	proration.TimeRatio = math.Round(proration.TimeRatio*10000) / 10000
+	proration.RefundRatio = math.Round(proration.RefundRatio*10000) / 10000
+	return proration
+}
+
+// clampRatio 将比例限制在 [0, 1]
+func clampRatio(ratio float64) float64 {
+	return math.Max(0, math.Min(1, ratio))
+}
+
+// CanCancelPackage 检查用户套餐是否可以取消
+// 已过期、已取消、已退款的套餐不能取消；有预扣中积分的套餐需等待结算或释放后再取消
+func CanCancelPackage(pkg *model.UserBillingPackage) error {
+	switch PackageStatus(pkg.Status) {
+	case PackageStatusPending, PackageStatusActive, PackageStatusDepleted:
+	case PackageStatusCancelled, PackageStatusRefunded:
+		return errors.New("套餐已取消")
+	default:
+		return fmt.Errorf("当前状态的套餐不能取消: %s", pkg.Status)
+	}
+	if pkg.ReservedCredits > 0 {
+		return fmt.Errorf("套餐有 %d 积分正在预扣中，请稍后再试", pkg.ReservedCredits)
+	}
+	return nil
+}
+
+// CancelUserPackageInTx 在给定事务中取消已锁定的用户套餐，剩余积分清零并记录 cancel 流水
+// status 为 cancelled（不退款）或 refunded（已退款）
+func (s *UserPackageService) CancelUserPackageInTx(tx *gorm.DB, pkg *model.UserBillingPackage, status PackageStatus, refundAmount int64, resourceType, resourceID, reason string) error {
+	revoked := pkg.RemainingCredits
+	now := time.Now()
+	if err := tx.Model(pkg).Updates(map[string]interface{}{
+		"status":            status,
+		"remaining_credits": 0,
+		"cancelled_at":      now,
+		"refund_amount":     refundAmount,
+	}).Error; err != nil {
+		return fmt.Errorf("更新用户套餐失败: %w", err)
+	}
+
+	if revoked > 0 {
+		if err := recordTransaction(tx, &model.CreditTransaction{
+			UserID:               pkg.UserID,
+			UserBillingPackageID: pkg.ID,
+			Type:                 string(TransactionCancel),
+			Credits:              -revoked,
+			ResourceType:         resourceType,
+			ResourceID:           resourceID,
+			Notes:                reason,
+		}); err != nil {
+			return fmt.Errorf("记录积分流水失败: %w", err)
+		}
+	}
+	return nil
+}
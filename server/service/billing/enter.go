@@ -5,6 +5,8 @@ type ServiceGroup struct {
 	ActionPriceService  ActionPriceService
 	PackageService      PackageService
 	UserPackageService  UserPackageService
+	TransactionService  TransactionService
+	HoldService         HoldService
 }
 
 var ServiceGroupApp = new(ServiceGroup)
@@ -0,0 +1,211 @@
+package billing
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+)
+
+// ActionEntitlement 用户套餐提供的动作权益（有效期内不限次使用，可设每日合理使用上限）
+type ActionEntitlement struct {
+	ActionKey            string     `json:"action_key"`
+	UserBillingPackageID int64      `json:"user_billing_package_id"`
+	PackageName          string     `json:"package_name"`
+	PackageType          string     `json:"package_type"`
+	DailyLimit           int        `json:"daily_limit"` // 每日合理使用上限，0=不限
+	UsedToday            int        `json:"used_today"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"` // 为空表示永久有效
+}
+
+// Available 今日是否仍可使用该权益
+func (e *ActionEntitlement) Available() bool {
+	return e.DailyLimit <= 0 || e.UsedToday < e.DailyLimit
+}
+
+// grantsCredits 套餐类型是否发放积分，时长型套餐只提供动作权益
+func grantsCredits(packageType string) bool {
+	return PackageType(packageType) != PackageTypeDuration
+}
+
+// grantsEntitlements 套餐类型是否提供动作权益，积分包只有积分
+func grantsEntitlements(packageType string) bool {
+	switch PackageType(packageType) {
+	case PackageTypeDuration, PackageTypeHybrid, PackageTypePermanent:
+		return true
+	}
+	return false
+}
+
+// parseCoveredActions 解析套餐覆盖的动作key列表
+func parseCoveredActions(data model.JSON) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var actions []string
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil
+	}
+	return actions
+}
+
+// ValidateBillingPackage 校验套餐类型与权益配置，并写入覆盖的动作key列表
+// 时长型必须设置有效期和覆盖动作，积分包、混合型、永久型必须发放积分
+func (s *PackageService) ValidateBillingPackage(pkg *model.BillingPackage, coveredActions []string) error {
+	switch PackageType(pkg.PackageType) {
+	case PackageTypeCredits, PackageTypeHybrid, PackageTypePermanent:
+		if pkg.CreditsAmount <= 0 {
+			return fmt.Errorf("该类型套餐的积分数量必须大于0")
+		}
+	case PackageTypeDuration:
+		if pkg.ValidityDays <= 0 {
+			return fmt.Errorf("时长型套餐必须设置有效天数")
+		}
+		if len(coveredActions) == 0 {
+			return fmt.Errorf("时长型套餐必须指定覆盖的动作")
+		}
+	default:
+		return fmt.Errorf("无效的套餐类型: %s", pkg.PackageType)
+	}
+
+	if pkg.DailyActionLimit < 0 {
+		return fmt.Errorf("每日使用上限不能为负数")
+	}
+
+	if !grantsEntitlements(pkg.PackageType) || len(coveredActions) == 0 {
+		pkg.CoveredActions = nil
+		return nil
+	}
+
+	seen := make(map[string]bool, len(coveredActions))
+	actions := make([]string, 0, len(coveredActions))
+	for _, key := range coveredActions {
+		if key == "" || seen[key] {
+			continue
+		}
+		var count int64
+		if err := global.DB.Model(&model.BillingActionPrice{}).Where("action_key = ?", key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("动作不存在: %s", key)
+		}
+		seen[key] = true
+		actions = append(actions, key)
+	}
+
+	data, err := json.Marshal(actions)
+	if err != nil {
+		return err
+	}
+	pkg.CoveredActions = data
+	return nil
+}
+
+// entitlementPackagesQuery 用户提供动作权益的有效套餐，先到期的优先使用
+// 积分耗尽的混合型、永久型套餐在有效期内权益仍然可用
+func entitlementPackagesQuery(db *gorm.DB, userID string) *gorm.DB {
+	return db.Where("user_id = ? AND status IN ? AND package_type IN ? AND covered_actions IS NOT NULL AND (expires_at IS NULL OR expires_at > ?)",
+		userID,
+		[]PackageStatus{PackageStatusActive, PackageStatusDepleted},
+		[]PackageType{PackageTypeDuration, PackageTypeHybrid, PackageTypePermanent},
+		time.Now()).
+		Order("expires_at ASC, id ASC")
+}
+
+// lockEntitlementPackages 锁定用户提供动作权益的有效套餐（悲观锁）
+func lockEntitlementPackages(tx *gorm.DB, userID string) ([]model.UserBillingPackage, error) {
+	var userPackages []model.UserBillingPackage
+	err := entitlementPackagesQuery(tx, userID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Find(&userPackages).Error
+	return userPackages, err
+}
+
+// findEntitlement 在已查询的套餐中查找覆盖指定动作且今日仍有余量的权益，没有时返回 nil
+func findEntitlement(tx *gorm.DB, userPackages []model.UserBillingPackage, actionKey string) (*ActionEntitlement, error) {
+	for i := range userPackages {
+		entitlement := buildEntitlement(&userPackages[i], actionKey)
+		if entitlement == nil {
+			continue
+		}
+
+		if entitlement.DailyLimit > 0 {
+			used, err := countEntitlementUsageToday(tx, entitlement.UserBillingPackageID, actionKey)
+			if err != nil {
+				return nil, fmt.Errorf("查询权益使用次数失败: %w", err)
+			}
+			entitlement.UsedToday = used
+		}
+
+		if entitlement.Available() {
+			return entitlement, nil
+		}
+	}
+	return nil, nil
+}
+
+// buildEntitlement 套餐覆盖指定动作时返回对应权益，否则返回 nil
+func buildEntitlement(pkg *model.UserBillingPackage, actionKey string) *ActionEntitlement {
+	if !grantsEntitlements(pkg.PackageType) {
+		return nil
+	}
+	for _, key := range parseCoveredActions(pkg.CoveredActions) {
+		if key == actionKey {
+			return &ActionEntitlement{
+				ActionKey:            actionKey,
+				UserBillingPackageID: pkg.ID,
+				PackageName:          pkg.PackageName,
+				PackageType:          pkg.PackageType,
+				DailyLimit:           pkg.DailyActionLimit,
+				ExpiresAt:            pkg.ExpiresAt,
+			}
+		}
+	}
+	return nil
+}
+
+// countEntitlementUsageToday 统计套餐权益今日已使用次数（预扣中与已扣减的均计入，已退还或已释放的不计入）
+func countEntitlementUsageToday(tx *gorm.DB, userPackageID int64, actionKey string) (int, error) {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var count int64
+	err := tx.Model(&model.CreditDeduction{}).
+		Where("entitlement_package_id = ? AND action_key = ? AND status IN ? AND created_at >= ?",
+			userPackageID, actionKey,
+			[]DeductionStatus{DeductionStatusReserved, DeductionStatusDeducted},
+			startOfDay).
+		Count(&count).Error
+	return int(count), err
+}
+
+// GetUserEntitlements 查询用户当前所有动作权益（每个套餐的每个动作一条）
+func (s *UserPackageService) GetUserEntitlements(userID string) ([]ActionEntitlement, error) {
+	var userPackages []model.UserBillingPackage
+	if err := entitlementPackagesQuery(global.DB, userID).Find(&userPackages).Error; err != nil {
+		return nil, err
+	}
+
+	entitlements := make([]ActionEntitlement, 0)
+	for i := range userPackages {
+		pkg := &userPackages[i]
+		for _, key := range parseCoveredActions(pkg.CoveredActions) {
+			entitlement := buildEntitlement(pkg, key)
+			if entitlement.DailyLimit > 0 {
+				used, err := countEntitlementUsageToday(global.DB, pkg.ID, key)
+				if err != nil {
+					return nil, err
+				}
+				entitlement.UsedToday = used
+			}
+			entitlements = append(entitlements, *entitlement)
+		}
+	}
+	return entitlements, nil
+}
@@ -0,0 +1,386 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+)
+
+// DefaultHoldTTL 预扣默认有效期
+const DefaultHoldTTL = 30 * time.Minute
+
+type HoldService struct{}
+
+// ReserveCredits 预扣积分（两阶段扣减第一阶段）
+// 预扣的积分从套餐剩余积分转入预扣积分，不再计入用户可用积分，直到结算或释放
+func (s *HoldService) ReserveCredits(req *ReserveCreditsRequest) (*ReserveCreditsResponse, error) {
+	actionPriceService := &ActionPriceService{}
+	actionPrice, err := actionPriceService.GetActionPrice(req.ActionKey.String())
+	if err != nil {
+		return nil, fmt.Errorf("获取动作价格失败: %w", err)
+	}
+
+	ttl := req.TTL
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+
+	var response *ReserveCreditsResponse
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		userPackages, err := lockActivePackages(tx, req.UserID)
+		if err != nil {
+			return fmt.Errorf("查询用户套餐失败: %w", err)
+		}
+		entitlementPackages, err := lockEntitlementPackages(tx, req.UserID)
+		if err != nil {
+			return fmt.Errorf("查询用户套餐失败: %w", err)
+		}
+
+		existing, err := findDeductionByKey(tx, req.UserID, req.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("查询扣减记录失败: %w", err)
+		}
+		if existing != nil {
+			response = &ReserveCreditsResponse{
+				Success:              true,
+				HoldID:               existing.ID,
+				ReservedCredits:      existing.Credits,
+				ExpiresAt:            existing.HoldExpiresAt,
+				EntitlementPackageID: existing.EntitlementPackageID,
+				Replayed:             true,
+			}
+			return nil
+		}
+
+		// 套餐权益覆盖该动作时预扣0积分，仍占用当日使用次数直到释放
+		// 按量计费的动作预扣单次上限，结算时按实际用量扣减
+		requiredCredits := maxActionCredits(actionPrice)
+		entitlement, err := findEntitlement(tx, entitlementPackages, req.ActionKey.String())
+		if err != nil {
+			return err
+		}
+		if entitlement != nil {
+			requiredCredits = 0
+		}
+
+		totalCredits := 0
+		for _, pkg := range userPackages {
+			totalCredits += pkg.RemainingCredits
+		}
+		if totalCredits < requiredCredits {
+			response = &ReserveCreditsResponse{
+				Success:          false,
+				RequiredCredits:  requiredCredits,
+				RemainingCredits: totalCredits,
+				Message:          fmt.Sprintf("积分不足，需要 %d 积分，当前仅有 %d 积分", requiredCredits, totalCredits),
+			}
+			return nil
+		}
+
+		expiresAt := time.Now().Add(ttl)
+		hold := &model.CreditDeduction{
+			UserID:        req.UserID,
+			ActionKey:     req.ActionKey.String(),
+			ResourceType:  req.ResourceType,
+			ResourceID:    req.ResourceID,
+			Credits:       requiredCredits,
+			Status:        string(DeductionStatusReserved),
+			HoldExpiresAt: &expiresAt,
+		}
+		if entitlement != nil {
+			hold.EntitlementPackageID = &entitlement.UserBillingPackageID
+		} else {
+			hold.PriceVersionID = actionPrice.PriceVersionID
+		}
+		if req.IdempotencyKey != "" {
+			hold.IdempotencyKey = &req.IdempotencyKey
+		}
+		if err := tx.Create(hold).Error; err != nil {
+			return fmt.Errorf("创建预扣记录失败: %w", err)
+		}
+
+		if err := allocateCredits(tx, userPackages, hold, TransactionReserve); err != nil {
+			return err
+		}
+
+		response = &ReserveCreditsResponse{
+			Success:              true,
+			HoldID:               hold.ID,
+			ReservedCredits:      hold.Credits,
+			ExpiresAt:            hold.HoldExpiresAt,
+			EntitlementPackageID: hold.EntitlementPackageID,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.RemainingCredits, _ = sumUserCredits(global.DB, req.UserID)
+	return response, nil
+}
+
+// CommitHold 结算预扣（两阶段扣减第二阶段），预扣积分转为已使用
+// 已结算的预扣重复结算直接返回成功；按量计费的动作按0 token（即基础价格）结算，已知用量时请使用 SettleHold
+func (s *HoldService) CommitHold(userID string, holdID int64) error {
+	return s.SettleHold(userID, holdID, 0)
+}
+
+// SettleHold 按实际用量结算预扣
+// 按量计费的动作根据 token 用量计算实际积分（不超过预扣积分），多余的预扣积分退回原套餐；固定价格的动作全额结算
+func (s *HoldService) SettleHold(userID string, holdID int64, totalTokens int) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		hold, err := lockHold(tx, userID, holdID)
+		if err != nil {
+			return err
+		}
+
+		switch DeductionStatus(hold.Status) {
+		case DeductionStatusDeducted, DeductionStatusRefunded:
+			return nil
+		case DeductionStatusReleased:
+			return errors.New("预扣已释放，无法结算")
+		}
+
+		credits := hold.Credits
+		if hold.EntitlementPackageID == nil && hold.Credits > 0 {
+			credits, err = settledHoldCredits(tx, hold, totalTokens)
+			if err != nil {
+				return err
+			}
+		}
+
+		entries, err := holdEntries(tx, hold.ID)
+		if err != nil {
+			return err
+		}
+
+		remainingCommit := credits
+		for _, entry := range entries {
+			reserved := -entry.Credits
+			committed := reserved
+			if committed > remainingCommit {
+				committed = remainingCommit
+			}
+			released := reserved - committed
+			remainingCommit -= committed
+
+			var pkg model.UserBillingPackage
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&pkg, entry.UserBillingPackageID).Error; err != nil {
+				return fmt.Errorf("查询用户套餐失败: %w", err)
+			}
+
+			pkg.ReservedCredits -= reserved
+			pkg.UsedCredits += committed
+			if pkg.Status == string(PackageStatusActive) && pkg.RemainingCredits+released == 0 && pkg.ReservedCredits == 0 {
+				pkg.Status = string(PackageStatusDepleted)
+			}
+
+			// 按实际用量结算后未使用的预扣积分记录为释放
+			if released > 0 {
+				note := fmt.Sprintf("按实际用量结算（%d tokens），退回多余预扣", totalTokens)
+				if err := returnCreditsToPackage(tx, &pkg, hold, TransactionRelease, released, note); err != nil {
+					return err
+				}
+			} else if err := tx.Save(&pkg).Error; err != nil {
+				return fmt.Errorf("更新套餐积分失败: %w", err)
+			}
+		}
+
+		now := time.Now()
+		return tx.Model(hold).Updates(map[string]interface{}{
+			"status":       DeductionStatusDeducted,
+			"settled_at":   now,
+			"credits":      credits,
+			"total_tokens": totalTokens,
+		}).Error
+	})
+}
+
+// settledHoldCredits 计算预扣的实际结算积分
+// 基础价格取预扣时生效的价格版本，保证执行期间的调价不影响本次结算
+func settledHoldCredits(tx *gorm.DB, hold *model.CreditDeduction, totalTokens int) (int, error) {
+	var actionPrice model.BillingActionPrice
+	if err := tx.Where("action_key = ?", hold.ActionKey).First(&actionPrice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return hold.Credits, nil
+		}
+		return 0, fmt.Errorf("获取动作价格失败: %w", err)
+	}
+	if PricingMode(actionPrice.PricingMode) != PricingModeMetered {
+		return hold.Credits, nil
+	}
+
+	baseCost := actionPrice.CreditsCost
+	if hold.PriceVersionID != nil {
+		var version model.BillingActionPriceVersion
+		if err := tx.First(&version, *hold.PriceVersionID).Error; err == nil {
+			baseCost = version.CreditsCost
+		}
+	}
+	return meteredCredits(&actionPrice, baseCost, totalTokens, hold.Credits), nil
+}
+
+// ReleaseHold 释放预扣，积分退回到预扣时的套餐
+// 已释放的预扣重复释放直接返回成功
+func (s *HoldService) ReleaseHold(userID string, holdID int64, reason string) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		hold, err := lockHold(tx, userID, holdID)
+		if err != nil {
+			return err
+		}
+
+		switch DeductionStatus(hold.Status) {
+		case DeductionStatusReleased:
+			return nil
+		case DeductionStatusDeducted, DeductionStatusRefunded:
+			return errors.New("预扣已结算，请使用退还")
+		}
+
+		entries, err := holdEntries(tx, hold.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			credits := -entry.Credits
+
+			var pkg model.UserBillingPackage
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&pkg, entry.UserBillingPackageID).Error; err != nil {
+				return fmt.Errorf("查询用户套餐失败: %w", err)
+			}
+
+			pkg.ReservedCredits -= credits
+			if err := returnCreditsToPackage(tx, &pkg, hold, TransactionRelease, credits, reason); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		return tx.Model(hold).Updates(map[string]interface{}{
+			"status":        DeductionStatusReleased,
+			"settled_at":    now,
+			"refund_reason": reason,
+		}).Error
+	})
+}
+
+// returnCreditsToPackage 将扣减或预扣的积分退回套餐并保存套餐，按 txType 记录 release（释放预扣）或 refund（退还扣减）流水
+// 调用方已更新套餐的 ReservedCredits/UsedCredits 和状态；
+// 套餐已过期、取消或退款时，过期清理和取消只收回了当时的剩余积分，
+// 退回的积分随即记录对应的 expire 或 cancel 流水收回，保证流水与套餐余额一致
+func returnCreditsToPackage(tx *gorm.DB, pkg *model.UserBillingPackage, deduction *model.CreditDeduction, txType TransactionType, credits int, notes string) error {
+	pkg.RemainingCredits += credits
+	if err := tx.Save(pkg).Error; err != nil {
+		return fmt.Errorf("更新套餐积分失败: %w", err)
+	}
+	if err := recordTransaction(tx, &model.CreditTransaction{
+		UserID:               deduction.UserID,
+		UserBillingPackageID: pkg.ID,
+		Type:                 string(txType),
+		Credits:              credits,
+		DeductionID:          &deduction.ID,
+		ActionKey:            deduction.ActionKey,
+		ResourceType:         deduction.ResourceType,
+		ResourceID:           deduction.ResourceID,
+		Notes:                notes,
+	}); err != nil {
+		return fmt.Errorf("记录积分流水失败: %w", err)
+	}
+
+	var forfeit TransactionType
+	switch PackageStatus(pkg.Status) {
+	case PackageStatusExpired:
+		forfeit = TransactionExpire
+	case PackageStatusCancelled, PackageStatusRefunded:
+		forfeit = TransactionCancel
+	default:
+		// 仍有效的套餐（包括已到期但尚未被过期清理的套餐，清理时按剩余积分记录 expire 流水）无需处理
+		return nil
+	}
+
+	pkg.RemainingCredits -= credits
+	if err := tx.Model(pkg).Update("remaining_credits", pkg.RemainingCredits).Error; err != nil {
+		return fmt.Errorf("更新套餐积分失败: %w", err)
+	}
+	if err := recordTransaction(tx, &model.CreditTransaction{
+		UserID:               deduction.UserID,
+		UserBillingPackageID: pkg.ID,
+		Type:                 string(forfeit),
+		Credits:              -credits,
+		DeductionID:          &deduction.ID,
+		ActionKey:            deduction.ActionKey,
+		ResourceType:         deduction.ResourceType,
+		ResourceID:           deduction.ResourceID,
+		Notes:                fmt.Sprintf("套餐已%s，收回退回的积分", packageStatusLabel(pkg.Status)),
+	}); err != nil {
+		return fmt.Errorf("记录积分流水失败: %w", err)
+	}
+	return nil
+}
+
+// packageStatusLabel 套餐失效状态的说明
+func packageStatusLabel(status string) string {
+	switch PackageStatus(status) {
+	case PackageStatusExpired:
+		return "过期"
+	case PackageStatusCancelled:
+		return "取消"
+	case PackageStatusRefunded:
+		return "退款"
+	}
+	return status
+}
+
+// SweepExpiredHolds 释放超时未结算的预扣（进程崩溃、客户端断开等情况下遗留的预扣）
+func (s *HoldService) SweepExpiredHolds() (int64, error) {
+	var holds []model.CreditDeduction
+	if err := global.DB.Where("status = ? AND hold_expires_at < ?", DeductionStatusReserved, time.Now()).
+		Find(&holds).Error; err != nil {
+		return 0, err
+	}
+
+	var released int64
+	for _, hold := range holds {
+		if err := s.ReleaseHold(hold.UserID, hold.ID, "预扣超时自动释放"); err != nil {
+			fmt.Printf("释放超时预扣失败: hold_id=%d, err=%v\n", hold.ID, err)
+			continue
+		}
+		released++
+	}
+
+	return released, nil
+}
+
+// lockHold 锁定预扣记录
+func lockHold(tx *gorm.DB, userID string, holdID int64) (*model.CreditDeduction, error) {
+	var hold model.CreditDeduction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", holdID, userID).
+		First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("预扣记录不存在")
+		}
+		return nil, fmt.Errorf("查询预扣记录失败: %w", err)
+	}
+	return &hold, nil
+}
+
+// holdEntries 查询预扣对应的流水
+func holdEntries(tx *gorm.DB, holdID int64) ([]model.CreditTransaction, error) {
+	var entries []model.CreditTransaction
+	if err := tx.Where("deduction_id = ? AND type = ?", holdID, TransactionReserve).
+		Order("id ASC").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("查询积分流水失败: %w", err)
+	}
+	return entries, nil
+}
@@ -3,21 +3,44 @@ package billing
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+
+	"server/model"
 )
 
-// CheckAndDeductForWorkflow 检查并扣减工作流执行所需积分
-// 这是MVP版本的简化实现，根据工作流名称判断动作类型
-func CheckAndDeductForWorkflow(userID string, workflowName string) error {
-	// 根据工作流名称映射到动作key
-	actionKey := mapWorkflowToActionKey(workflowName)
-	if actionKey == "" {
-		// 如果工作流不需要扣费，直接返回成功
-		return nil
+// InsufficientCreditsError 积分不足错误，API层据此返回402
+type InsufficientCreditsError struct {
+	ActionKey        string `json:"action_key"`
+	RequiredCredits  int    `json:"required_credits"`
+	AvailableCredits int    `json:"available_credits"`
+}
+
+func (e *InsufficientCreditsError) Error() string {
+	return fmt.Sprintf("积分不足，需要 %d 积分，当前仅有 %d 积分", e.RequiredCredits, e.AvailableCredits)
+}
+
+// workflowActionKey 获取工作流关联的计费动作
+// 未关联动作、动作未配置价格或价格已停用时视为免费，返回空
+func workflowActionKey(workflow *model.Workflow) (ActionKey, error) {
+	if workflow.BillingActionKey == "" {
+		return "", nil
 	}
 
-	// 检查积分是否足够
+	actionPriceService := &ActionPriceService{}
+	if _, err := actionPriceService.GetActionPrice(workflow.BillingActionKey); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", fmt.Errorf("获取动作价格失败: %w", err)
+	}
+
+	return ActionKey(workflow.BillingActionKey), nil
+}
+
+// ensureEnoughCredits 检查积分是否足够，不足时返回 InsufficientCreditsError
+func ensureEnoughCredits(userID string, actionKey ActionKey) error {
 	userPackageService := &UserPackageService{}
 	checkResult, err := userPackageService.CheckCredits(userID, actionKey)
 	if err != nil {
@@ -25,55 +48,220 @@ func CheckAndDeductForWorkflow(userID string, workflowName string) error {
 	}
 
 	if !checkResult.HasEnough {
-		return fmt.Errorf("积分不足，需要 %d 积分，当前仅有 %d 积分",
-			checkResult.RequiredCredits, checkResult.TotalCredits)
+		return &InsufficientCreditsError{
+			ActionKey:        actionKey.String(),
+			RequiredCredits:  checkResult.RequiredCredits,
+			AvailableCredits: checkResult.TotalCredits,
+		}
+	}
+	return nil
+}
+
+// consumeWorkflowQuota 占用工作流关联动作的使用配额，配额与积分无关，未配置价格的动作同样受限
+func consumeWorkflowQuota(userID string, workflow *model.Workflow) (int64, error) {
+	if workflow.BillingActionKey == "" {
+		return 0, nil
+	}
+	quotaService := &QuotaService{}
+	_, usageID, err := quotaService.ConsumeQuota(userID, ActionKey(workflow.BillingActionKey))
+	return usageID, err
+}
+
+// DeductForWorkflowCacheHit 工作流命中响应缓存时按缓存计费动作（Workflow.CacheActionKey）扣费
+// 命中缓存不调用上游，不占用使用配额；未配置缓存计费动作、动作未配置价格或已停用时不扣费，返回0
+func DeductForWorkflowCacheHit(userID string, workflow *model.Workflow, idempotencyKey string) (int64, error) {
+	if workflow.CacheActionKey == "" {
+		return 0, nil
+	}
+
+	actionPriceService := &ActionPriceService{}
+	if _, err := actionPriceService.GetActionPrice(workflow.CacheActionKey); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("获取动作价格失败: %w", err)
+	}
+	return deductForAction(userID, ActionKey(workflow.CacheActionKey), "workflow_cache", workflow.ID, idempotencyKey)
+}
+
+// deductForAction 检查并扣减指定动作所需积分
+func deductForAction(userID string, actionKey ActionKey, resourceType, resourceID, idempotencyKey string) (int64, error) {
+	// 检查积分是否足够
+	if err := ensureEnoughCredits(userID, actionKey); err != nil {
+		return 0, err
 	}
 
 	// 扣减积分
+	userPackageService := &UserPackageService{}
 	deductReq := &DeductCreditsRequest{
-		UserID:    userID,
-		ActionKey: actionKey,
+		UserID:         userID,
+		ActionKey:      actionKey,
+		ResourceType:   resourceType,
+		ResourceID:     resourceID,
+		IdempotencyKey: idempotencyKey,
 	}
 
 	deductResult, err := userPackageService.DeductCredits(deductReq)
 	if err != nil {
-		return fmt.Errorf("扣减积分失败: %w", err)
+		return 0, fmt.Errorf("扣减积分失败: %w", err)
 	}
 
 	if !deductResult.Success {
-		return fmt.Errorf(deductResult.Message)
+		return 0, errors.New(deductResult.Message)
 	}
 
-	return nil
+	return deductResult.DeductionID, nil
+}
+
+// CheckCreditsForWorkflow 检查积分是否足够执行工作流，不足时返回 InsufficientCreditsError
+// 用于异步任务提交时提前提示，不占用配额也不预扣，执行时仍需调用 ReserveForWorkflow
+func CheckCreditsForWorkflow(userID string, workflow *model.Workflow) error {
+	actionKey, err := workflowActionKey(workflow)
+	if err != nil {
+		return err
+	}
+	if actionKey == "" {
+		return nil
+	}
+	return ensureEnoughCredits(userID, actionKey)
 }
 
-// mapWorkflowToActionKey 将工作流名称映射到动作key
-// MVP版本：仅支持简历优化相关工作流
-func mapWorkflowToActionKey(workflowName string) ActionKey {
-	// 根据工作流名称判断动作类型
-	// 这里可以根据实际业务需求扩展
-	switch workflowName {
-	case "简历优化", "resume_optimize", "优化简历":
-		return ActionResumeOptimize
-	case "AI对话", "ai_chat":
-		return ActionAIChat
-	case "PDF导出", "pdf_export":
-		return ActionPDFExport
-	case "高级分析", "advanced_analysis":
-		return ActionAdvancedAnalysis
-	default:
-		// 其他工作流暂不扣费
-		return ""
+// ReserveForWorkflow 为工作流执行预扣积分（用于流式等无法立即确认结果的执行）
+// 超出使用配额时返回 QuotaExceededError；返回预扣ID（不需要扣费时为0），结束后需调用 CommitCreditHold 或 ReleaseCreditHold
+func ReserveForWorkflow(userID string, workflow *model.Workflow, ttl time.Duration) (int64, error) {
+	usageID, err := consumeWorkflowQuota(userID, workflow)
+	if err != nil {
+		return 0, err
 	}
+
+	holdID, err := reserveForWorkflow(userID, workflow, ttl)
+	if err != nil {
+		quotaService := &QuotaService{}
+		quotaService.ReleaseQuota(usageID)
+		return 0, err
+	}
+	return holdID, nil
 }
 
-// GetWorkflowCreditsCost 获取工作流执行所需积分
-// 用于前端显示
-func GetWorkflowCreditsCost(workflowName string) (int, error) {
-	actionKey := mapWorkflowToActionKey(workflowName)
+// reserveForWorkflow 为工作流执行预扣积分，不检查使用配额
+func reserveForWorkflow(userID string, workflow *model.Workflow, ttl time.Duration) (int64, error) {
+	actionKey, err := workflowActionKey(workflow)
+	if err != nil {
+		return 0, err
+	}
 	if actionKey == "" {
 		return 0, nil
 	}
+	return reserveForAction(userID, actionKey, "workflow", workflow.ID, ttl)
+}
+
+// ReserveForAction 检查动作使用配额并预扣积分
+// 超出配额时返回 QuotaExceededError；动作未配置价格（或已停用）时视为免费，返回0
+func ReserveForAction(userID string, actionKey ActionKey, resourceType, resourceID string, ttl time.Duration) (int64, error) {
+	quotaService := &QuotaService{}
+	_, usageID, err := quotaService.ConsumeQuota(userID, actionKey)
+	if err != nil {
+		return 0, err
+	}
+
+	holdID, err := reserveForAction(userID, actionKey, resourceType, resourceID, ttl)
+	if err != nil {
+		quotaService.ReleaseQuota(usageID)
+		return 0, err
+	}
+	return holdID, nil
+}
+
+// reserveForAction 为指定动作预扣积分，不检查使用配额
+func reserveForAction(userID string, actionKey ActionKey, resourceType, resourceID string, ttl time.Duration) (int64, error) {
+	actionPriceService := &ActionPriceService{}
+	if _, err := actionPriceService.GetActionPrice(actionKey.String()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("获取动作价格失败: %w", err)
+	}
+
+	if err := ensureEnoughCredits(userID, actionKey); err != nil {
+		return 0, err
+	}
+
+	holdService := &HoldService{}
+	result, err := holdService.ReserveCredits(&ReserveCreditsRequest{
+		UserID:       userID,
+		ActionKey:    actionKey,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		TTL:          ttl,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("预扣积分失败: %w", err)
+	}
+	if !result.Success {
+		// 检查与预扣之间积分被并发消耗时，与检查阶段一样返回积分不足错误
+		return 0, &InsufficientCreditsError{
+			ActionKey:        actionKey.String(),
+			RequiredCredits:  result.RequiredCredits,
+			AvailableCredits: result.RemainingCredits,
+		}
+	}
+
+	return result.HoldID, nil
+}
+
+// CommitCreditHold 结算预扣，holdID 为0时直接返回
+func CommitCreditHold(userID string, holdID int64) error {
+	if holdID == 0 {
+		return nil
+	}
+	holdService := &HoldService{}
+	return holdService.CommitHold(userID, holdID)
+}
+
+// SettleCreditHold 按实际token用量结算预扣（按量计费的动作据此计算积分），holdID 为0时直接返回
+func SettleCreditHold(userID string, holdID int64, totalTokens int) error {
+	if holdID == 0 {
+		return nil
+	}
+	holdService := &HoldService{}
+	return holdService.SettleHold(userID, holdID, totalTokens)
+}
+
+// ReleaseCreditHold 释放预扣，holdID 为0时直接返回
+func ReleaseCreditHold(userID string, holdID int64, reason string) error {
+	if holdID == 0 {
+		return nil
+	}
+	holdService := &HoldService{}
+	return holdService.ReleaseHold(userID, holdID, reason)
+}
+
+// GetWorkflowQuotaStatus 查询用户执行工作流关联动作的配额使用情况，未关联动作或不受限时返回 nil
+func GetWorkflowQuotaStatus(userID string, workflow *model.Workflow) *QuotaStatus {
+	if workflow.BillingActionKey == "" {
+		return nil
+	}
+	return GetActionQuotaStatus(userID, ActionKey(workflow.BillingActionKey))
+}
+
+// GetActionQuotaStatus 查询用户动作的配额使用情况，用于设置响应头；查询失败或不受限时返回 nil
+func GetActionQuotaStatus(userID string, actionKey ActionKey) *QuotaStatus {
+	quotaService := &QuotaService{}
+	status, err := quotaService.GetQuotaStatus(userID, actionKey)
+	if err != nil {
+		fmt.Printf("查询使用配额失败: user_id=%s, action_key=%s, err=%v\n", userID, actionKey, err)
+		return nil
+	}
+	return status
+}
+
+// GetWorkflowCreditsCost 获取工作流执行所需积分
+// 用于前端显示，免费工作流返回0
+func GetWorkflowCreditsCost(workflow *model.Workflow) (int, error) {
+	actionKey, err := workflowActionKey(workflow)
+	if err != nil || actionKey == "" {
+		return 0, err
+	}
 
 	actionPriceService := &ActionPriceService{}
 	actionPrice, err := actionPriceService.GetActionPrice(actionKey.String())
@@ -0,0 +1,563 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+)
+
+type QuotaService struct{}
+
+// QuotaWindow 配额滑动窗口
+type QuotaWindow string
+
+const (
+	QuotaWindowMinute QuotaWindow = "minute"
+	QuotaWindowDay    QuotaWindow = "day"
+	QuotaWindowMonth  QuotaWindow = "month"
+)
+
+// quotaWindows 各窗口的时长，按从短到长排列
+var quotaWindows = []struct {
+	Window   QuotaWindow
+	Duration time.Duration
+}{
+	{QuotaWindowMinute, time.Minute},
+	{QuotaWindowDay, 24 * time.Hour},
+	{QuotaWindowMonth, 30 * 24 * time.Hour},
+}
+
+// quotaUsageRetention 使用记录保留时长，需覆盖最长窗口
+const quotaUsageRetention = 31 * 24 * time.Hour
+
+// 配额规则来源
+const (
+	QuotaSourceOverride = "override" // 管理员为用户单独设置
+	QuotaSourcePackage  = "package"  // 用户有效套餐的档位规则
+	QuotaSourceDefault  = "default"  // 默认规则
+	QuotaSourceBuiltin  = "builtin"  // 未配置规则时的内置限制
+)
+
+// builtinQuotaLimits 未配置任何规则时的内置限制，防止高成本动作被滥用
+var builtinQuotaLimits = map[ActionKey]QuotaLimits{
+	ActionPDFExport: {PerMinute: 4},
+}
+
+// QuotaLimits 动作在各窗口的使用上限，0表示不限
+type QuotaLimits struct {
+	PerMinute int `json:"per_minute"`
+	PerDay    int `json:"per_day"`
+	PerMonth  int `json:"per_month"`
+}
+
+// limit 返回指定窗口的上限
+func (l QuotaLimits) limit(window QuotaWindow) int {
+	switch window {
+	case QuotaWindowMinute:
+		return l.PerMinute
+	case QuotaWindowDay:
+		return l.PerDay
+	case QuotaWindowMonth:
+		return l.PerMonth
+	}
+	return 0
+}
+
+// unlimited 是否所有窗口都不限
+func (l QuotaLimits) unlimited() bool {
+	return l.PerMinute <= 0 && l.PerDay <= 0 && l.PerMonth <= 0
+}
+
+// QuotaWindowStatus 单个窗口的配额使用情况
+type QuotaWindowStatus struct {
+	Window    QuotaWindow `json:"window"`
+	Limit     int         `json:"limit"`
+	Used      int         `json:"used"`
+	Remaining int         `json:"remaining"`
+	ResetAt   *time.Time  `json:"reset_at,omitempty"` // 下一次可用次数恢复的时间，未使用时为空
+}
+
+// QuotaStatus 用户某个动作的配额使用情况，只包含设置了上限的窗口
+type QuotaStatus struct {
+	ActionKey string              `json:"action_key"`
+	Source    string              `json:"source"`
+	Windows   []QuotaWindowStatus `json:"windows"`
+}
+
+// Headers 转换为响应头，窗口名首字母大写作为后缀，如 X-Quota-Remaining-Day
+// Reset 为距离下一次可用次数恢复的秒数
+func (s *QuotaStatus) Headers() map[string]string {
+	headers := make(map[string]string)
+	if s == nil || len(s.Windows) == 0 {
+		return headers
+	}
+	headers["X-Quota-Action"] = s.ActionKey
+	now := time.Now()
+	for _, w := range s.Windows {
+		suffix := strings.ToUpper(string(w.Window[:1])) + string(w.Window[1:])
+		headers["X-Quota-Limit-"+suffix] = strconv.Itoa(w.Limit)
+		headers["X-Quota-Remaining-"+suffix] = strconv.Itoa(w.Remaining)
+		if w.ResetAt != nil {
+			headers["X-Quota-Reset-"+suffix] = strconv.Itoa(secondsUntil(now, *w.ResetAt))
+		}
+	}
+	return headers
+}
+
+// secondsUntil 计算距离指定时间的秒数（向上取整，至少为1）
+func secondsUntil(now, t time.Time) int {
+	seconds := int((t.Sub(now) + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// QuotaExceededError 超出动作使用配额错误，API层据此返回429
+type QuotaExceededError struct {
+	Status     *QuotaStatus `json:"status"`
+	Window     QuotaWindow  `json:"window"`
+	Limit      int          `json:"limit"`
+	RetryAfter int          `json:"retry_after"` // 秒
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("操作过于频繁，已达到%s内 %d 次的使用上限，请在 %s 后重试",
+		quotaWindowName(e.Window), e.Limit, formatRetryAfter(e.RetryAfter))
+}
+
+// Headers 超出配额时的响应头，在配额头之外附加 Retry-After
+func (e *QuotaExceededError) Headers() map[string]string {
+	headers := e.Status.Headers()
+	headers["Retry-After"] = strconv.Itoa(e.RetryAfter)
+	return headers
+}
+
+// quotaWindowName 窗口的中文名称
+func quotaWindowName(window QuotaWindow) string {
+	switch window {
+	case QuotaWindowMinute:
+		return "1分钟"
+	case QuotaWindowDay:
+		return "24小时"
+	case QuotaWindowMonth:
+		return "30天"
+	}
+	return string(window)
+}
+
+// formatRetryAfter 将等待秒数格式化为便于阅读的文字
+func formatRetryAfter(seconds int) string {
+	switch {
+	case seconds < 60:
+		return fmt.Sprintf("%d 秒", seconds)
+	case seconds < 3600:
+		return fmt.Sprintf("%d 分钟", (seconds+59)/60)
+	default:
+		return fmt.Sprintf("%d 小时", (seconds+3599)/3600)
+	}
+}
+
+// resolveQuotaLimits 解析用户某个动作生效的配额上限，没有任何限制时返回 nil
+// 优先级：用户覆盖 > 用户有效套餐的档位规则（多个套餐取最宽松的上限）> 默认规则 > 内置限制
+func resolveQuotaLimits(db *gorm.DB, userID string, actionKey ActionKey) (*QuotaLimits, string, error) {
+	now := time.Now()
+
+	var overrides []model.ActionQuotaOverride
+	if err := db.Where("user_id = ? AND action_key = ? AND (expires_at IS NULL OR expires_at > ?)", userID, actionKey, now).
+		Limit(1).Find(&overrides).Error; err != nil {
+		return nil, "", err
+	}
+	if len(overrides) > 0 {
+		o := overrides[0]
+		return &QuotaLimits{PerMinute: o.PerMinute, PerDay: o.PerDay, PerMonth: o.PerMonth}, QuotaSourceOverride, nil
+	}
+
+	var packageIDs []int64
+	if err := db.Model(&model.UserBillingPackage{}).
+		Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Where("status = ? OR (status = ? AND covered_actions IS NOT NULL)", PackageStatusActive, PackageStatusDepleted).
+		Distinct().Pluck("billing_package_id", &packageIDs).Error; err != nil {
+		return nil, "", err
+	}
+	if len(packageIDs) > 0 {
+		var rules []model.ActionQuota
+		if err := db.Where("action_key = ? AND billing_package_id IN ?", actionKey, packageIDs).Find(&rules).Error; err != nil {
+			return nil, "", err
+		}
+		if len(rules) > 0 {
+			limits := QuotaLimits{PerMinute: rules[0].PerMinute, PerDay: rules[0].PerDay, PerMonth: rules[0].PerMonth}
+			for _, rule := range rules[1:] {
+				limits.PerMinute = looserLimit(limits.PerMinute, rule.PerMinute)
+				limits.PerDay = looserLimit(limits.PerDay, rule.PerDay)
+				limits.PerMonth = looserLimit(limits.PerMonth, rule.PerMonth)
+			}
+			return &limits, QuotaSourcePackage, nil
+		}
+	}
+
+	var defaults []model.ActionQuota
+	if err := db.Where("action_key = ? AND billing_package_id = 0", actionKey).Limit(1).Find(&defaults).Error; err != nil {
+		return nil, "", err
+	}
+	if len(defaults) > 0 {
+		d := defaults[0]
+		return &QuotaLimits{PerMinute: d.PerMinute, PerDay: d.PerDay, PerMonth: d.PerMonth}, QuotaSourceDefault, nil
+	}
+
+	if limits, ok := builtinQuotaLimits[actionKey]; ok {
+		return &limits, QuotaSourceBuiltin, nil
+	}
+	return nil, "", nil
+}
+
+// looserLimit 取两个上限中更宽松的一个，0表示不限
+func looserLimit(a, b int) int {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// buildQuotaStatus 统计各窗口的使用次数，返回配额使用情况
+// 超出任一窗口上限时同时返回 QuotaExceededError（取需要等待最久的窗口）
+func buildQuotaStatus(db *gorm.DB, userID string, actionKey ActionKey, limits *QuotaLimits, source string) (*QuotaStatus, *QuotaExceededError, error) {
+	now := time.Now()
+	status := &QuotaStatus{ActionKey: actionKey.String(), Source: source, Windows: make([]QuotaWindowStatus, 0, len(quotaWindows))}
+	var exceeded *QuotaExceededError
+
+	for _, w := range quotaWindows {
+		limit := limits.limit(w.Window)
+		if limit <= 0 {
+			continue
+		}
+
+		since := now.Add(-w.Duration)
+		var used int64
+		if err := db.Model(&model.ActionUsage{}).
+			Where("user_id = ? AND action_key = ? AND created_at > ?", userID, actionKey, since).
+			Count(&used).Error; err != nil {
+			return nil, nil, err
+		}
+
+		windowStatus := QuotaWindowStatus{Window: w.Window, Limit: limit, Used: int(used)}
+		if remaining := limit - int(used); remaining > 0 {
+			windowStatus.Remaining = remaining
+		}
+		if used > 0 {
+			// 第 (used-limit+1) 早的记录滑出窗口后恢复一次可用次数，未超限时取最早的记录
+			offset := int(used) - limit
+			if offset < 0 {
+				offset = 0
+			}
+			var oldest []time.Time
+			if err := db.Model(&model.ActionUsage{}).
+				Where("user_id = ? AND action_key = ? AND created_at > ?", userID, actionKey, since).
+				Order("created_at ASC").Offset(offset).Limit(1).
+				Pluck("created_at", &oldest).Error; err != nil {
+				return nil, nil, err
+			}
+			if len(oldest) > 0 {
+				resetAt := oldest[0].Add(w.Duration)
+				windowStatus.ResetAt = &resetAt
+			}
+		}
+		status.Windows = append(status.Windows, windowStatus)
+
+		if int(used) >= limit && windowStatus.ResetAt != nil {
+			retryAfter := secondsUntil(now, *windowStatus.ResetAt)
+			if exceeded == nil || retryAfter > exceeded.RetryAfter {
+				exceeded = &QuotaExceededError{Window: w.Window, Limit: limit, RetryAfter: retryAfter}
+			}
+		}
+	}
+
+	if exceeded != nil {
+		exceeded.Status = status
+	}
+	return status, exceeded, nil
+}
+
+// ConsumeQuota 检查并占用一次动作使用配额
+// 超出任一窗口上限时返回 QuotaExceededError；没有任何限制时返回 (nil, 0, nil)
+// 返回的使用记录ID可在后续步骤失败时用 ReleaseQuota 归还
+func (s *QuotaService) ConsumeQuota(userID string, actionKey ActionKey) (*QuotaStatus, int64, error) {
+	var status *QuotaStatus
+	var usageID int64
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		limits, source, err := resolveQuotaLimits(tx, userID, actionKey)
+		if err != nil {
+			return fmt.Errorf("查询使用配额失败: %w", err)
+		}
+		if limits == nil || limits.unlimited() {
+			return nil
+		}
+
+		// 同一用户同一动作串行计数，避免并发请求同时通过检查
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "action_quota:"+userID+":"+actionKey.String()).Error; err != nil {
+			return fmt.Errorf("锁定使用配额失败: %w", err)
+		}
+
+		current, exceeded, err := buildQuotaStatus(tx, userID, actionKey, limits, source)
+		if err != nil {
+			return fmt.Errorf("统计使用次数失败: %w", err)
+		}
+		if exceeded != nil {
+			return exceeded
+		}
+
+		usage := &model.ActionUsage{UserID: userID, ActionKey: actionKey.String()}
+		if err := tx.Create(usage).Error; err != nil {
+			return fmt.Errorf("记录使用次数失败: %w", err)
+		}
+		usageID = usage.ID
+
+		for i := range current.Windows {
+			w := &current.Windows[i]
+			w.Used++
+			w.Remaining = w.Limit - w.Used
+			if w.ResetAt == nil {
+				resetAt := usage.CreatedAt.Add(quotaWindowDuration(w.Window))
+				w.ResetAt = &resetAt
+			}
+		}
+		status = current
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return status, usageID, nil
+}
+
+// quotaWindowDuration 返回窗口时长
+func quotaWindowDuration(window QuotaWindow) time.Duration {
+	for _, w := range quotaWindows {
+		if w.Window == window {
+			return w.Duration
+		}
+	}
+	return 0
+}
+
+// ReleaseQuota 归还一次动作使用配额（动作因积分不足等原因未执行时调用），usageID 为0时直接返回
+func (s *QuotaService) ReleaseQuota(usageID int64) {
+	if usageID == 0 {
+		return
+	}
+	if err := global.DB.Delete(&model.ActionUsage{}, usageID).Error; err != nil {
+		fmt.Printf("归还使用配额失败: usage_id=%d, err=%v\n", usageID, err)
+	}
+}
+
+// GetQuotaStatus 查询用户某个动作的配额使用情况，没有任何限制时返回 nil
+func (s *QuotaService) GetQuotaStatus(userID string, actionKey ActionKey) (*QuotaStatus, error) {
+	limits, source, err := resolveQuotaLimits(global.DB, userID, actionKey)
+	if err != nil {
+		return nil, fmt.Errorf("查询使用配额失败: %w", err)
+	}
+	if limits == nil || limits.unlimited() {
+		return nil, nil
+	}
+	status, _, err := buildQuotaStatus(global.DB, userID, actionKey, limits, source)
+	if err != nil {
+		return nil, fmt.Errorf("统计使用次数失败: %w", err)
+	}
+	return status, nil
+}
+
+// ListUserQuotaStatus 查询用户所有受限动作的配额使用情况
+func (s *QuotaService) ListUserQuotaStatus(userID string) ([]QuotaStatus, error) {
+	var actionKeys []string
+	if err := global.DB.Model(&model.BillingActionPrice{}).Order("sort_order ASC, id ASC").
+		Pluck("action_key", &actionKeys).Error; err != nil {
+		return nil, fmt.Errorf("查询动作列表失败: %w", err)
+	}
+
+	// 只配置了配额、没有配置价格的动作也需要列出
+	seen := make(map[string]bool, len(actionKeys))
+	for _, key := range actionKeys {
+		seen[key] = true
+	}
+	var quotaKeys []string
+	if err := global.DB.Model(&model.ActionQuota{}).Distinct().Pluck("action_key", &quotaKeys).Error; err != nil {
+		return nil, fmt.Errorf("查询配额规则失败: %w", err)
+	}
+	for key := range builtinQuotaLimits {
+		quotaKeys = append(quotaKeys, key.String())
+	}
+	for _, key := range quotaKeys {
+		if !seen[key] {
+			seen[key] = true
+			actionKeys = append(actionKeys, key)
+		}
+	}
+
+	statuses := make([]QuotaStatus, 0)
+	for _, key := range actionKeys {
+		status, err := s.GetQuotaStatus(userID, ActionKey(key))
+		if err != nil {
+			return nil, err
+		}
+		if status != nil {
+			statuses = append(statuses, *status)
+		}
+	}
+	return statuses, nil
+}
+
+// CleanActionUsages 删除超过最长窗口的使用记录
+func (s *QuotaService) CleanActionUsages() (int64, error) {
+	result := global.DB.Where("created_at < ?", time.Now().Add(-quotaUsageRetention)).Delete(&model.ActionUsage{})
+	return result.RowsAffected, result.Error
+}
+
+// validateQuotaLimits 校验配额上限
+func validateQuotaLimits(perMinute, perDay, perMonth int) error {
+	if perMinute < 0 || perDay < 0 || perMonth < 0 {
+		return errors.New("使用上限不能为负数")
+	}
+	return nil
+}
+
+// ListQuotaRules 查询配额规则，可按动作或套餐过滤
+func (s *QuotaService) ListQuotaRules(req *QuotaRuleQueryRequest) ([]model.ActionQuota, error) {
+	query := global.DB.Model(&model.ActionQuota{})
+	if req.ActionKey != "" {
+		query = query.Where("action_key = ?", req.ActionKey)
+	}
+	if req.BillingPackageID != nil {
+		query = query.Where("billing_package_id = ?", *req.BillingPackageID)
+	}
+
+	var rules []model.ActionQuota
+	if err := query.Order("action_key ASC, billing_package_id ASC").Find(&rules).Error; err != nil {
+		return nil, errors.New("查询配额规则失败: " + err.Error())
+	}
+	return rules, nil
+}
+
+// SaveQuotaRule 创建或更新配额规则，同一套餐档位的同一动作只有一条规则
+func (s *QuotaService) SaveQuotaRule(req *QuotaRuleRequest) (*model.ActionQuota, error) {
+	if err := validateQuotaLimits(req.PerMinute, req.PerDay, req.PerMonth); err != nil {
+		return nil, err
+	}
+	if req.BillingPackageID > 0 {
+		var count int64
+		if err := global.DB.Model(&model.BillingPackage{}).Where("id = ?", req.BillingPackageID).Count(&count).Error; err != nil {
+			return nil, errors.New("查询套餐失败")
+		}
+		if count == 0 {
+			return nil, errors.New("套餐不存在")
+		}
+	}
+
+	rule := &model.ActionQuota{
+		BillingPackageID: req.BillingPackageID,
+		ActionKey:        req.ActionKey,
+		PerMinute:        req.PerMinute,
+		PerDay:           req.PerDay,
+		PerMonth:         req.PerMonth,
+		Notes:            req.Notes,
+	}
+	if err := global.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "billing_package_id"}, {Name: "action_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"per_minute", "per_day", "per_month", "notes", "updated_at"}),
+	}).Create(rule).Error; err != nil {
+		return nil, errors.New("保存配额规则失败: " + err.Error())
+	}
+
+	if err := global.DB.Where("billing_package_id = ? AND action_key = ?", req.BillingPackageID, req.ActionKey).
+		First(rule).Error; err != nil {
+		return nil, errors.New("查询配额规则失败")
+	}
+	return rule, nil
+}
+
+// DeleteQuotaRule 删除配额规则
+func (s *QuotaService) DeleteQuotaRule(id int64) error {
+	result := global.DB.Delete(&model.ActionQuota{}, id)
+	if result.Error != nil {
+		return errors.New("删除配额规则失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("配额规则不存在")
+	}
+	return nil
+}
+
+// GetUserQuotas 查询用户的配额覆盖与当前配额使用情况（管理员）
+func (s *QuotaService) GetUserQuotas(userID string) (*UserQuotasResponse, error) {
+	var overrides []model.ActionQuotaOverride
+	if err := global.DB.Where("user_id = ?", userID).Order("action_key ASC").Find(&overrides).Error; err != nil {
+		return nil, errors.New("查询配额覆盖失败")
+	}
+	statuses, err := s.ListUserQuotaStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &UserQuotasResponse{Overrides: overrides, Statuses: statuses}, nil
+}
+
+// SetQuotaOverride 为用户设置动作配额覆盖（管理员），已存在时更新
+func (s *QuotaService) SetQuotaOverride(userID string, req *QuotaOverrideRequest, operatorID string) (*model.ActionQuotaOverride, error) {
+	if err := validateQuotaLimits(req.PerMinute, req.PerDay, req.PerMonth); err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("过期时间不能早于当前时间")
+	}
+
+	var userCount int64
+	if err := global.DB.Model(&model.User{}).Where("id = ?", userID).Count(&userCount).Error; err != nil {
+		return nil, errors.New("查询用户失败")
+	}
+	if userCount == 0 {
+		return nil, errors.New("用户不存在")
+	}
+
+	override := &model.ActionQuotaOverride{
+		UserID:     userID,
+		ActionKey:  req.ActionKey,
+		PerMinute:  req.PerMinute,
+		PerDay:     req.PerDay,
+		PerMonth:   req.PerMonth,
+		ExpiresAt:  req.ExpiresAt,
+		OperatorID: operatorID,
+		Notes:      req.Notes,
+	}
+	if err := global.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "action_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"per_minute", "per_day", "per_month", "expires_at", "operator_id", "notes", "updated_at"}),
+	}).Create(override).Error; err != nil {
+		return nil, errors.New("保存配额覆盖失败: " + err.Error())
+	}
+
+	if err := global.DB.Where("user_id = ? AND action_key = ?", userID, req.ActionKey).First(override).Error; err != nil {
+		return nil, errors.New("查询配额覆盖失败")
+	}
+	return override, nil
+}
+
+// DeleteQuotaOverride 删除用户的动作配额覆盖（管理员），恢复使用套餐档位规则
+func (s *QuotaService) DeleteQuotaOverride(userID, actionKey string) error {
+	result := global.DB.Where("user_id = ? AND action_key = ?", userID, actionKey).Delete(&model.ActionQuotaOverride{})
+	if result.Error != nil {
+		return errors.New("删除配额覆盖失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("配额覆盖不存在")
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package billing
+
+import (
+	"strconv"
+)
+
+// 报表的 CSV() 方法返回导出用的各行数据（首行为表头），金额单位为分
+
+// itoa 格式化整数
+func itoa(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+// ftoa 格式化百分比等小数，保留两位
+func ftoa(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// CSV 收入报表
+func (r *RevenueReportResponse) CSV() [][]string {
+	rows := [][]string{{"日期", "套餐ID", "套餐名称", "订单数", "原价金额(分)", "优惠金额(分)", "实收金额(分)", "退款金额(分)", "净收入(分)"}}
+	for _, item := range r.Items {
+		rows = append(rows, []string{
+			item.Date, itoa(item.PackageID), item.PackageName, itoa(item.Orders),
+			itoa(item.OriginalAmount), itoa(item.DiscountAmount), itoa(item.GrossAmount),
+			itoa(item.RefundedAmount), itoa(item.NetAmount),
+		})
+	}
+	rows = append(rows, []string{"合计", "", "", itoa(r.TotalOrders), "", "", itoa(r.TotalGross), itoa(r.TotalRefunded), itoa(r.TotalNetAmount)})
+	return rows
+}
+
+// CSV 积分发放与消耗报表，动作明细之后附汇总
+func (r *CreditsReportResponse) CSV() [][]string {
+	rows := [][]string{{"动作", "动作名称", "扣减次数", "扣减积分", "退还积分", "净消耗积分"}}
+	for _, item := range r.Actions {
+		rows = append(rows, []string{
+			item.ActionKey, item.ActionName, itoa(item.Deductions),
+			itoa(item.ConsumedCredits), itoa(item.RefundedCredits), itoa(item.NetCredits),
+		})
+	}
+	rows = append(rows,
+		[]string{},
+		[]string{"汇总", "积分"},
+		[]string{"套餐发放", itoa(r.IssuedCredits)},
+		[]string{"管理员调整", itoa(r.AdjustedCredits)},
+		[]string{"净消耗", itoa(r.ConsumedCredits)},
+		[]string{"过期作废", itoa(r.ExpiredCredits)},
+		[]string{"取消收回", itoa(r.CancelledCredits)},
+	)
+	return rows
+}
+
+// CSV 积分沉淀报表
+func (r *BreakageReportResponse) CSV() [][]string {
+	rows := [][]string{{"日期", "过期套餐数", "过期作废积分"}}
+	for _, item := range r.Items {
+		rows = append(rows, []string{item.Date, itoa(item.Packages), itoa(item.ExpiredCredits)})
+	}
+	rows = append(rows,
+		[]string{},
+		[]string{"期间发放积分", itoa(r.IssuedCredits)},
+		[]string{"期间过期作废积分", itoa(r.ExpiredCredits)},
+		[]string{"沉淀率(%)", ftoa(r.BreakageRate)},
+	)
+	return rows
+}
+
+// CSV 积分消耗排行报表
+func (r *TopConsumersReportResponse) CSV() [][]string {
+	rows := [][]string{{"用户ID", "用户名", "扣减次数", "净消耗积分", "支付金额(分)"}}
+	for _, item := range r.Items {
+		rows = append(rows, []string{
+			item.UserID, item.UserName, itoa(item.Deductions), itoa(item.ConsumedCredits), itoa(item.PaidAmount),
+		})
+	}
+	return rows
+}
+
+// CSV 赠送→购买转化报表
+func (r *ConversionReportResponse) CSV() [][]string {
+	rows := [][]string{{"套餐名称", "来源", "获赠用户数", "转化用户数", "转化率(%)", "转化支付金额(分)"}}
+	for _, item := range r.Items {
+		rows = append(rows, []string{
+			item.PackageName, item.Source, itoa(item.GiftedUsers), itoa(item.ConvertedUsers),
+			ftoa(item.ConversionRate), itoa(item.ConvertedAmount),
+		})
+	}
+	return rows
+}
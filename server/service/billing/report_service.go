@@ -0,0 +1,284 @@
+package billing
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"server/global"
+	"server/model"
+)
+
+type ReportService struct{}
+
+// 报表统计的积分消耗相关流水：扣减、预扣、释放预扣、退还
+var consumptionTransactionTypes = []TransactionType{TransactionDeduct, TransactionReserve, TransactionRelease, TransactionRefund}
+
+// 计入收入的订单状态，已退款订单同时计入退款金额
+var revenueOrderStatuses = []string{model.OrderStatusPaid, model.OrderStatusFulfilled, model.OrderStatusRefunded}
+
+// parseReportRange 解析报表时间范围，默认最近30天
+func parseReportRange(req *ReportRequest) (ReportRange, error) {
+	var startDate, endDate time.Time
+	var err error
+
+	if req.Days > 0 {
+		endDate = time.Now()
+		startDate = endDate.AddDate(0, 0, -req.Days)
+	} else if req.StartDate != "" && req.EndDate != "" {
+		startDate, err = time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			return ReportRange{}, errors.New("开始日期格式错误")
+		}
+		endDate, err = time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			return ReportRange{}, errors.New("结束日期格式错误")
+		}
+		// 设置为当天的结束时间
+		endDate = endDate.Add(24*time.Hour - time.Second)
+		if endDate.Before(startDate) {
+			return ReportRange{}, errors.New("结束日期不能早于开始日期")
+		}
+	} else {
+		endDate = time.Now()
+		startDate = endDate.AddDate(0, 0, -30)
+	}
+
+	return ReportRange{StartTime: startDate, EndTime: endDate}, nil
+}
+
+// percentage 计算百分比，保留两位小数
+func percentage(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 100
+}
+
+// GetRevenueReport 按支付日期和套餐统计收入
+// 退款金额（含取消套餐的部分退款）按原订单支付日期归集，便于与当日收入对账
+func (s *ReportService) GetRevenueReport(req *ReportRequest) (*RevenueReportResponse, error) {
+	r, err := parseReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]RevenueReportItem, 0)
+	if err := global.DB.Model(&model.Order{}).
+		Select("TO_CHAR(paid_at, 'YYYY-MM-DD') AS date, billing_package_id AS package_id, package_name, "+
+			"COUNT(*) AS orders, "+
+			"COALESCE(SUM(original_amount), 0) AS original_amount, "+
+			"COALESCE(SUM(discount_amount), 0) AS discount_amount, "+
+			"COALESCE(SUM(amount), 0) AS gross_amount, "+
+			"COALESCE(SUM(CASE WHEN refund_amount > 0 THEN refund_amount WHEN status = ? THEN amount ELSE 0 END), 0) AS refunded_amount",
+			model.OrderStatusRefunded).
+		Where("status IN ? AND paid_at >= ? AND paid_at <= ?", revenueOrderStatuses, r.StartTime, r.EndTime).
+		Group("TO_CHAR(paid_at, 'YYYY-MM-DD'), billing_package_id, package_name").
+		Order("date ASC, package_id ASC").
+		Scan(&items).Error; err != nil {
+		return nil, errors.New("查询收入统计失败: " + err.Error())
+	}
+
+	report := &RevenueReportResponse{Range: r, Items: items}
+	for i := range report.Items {
+		item := &report.Items[i]
+		item.NetAmount = item.GrossAmount - item.RefundedAmount
+		report.TotalOrders += item.Orders
+		report.TotalGross += item.GrossAmount
+		report.TotalRefunded += item.RefundedAmount
+		report.TotalNetAmount += item.NetAmount
+	}
+	return report, nil
+}
+
+// GetCreditsReport 统计期间积分发放与各动作的积分消耗
+func (s *ReportService) GetCreditsReport(req *ReportRequest) (*CreditsReportResponse, error) {
+	r, err := parseReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]ActionCreditsItem, 0)
+	if err := global.DB.Table("credit_transactions AS t").
+		Select("t.action_key, COALESCE(MAX(p.action_name), t.action_key) AS action_name, "+
+			"COUNT(DISTINCT CASE WHEN t.type IN ? THEN t.deduction_id END) AS deductions, "+
+			"COALESCE(-SUM(CASE WHEN t.type IN ? THEN t.credits ELSE 0 END), 0) AS consumed_credits, "+
+			"COALESCE(SUM(CASE WHEN t.type = ? THEN t.credits ELSE 0 END), 0) AS refunded_credits, "+
+			"COALESCE(-SUM(t.credits), 0) AS net_credits",
+			[]TransactionType{TransactionDeduct, TransactionReserve},
+			[]TransactionType{TransactionDeduct, TransactionReserve, TransactionRelease},
+			TransactionRefund).
+		Joins("LEFT JOIN billing_action_prices AS p ON p.action_key = t.action_key").
+		Where("t.type IN ? AND t.created_at >= ? AND t.created_at <= ?", consumptionTransactionTypes, r.StartTime, r.EndTime).
+		Group("t.action_key").
+		Order("net_credits DESC").
+		Scan(&actions).Error; err != nil {
+		return nil, errors.New("查询积分消耗统计失败: " + err.Error())
+	}
+
+	var totals struct {
+		Issued    int64
+		Adjusted  int64
+		Expired   int64
+		Cancelled int64
+	}
+	if err := global.DB.Model(&model.CreditTransaction{}).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN credits ELSE 0 END), 0) AS issued, "+
+			"COALESCE(SUM(CASE WHEN type = ? THEN credits ELSE 0 END), 0) AS adjusted, "+
+			"COALESCE(-SUM(CASE WHEN type = ? THEN credits ELSE 0 END), 0) AS expired, "+
+			"COALESCE(-SUM(CASE WHEN type = ? THEN credits ELSE 0 END), 0) AS cancelled",
+			TransactionGrant, TransactionAdjust, TransactionExpire, TransactionCancel).
+		Where("created_at >= ? AND created_at <= ?", r.StartTime, r.EndTime).
+		Scan(&totals).Error; err != nil {
+		return nil, errors.New("查询积分发放统计失败: " + err.Error())
+	}
+
+	report := &CreditsReportResponse{
+		Range:           r,
+		Actions:         actions,
+		IssuedCredits:   totals.Issued,
+		AdjustedCredits: totals.Adjusted,
+		ExpiredCredits:  totals.Expired,
+
+		CancelledCredits: totals.Cancelled,
+	}
+	for _, item := range actions {
+		report.ConsumedCredits += item.NetCredits
+	}
+	return report, nil
+}
+
+// GetBreakageReport 统计期间过期作废（发放后未使用）的积分
+func (s *ReportService) GetBreakageReport(req *ReportRequest) (*BreakageReportResponse, error) {
+	r, err := parseReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]BreakageReportItem, 0)
+	if err := global.DB.Model(&model.CreditTransaction{}).
+		Select("TO_CHAR(created_at, 'YYYY-MM-DD') AS date, "+
+			"COUNT(DISTINCT user_billing_package_id) AS packages, "+
+			"COALESCE(-SUM(credits), 0) AS expired_credits").
+		Where("type = ? AND created_at >= ? AND created_at <= ?", TransactionExpire, r.StartTime, r.EndTime).
+		Group("TO_CHAR(created_at, 'YYYY-MM-DD')").
+		Order("date ASC").
+		Scan(&items).Error; err != nil {
+		return nil, errors.New("查询过期积分统计失败: " + err.Error())
+	}
+
+	var issued int64
+	if err := global.DB.Model(&model.CreditTransaction{}).
+		Select("COALESCE(SUM(credits), 0)").
+		Where("(type = ? OR (type = ? AND credits > 0)) AND created_at >= ? AND created_at <= ?",
+			TransactionGrant, TransactionAdjust, r.StartTime, r.EndTime).
+		Scan(&issued).Error; err != nil {
+		return nil, errors.New("查询积分发放统计失败: " + err.Error())
+	}
+
+	report := &BreakageReportResponse{Range: r, Items: items, IssuedCredits: issued}
+	for _, item := range items {
+		report.ExpiredCredits += item.ExpiredCredits
+	}
+	report.BreakageRate = percentage(report.ExpiredCredits, report.IssuedCredits)
+	return report, nil
+}
+
+// GetTopConsumersReport 统计期间积分净消耗最多的用户
+func (s *ReportService) GetTopConsumersReport(req *ReportRequest) (*TopConsumersReportResponse, error) {
+	r, err := parseReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	items := make([]TopConsumerItem, 0)
+	if err := global.DB.Table("credit_transactions AS t").
+		Select("t.user_id, COALESCE(MAX(u.name), '') AS user_name, "+
+			"COUNT(DISTINCT CASE WHEN t.type IN ? THEN t.deduction_id END) AS deductions, "+
+			"COALESCE(-SUM(t.credits), 0) AS consumed_credits",
+			[]TransactionType{TransactionDeduct, TransactionReserve}).
+		Joins("LEFT JOIN users AS u ON u.id = t.user_id").
+		Where("t.type IN ? AND t.created_at >= ? AND t.created_at <= ?", consumptionTransactionTypes, r.StartTime, r.EndTime).
+		Group("t.user_id").
+		Order("consumed_credits DESC").
+		Limit(limit).
+		Scan(&items).Error; err != nil {
+		return nil, errors.New("查询积分消耗排行失败: " + err.Error())
+	}
+	if len(items) == 0 {
+		return &TopConsumersReportResponse{Range: r, Items: items}, nil
+	}
+
+	userIDs := make([]string, len(items))
+	for i, item := range items {
+		userIDs[i] = item.UserID
+	}
+	var payments []struct {
+		UserID string
+		Amount int64
+	}
+	if err := global.DB.Model(&model.Order{}).
+		Select("user_id, COALESCE(SUM(amount), 0) AS amount").
+		Where("user_id IN ? AND status IN ? AND paid_at >= ? AND paid_at <= ?",
+			userIDs, []string{model.OrderStatusPaid, model.OrderStatusFulfilled}, r.StartTime, r.EndTime).
+		Group("user_id").
+		Scan(&payments).Error; err != nil {
+		return nil, errors.New("查询用户支付金额失败: " + err.Error())
+	}
+	paid := make(map[string]int64, len(payments))
+	for _, p := range payments {
+		paid[p.UserID] = p.Amount
+	}
+	for i := range items {
+		items[i].PaidAmount = paid[items[i].UserID]
+	}
+
+	return &TopConsumersReportResponse{Range: r, Items: items}, nil
+}
+
+// GetConversionReport 统计期间获赠套餐（非购买来源）的用户此后完成购买的情况
+func (s *ReportService) GetConversionReport(req *ReportRequest) (*ConversionReportResponse, error) {
+	r, err := parseReportRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ConversionReportItem, 0)
+	if err := global.DB.Raw(`
+WITH gifts AS (
+	SELECT user_id, package_name, source, MIN(created_at) AS gifted_at
+	FROM user_billing_packages
+	WHERE source <> ? AND created_at >= ? AND created_at <= ?
+	GROUP BY user_id, package_name, source
+)
+SELECT g.package_name, g.source,
+	COUNT(*) AS gifted_users,
+	COUNT(o.user_id) AS converted_users,
+	COALESCE(SUM(o.amount), 0) AS converted_amount
+FROM gifts g
+LEFT JOIN LATERAL (
+	SELECT user_id, SUM(amount) AS amount
+	FROM orders
+	WHERE user_id = g.user_id AND status IN ? AND paid_at > g.gifted_at
+	GROUP BY user_id
+) o ON TRUE
+GROUP BY g.package_name, g.source
+ORDER BY gifted_users DESC`,
+		PackageSourcePurchase, r.StartTime, r.EndTime,
+		[]string{model.OrderStatusPaid, model.OrderStatusFulfilled}).
+		Scan(&items).Error; err != nil {
+		return nil, errors.New("查询套餐转化统计失败: " + err.Error())
+	}
+
+	for i := range items {
+		items[i].ConversionRate = percentage(items[i].ConvertedUsers, items[i].GiftedUsers)
+	}
+	return &ConversionReportResponse{Range: r, Items: items}, nil
+}
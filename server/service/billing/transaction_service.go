@@ -0,0 +1,91 @@
+package billing
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+)
+
+type TransactionService struct{}
+
+// recordTransaction 在给定事务中写入一条积分流水
+// BalanceAfter 在写入时根据事务内的最新套餐数据计算，因此必须在套餐更新之后调用
+func recordTransaction(tx *gorm.DB, entry *model.CreditTransaction) error {
+	balance, err := sumUserCredits(tx, entry.UserID)
+	if err != nil {
+		return err
+	}
+	entry.BalanceAfter = balance
+	return tx.Create(entry).Error
+}
+
+// sumUserCredits 在给定连接（可为事务）中统计用户可用总积分
+func sumUserCredits(db *gorm.DB, userID string) (int, error) {
+	var total int64
+	err := db.Model(&model.UserBillingPackage{}).
+		Where("user_id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+			userID, PackageStatusActive, time.Now()).
+		Select("COALESCE(SUM(remaining_credits), 0)").
+		Scan(&total).Error
+
+	return int(total), err
+}
+
+// ListTransactions 分页查询积分流水
+func (s *TransactionService) ListTransactions(req *TransactionQueryRequest) (*TransactionQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.CreditTransaction{})
+
+	if req.UserID != "" {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+	if req.ActionKey != "" {
+		query = query.Where("action_key = ?", req.ActionKey)
+	}
+	if req.UserBillingPackageID > 0 {
+		query = query.Where("user_billing_package_id = ?", req.UserBillingPackageID)
+	}
+	if !req.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", req.StartTime)
+	}
+	if !req.EndTime.IsZero() {
+		query = query.Where("created_at <= ?", req.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计积分流水失败: " + err.Error())
+	}
+
+	var transactions []model.CreditTransaction
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&transactions).Error; err != nil {
+		return nil, errors.New("查询积分流水失败: " + err.Error())
+	}
+
+	return &TransactionQueryResponse{
+		List:     transactions,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
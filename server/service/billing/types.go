@@ -1,5 +1,11 @@
 package billing
 
+import (
+	"time"
+
+	"server/model"
+)
+
 // PackageType 套餐类型枚举
 type PackageType string
 
@@ -18,6 +24,9 @@ const (
 	PackageStatusActive   PackageStatus = "active"   // 使用中，可以正常使用
 	PackageStatusExpired  PackageStatus = "expired"  // 已过期
 	PackageStatusDepleted PackageStatus = "depleted" // 已耗尽
+
+	PackageStatusCancelled PackageStatus = "cancelled" // 已取消（未退款）
+	PackageStatusRefunded  PackageStatus = "refunded"  // 已退款
 )
 
 // PackageSource 套餐来源枚举
@@ -30,6 +39,44 @@ const (
 	PackageSourceSystem    PackageSource = "system"    // 系统
 )
 
+// TransactionType 积分流水类型枚举
+type TransactionType string
+
+const (
+	TransactionGrant  TransactionType = "grant"  // 发放
+	TransactionDeduct TransactionType = "deduct" // 扣减
+	TransactionRefund TransactionType = "refund" // 退还
+	TransactionExpire TransactionType = "expire" // 过期
+	TransactionAdjust TransactionType = "adjust" // 调整
+
+	TransactionReserve TransactionType = "reserve" // 预扣（两阶段扣减的第一阶段）
+	TransactionRelease TransactionType = "release" // 释放预扣
+
+	TransactionCancel TransactionType = "cancel" // 取消或退款套餐收回剩余积分
+)
+
+// AdjustmentReason 管理员积分调整原因代码
+type AdjustmentReason string
+
+const (
+	AdjustmentReasonCompensation AdjustmentReason = "compensation" // 服务故障补偿
+	AdjustmentReasonGoodwill     AdjustmentReason = "goodwill"     // 客服关怀
+	AdjustmentReasonPromotion    AdjustmentReason = "promotion"    // 活动奖励
+	AdjustmentReasonCorrection   AdjustmentReason = "correction"   // 账务更正
+	AdjustmentReasonAbuse        AdjustmentReason = "abuse"        // 违规扣回
+	AdjustmentReasonOther        AdjustmentReason = "other"        // 其他
+)
+
+// IsValid 是否为已定义的调整原因
+func (r AdjustmentReason) IsValid() bool {
+	switch r {
+	case AdjustmentReasonCompensation, AdjustmentReasonGoodwill, AdjustmentReasonPromotion,
+		AdjustmentReasonCorrection, AdjustmentReasonAbuse, AdjustmentReasonOther:
+		return true
+	}
+	return false
+}
+
 // ActionKey 动作key枚举
 type ActionKey string
 
@@ -45,25 +92,298 @@ func (a ActionKey) String() string {
 	return string(a)
 }
 
+// PricingMode 动作计费方式枚举
+type PricingMode string
+
+const (
+	PricingModeFixed   PricingMode = "fixed"   // 固定价格
+	PricingModeMetered PricingMode = "metered" // 按token用量计费
+)
+
+// DeductionStatus 扣减记录状态枚举
+type DeductionStatus string
+
+const (
+	DeductionStatusReserved DeductionStatus = "reserved" // 已预扣，等待结算
+	DeductionStatusDeducted DeductionStatus = "deducted" // 已扣减（含已结算的预扣）
+	DeductionStatusReleased DeductionStatus = "released" // 预扣已释放
+	DeductionStatusRefunded DeductionStatus = "refunded" // 已退还
+)
+
 // DeductCreditsRequest 扣减积分请求
 type DeductCreditsRequest struct {
-	UserID       string    `json:"user_id" binding:"required"`
-	ActionKey    ActionKey `json:"action_key" binding:"required"`
-	ResourceType string    `json:"resource_type,omitempty"`
-	ResourceID   string    `json:"resource_id,omitempty"`
+	UserID         string    `json:"user_id" binding:"required"`
+	ActionKey      ActionKey `json:"action_key" binding:"required"`
+	ResourceType   string    `json:"resource_type,omitempty"`
+	ResourceID     string    `json:"resource_id,omitempty"`
+	IdempotencyKey string    `json:"idempotency_key,omitempty"` // 幂等键，相同的键重复请求只扣减一次
+	TotalTokens    int       `json:"total_tokens,omitempty"`    // token用量，按量计费的动作据此计算积分
 }
 
 // DeductCreditsResponse 扣减积分响应
 type DeductCreditsResponse struct {
 	Success          bool   `json:"success"`
+	DeductionID      int64  `json:"deduction_id,omitempty"`
 	DeductedCredits  int    `json:"deducted_credits"`
 	RemainingCredits int    `json:"remaining_credits"`
+	Replayed         bool   `json:"replayed,omitempty"` // 是否为幂等重放（返回的是首次扣减的结果）
 	Message          string `json:"message,omitempty"`
+
+	EntitlementPackageID *int64 `json:"entitlement_package_id,omitempty"` // 由套餐权益覆盖时的用户套餐ID
+}
+
+// RefundCreditsRequest 退还积分请求
+// DeductionID 与 IdempotencyKey 二选一
+type RefundCreditsRequest struct {
+	UserID         string `json:"user_id" binding:"required"`
+	DeductionID    int64  `json:"deduction_id,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// RefundCreditsResponse 退还积分响应
+type RefundCreditsResponse struct {
+	DeductionID      int64 `json:"deduction_id"`
+	RefundedCredits  int   `json:"refunded_credits"`
+	RemainingCredits int   `json:"remaining_credits"`
+	Replayed         bool  `json:"replayed,omitempty"` // 该扣减此前已退还
 }
 
 // CheckCreditsResponse 检查积分响应
+// 套餐权益覆盖该动作时 HasEnough 为 true、RequiredCredits 为0，并返回所使用的权益
 type CheckCreditsResponse struct {
-	HasEnough       bool `json:"has_enough"`
-	TotalCredits    int  `json:"total_credits"`
-	RequiredCredits int  `json:"required_credits"`
+	HasEnough       bool               `json:"has_enough"`
+	TotalCredits    int                `json:"total_credits"`
+	RequiredCredits int                `json:"required_credits"`
+	Entitlement     *ActionEntitlement `json:"entitlement,omitempty"`
+}
+
+// TransactionQueryRequest 积分流水查询请求
+type TransactionQueryRequest struct {
+	Page                 int       `form:"page" binding:"omitempty,min=1"`
+	PageSize             int       `form:"page_size" binding:"omitempty,min=1,max=100"`
+	UserID               string    `form:"user_id"`
+	Type                 string    `form:"type"`
+	ActionKey            string    `form:"action_key"`
+	UserBillingPackageID int64     `form:"user_billing_package_id"`
+	StartTime            time.Time `form:"start_time" time_format:"2006-01-02T15:04:05"`
+	EndTime              time.Time `form:"end_time" time_format:"2006-01-02T15:04:05"`
+}
+
+// TransactionQueryResponse 积分流水查询响应
+type TransactionQueryResponse struct {
+	List     interface{} `json:"list"`
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+}
+
+// ReserveCreditsRequest 预扣积分请求
+type ReserveCreditsRequest struct {
+	UserID         string        `json:"user_id" binding:"required"`
+	ActionKey      ActionKey     `json:"action_key" binding:"required"`
+	ResourceType   string        `json:"resource_type,omitempty"`
+	ResourceID     string        `json:"resource_id,omitempty"`
+	IdempotencyKey string        `json:"idempotency_key,omitempty"`
+	TTL            time.Duration `json:"-"` // 预扣有效期，超时未结算的预扣会被自动释放
+}
+
+// ReserveCreditsResponse 预扣积分响应
+type ReserveCreditsResponse struct {
+	Success          bool       `json:"success"`
+	HoldID           int64      `json:"hold_id,omitempty"`
+	ReservedCredits  int        `json:"reserved_credits"`
+	RequiredCredits  int        `json:"required_credits,omitempty"` // 积分不足时为所需积分
+	RemainingCredits int        `json:"remaining_credits"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	Replayed         bool       `json:"replayed,omitempty"`
+	Message          string     `json:"message,omitempty"`
+
+	EntitlementPackageID *int64 `json:"entitlement_package_id,omitempty"` // 由套餐权益覆盖时的用户套餐ID
+}
+
+// SchedulePriceRequest 排期动作价格版本请求
+type SchedulePriceRequest struct {
+	CreditsCost   int        `json:"credits_cost" binding:"min=0"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"` // 为空表示立即生效
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`   // 为空表示长期有效
+	Notes         string     `json:"notes,omitempty"`
+}
+
+// UpdateActionPriceRequest 更新动作配置请求
+// 基础价格通过价格版本调整，此处只修改展示信息与计费方式
+type UpdateActionPriceRequest struct {
+	ActionName         string       `json:"action_name"`                                     // 为空表示不修改
+	Description        *string      `json:"description"`                                     // nil 表示不修改
+	IsActive           *bool        `json:"is_active"`                                       // nil 表示不修改
+	SortOrder          *int         `json:"sort_order"`                                      // nil 表示不修改
+	PricingMode        *PricingMode `json:"pricing_mode"`                                    // nil 表示不修改
+	CreditsPer1KTokens *int         `json:"credits_per_1k_tokens" binding:"omitempty,min=0"` // nil 表示不修改
+	MaxCreditsPerCall  *int         `json:"max_credits_per_call" binding:"omitempty,min=0"`  // nil 表示不修改
+}
+
+// AdjustCreditsRequest 管理员积分调整请求
+type AdjustCreditsRequest struct {
+	UserID       string           `json:"user_id" binding:"required"`
+	Credits      int              `json:"credits" binding:"required"` // 正数发放，负数扣回
+	ReasonCode   AdjustmentReason `json:"reason_code" binding:"required"`
+	Notes        string           `json:"notes" binding:"required"`
+	ValidityDays int              `json:"validity_days" binding:"min=0"` // 发放积分的有效天数，0=永久有效
+}
+
+// AdjustmentQueryRequest 积分调整记录查询请求
+type AdjustmentQueryRequest struct {
+	Page       int       `form:"page" binding:"omitempty,min=1"`
+	PageSize   int       `form:"page_size" binding:"omitempty,min=1,max=100"`
+	UserID     string    `form:"user_id"`
+	OperatorID string    `form:"operator_id"`
+	ReasonCode string    `form:"reason_code"`
+	StartTime  time.Time `form:"start_time" time_format:"2006-01-02T15:04:05"`
+	EndTime    time.Time `form:"end_time" time_format:"2006-01-02T15:04:05"`
+}
+
+// AdjustmentQueryResponse 积分调整记录查询响应
+type AdjustmentQueryResponse struct {
+	List     []model.CreditAdjustment `json:"list"`
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// ReportRequest 计费报表通用查询条件
+type ReportRequest struct {
+	StartDate string `form:"start_date"` // 开始日期 (YYYY-MM-DD)
+	EndDate   string `form:"end_date"`   // 结束日期 (YYYY-MM-DD)
+	Days      int    `form:"days"`       // 最近N天，与start_date/end_date二选一
+	Limit     int    `form:"limit"`      // 排行类报表的条数，默认20，最大100
+}
+
+// ReportRange 报表实际使用的时间范围
+type ReportRange struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// RevenueReportItem 每日每套餐收入
+type RevenueReportItem struct {
+	Date           string `json:"date"`
+	PackageID      int64  `json:"package_id"`
+	PackageName    string `json:"package_name"`
+	Orders         int64  `json:"orders"`
+	OriginalAmount int64  `json:"original_amount"` // 优惠前金额（分）
+	DiscountAmount int64  `json:"discount_amount"` // 优惠减免金额（分）
+	GrossAmount    int64  `json:"gross_amount"`    // 实收金额（分）
+	RefundedAmount int64  `json:"refunded_amount"` // 已退款金额（分）
+	NetAmount      int64  `json:"net_amount"`      // 净收入（分）
+}
+
+// RevenueReportResponse 收入报表
+type RevenueReportResponse struct {
+	Range          ReportRange         `json:"range"`
+	Items          []RevenueReportItem `json:"items"`
+	TotalOrders    int64               `json:"total_orders"`
+	TotalGross     int64               `json:"total_gross"`
+	TotalRefunded  int64               `json:"total_refunded"`
+	TotalNetAmount int64               `json:"total_net_amount"`
+}
+
+// ActionCreditsItem 单个动作的积分消耗
+type ActionCreditsItem struct {
+	ActionKey       string `json:"action_key"`
+	ActionName      string `json:"action_name"`
+	Deductions      int64  `json:"deductions"`       // 扣减次数
+	ConsumedCredits int64  `json:"consumed_credits"` // 扣减积分（含预扣结算）
+	RefundedCredits int64  `json:"refunded_credits"` // 退还积分
+	NetCredits      int64  `json:"net_credits"`      // 净消耗积分
+}
+
+// CreditsReportResponse 积分发放与消耗报表
+type CreditsReportResponse struct {
+	Range           ReportRange         `json:"range"`
+	Actions         []ActionCreditsItem `json:"actions"`
+	IssuedCredits   int64               `json:"issued_credits"`   // 套餐发放积分
+	AdjustedCredits int64               `json:"adjusted_credits"` // 管理员调整净值（发放为正，扣回为负）
+	ConsumedCredits int64               `json:"consumed_credits"` // 各动作净消耗合计
+	ExpiredCredits  int64               `json:"expired_credits"`  // 过期作废积分
+
+	CancelledCredits int64 `json:"cancelled_credits"` // 取消或退款套餐收回的积分
+}
+
+// BreakageReportItem 每日过期作废积分
+type BreakageReportItem struct {
+	Date           string `json:"date"`
+	Packages       int64  `json:"packages"`        // 过期时仍有剩余积分的套餐数
+	ExpiredCredits int64  `json:"expired_credits"` // 过期作废积分
+}
+
+// BreakageReportResponse 积分沉淀（过期未使用）报表
+type BreakageReportResponse struct {
+	Range          ReportRange          `json:"range"`
+	Items          []BreakageReportItem `json:"items"`
+	IssuedCredits  int64                `json:"issued_credits"`  // 期间发放积分
+	ExpiredCredits int64                `json:"expired_credits"` // 期间过期作废积分
+	BreakageRate   float64              `json:"breakage_rate"`   // 过期作废积分占发放积分的百分比
+}
+
+// TopConsumerItem 积分消耗排行
+type TopConsumerItem struct {
+	UserID          string `json:"user_id"`
+	UserName        string `json:"user_name"`
+	Deductions      int64  `json:"deductions"`
+	ConsumedCredits int64  `json:"consumed_credits"` // 净消耗积分
+	PaidAmount      int64  `json:"paid_amount"`      // 期间支付金额（分）
+}
+
+// TopConsumersReportResponse 积分消耗排行报表
+type TopConsumersReportResponse struct {
+	Range ReportRange       `json:"range"`
+	Items []TopConsumerItem `json:"items"`
+}
+
+// ConversionReportItem 赠送套餐转化为购买的情况
+type ConversionReportItem struct {
+	PackageName     string  `json:"package_name"`
+	Source          string  `json:"source"`           // gift/promotion/system
+	GiftedUsers     int64   `json:"gifted_users"`     // 获赠用户数
+	ConvertedUsers  int64   `json:"converted_users"`  // 获赠后完成购买的用户数
+	ConversionRate  float64 `json:"conversion_rate"`  // 转化率（百分比）
+	ConvertedAmount int64   `json:"converted_amount"` // 转化用户的支付金额（分）
+}
+
+// ConversionReportResponse 赠送→购买转化报表
+type ConversionReportResponse struct {
+	Range ReportRange            `json:"range"`
+	Items []ConversionReportItem `json:"items"`
+}
+
+// QuotaRuleQueryRequest 配额规则查询请求
+type QuotaRuleQueryRequest struct {
+	ActionKey        string `form:"action_key"`
+	BillingPackageID *int64 `form:"billing_package_id"`
+}
+
+// QuotaRuleRequest 创建或更新配额规则请求，BillingPackageID 为0表示默认规则
+type QuotaRuleRequest struct {
+	BillingPackageID int64  `json:"billing_package_id" binding:"omitempty,min=0"`
+	ActionKey        string `json:"action_key" binding:"required"`
+	PerMinute        int    `json:"per_minute" binding:"omitempty,min=0"`
+	PerDay           int    `json:"per_day" binding:"omitempty,min=0"`
+	PerMonth         int    `json:"per_month" binding:"omitempty,min=0"`
+	Notes            string `json:"notes"`
+}
+
+// QuotaOverrideRequest 设置用户配额覆盖请求，各窗口上限为0表示不限
+type QuotaOverrideRequest struct {
+	ActionKey string     `json:"action_key" binding:"required"`
+	PerMinute int        `json:"per_minute" binding:"omitempty,min=0"`
+	PerDay    int        `json:"per_day" binding:"omitempty,min=0"`
+	PerMonth  int        `json:"per_month" binding:"omitempty,min=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Notes     string     `json:"notes"`
+}
+
+// UserQuotasResponse 用户配额覆盖与使用情况
+type UserQuotasResponse struct {
+	Overrides []model.ActionQuotaOverride `json:"overrides"`
+	Statuses  []QuotaStatus               `json:"statuses"`
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 
 	"server/global"
@@ -20,58 +21,169 @@ func (s *UserPackageService) AssignBillingPackageToUser(
 	source PackageSource,
 	notes string,
 	autoActivate bool,
+) (*model.UserBillingPackage, error) {
+	var userPackage *model.UserBillingPackage
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		userPackage, err = s.AssignBillingPackageInTx(tx, userID, packageID, source, notes, nil, autoActivate)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userPackage, nil
+}
+
+// AssignBillingPackageInTx 在给定事务中为用户分配套餐
+// orderID 不为空时关联购买订单，同一订单只能生成一个用户套餐
+func (s *UserPackageService) AssignBillingPackageInTx(
+	tx *gorm.DB,
+	userID string,
+	packageID int64,
+	source PackageSource,
+	notes string,
+	orderID *string,
+	autoActivate bool,
 ) (*model.UserBillingPackage, error) {
 	// 获取套餐信息
 	var pkg model.BillingPackage
-	if err := global.DB.First(&pkg, packageID).Error; err != nil {
+	if err := tx.First(&pkg, packageID).Error; err != nil {
 		return nil, fmt.Errorf("套餐不存在: %w", err)
 	}
 
-	// 创建用户套餐实例
+	// 时长型套餐只提供动作权益，不发放积分
+	credits := pkg.CreditsAmount
+	if !grantsCredits(pkg.PackageType) {
+		credits = 0
+	}
+
+	// 创建用户套餐实例，权益配置取分配时的快照
 	userPackage := &model.UserBillingPackage{
 		UserID:           userID,
 		BillingPackageID: packageID,
 		PackageName:      pkg.Name,
 		PackageType:      pkg.PackageType,
-		TotalCredits:     pkg.CreditsAmount,
+		TotalCredits:     credits,
 		UsedCredits:      0,
-		RemainingCredits: pkg.CreditsAmount,
+		RemainingCredits: credits,
 		Status:           string(PackageStatusPending),
 		Priority:         0,
 		Source:           string(source),
+		OrderID:          orderID,
 		Notes:            notes,
 	}
+	if grantsEntitlements(pkg.PackageType) {
+		userPackage.CoveredActions = pkg.CoveredActions
+		userPackage.DailyActionLimit = pkg.DailyActionLimit
+	}
 
 	// 如果自动激活
 	if autoActivate {
 		now := time.Now()
 		userPackage.ActivatedAt = &now
 		userPackage.Status = string(PackageStatusActive)
-
-		// 计算过期时间
-		if pkg.ValidityDays > 0 {
-			expiresAt := now.AddDate(0, 0, pkg.ValidityDays)
-			userPackage.ExpiresAt = &expiresAt
-		}
+		userPackage.ExpiresAt = packageExpiresAt(&pkg, now)
 	}
 
-	if err := global.DB.Create(userPackage).Error; err != nil {
+	if err := tx.Create(userPackage).Error; err != nil {
 		return nil, fmt.Errorf("创建用户套餐失败: %w", err)
 	}
 
+	// 已激活的套餐立即计入积分流水，待激活的套餐在激活时记录
+	if userPackage.Status == string(PackageStatusActive) {
+		if err := recordGrantTransaction(tx, userPackage); err != nil {
+			return nil, err
+		}
+	}
+
 	return userPackage, nil
 }
 
+// RevokeUserPackageInTx 在给定事务中收回订单全额退款的用户套餐，剩余积分清零并记录 cancel 流水
+// 已取消或已退款的套餐直接返回；预扣中的积分不受影响，结算或释放后按套餐当前状态处理
+func (s *UserPackageService) RevokeUserPackageInTx(tx *gorm.DB, userPackageID int64, orderID string, refundAmount int64, reason string) error {
+	var userPackage model.UserBillingPackage
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&userPackage, userPackageID).Error; err != nil {
+		return fmt.Errorf("用户套餐不存在: %w", err)
+	}
+
+	switch PackageStatus(userPackage.Status) {
+	case PackageStatusCancelled, PackageStatusRefunded:
+		return nil
+	}
+	return s.CancelUserPackageInTx(tx, &userPackage, PackageStatusRefunded, refundAmount, "order", orderID, reason)
+}
+
+// GrantBonusCreditsInTx 在给定事务中为用户套餐追加赠送积分（如优惠券赠送），已激活的套餐立即记录 grant 流水
+// 待激活的套餐在激活时随套餐积分一并记录
+func (s *UserPackageService) GrantBonusCreditsInTx(tx *gorm.DB, userPackageID int64, credits int, reason string) error {
+	if credits <= 0 {
+		return nil
+	}
+
+	var userPackage model.UserBillingPackage
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&userPackage, userPackageID).Error; err != nil {
+		return fmt.Errorf("用户套餐不存在: %w", err)
+	}
+
+	updates := map[string]interface{}{
+		"total_credits":     userPackage.TotalCredits + credits,
+		"remaining_credits": userPackage.RemainingCredits + credits,
+	}
+	if userPackage.Status == string(PackageStatusDepleted) {
+		updates["status"] = PackageStatusActive
+	}
+	if err := tx.Model(&userPackage).Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新用户套餐失败: %w", err)
+	}
+
+	if userPackage.Status == string(PackageStatusPending) {
+		return nil
+	}
+	if err := recordTransaction(tx, &model.CreditTransaction{
+		UserID:               userPackage.UserID,
+		UserBillingPackageID: userPackage.ID,
+		Type:                 string(TransactionGrant),
+		Credits:              credits,
+		Notes:                reason,
+	}); err != nil {
+		return fmt.Errorf("记录积分流水失败: %w", err)
+	}
+	return nil
+}
+
+// packageExpiresAt 计算套餐激活后的过期时间，永久型套餐及未设置有效天数的套餐不过期
+func packageExpiresAt(pkg *model.BillingPackage, activatedAt time.Time) *time.Time {
+	if PackageType(pkg.PackageType) == PackageTypePermanent || pkg.ValidityDays <= 0 {
+		return nil
+	}
+	expiresAt := activatedAt.AddDate(0, 0, pkg.ValidityDays)
+	return &expiresAt
+}
+
+// recordGrantTransaction 记录套餐发放的积分流水
+func recordGrantTransaction(tx *gorm.DB, userPackage *model.UserBillingPackage) error {
+	if userPackage.RemainingCredits <= 0 {
+		return nil
+	}
+	if err := recordTransaction(tx, &model.CreditTransaction{
+		UserID:               userPackage.UserID,
+		UserBillingPackageID: userPackage.ID,
+		Type:                 string(TransactionGrant),
+		Credits:              userPackage.RemainingCredits,
+		Notes:                userPackage.Notes,
+	}); err != nil {
+		return fmt.Errorf("记录积分流水失败: %w", err)
+	}
+	return nil
+}
+
 // GetUserTotalCredits 查询用户总剩余积分
 func (s *UserPackageService) GetUserTotalCredits(userID string) (int, error) {
-	var total int64
-	err := global.DB.Model(&model.UserBillingPackage{}).
-		Where("user_id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
-			userID, PackageStatusActive, time.Now()).
-		Select("COALESCE(SUM(remaining_credits), 0)").
-		Scan(&total).Error
-
-	return int(total), err
+	return sumUserCredits(global.DB, userID)
 }
 
 // GetUserActiveBillingPackages 查询用户有效套餐列表
@@ -112,11 +224,29 @@ func (s *UserPackageService) CheckCredits(userID string, actionKey ActionKey) (*
 		return nil, fmt.Errorf("获取用户积分失败: %w", err)
 	}
 
-	return &CheckCreditsResponse{
-		HasEnough:       totalCredits >= actionPrice.CreditsCost,
+	// 套餐权益覆盖该动作时无需积分
+	var userPackages []model.UserBillingPackage
+	if err := entitlementPackagesQuery(global.DB, userID).Find(&userPackages).Error; err != nil {
+		return nil, fmt.Errorf("查询用户套餐失败: %w", err)
+	}
+	entitlement, err := findEntitlement(global.DB, userPackages, actionKey.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// 按量计费的动作按单次上限检查
+	requiredCredits := maxActionCredits(actionPrice)
+	response := &CheckCreditsResponse{
+		HasEnough:       totalCredits >= requiredCredits,
 		TotalCredits:    totalCredits,
-		RequiredCredits: actionPrice.CreditsCost,
-	}, nil
+		RequiredCredits: requiredCredits,
+	}
+	if entitlement != nil {
+		response.HasEnough = true
+		response.RequiredCredits = 0
+		response.Entitlement = entitlement
+	}
+	return response, nil
 }
 
 // DeductCredits 扣减积分（原子操作）
@@ -128,7 +258,8 @@ func (s *UserPackageService) DeductCredits(req *DeductCreditsRequest) (*DeductCr
 		return nil, fmt.Errorf("获取动作价格失败: %w", err)
 	}
 
-	requiredCredits := actionPrice.CreditsCost
+	// 按量计费的动作按请求中的token用量计算
+	requiredCredits := meteredCredits(actionPrice, actionPrice.CreditsCost, req.TotalTokens, maxActionCredits(actionPrice))
 
 	// 开始事务
 	tx := global.DB.Begin()
@@ -139,18 +270,46 @@ func (s *UserPackageService) DeductCredits(req *DeductCreditsRequest) (*DeductCr
 	}()
 
 	// 锁定用户有效套餐（悲观锁）
-	var userPackages []model.UserBillingPackage
-	err = tx.Where("user_id = ? AND status = ? AND remaining_credits > 0 AND (expires_at IS NULL OR expires_at > ?)",
-		req.UserID, PackageStatusActive, time.Now()).
-		Order("priority ASC, expires_at ASC").
-		Clauses(clause.Locking{Strength: "UPDATE"}).
-		Find(&userPackages).Error
-
+	userPackages, err := lockActivePackages(tx, req.UserID)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("查询用户套餐失败: %w", err)
+	}
+	entitlementPackages, err := lockEntitlementPackages(tx, req.UserID)
 	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("查询用户套餐失败: %w", err)
 	}
 
+	// 幂等检查：套餐行锁保证同一用户的扣减串行执行，此处可读到已提交的同键扣减
+	existing, err := findDeductionByKey(tx, req.UserID, req.IdempotencyKey)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("查询扣减记录失败: %w", err)
+	}
+	if existing != nil {
+		tx.Rollback()
+		remainingTotal, _ := s.GetUserTotalCredits(req.UserID)
+		return &DeductCreditsResponse{
+			Success:              true,
+			DeductionID:          existing.ID,
+			DeductedCredits:      existing.Credits,
+			RemainingCredits:     remainingTotal,
+			EntitlementPackageID: existing.EntitlementPackageID,
+			Replayed:             true,
+		}, nil
+	}
+
+	// 套餐权益覆盖该动作时记录一条0积分的扣减，用于统计每日使用次数
+	entitlement, err := findEntitlement(tx, entitlementPackages, req.ActionKey.String())
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if entitlement != nil {
+		requiredCredits = 0
+	}
+
 	// 检查总积分是否足够
 	totalCredits := 0
 	for _, pkg := range userPackages {
@@ -165,8 +324,83 @@ func (s *UserPackageService) DeductCredits(req *DeductCreditsRequest) (*DeductCr
 		}, nil
 	}
 
+	// 创建扣减记录
+	deduction := &model.CreditDeduction{
+		UserID:       req.UserID,
+		ActionKey:    req.ActionKey.String(),
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Credits:      requiredCredits,
+		Status:       string(DeductionStatusDeducted),
+		TotalTokens:  req.TotalTokens,
+	}
+	if entitlement != nil {
+		deduction.EntitlementPackageID = &entitlement.UserBillingPackageID
+	} else {
+		deduction.PriceVersionID = actionPrice.PriceVersionID
+	}
+	if req.IdempotencyKey != "" {
+		deduction.IdempotencyKey = &req.IdempotencyKey
+	}
+	if err := tx.Create(deduction).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("创建扣减记录失败: %w", err)
+	}
+
 	// 按优先级扣减积分
-	remainingCost := requiredCredits
+	if err := allocateCredits(tx, userPackages, deduction, TransactionDeduct); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	// 重新计算剩余积分
+	remainingTotal, _ := s.GetUserTotalCredits(req.UserID)
+
+	return &DeductCreditsResponse{
+		Success:              true,
+		DeductionID:          deduction.ID,
+		DeductedCredits:      requiredCredits,
+		RemainingCredits:     remainingTotal,
+		EntitlementPackageID: deduction.EntitlementPackageID,
+	}, nil
+}
+
+// lockActivePackages 锁定用户有效套餐（悲观锁），按扣减优先级排序
+func lockActivePackages(tx *gorm.DB, userID string) ([]model.UserBillingPackage, error) {
+	var userPackages []model.UserBillingPackage
+	err := tx.Where("user_id = ? AND status = ? AND remaining_credits > 0 AND (expires_at IS NULL OR expires_at > ?)",
+		userID, PackageStatusActive, time.Now()).
+		Order("priority ASC, expires_at ASC").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Find(&userPackages).Error
+	return userPackages, err
+}
+
+// findDeductionByKey 按幂等键查询扣减记录，键为空或不存在时返回 nil
+func findDeductionByKey(tx *gorm.DB, userID, idempotencyKey string) (*model.CreditDeduction, error) {
+	if idempotencyKey == "" {
+		return nil, nil
+	}
+	var existing model.CreditDeduction
+	err := tx.Where("user_id = ? AND idempotency_key = ?", userID, idempotencyKey).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// allocateCredits 按优先级从已锁定的套餐中占用积分，并为每个套餐记录一条流水
+// txType 为 deduct 时积分直接计入已使用；为 reserve 时转入预扣积分，等待结算
+func allocateCredits(tx *gorm.DB, userPackages []model.UserBillingPackage, deduction *model.CreditDeduction, txType TransactionType) error {
+	remainingCost := deduction.Credits
 	for i := range userPackages {
 		if remainingCost <= 0 {
 			break
@@ -179,73 +413,212 @@ func (s *UserPackageService) DeductCredits(req *DeductCreditsRequest) (*DeductCr
 		}
 
 		// 更新套餐积分
-		pkg.UsedCredits += deduct
 		pkg.RemainingCredits -= deduct
-
-		if pkg.RemainingCredits == 0 {
-			pkg.Status = string(PackageStatusDepleted)
+		if txType == TransactionReserve {
+			pkg.ReservedCredits += deduct
+		} else {
+			pkg.UsedCredits += deduct
+			if pkg.RemainingCredits == 0 && pkg.ReservedCredits == 0 {
+				pkg.Status = string(PackageStatusDepleted)
+			}
 		}
 
 		if err := tx.Save(pkg).Error; err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("更新套餐积分失败: %w", err)
+			return fmt.Errorf("更新套餐积分失败: %w", err)
+		}
+
+		// 每个被扣减的套餐记录一条流水
+		if err := recordTransaction(tx, &model.CreditTransaction{
+			UserID:               deduction.UserID,
+			UserBillingPackageID: pkg.ID,
+			Type:                 string(txType),
+			Credits:              -deduct,
+			DeductionID:          &deduction.ID,
+			ActionKey:            deduction.ActionKey,
+			ResourceType:         deduction.ResourceType,
+			ResourceID:           deduction.ResourceID,
+		}); err != nil {
+			return fmt.Errorf("记录积分流水失败: %w", err)
 		}
 
 		remainingCost -= deduct
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("提交事务失败: %w", err)
+	return nil
+}
+
+// RefundDeduction 退还一次扣减（原子操作）
+// 积分按原扣减流水退回到当时被扣减的套餐，重复退还同一扣减不会重复加回积分
+func (s *UserPackageService) RefundDeduction(req *RefundCreditsRequest) (*RefundCreditsResponse, error) {
+	if req.DeductionID == 0 && req.IdempotencyKey == "" {
+		return nil, errors.New("必须指定扣减记录ID或幂等键")
 	}
 
-	// 重新计算剩余积分
-	remainingTotal, _ := s.GetUserTotalCredits(req.UserID)
+	var response *RefundCreditsResponse
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定扣减记录，防止并发退还
+		var deduction model.CreditDeduction
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", req.UserID)
+		if req.DeductionID != 0 {
+			query = query.Where("id = ?", req.DeductionID)
+		} else {
+			query = query.Where("idempotency_key = ?", req.IdempotencyKey)
+		}
+		if err := query.First(&deduction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("扣减记录不存在")
+			}
+			return fmt.Errorf("查询扣减记录失败: %w", err)
+		}
 
-	return &DeductCreditsResponse{
-		Success:          true,
-		DeductedCredits:  requiredCredits,
-		RemainingCredits: remainingTotal,
-	}, nil
+		switch DeductionStatus(deduction.Status) {
+		case DeductionStatusRefunded:
+			response = &RefundCreditsResponse{
+				DeductionID:     deduction.ID,
+				RefundedCredits: deduction.Credits,
+				Replayed:        true,
+			}
+			return nil
+		case DeductionStatusReserved:
+			return errors.New("预扣积分尚未结算，请释放预扣")
+		case DeductionStatusReleased:
+			return errors.New("预扣积分已释放，无需退还")
+		}
+
+		// 查询该扣减对应的流水（直接扣减或已结算的预扣），按原套餐退回
+		// 按量结算时退回的多余预扣记为 release，需从对应套餐的退还积分中扣除
+		var entries []model.CreditTransaction
+		if err := tx.Where("deduction_id = ? AND type IN ?", deduction.ID, []TransactionType{TransactionDeduct, TransactionReserve, TransactionRelease}).
+			Order("id ASC").
+			Find(&entries).Error; err != nil {
+			return fmt.Errorf("查询积分流水失败: %w", err)
+		}
+
+		var packageIDs []int64
+		netByPackage := make(map[int64]int)
+		for _, entry := range entries {
+			if _, ok := netByPackage[entry.UserBillingPackageID]; !ok {
+				packageIDs = append(packageIDs, entry.UserBillingPackageID)
+			}
+			netByPackage[entry.UserBillingPackageID] += entry.Credits
+		}
+
+		refunded := 0
+		for _, packageID := range packageIDs {
+			credits := -netByPackage[packageID]
+			if credits <= 0 {
+				continue
+			}
+
+			var pkg model.UserBillingPackage
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&pkg, packageID).Error; err != nil {
+				return fmt.Errorf("查询用户套餐失败: %w", err)
+			}
+
+			// 已失效的套餐退还后随即收回（记录 expire 或 cancel 流水）
+			pkg.UsedCredits -= credits
+			if pkg.Status == string(PackageStatusDepleted) {
+				if pkg.IsExpired() {
+					pkg.Status = string(PackageStatusExpired)
+				} else {
+					pkg.Status = string(PackageStatusActive)
+				}
+			}
+			if err := returnCreditsToPackage(tx, &pkg, &deduction, TransactionRefund, credits, req.Reason); err != nil {
+				return err
+			}
+			refunded += credits
+		}
+
+		now := time.Now()
+		if err := tx.Model(&deduction).Updates(map[string]interface{}{
+			"status":        DeductionStatusRefunded,
+			"refunded_at":   now,
+			"refund_reason": req.Reason,
+		}).Error; err != nil {
+			return fmt.Errorf("更新扣减记录失败: %w", err)
+		}
+
+		response = &RefundCreditsResponse{
+			DeductionID:     deduction.ID,
+			RefundedCredits: refunded,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.RemainingCredits, _ = s.GetUserTotalCredits(req.UserID)
+	return response, nil
 }
 
 // CleanExpiredBillingPackages 清理过期套餐
+// 过期时仍有剩余积分的套餐会记录一条 expire 流水
 func (s *UserPackageService) CleanExpiredBillingPackages() (int64, error) {
-	result := global.DB.Model(&model.UserBillingPackage{}).
-		Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?",
+	var expired int64
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		var packages []model.UserBillingPackage
+		if err := tx.Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?",
 			PackageStatusActive, time.Now()).
-		Update("status", PackageStatusExpired)
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Find(&packages).Error; err != nil {
+			return err
+		}
 
-	if result.Error != nil {
-		return 0, result.Error
+		for i := range packages {
+			pkg := &packages[i]
+			if err := tx.Model(pkg).Update("status", PackageStatusExpired).Error; err != nil {
+				return err
+			}
+			if pkg.RemainingCredits > 0 {
+				if err := recordTransaction(tx, &model.CreditTransaction{
+					UserID:               pkg.UserID,
+					UserBillingPackageID: pkg.ID,
+					Type:                 string(TransactionExpire),
+					Credits:              -pkg.RemainingCredits,
+				}); err != nil {
+					return err
+				}
+			}
+			expired++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return result.RowsAffected, nil
+	return expired, nil
 }
 
 // ActivateBillingPackage 激活套餐
 func (s *UserPackageService) ActivateBillingPackage(packageID int64) error {
-	var userPackage model.UserBillingPackage
-	if err := global.DB.First(&userPackage, packageID).Error; err != nil {
-		return errors.New("套餐不存在")
-	}
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		var userPackage model.UserBillingPackage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&userPackage, packageID).Error; err != nil {
+			return errors.New("套餐不存在")
+		}
 
-	if userPackage.Status != string(PackageStatusPending) {
-		return errors.New("套餐状态不是待激活")
-	}
+		if userPackage.Status != string(PackageStatusPending) {
+			return errors.New("套餐状态不是待激活")
+		}
 
-	now := time.Now()
-	userPackage.ActivatedAt = &now
-	userPackage.Status = string(PackageStatusActive)
+		now := time.Now()
+		userPackage.ActivatedAt = &now
+		userPackage.Status = string(PackageStatusActive)
 
-	// 获取原始套餐信息计算过期时间
-	var pkg model.BillingPackage
-	if err := global.DB.First(&pkg, userPackage.BillingPackageID).Error; err == nil {
-		if pkg.ValidityDays > 0 {
-			expiresAt := now.AddDate(0, 0, pkg.ValidityDays)
-			userPackage.ExpiresAt = &expiresAt
+		// 获取原始套餐信息计算过期时间
+		var pkg model.BillingPackage
+		if err := tx.First(&pkg, userPackage.BillingPackageID).Error; err == nil {
+			userPackage.ExpiresAt = packageExpiresAt(&pkg, now)
 		}
-	}
 
-	return global.DB.Save(&userPackage).Error
+		if err := tx.Save(&userPackage).Error; err != nil {
+			return err
+		}
+		return recordGrantTransaction(tx, &userPackage)
+	})
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"server/service/apiclient"
 	"server/service/app"
 	"server/service/eventlog"
 	"server/service/file"
@@ -21,4 +22,5 @@ var (
 	InvitationService   = invitation.InvitationService
 	SiteVariableService = sitevariable.SiteVariableService
 	EventLogService     = eventlog.EventLogService
+	APIClientService    = apiclient.APIClientService
 )
@@ -32,15 +32,17 @@ const (
 	EventResumeExport   = "resume_export"   // 导出简历
 
 	// 系统事件 (system)
-	EventBusinessError    = "business_error"    // 业务错误
-	EventSystemError      = "system_error"      // 系统错误
-	EventInvitationReward = "invitation_reward" // 邀请奖励
+	EventBusinessError     = "business_error"      // 业务错误
+	EventSystemError       = "system_error"        // 系统错误
+	EventInvitationReward  = "invitation_reward"   // 邀请奖励
+	EventServiceAuthFailed = "service_auth_failed" // 服务间调用鉴权失败
 
 	// 付费相关 (payment) - 预留
 	EventOrderCreate    = "order_create"    // 创建订单
 	EventPaymentSuccess = "payment_success" // 支付成功
 	EventPaymentFailed  = "payment_failed"  // 支付失败
 	EventBalanceChange  = "balance_change"  // 余额变动
+	EventPackageCancel  = "package_cancel"  // 取消套餐
 )
 
 // 事件状态
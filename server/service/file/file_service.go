@@ -2,6 +2,7 @@ package file
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,6 +21,8 @@ import (
 
 	"server/global"
 	"server/model"
+	"server/service/app"
+	"server/service/upstream"
 	"server/utils"
 )
 
@@ -27,6 +30,9 @@ type fileService struct{}
 
 var FileService = &fileService{}
 
+// uploadClient 上传文件到 Dify 的客户端
+var uploadClient = upstream.NewClient(&http.Client{Timeout: 30 * time.Second}, upstream.RetryPolicy{Idempotent: true})
+
 // UploadFile 上传文件（统一接口）
 func (s *fileService) UploadFile(userID string, fileHeader *multipart.FileHeader, toDify bool) (*UploadFileResponse, error) {
 	// 验证用户ID
@@ -100,7 +106,10 @@ func (s *fileService) UploadFile(userID string, fileHeader *multipart.FileHeader
 		}
 
 		url := workflow.ApiURL
-		apiKey := workflow.ApiKey
+		apiKey, err := app.ResolveWorkflowAPIKey(&workflow)
+		if err != nil {
+			return nil, err
+		}
 
 		body := &bytes.Buffer{}
 		writer := multipart.NewWriter(body)
@@ -123,16 +132,18 @@ func (s *fileService) UploadFile(userID string, fileHeader *multipart.FileHeader
 		writer.WriteField("user", userID)
 		writer.Close()
 
-		req, err := http.NewRequest("POST", url, body)
-		if err != nil {
-			return nil, fmt.Errorf("创建请求失败: %v", err)
-		}
-
-		req.Header.Set("Content-Type", writer.FormDataContentType())
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
+		header := http.Header{}
+		header.Set("Content-Type", writer.FormDataContentType())
+		header.Set("Authorization", "Bearer "+apiKey)
+
+		// 上传文件不产生执行用量，可以安全重试
+		resp, err := uploadClient.Do(context.Background(), &upstream.Request{
+			Method:  "POST",
+			URL:     url,
+			Header:  header,
+			Body:    body.Bytes(),
+			Breaker: app.WorkflowBreakerKey(workflow.ID),
+		})
 		if err != nil {
 			return nil, fmt.Errorf("发送请求失败: %v", err)
 		}
@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"math"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"server/global"
 	"server/model"
 	"server/service/app"
+	"server/service/billing"
+	"server/service/jobqueue"
 
 	"gorm.io/gorm"
 )
@@ -494,6 +497,11 @@ func (s *interviewService) TriggerAnalysis(reviewID int64, userID string) (*mode
 		return nil, err
 	}
 
+	// 提交前检查积分，积分在任务开始执行时预扣，避免排队时间超过预扣有效期
+	if err := billing.CheckCreditsForWorkflow(userID, workflow); err != nil {
+		return nil, err
+	}
+
 	// 更新状态为analyzing
 	metadata["status"] = model.InterviewReviewStatusAnalyzing
 	metadata["workflow_id"] = workflow.ID
@@ -503,8 +511,6 @@ func (s *interviewService) TriggerAnalysis(reviewID int64, userID string) (*mode
 		return nil, errors.New("更新状态失败")
 	}
 
-	// 调用工作流服务执行分析
-	// analysisResult, err := s.executeAnalysisWorkflow(workflow.ID, userID, asrResult)
 	// 构建输入参数
 	inputs := map[string]interface{}{
 		"speech":          speech,
@@ -513,51 +519,112 @@ func (s *interviewService) TriggerAnalysis(reviewID int64, userID string) (*mode
 		"job_description": jobDescription,
 	}
 
-	// 使用标准工作流服务执行
-	response, err := app.AppService.ExecuteWorkflow(workflow.ID, userID, inputs)
+	// 分析在异步任务中执行，前端轮询记录状态获取结果
+	job, err := jobqueue.JobQueueService.Submit(jobqueue.SubmitRequest{
+		Type:       JobTypeAnalysis,
+		UserID:     userID,
+		WorkflowID: workflow.ID,
+		ResourceID: strconv.FormatInt(reviewID, 10),
+		Payload: analysisJobPayload{
+			ReviewID: reviewID,
+			Inputs:   inputs,
+		},
+	})
 	if err != nil {
 		s.updateAnalysisError(reviewID, err.Error())
-		return nil, fmt.Errorf("执行工作流失败: %w", err)
+		return nil, fmt.Errorf("提交分析任务失败: %w", err)
 	}
 
+	// 记录任务ID，只修改该键，避免覆盖任务可能已写入的分析结果
+	if err := global.DB.Model(review).
+		Update("metadata", gorm.Expr("jsonb_set(metadata, '{analysis_job_id}', to_jsonb(?::text))", job.ID)).Error; err != nil {
+		fmt.Printf("记录面试分析任务ID失败: review_id=%d, job_id=%s, err=%v\n", reviewID, job.ID, err)
+	}
+
+	return s.GetInterviewReview(reviewID, userID)
+}
+
+// RunAnalysisJob 执行面试分析任务，保存分析结果并按本次用量结算预扣积分
+// 每次执行开始时按工作流关联的计费动作预扣积分，执行失败时释放；任务超时（ctx 取消）时中止上游调用
+// 执行失败时由 OnAnalysisFailed 记录错误
+func (s *interviewService) RunAnalysisJob(ctx context.Context, job *jobqueue.Job) (result interface{}, err error) {
+	var payload analysisJobPayload
+	if err := job.Bind(&payload); err != nil {
+		return nil, err
+	}
+
+	var workflow model.Workflow
+	if err := global.DB.Where("id = ?", job.WorkflowID).First(&workflow).Error; err != nil {
+		return nil, errors.New("查询工作流失败")
+	}
+	holdID, err := billing.ReserveForWorkflow(job.UserID, &workflow, analysisHoldTTL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.releaseAnalysisHold(job.UserID, holdID, err.Error())
+		}
+	}()
+
+	// 使用标准工作流服务执行（积分由上面的预扣负责，此处不再单独计费）
+	job.Progress(10, "正在分析面试内容")
+	response, err := app.AppService.ExecuteWorkflowWithoutBilling(ctx, job.WorkflowID, job.UserID, payload.Inputs, holdID)
+	if err != nil {
+		return nil, fmt.Errorf("执行工作流失败: %w", err)
+	}
 	if !response.Success {
-		s.updateAnalysisError(reviewID, response.Message)
 		return nil, fmt.Errorf("工作流执行失败: %s", response.Message)
 	}
 
 	// 从outputs中提取结果
 	outputs, ok := response.Data["outputs"].(map[string]interface{})
 	if !ok {
-		s.updateAnalysisError(reviewID, "工作流输出格式错误")
 		return nil, errors.New("工作流输出格式错误")
 	}
-
-	// 获取分析结果（假设输出字段名为 result 或 answer）
-
-	// 序列化结果
 	resultJSON, err := json.Marshal(outputs)
 	if err != nil {
-		s.updateAnalysisError(reviewID, err.Error())
 		return nil, errors.New("序列化分析结果失败")
 	}
-	fmt.Println("analysisResult", string(resultJSON))
 
-	// 更新记录：保存分析结果到data字段，更新状态为completed
+	// 重新读取元数据，保留提交后写入的字段（如任务ID）
+	job.Progress(90, "正在保存分析结果")
+	var review model.InterviewReview
+	if err := global.DB.Where("id = ?", payload.ReviewID).First(&review).Error; err != nil {
+		return nil, errors.New("查询面试记录失败")
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(review.Metadata, &metadata); err != nil {
+		return nil, errors.New("解析元数据失败")
+	}
 	metadata["status"] = model.InterviewReviewStatusCompleted
-	metadataJSON, _ = json.Marshal(metadata)
+	metadataJSON, _ := json.Marshal(metadata)
 
-	// 直接存储分析结果字符串到data字段
-	updates := map[string]interface{}{
+	// 直接存储分析结果到data字段
+	if err := global.DB.Model(&review).Updates(map[string]interface{}{
 		"data":     model.JSON(resultJSON),
 		"metadata": model.JSON(metadataJSON),
+	}).Error; err != nil {
+		return nil, errors.New("保存分析结果失败")
 	}
 
-	if err := global.DB.Model(review).Updates(updates).Error; err != nil {
-		return nil, errors.New("保存分析结果失败")
+	// 按量计费的动作按本次分析的token用量结算
+	totalTokens, _ := response.Data["total_tokens"].(int)
+	if err := billing.SettleCreditHold(job.UserID, holdID, totalTokens); err != nil {
+		fmt.Printf("结算面试分析预扣失败: hold_id=%d, err=%v\n", holdID, err)
 	}
 
-	// 重新获取更新后的记录
-	return s.GetInterviewReview(reviewID, userID)
+	return map[string]interface{}{"review_id": payload.ReviewID}, nil
+}
+
+// OnAnalysisFailed 面试分析任务最终失败时记录错误（预扣已在执行结束时释放）
+func (s *interviewService) OnAnalysisFailed(job *jobqueue.Job, errorMessage string) {
+	var payload analysisJobPayload
+	if err := job.Bind(&payload); err != nil {
+		fmt.Printf("解析面试分析任务参数失败: job_id=%s, err=%v\n", job.ID, err)
+		return
+	}
+	s.updateAnalysisError(payload.ReviewID, errorMessage)
 }
 
 // getWorkflowByName 从workflows表按名称查找工作流
@@ -631,3 +698,10 @@ func (s *interviewService) updateAnalysisError(reviewID int64, errorMsg string)
 	metadataJSON, _ := json.Marshal(metadata)
 	global.DB.Model(&review).Update("metadata", metadataJSON)
 }
+
+// releaseAnalysisHold 释放面试分析的预扣积分
+func (s *interviewService) releaseAnalysisHold(userID string, holdID int64, reason string) {
+	if err := billing.ReleaseCreditHold(userID, holdID, reason); err != nil {
+		fmt.Printf("释放面试分析预扣失败: hold_id=%d, err=%v\n", holdID, err)
+	}
+}
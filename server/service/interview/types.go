@@ -1,6 +1,10 @@
 package interview
 
-import "server/model"
+import (
+	"time"
+
+	"server/model"
+)
 
 // InterviewReviewListResponse 面试复盘记录列表响应
 type InterviewReviewListResponse struct {
@@ -10,3 +14,19 @@ type InterviewReviewListResponse struct {
 	PageSize   int                     `json:"page_size"`
 	TotalPages int                     `json:"total_pages"`
 }
+
+// JobTypeAnalysis 面试分析的异步任务类型
+const JobTypeAnalysis = "interview_analysis"
+
+const (
+	// AnalysisJobTimeout 面试分析任务单次执行超时
+	AnalysisJobTimeout = 20 * time.Minute
+	// analysisHoldTTL 面试分析预扣有效期，预扣在任务开始执行时创建，需覆盖单次执行超时
+	analysisHoldTTL = AnalysisJobTimeout + 10*time.Minute
+)
+
+// analysisJobPayload 面试分析任务参数
+type analysisJobPayload struct {
+	ReviewID int64                  `json:"review_id"`
+	Inputs   map[string]interface{} `json:"inputs"`
+}
@@ -0,0 +1,532 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+	"server/utils"
+)
+
+type jobQueueService struct {
+	mu       sync.RWMutex
+	types    map[string]*JobType
+	workerID string
+	slots    chan struct{} // 本实例的执行槽位，容量为工作协程数
+	wake     chan struct{}
+}
+
+var JobQueueService = &jobQueueService{
+	types: make(map[string]*JobType),
+	wake:  make(chan struct{}, 1),
+}
+
+// Register 注册任务类型，需在 Start 之前调用
+func (s *jobQueueService) Register(jobType *JobType) {
+	if jobType.Name == "" || jobType.Handler == nil {
+		panic("异步任务类型缺少名称或执行函数")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.types[jobType.Name] = jobType
+}
+
+// Start 恢复中断的任务并启动工作协程池
+// 多实例部署时每个实例都会领取任务，通过 FOR UPDATE SKIP LOCKED 保证同一任务只被一个实例执行
+func (s *jobQueueService) Start() error {
+	workers := global.CONFIG.JobQueue.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	s.slots = make(chan struct{}, workers)
+	host, _ := os.Hostname()
+	s.workerID = fmt.Sprintf("%s-%d-%s", host, os.Getpid(), utils.GenerateTLID())
+
+	if _, _, err := s.RecoverStaleJobs(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			}
+			s.dispatch()
+		}
+	}()
+	return nil
+}
+
+// Submit 提交任务，返回任务记录（可通过任务ID查询进度）
+// 指定 ResourceID 时，同一用户同一类型同一记录已有未完成的任务则直接返回该任务
+func (s *jobQueueService) Submit(req SubmitRequest) (*model.WorkflowJob, error) {
+	jobType := s.getType(req.Type)
+	if jobType == nil {
+		return nil, ErrUnknownJobType
+	}
+
+	if req.ResourceID != "" {
+		var existing model.WorkflowJob
+		err := global.DB.Where("type = ? AND user_id = ? AND resource_id = ? AND status IN ?",
+			req.Type, req.UserID, req.ResourceID,
+			[]string{model.WorkflowJobStatusPending, model.WorkflowJobStatusRunning}).
+			Order("created_at DESC").
+			First(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("查询任务失败")
+		}
+	}
+
+	payload, err := json.Marshal(req.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务参数失败: %w", err)
+	}
+	maxAttempts := jobType.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	record := &model.WorkflowJob{
+		ID:          utils.GenerateTLID(),
+		Type:        req.Type,
+		UserID:      req.UserID,
+		WorkflowID:  req.WorkflowID,
+		ResourceID:  req.ResourceID,
+		Payload:     model.JSON(payload),
+		Status:      model.WorkflowJobStatusPending,
+		Message:     "排队中",
+		MaxAttempts: maxAttempts,
+	}
+	if err := global.DB.Create(record).Error; err != nil {
+		return nil, errors.New("创建任务失败")
+	}
+
+	s.notify()
+	return record, nil
+}
+
+// GetUserJob 查询用户的任务
+func (s *jobQueueService) GetUserJob(jobID, userID string) (*model.WorkflowJob, error) {
+	var record model.WorkflowJob
+	if err := global.DB.Where("id = ? AND user_id = ?", jobID, userID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, errors.New("查询任务失败")
+	}
+	return &record, nil
+}
+
+// ListUserJobs 查询用户的任务列表，可按类型和关联记录筛选
+func (s *jobQueueService) ListUserJobs(userID, jobType, resourceID string, page, pageSize int) (*JobListResponse, error) {
+	query := global.DB.Model(&model.WorkflowJob{}).Where("user_id = ?", userID)
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	if resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+	return listJobs(query, page, pageSize)
+}
+
+// ListJobs 查询所有任务（管理员），可按状态和类型筛选
+func (s *jobQueueService) ListJobs(status, jobType string, page, pageSize int) (*JobListResponse, error) {
+	query := global.DB.Model(&model.WorkflowJob{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if jobType != "" {
+		query = query.Where("type = ?", jobType)
+	}
+	return listJobs(query, page, pageSize)
+}
+
+// listJobs 分页查询任务，按创建时间倒序
+func listJobs(query *gorm.DB, page, pageSize int) (*JobListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	response := &JobListResponse{Page: page, PageSize: pageSize, Items: []model.WorkflowJob{}}
+	if err := query.Count(&response.Total).Error; err != nil {
+		return nil, errors.New("查询任务失败")
+	}
+	if err := query.Order("created_at DESC").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Find(&response.Items).Error; err != nil {
+		return nil, errors.New("查询任务失败")
+	}
+	return response, nil
+}
+
+// RecoverStaleJobs 处理心跳超时的执行中任务（执行实例已退出或失联）
+// 未达到最大执行次数的任务重新排队，否则标记为失败
+func (s *jobQueueService) RecoverStaleJobs() (requeued, failed int, err error) {
+	var stale []model.WorkflowJob
+	if err := global.DB.Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)",
+		model.WorkflowJobStatusRunning, time.Now().Add(-staleAfter)).
+		Find(&stale).Error; err != nil {
+		return 0, 0, fmt.Errorf("查询中断的任务失败: %w", err)
+	}
+
+	for i := range stale {
+		record := &stale[i]
+		// 仅当任务仍由原实例持有时修改，避免与其他实例的恢复或刚恢复的心跳冲突
+		query := global.DB.Model(&model.WorkflowJob{}).
+			Where("id = ? AND status = ? AND worker_id = ?", record.ID, model.WorkflowJobStatusRunning, record.WorkerID)
+
+		if record.Attempts < record.MaxAttempts {
+			result := query.Updates(map[string]interface{}{
+				"status":       model.WorkflowJobStatusPending,
+				"worker_id":    "",
+				"heartbeat_at": nil,
+				"message":      "执行中断，等待重新执行",
+			})
+			if result.Error != nil {
+				return requeued, failed, fmt.Errorf("重新排队任务失败: %w", result.Error)
+			}
+			requeued += int(result.RowsAffected)
+			continue
+		}
+
+		errorMessage := fmt.Sprintf("任务执行中断次数过多（%d次）", record.Attempts)
+		result := query.Updates(map[string]interface{}{
+			"status":        model.WorkflowJobStatusFailed,
+			"error_message": errorMessage,
+			"message":       "执行失败",
+			"worker_id":     "",
+			"heartbeat_at":  nil,
+			"finished_at":   time.Now(),
+		})
+		if result.Error != nil {
+			return requeued, failed, fmt.Errorf("更新任务状态失败: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			failed++
+			s.onFailed(toJob(record, ""), errorMessage)
+		}
+	}
+
+	if requeued > 0 {
+		s.notify()
+	}
+	return requeued, failed, nil
+}
+
+// notify 唤醒调度循环立即检查待执行任务
+func (s *jobQueueService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch 在有空闲槽位时持续领取并执行任务
+func (s *jobQueueService) dispatch() {
+	for {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			return
+		}
+
+		record, err := s.claim()
+		if err != nil || record == nil {
+			<-s.slots
+			if err != nil {
+				fmt.Printf("领取异步任务失败: %v\n", err)
+			}
+			return
+		}
+		go s.run(record)
+	}
+}
+
+// claim 领取一个可执行的任务并标记为执行中，没有可执行的任务时返回nil
+// 已达到并发上限的工作流的任务会被跳过，留待其他任务完成后再领取
+func (s *jobQueueService) claim() (*model.WorkflowJob, error) {
+	saturated, err := saturatedWorkflows()
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed *model.WorkflowJob
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", model.WorkflowJobStatusPending)
+		if len(saturated) > 0 {
+			query = query.Where("workflow_id NOT IN ?", saturated)
+		}
+		var candidates []model.WorkflowJob
+		if err := query.Order("created_at ASC").Limit(claimBatchSize).Find(&candidates).Error; err != nil {
+			return err
+		}
+
+		for i := range candidates {
+			record := &candidates[i]
+			// 其他版本的实例注册的任务类型留给对应实例执行
+			if s.getType(record.Type) == nil {
+				continue
+			}
+			if record.WorkflowID != "" {
+				ok, err := hasWorkflowCapacity(tx, record.WorkflowID)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					continue
+				}
+			}
+
+			now := time.Now()
+			if err := tx.Model(record).Updates(map[string]interface{}{
+				"status":        model.WorkflowJobStatusRunning,
+				"attempts":      gorm.Expr("attempts + 1"),
+				"worker_id":     s.workerID,
+				"heartbeat_at":  now,
+				"started_at":    now,
+				"message":       "执行中",
+				"error_message": "",
+			}).Error; err != nil {
+				return err
+			}
+			record.Status = model.WorkflowJobStatusRunning
+			record.Attempts++
+			claimed = record
+			return nil
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// saturatedWorkflows 返回执行中任务数已达到并发上限的工作流，用于领取前过滤候选任务
+func saturatedWorkflows() ([]string, error) {
+	var rows []struct {
+		WorkflowID     string
+		Running        int64
+		MaxConcurrency int
+	}
+	if err := global.DB.Model(&model.WorkflowJob{}).
+		Select("workflow_jobs.workflow_id, COUNT(*) AS running, COALESCE(MAX(workflows.max_concurrency), 0) AS max_concurrency").
+		Joins("LEFT JOIN workflows ON workflows.id = workflow_jobs.workflow_id").
+		Where("workflow_jobs.status = ? AND workflow_jobs.workflow_id <> ''", model.WorkflowJobStatusRunning).
+		Group("workflow_jobs.workflow_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("统计执行中任务失败: %w", err)
+	}
+
+	var saturated []string
+	for _, row := range rows {
+		if row.Running >= int64(workflowLimit(row.MaxConcurrency)) {
+			saturated = append(saturated, row.WorkflowID)
+		}
+	}
+	return saturated, nil
+}
+
+// hasWorkflowCapacity 判断工作流的执行中任务数是否未达到并发上限
+// 通过事务级咨询锁串行化同一工作流的领取，保证多实例下并发数不超过上限；锁被其他实例持有时视为暂无空位
+func hasWorkflowCapacity(tx *gorm.DB, workflowID string) (bool, error) {
+	var locked bool
+	if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", lockKey(workflowID)).Row().Scan(&locked); err != nil {
+		return false, fmt.Errorf("获取工作流并发锁失败: %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+
+	var running int64
+	if err := tx.Model(&model.WorkflowJob{}).
+		Where("workflow_id = ? AND status = ?", workflowID, model.WorkflowJobStatusRunning).
+		Count(&running).Error; err != nil {
+		return false, fmt.Errorf("统计执行中任务失败: %w", err)
+	}
+	var maxConcurrency int
+	if err := tx.Model(&model.Workflow{}).
+		Select("max_concurrency").
+		Where("id = ?", workflowID).
+		Scan(&maxConcurrency).Error; err != nil {
+		return false, fmt.Errorf("查询工作流并发上限失败: %w", err)
+	}
+	return running < int64(workflowLimit(maxConcurrency)), nil
+}
+
+// workflowLimit 返回工作流的并发上限，未单独设置时使用全局默认值
+func workflowLimit(maxConcurrency int) int {
+	if maxConcurrency > 0 {
+		return maxConcurrency
+	}
+	if global.CONFIG.JobQueue.WorkflowConcurrency > 0 {
+		return global.CONFIG.JobQueue.WorkflowConcurrency
+	}
+	return defaultWorkflowConcurrency
+}
+
+// run 执行已领取的任务并记录结果，执行期间定期更新心跳
+func (s *jobQueueService) run(record *model.WorkflowJob) {
+	defer func() {
+		<-s.slots
+		s.notify()
+	}()
+
+	jobType := s.getType(record.Type)
+	job := toJob(record, s.workerID)
+
+	stop := make(chan struct{})
+	go s.heartbeat(job.ID, stop)
+	result, runErr := invoke(jobType, job)
+	close(stop)
+
+	updates := map[string]interface{}{
+		"worker_id":    "",
+		"heartbeat_at": nil,
+		"finished_at":  time.Now(),
+	}
+	if runErr == nil {
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			runErr = fmt.Errorf("序列化任务结果失败: %w", err)
+		} else {
+			updates["status"] = model.WorkflowJobStatusSucceeded
+			updates["progress"] = 100
+			updates["message"] = "已完成"
+			updates["result"] = model.JSON(resultJSON)
+		}
+	}
+	if runErr != nil {
+		updates["status"] = model.WorkflowJobStatusFailed
+		updates["message"] = "执行失败"
+		updates["error_message"] = runErr.Error()
+	}
+
+	// 仅当任务仍由本实例持有时写入结果，心跳长时间未更新的任务可能已被重新排队
+	saved := global.DB.Model(&model.WorkflowJob{}).
+		Where("id = ? AND worker_id = ? AND status = ?", job.ID, s.workerID, model.WorkflowJobStatusRunning).
+		Updates(updates)
+	if saved.Error != nil {
+		fmt.Printf("保存异步任务结果失败: job_id=%s, err=%v\n", job.ID, saved.Error)
+		return
+	}
+	if saved.RowsAffected == 0 {
+		fmt.Printf("异步任务已被重新分配，丢弃本次结果: job_id=%s\n", job.ID)
+		return
+	}
+	if runErr != nil {
+		s.onFailed(job, runErr.Error())
+	}
+}
+
+// heartbeat 定期更新执行中任务的心跳，直到 stop 关闭
+func (s *jobQueueService) heartbeat(jobID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := global.DB.Model(&model.WorkflowJob{}).
+				Where("id = ? AND worker_id = ?", jobID, s.workerID).
+				UpdateColumn("heartbeat_at", time.Now()).Error; err != nil {
+				fmt.Printf("更新异步任务心跳失败: job_id=%s, err=%v\n", jobID, err)
+			}
+		}
+	}
+}
+
+// invoke 调用任务执行函数，捕获panic并应用超时
+func invoke(jobType *JobType, job *Job) (result interface{}, err error) {
+	timeout := jobType.Timeout
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务执行异常: %v", r)
+		}
+	}()
+	return jobType.Handler(ctx, job)
+}
+
+// onFailed 调用任务类型的失败回调
+func (s *jobQueueService) onFailed(job *Job, errorMessage string) {
+	jobType := s.getType(job.Type)
+	if jobType == nil || jobType.OnFailed == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("异步任务失败回调异常: job_id=%s, err=%v\n", job.ID, r)
+		}
+	}()
+	jobType.OnFailed(job, errorMessage)
+}
+
+// Progress 更新任务进度，percent 取值 0-100
+func (j *Job) Progress(percent int, message string) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 99 {
+		// 100 仅在任务成功完成时设置
+		percent = 99
+	}
+	if err := global.DB.Model(&model.WorkflowJob{}).
+		Where("id = ? AND worker_id = ?", j.ID, j.workerID).
+		Updates(map[string]interface{}{"progress": percent, "message": message}).Error; err != nil {
+		fmt.Printf("更新异步任务进度失败: job_id=%s, err=%v\n", j.ID, err)
+	}
+}
+
+func (s *jobQueueService) getType(name string) *JobType {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.types[name]
+}
+
+// toJob 将任务记录转换为执行上下文
+func toJob(record *model.WorkflowJob, workerID string) *Job {
+	return &Job{
+		ID:         record.ID,
+		Type:       record.Type,
+		UserID:     record.UserID,
+		WorkflowID: record.WorkflowID,
+		ResourceID: record.ResourceID,
+		Payload:    record.Payload,
+		Attempt:    record.Attempts,
+		workerID:   workerID,
+	}
+}
+
+// lockKey 根据工作流ID生成咨询锁的键
+func lockKey(workflowID string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("workflow_job:" + workflowID))
+	return int64(h.Sum64())
+}
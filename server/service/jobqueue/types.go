@@ -0,0 +1,88 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"server/model"
+)
+
+// Handler 任务执行函数，返回值序列化后作为任务结果保存
+type Handler func(ctx context.Context, job *Job) (interface{}, error)
+
+// JobType 任务类型定义
+type JobType struct {
+	Name        string        // 任务类型（唯一）
+	Timeout     time.Duration // 单次执行超时，0表示使用默认值
+	MaxAttempts int           // 最大执行次数，执行被中断（如服务重启）时重新排队，0表示使用默认值；执行函数返回错误不会重试
+	Handler     Handler
+	// OnFailed 任务最终失败时调用（执行出错、超时或中断次数过多），用于回滚业务状态，可为空
+	OnFailed func(job *Job, errorMessage string)
+}
+
+// Job 执行中的任务
+type Job struct {
+	ID         string
+	Type       string
+	UserID     string
+	WorkflowID string
+	ResourceID string
+	Payload    model.JSON
+	Attempt    int // 当前是第几次执行
+
+	workerID string
+}
+
+// Bind 解析任务参数
+func (j *Job) Bind(v interface{}) error {
+	if len(j.Payload) == 0 {
+		return errors.New("任务参数为空")
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+// SubmitRequest 提交任务请求
+type SubmitRequest struct {
+	Type       string      // 任务类型，需已注册
+	UserID     string      // 提交用户
+	WorkflowID string      // 执行的工作流，用于并发限制，为空表示不限制
+	ResourceID string      // 关联的业务记录，同一用户同一类型同一记录同时只保留一个未完成的任务
+	Payload    interface{} // 任务参数
+}
+
+// JobListResponse 任务列表
+type JobListResponse struct {
+	Total    int64               `json:"total"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Items    []model.WorkflowJob `json:"items"`
+}
+
+const (
+	// DefaultJobTimeout 任务默认执行超时
+	DefaultJobTimeout = 15 * time.Minute
+	// DefaultMaxAttempts 任务默认最大执行次数
+	DefaultMaxAttempts = 3
+	// defaultWorkers 每个实例默认同时执行的任务数
+	defaultWorkers = 8
+	// defaultWorkflowConcurrency 单个工作流默认最大并发任务数
+	defaultWorkflowConcurrency = 4
+
+	// pollInterval 检查待执行任务的间隔（本实例提交任务时会立即检查）
+	pollInterval = 2 * time.Second
+	// heartbeatInterval 执行中任务的心跳间隔
+	heartbeatInterval = 15 * time.Second
+	// staleAfter 心跳超过该时长未更新的执行中任务视为已中断
+	staleAfter = 2 * time.Minute
+	// claimBatchSize 每次领取任务时查询的候选任务数
+	claimBatchSize = 20
+)
+
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrUnknownJobType 任务类型未注册
+	ErrUnknownJobType = errors.New("任务类型未注册")
+)
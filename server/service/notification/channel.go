@@ -0,0 +1,34 @@
+package notification
+
+import (
+	"sync"
+
+	"server/model"
+)
+
+// Channel 外部通知渠道（短信、邮件等），站内通知记录之外的补充触达方式
+type Channel interface {
+	// Name 渠道名称，如 sms、email
+	Name() string
+	// Send 向用户发送通知，用户缺少该渠道的联系方式时应返回 nil 跳过
+	Send(user *model.User, notification *model.Notification) error
+}
+
+var (
+	channelsMu sync.RWMutex
+	channels   []Channel
+)
+
+// RegisterChannel 注册外部通知渠道
+func RegisterChannel(channel Channel) {
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	channels = append(channels, channel)
+}
+
+// registeredChannels 返回已注册的外部通知渠道
+func registeredChannels() []Channel {
+	channelsMu.RLock()
+	defer channelsMu.RUnlock()
+	return append([]Channel(nil), channels...)
+}
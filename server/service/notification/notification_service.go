@@ -0,0 +1,310 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+	"server/service/billing"
+	"server/service/sitevariable"
+)
+
+type notificationService struct{}
+
+var NotificationService = &notificationService{}
+
+// ListNotifications 分页查询用户的站内通知
+func (s *notificationService) ListNotifications(req *NotificationQueryRequest) (*NotificationQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.Notification{}).Where("user_id = ?", req.UserID)
+	if req.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计通知失败: " + err.Error())
+	}
+
+	var unread int64
+	if err := global.DB.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", req.UserID).
+		Count(&unread).Error; err != nil {
+		return nil, errors.New("统计未读通知失败: " + err.Error())
+	}
+
+	var notifications []model.Notification
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&notifications).Error; err != nil {
+		return nil, errors.New("查询通知失败: " + err.Error())
+	}
+
+	return &NotificationQueryResponse{
+		List:        notifications,
+		Total:       total,
+		UnreadCount: unread,
+		Page:        req.Page,
+		PageSize:    req.PageSize,
+	}, nil
+}
+
+// MarkRead 将用户的通知标记为已读，id 为0时标记全部
+func (s *notificationService) MarkRead(userID string, id int64) error {
+	query := global.DB.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if id > 0 {
+		query = query.Where("id = ?", id)
+	}
+	return query.Update("read_at", time.Now()).Error
+}
+
+// GetPreference 获取用户通知偏好，没有记录时返回默认值（全部开启）
+func (s *notificationService) GetPreference(userID string) (*model.NotificationPreference, error) {
+	var prefs []model.NotificationPreference
+	if err := global.DB.Where("user_id = ?", userID).Limit(1).Find(&prefs).Error; err != nil {
+		return nil, errors.New("查询通知偏好失败")
+	}
+	if len(prefs) == 0 {
+		return &model.NotificationPreference{
+			UserID:           userID,
+			PackageExpiry:    true,
+			LowBalance:       true,
+			ExternalChannels: true,
+		}, nil
+	}
+	return &prefs[0], nil
+}
+
+// UpdatePreference 更新用户通知偏好
+func (s *notificationService) UpdatePreference(userID string, req *UpdatePreferenceRequest) (*model.NotificationPreference, error) {
+	pref := &model.NotificationPreference{
+		UserID:           userID,
+		PackageExpiry:    req.PackageExpiry,
+		LowBalance:       req.LowBalance,
+		ExternalChannels: req.ExternalChannels,
+	}
+	// 显式写入所有字段，避免 false 被数据库默认值覆盖
+	if err := global.DB.Select("*").
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(pref).Error; err != nil {
+		return nil, errors.New("更新通知偏好失败")
+	}
+	return pref, nil
+}
+
+// parseExpiryNoticeDays 解析到期提醒提前天数，去重后升序排列
+func parseExpiryNoticeDays(value string) []int {
+	seen := make(map[int]bool)
+	days := make([]int, 0)
+	for _, part := range strings.Split(value, ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || d <= 0 || seen[d] {
+			continue
+		}
+		seen[d] = true
+		days = append(days, d)
+	}
+	sort.Ints(days)
+	return days
+}
+
+// SendPackageExpiryNotices 为即将到期的用户套餐发送提醒
+// 按配置的提前天数分档，每个套餐在每一档只提醒一次；同时落入多档时只发送最近的一档
+func (s *notificationService) SendPackageExpiryNotices() (int, error) {
+	days := parseExpiryNoticeDays(sitevariable.SiteVariableService.GetValue(VarExpiryNoticeDays, defaultExpiryNoticeDays))
+	if len(days) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var packages []model.UserBillingPackage
+	if err := global.DB.
+		Where("status IN ? AND expires_at > ? AND expires_at <= ? AND (remaining_credits > 0 OR covered_actions IS NOT NULL)",
+			[]billing.PackageStatus{billing.PackageStatusActive, billing.PackageStatusDepleted},
+			now, now.AddDate(0, 0, days[len(days)-1])).
+		Find(&packages).Error; err != nil {
+		return 0, fmt.Errorf("查询即将到期的套餐失败: %w", err)
+	}
+
+	prefs := make(map[string]*model.NotificationPreference)
+	sent := 0
+	for i := range packages {
+		pkg := &packages[i]
+		pref, err := s.cachedPreference(prefs, pkg.UserID)
+		if err != nil {
+			return sent, err
+		}
+		if !pref.PackageExpiry {
+			continue
+		}
+
+		// 找到剩余时间所在的最近一档
+		remaining := pkg.ExpiresAt.Sub(now)
+		lead := days[len(days)-1]
+		for _, d := range days {
+			if remaining <= time.Duration(d)*24*time.Hour {
+				lead = d
+				break
+			}
+		}
+
+		content := fmt.Sprintf("您的套餐「%s」将于 %s 到期", pkg.PackageName, pkg.ExpiresAt.Format("2006-01-02 15:04"))
+		if pkg.RemainingCredits > 0 {
+			content += fmt.Sprintf("，剩余 %d 积分到期后将作废，请尽快使用", pkg.RemainingCredits)
+		}
+		created, err := s.notify(pref, &model.Notification{
+			UserID:       pkg.UserID,
+			Type:         TypePackageExpiring,
+			DedupKey:     fmt.Sprintf("%s:%d:%d", TypePackageExpiring, pkg.ID, lead),
+			Title:        fmt.Sprintf("套餐将在 %d 天内到期", lead),
+			Content:      content,
+			ResourceType: "user_billing_package",
+			ResourceID:   strconv.FormatInt(pkg.ID, 10),
+		})
+		if err != nil {
+			return sent, err
+		}
+		if created {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// SendLowBalanceNotices 为积分余额低于阈值的用户发送提醒
+// 只提醒最近一次获得积分后因使用而降到阈值以下的用户；每次获得积分后最多提醒一次
+func (s *notificationService) SendLowBalanceNotices() (int, error) {
+	threshold, _ := strconv.Atoi(strings.TrimSpace(
+		sitevariable.SiteVariableService.GetValue(VarLowBalanceThreshold, defaultLowBalanceThreshold)))
+	if threshold <= 0 {
+		return 0, nil
+	}
+
+	var rows []struct {
+		UserID      string
+		LastGrantID int64
+		Balance     int64
+	}
+	if err := global.DB.Raw(`
+SELECT g.user_id, g.last_grant_id, COALESCE(b.balance, 0) AS balance
+FROM (
+	SELECT user_id, MAX(id) AS last_grant_id
+	FROM credit_transactions
+	WHERE type = ? OR (type = ? AND credits > 0)
+	GROUP BY user_id
+) g
+LEFT JOIN (
+	SELECT user_id, SUM(remaining_credits) AS balance
+	FROM user_billing_packages
+	WHERE status = ? AND (expires_at IS NULL OR expires_at > ?)
+	GROUP BY user_id
+) b ON b.user_id = g.user_id
+WHERE COALESCE(b.balance, 0) < ?
+	AND EXISTS (
+		SELECT 1 FROM credit_transactions d
+		WHERE d.user_id = g.user_id AND d.type IN ? AND d.id > g.last_grant_id
+	)`,
+		billing.TransactionGrant, billing.TransactionAdjust,
+		billing.PackageStatusActive, time.Now(),
+		threshold,
+		[]billing.TransactionType{billing.TransactionDeduct, billing.TransactionReserve}).
+		Scan(&rows).Error; err != nil {
+		return 0, fmt.Errorf("查询积分余额不足的用户失败: %w", err)
+	}
+
+	sent := 0
+	for _, row := range rows {
+		pref, err := s.GetPreference(row.UserID)
+		if err != nil {
+			return sent, err
+		}
+		if !pref.LowBalance {
+			continue
+		}
+
+		created, err := s.notify(pref, &model.Notification{
+			UserID:   row.UserID,
+			Type:     TypeLowBalance,
+			DedupKey: fmt.Sprintf("%s:%s:%d", TypeLowBalance, row.UserID, row.LastGrantID),
+			Title:    "积分余额不足",
+			Content:  fmt.Sprintf("您当前的可用积分为 %d，已低于 %d，为避免影响使用，请及时购买套餐", row.Balance, threshold),
+		})
+		if err != nil {
+			return sent, err
+		}
+		if created {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// cachedPreference 在一次扫描中缓存用户通知偏好
+func (s *notificationService) cachedPreference(cache map[string]*model.NotificationPreference, userID string) (*model.NotificationPreference, error) {
+	if pref, ok := cache[userID]; ok {
+		return pref, nil
+	}
+	pref, err := s.GetPreference(userID)
+	if err != nil {
+		return nil, err
+	}
+	cache[userID] = pref
+	return pref, nil
+}
+
+// notify 写入站内通知并按用户偏好通过外部渠道发送
+// 依靠 dedup_key 唯一索引保证同一通知只发送一次，已发送过时返回 false
+func (s *notificationService) notify(pref *model.NotificationPreference, notification *model.Notification) (bool, error) {
+	result := global.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dedup_key"}},
+		DoNothing: true,
+	}).Create(notification)
+	if result.Error != nil {
+		return false, fmt.Errorf("写入通知失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	channels := registeredChannels()
+	if !pref.ExternalChannels || len(channels) == 0 {
+		return true, nil
+	}
+
+	var user model.User
+	if err := global.DB.Where("id = ?", notification.UserID).First(&user).Error; err != nil {
+		return true, nil
+	}
+
+	// 外部渠道发送失败不影响站内通知，结果记录在通知上便于排查
+	deliveries := make(map[string]string, len(channels))
+	for _, channel := range channels {
+		if err := channel.Send(&user, notification); err != nil {
+			deliveries[channel.Name()] = err.Error()
+			continue
+		}
+		deliveries[channel.Name()] = "sent"
+	}
+	data, _ := json.Marshal(deliveries)
+	global.DB.Model(notification).Update("deliveries", model.JSON(data))
+	return true, nil
+}
@@ -0,0 +1,44 @@
+package notification
+
+import "server/model"
+
+// 通知类型
+const (
+	TypePackageExpiring = "package_expiring" // 套餐即将到期
+	TypeLowBalance      = "low_balance"      // 积分余额不足
+)
+
+// 通知相关的网站变量
+const (
+	// VarExpiryNoticeDays 到期前提前提醒的天数，多个以逗号分隔（如 "7,1"），为空或0表示不提醒
+	VarExpiryNoticeDays = "billing_expiry_notice_days"
+	// VarLowBalanceThreshold 积分余额低于该值时提醒，0表示不提醒
+	VarLowBalanceThreshold = "billing_low_balance_threshold"
+
+	defaultExpiryNoticeDays    = "3"
+	defaultLowBalanceThreshold = "10"
+)
+
+// NotificationQueryRequest 通知查询请求
+type NotificationQueryRequest struct {
+	Page       int    `form:"page" binding:"omitempty,min=1"`
+	PageSize   int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	UnreadOnly bool   `form:"unread_only"`
+	UserID     string `form:"-"`
+}
+
+// NotificationQueryResponse 通知查询响应
+type NotificationQueryResponse struct {
+	List        []model.Notification `json:"list"`
+	Total       int64                `json:"total"`
+	UnreadCount int64                `json:"unread_count"`
+	Page        int                  `json:"page"`
+	PageSize    int                  `json:"page_size"`
+}
+
+// UpdatePreferenceRequest 更新通知偏好请求
+type UpdatePreferenceRequest struct {
+	PackageExpiry    bool `json:"package_expiry"`
+	LowBalance       bool `json:"low_balance"`
+	ExternalChannels bool `json:"external_channels"`
+}
@@ -0,0 +1,232 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+	"server/service/billing"
+	"server/service/eventlog"
+)
+
+type cancellationService struct{}
+
+var CancellationService = &cancellationService{}
+
+// lockUserPackage 锁定用户套餐行
+func lockUserPackage(tx *gorm.DB, userPackageID int64) (*model.UserBillingPackage, error) {
+	var userPackage model.UserBillingPackage
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&userPackage, userPackageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户套餐不存在")
+		}
+		return nil, fmt.Errorf("查询用户套餐失败: %w", err)
+	}
+	return &userPackage, nil
+}
+
+// paidAmountOf 返回套餐原订单的可退金额，非购买来源或订单未支付时为0
+func paidAmountOf(order *model.Order) int64 {
+	if order == nil {
+		return 0
+	}
+	switch order.Status {
+	case model.OrderStatusPaid, model.OrderStatusFulfilled:
+		return order.Amount - order.RefundAmount
+	}
+	return 0
+}
+
+// quoteCancellation 计算套餐取消的退款试算结果
+func quoteCancellation(userPackage *model.UserBillingPackage, order *model.Order) *CancellationQuote {
+	return &CancellationQuote{
+		UserBillingPackageID: userPackage.ID,
+		PackageName:          userPackage.PackageName,
+		OrderID:              userPackage.OrderID,
+		RemainingCredits:     userPackage.RemainingCredits,
+		RefundProration:      billing.ProrateRefund(userPackage, paidAmountOf(order), time.Now()),
+	}
+}
+
+// PreviewCancellation 试算取消用户套餐可退款的金额（管理员）
+func (s *cancellationService) PreviewCancellation(userPackageID int64) (*CancellationQuote, error) {
+	var userPackage model.UserBillingPackage
+	if err := global.DB.First(&userPackage, userPackageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户套餐不存在")
+		}
+		return nil, fmt.Errorf("查询用户套餐失败: %w", err)
+	}
+	if err := billing.CanCancelPackage(&userPackage); err != nil {
+		return nil, err
+	}
+
+	var order *model.Order
+	if userPackage.OrderID != nil {
+		order = &model.Order{}
+		if err := global.DB.Where("id = ?", *userPackage.OrderID).First(order).Error; err != nil {
+			return nil, fmt.Errorf("查询原订单失败: %w", err)
+		}
+	}
+	return quoteCancellation(&userPackage, order), nil
+}
+
+// CancelPackage 取消用户套餐（管理员）
+// 按未使用积分与剩余有效期折算退款金额，剩余积分清零并记录 cancel 流水
+// 退款金额大于0时套餐状态为 refunded，原订单累计记录退款金额，全额退款时订单标记为已退款，部分退款时订单保持原状态；
+// 否则套餐状态为 cancelled
+// 退款金额需由管理员通过支付渠道原路退回，渠道的退款回调到达时订单已记录退款金额，不会重复处理
+func (s *cancellationService) CancelPackage(userPackageID int64, req *CancelPackageRequest, operatorID string) (*model.PackageCancellation, error) {
+	var target model.UserBillingPackage
+	if err := global.DB.Select("id", "order_id").First(&target, userPackageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户套餐不存在")
+		}
+		return nil, fmt.Errorf("查询用户套餐失败: %w", err)
+	}
+	orderID := target.OrderID
+
+	var cancellation *model.PackageCancellation
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		// 与订单退款回调保持一致的加锁顺序：订单 → 套餐
+		var order *model.Order
+		if orderID != nil {
+			var err error
+			if order, err = lockOrder(tx, *orderID); err != nil {
+				return err
+			}
+		}
+		userPackage, err := lockUserPackage(tx, userPackageID)
+		if err != nil {
+			return err
+		}
+		if err := billing.CanCancelPackage(userPackage); err != nil {
+			return err
+		}
+
+		quote := quoteCancellation(userPackage, order)
+		refundAmount := quote.ProratedAmount
+		if req.RefundAmount != nil {
+			if *req.RefundAmount > quote.PaidAmount {
+				return fmt.Errorf("退款金额不能超过实付金额 %.2f 元", float64(quote.PaidAmount)/100)
+			}
+			refundAmount = *req.RefundAmount
+		}
+
+		status := billing.PackageStatusCancelled
+		if refundAmount > 0 {
+			status = billing.PackageStatusRefunded
+		}
+
+		cancellation = &model.PackageCancellation{
+			UserID:               userPackage.UserID,
+			UserBillingPackageID: userPackage.ID,
+			OrderID:              userPackage.OrderID,
+			OperatorID:           operatorID,
+			PackageName:          userPackage.PackageName,
+			TotalCredits:         userPackage.TotalCredits,
+			RemainingCredits:     userPackage.RemainingCredits,
+			CreditsRatio:         quote.CreditsRatio,
+			TimeRatio:            quote.TimeRatio,
+			RefundRatio:          quote.RefundRatio,
+			PaidAmount:           quote.PaidAmount,
+			ProratedAmount:       quote.ProratedAmount,
+			RefundAmount:         refundAmount,
+			Status:               string(status),
+			Reason:               req.Reason,
+		}
+		if err := tx.Create(cancellation).Error; err != nil {
+			return fmt.Errorf("创建取消记录失败: %w", err)
+		}
+
+		userPackageService := &billing.UserPackageService{}
+		if err := userPackageService.CancelUserPackageInTx(tx, userPackage, status, refundAmount,
+			"package_cancellation", strconv.FormatInt(cancellation.ID, 10), req.Reason); err != nil {
+			return err
+		}
+
+		if order != nil && refundAmount > 0 {
+			updates := map[string]interface{}{
+				"refund_amount": order.RefundAmount + refundAmount,
+			}
+			if order.RefundAmount+refundAmount >= order.Amount {
+				updates["status"] = model.OrderStatusRefunded
+				updates["refunded_at"] = time.Now()
+			}
+			if err := tx.Model(order).Updates(updates).Error; err != nil {
+				return fmt.Errorf("更新订单失败: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logCancellationEvent(cancellation)
+	return cancellation, nil
+}
+
+// logCancellationEvent 记录套餐取消事件
+func logCancellationEvent(cancellation *model.PackageCancellation) {
+	order := &model.Order{UserID: cancellation.UserID, Amount: cancellation.PaidAmount}
+	if cancellation.OrderID != nil {
+		order.ID = *cancellation.OrderID
+	}
+	logPaymentEvent(eventlog.EventPackageCancel, order, eventlog.StatusSuccess, "", map[string]interface{}{
+		"cancellation_id":         cancellation.ID,
+		"user_billing_package_id": cancellation.UserBillingPackageID,
+		"revoked_credits":         cancellation.RemainingCredits,
+		"refund_amount":           cancellation.RefundAmount,
+		"operator_id":             cancellation.OperatorID,
+	})
+}
+
+// ListCancellations 分页查询套餐取消记录（管理员）
+func (s *cancellationService) ListCancellations(req *CancellationQueryRequest) (*CancellationQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.PackageCancellation{})
+	if req.UserID != "" {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.OrderID != "" {
+		query = query.Where("order_id = ?", req.OrderID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计取消记录失败: " + err.Error())
+	}
+
+	var cancellations []model.PackageCancellation
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&cancellations).Error; err != nil {
+		return nil, errors.New("查询取消记录失败: " + err.Error())
+	}
+
+	return &CancellationQueryResponse{
+		List:     cancellations,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
@@ -0,0 +1,519 @@
+package payment
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+)
+
+type couponService struct{}
+
+var CouponService = &couponService{}
+
+// normalizeCouponCode 优惠码统一去除空白并转为大写
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// generateCouponCode 生成随机优惠码
+func generateCouponCode() (string, error) {
+	b := make([]byte, 5) // 5字节可以生成8个字符（base32编码）
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New("生成优惠码失败")
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
+
+// parseCouponPackageIDs 解析优惠券限定的套餐ID列表
+func parseCouponPackageIDs(data model.JSON) []int64 {
+	if len(data) == 0 {
+		return nil
+	}
+	var ids []int64
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil
+	}
+	return ids
+}
+
+// applyCouponRequest 校验优惠券配置并写入模型
+func applyCouponRequest(coupon *model.Coupon, req *CouponRequest) error {
+	switch req.DiscountType {
+	case model.CouponDiscountPercent:
+		if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+			return errors.New("折扣百分比必须在1-100之间")
+		}
+	case model.CouponDiscountFixed:
+		if req.DiscountValue <= 0 {
+			return errors.New("减免金额必须大于0")
+		}
+	case model.CouponDiscountNone:
+		if req.BonusCredits <= 0 {
+			return errors.New("不减免金额的优惠券必须赠送积分")
+		}
+		req.DiscountValue = 0
+	default:
+		return fmt.Errorf("无效的折扣类型: %s", req.DiscountType)
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+
+	var packageIDs model.JSON
+	if len(req.PackageIDs) > 0 {
+		var count int64
+		if err := global.DB.Model(&model.BillingPackage{}).Where("id IN ?", req.PackageIDs).Count(&count).Error; err != nil {
+			return errors.New("查询套餐失败")
+		}
+		if int(count) != len(req.PackageIDs) {
+			return errors.New("限定的套餐不存在")
+		}
+		data, err := json.Marshal(req.PackageIDs)
+		if err != nil {
+			return err
+		}
+		packageIDs = data
+	}
+
+	coupon.Name = req.Name
+	coupon.Description = req.Description
+	coupon.DiscountType = req.DiscountType
+	coupon.DiscountValue = req.DiscountValue
+	coupon.MaxDiscount = req.MaxDiscount
+	coupon.MinAmount = req.MinAmount
+	coupon.BonusCredits = req.BonusCredits
+	coupon.PackageIDs = packageIDs
+	coupon.MaxRedemptions = req.MaxRedemptions
+	coupon.PerUserLimit = req.PerUserLimit
+	coupon.StartsAt = req.StartsAt
+	coupon.EndsAt = req.EndsAt
+	coupon.IsActive = req.IsActive
+	return nil
+}
+
+// CreateCoupon 创建优惠券
+func (s *couponService) CreateCoupon(req *CreateCouponRequest, createdBy string) (*model.Coupon, error) {
+	code := normalizeCouponCode(req.Code)
+	if code == "" {
+		generated, err := generateCouponCode()
+		if err != nil {
+			return nil, err
+		}
+		code = generated
+	}
+
+	var count int64
+	if err := global.DB.Model(&model.Coupon{}).Where("code = ?", code).Count(&count).Error; err != nil {
+		return nil, errors.New("查询优惠券失败")
+	}
+	if count > 0 {
+		return nil, errors.New("优惠码已存在")
+	}
+
+	coupon := &model.Coupon{Code: code, CreatedBy: createdBy}
+	if err := applyCouponRequest(coupon, &req.CouponRequest); err != nil {
+		return nil, err
+	}
+	if err := global.DB.Create(coupon).Error; err != nil {
+		return nil, errors.New("创建优惠券失败: " + err.Error())
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon 更新优惠券配置，优惠码创建后不可修改
+func (s *couponService) UpdateCoupon(id int64, req *CouponRequest) (*model.Coupon, error) {
+	coupon, err := s.GetCoupon(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCouponRequest(coupon, req); err != nil {
+		return nil, err
+	}
+	if err := global.DB.Select("*").Omit("id", "code", "created_at", "created_by", "redeemed_count").
+		Updates(coupon).Error; err != nil {
+		return nil, errors.New("更新优惠券失败: " + err.Error())
+	}
+	return coupon, nil
+}
+
+// GetCoupon 获取优惠券
+func (s *couponService) GetCoupon(id int64) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := global.DB.First(&coupon, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("优惠券不存在")
+		}
+		return nil, errors.New("查询优惠券失败")
+	}
+	return &coupon, nil
+}
+
+// DeleteCoupon 删除优惠券，已被使用过的优惠券只能停用
+func (s *couponService) DeleteCoupon(id int64) error {
+	if _, err := s.GetCoupon(id); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := global.DB.Model(&model.CouponRedemption{}).Where("coupon_id = ?", id).Count(&count).Error; err != nil {
+		return errors.New("查询优惠券使用记录失败")
+	}
+	if count > 0 {
+		return errors.New("优惠券已被使用，只能停用")
+	}
+
+	return global.DB.Delete(&model.Coupon{}, id).Error
+}
+
+// ListCoupons 分页查询优惠券
+func (s *couponService) ListCoupons(req *CouponQueryRequest) (*CouponQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.Coupon{})
+	if req.Keyword != "" {
+		keyword := "%" + req.Keyword + "%"
+		query = query.Where("code ILIKE ? OR name ILIKE ?", keyword, keyword)
+	}
+	if req.IsActive != nil {
+		query = query.Where("is_active = ?", *req.IsActive)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计优惠券失败: " + err.Error())
+	}
+
+	var coupons []model.Coupon
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("created_at DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&coupons).Error; err != nil {
+		return nil, errors.New("查询优惠券失败: " + err.Error())
+	}
+
+	return &CouponQueryResponse{
+		List:     coupons,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// ListRedemptions 分页查询优惠券使用记录，可按优惠券或用户筛选
+func (s *couponService) ListRedemptions(req *CouponRedemptionQueryRequest) (*CouponRedemptionQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.CouponRedemption{})
+	if req.CouponID > 0 {
+		query = query.Where("coupon_id = ?", req.CouponID)
+	}
+	if req.UserID != "" {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计使用记录失败: " + err.Error())
+	}
+
+	var redemptions []model.CouponRedemption
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("created_at DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&redemptions).Error; err != nil {
+		return nil, errors.New("查询使用记录失败: " + err.Error())
+	}
+
+	return &CouponRedemptionQueryResponse{
+		List:     redemptions,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// GetCouponStats 统计优惠券使用情况
+func (s *couponService) GetCouponStats(id int64) (*CouponStatsResponse, error) {
+	coupon, err := s.GetCoupon(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Status       string
+		Count        int64
+		Discount     int64
+		PaidAmount   int64
+		BonusCredits int64
+	}
+	if err := global.DB.Model(&model.CouponRedemption{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(discount_amount), 0) AS discount, "+
+			"COALESCE(SUM(original_amount - discount_amount), 0) AS paid_amount, COALESCE(SUM(bonus_credits), 0) AS bonus_credits").
+		Where("coupon_id = ?", id).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, errors.New("统计使用记录失败: " + err.Error())
+	}
+
+	stats := &CouponStatsResponse{Coupon: coupon}
+	for _, row := range rows {
+		switch row.Status {
+		case model.CouponRedemptionPending:
+			stats.PendingCount = row.Count
+		case model.CouponRedemptionRedeemed:
+			stats.RedeemedCount = row.Count
+			stats.TotalDiscount = row.Discount
+			stats.TotalPaidAmount = row.PaidAmount
+			stats.TotalBonusCredits = row.BonusCredits
+		case model.CouponRedemptionReleased:
+			stats.ReleasedCount = row.Count
+		}
+	}
+
+	if err := global.DB.Model(&model.CouponRedemption{}).
+		Where("coupon_id = ? AND status = ?", id, model.CouponRedemptionRedeemed).
+		Distinct("user_id").
+		Count(&stats.UniqueUsers).Error; err != nil {
+		return nil, errors.New("统计使用用户失败: " + err.Error())
+	}
+
+	return stats, nil
+}
+
+// PreviewCoupon 试算优惠券在指定套餐上的优惠，不占用使用次数
+func (s *couponService) PreviewCoupon(userID string, req *PreviewCouponRequest) (*CouponQuote, error) {
+	var pkg model.BillingPackage
+	if err := global.DB.First(&pkg, req.PackageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("套餐不存在")
+		}
+		return nil, errors.New("查询套餐失败")
+	}
+
+	var coupon model.Coupon
+	if err := global.DB.Where("code = ?", normalizeCouponCode(req.Code)).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("优惠码不存在")
+		}
+		return nil, errors.New("查询优惠券失败")
+	}
+
+	amount := int64(math.Round(pkg.Price))
+	if err := checkCoupon(global.DB, &coupon, userID, pkg.ID, amount); err != nil {
+		return nil, err
+	}
+	return quoteCoupon(&coupon, amount), nil
+}
+
+// checkCoupon 校验优惠券对用户和套餐是否可用
+func checkCoupon(db *gorm.DB, coupon *model.Coupon, userID string, packageID int64, amount int64) error {
+	now := time.Now()
+	if !coupon.IsActive {
+		return errors.New("优惠券已停用")
+	}
+	if coupon.StartsAt != nil && coupon.StartsAt.After(now) {
+		return errors.New("优惠券尚未生效")
+	}
+	if coupon.EndsAt != nil && !coupon.EndsAt.After(now) {
+		return errors.New("优惠券已过期")
+	}
+
+	if packageIDs := parseCouponPackageIDs(coupon.PackageIDs); len(packageIDs) > 0 {
+		applicable := false
+		for _, id := range packageIDs {
+			if id == packageID {
+				applicable = true
+				break
+			}
+		}
+		if !applicable {
+			return errors.New("该优惠券不适用于此套餐")
+		}
+	}
+	if coupon.MinAmount > 0 && amount < coupon.MinAmount {
+		return fmt.Errorf("订单金额满 %.2f 元才可使用该优惠券", float64(coupon.MinAmount)/100)
+	}
+
+	if coupon.MaxRedemptions > 0 && coupon.RedeemedCount >= coupon.MaxRedemptions {
+		return errors.New("优惠券已被领完")
+	}
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		if err := db.Model(&model.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ? AND status IN ?", coupon.ID, userID,
+				[]string{model.CouponRedemptionPending, model.CouponRedemptionRedeemed}).
+			Count(&used).Error; err != nil {
+			return errors.New("查询优惠券使用记录失败")
+		}
+		if int(used) >= coupon.PerUserLimit {
+			return errors.New("已达到该优惠券的使用次数上限")
+		}
+	}
+	return nil
+}
+
+// quoteCoupon 计算优惠券在指定金额上的减免，减免金额不超过订单金额
+func quoteCoupon(coupon *model.Coupon, amount int64) *CouponQuote {
+	var discount int64
+	switch coupon.DiscountType {
+	case model.CouponDiscountPercent:
+		discount = amount * coupon.DiscountValue / 100
+		if coupon.MaxDiscount > 0 && discount > coupon.MaxDiscount {
+			discount = coupon.MaxDiscount
+		}
+	case model.CouponDiscountFixed:
+		discount = coupon.DiscountValue
+	}
+	if discount > amount {
+		discount = amount
+	}
+
+	return &CouponQuote{
+		Code:           coupon.Code,
+		Name:           coupon.Name,
+		OriginalAmount: amount,
+		DiscountAmount: discount,
+		FinalAmount:    amount - discount,
+		BonusCredits:   coupon.BonusCredits,
+	}
+}
+
+// redeemCouponInTx 在创建订单的事务中使用优惠券：锁定优惠券行后校验并占用一次使用次数
+// 同一优惠券的并发下单在行锁上排队，保证总次数与每人次数上限不会被超卖
+func redeemCouponInTx(tx *gorm.DB, code string, order *model.Order) error {
+	var coupon model.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", normalizeCouponCode(code)).
+		First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("优惠码不存在")
+		}
+		return fmt.Errorf("查询优惠券失败: %w", err)
+	}
+
+	if err := checkCoupon(tx, &coupon, order.UserID, order.BillingPackageID, order.OriginalAmount); err != nil {
+		return err
+	}
+	quote := quoteCoupon(&coupon, order.OriginalAmount)
+
+	if err := tx.Model(&coupon).
+		Update("redeemed_count", gorm.Expr("redeemed_count + 1")).Error; err != nil {
+		return fmt.Errorf("更新优惠券使用次数失败: %w", err)
+	}
+
+	order.CouponCode = coupon.Code
+	order.DiscountAmount = quote.DiscountAmount
+	order.Amount = quote.FinalAmount
+	order.BonusCredits = quote.BonusCredits
+
+	redemption := &model.CouponRedemption{
+		CouponID:       coupon.ID,
+		Code:           coupon.Code,
+		UserID:         order.UserID,
+		OrderID:        order.ID,
+		OriginalAmount: quote.OriginalAmount,
+		DiscountAmount: quote.DiscountAmount,
+		BonusCredits:   quote.BonusCredits,
+		Status:         model.CouponRedemptionPending,
+	}
+	if order.Amount == 0 {
+		now := time.Now()
+		redemption.Status = model.CouponRedemptionRedeemed
+		redemption.RedeemedAt = &now
+	}
+	if err := tx.Create(redemption).Error; err != nil {
+		return fmt.Errorf("记录优惠券使用失败: %w", err)
+	}
+	return nil
+}
+
+// lockOrderRedemption 锁定订单的优惠券使用记录，订单未使用优惠券时返回 nil
+func lockOrderRedemption(tx *gorm.DB, order *model.Order) (*model.CouponRedemption, error) {
+	if order.CouponCode == "" {
+		return nil, nil
+	}
+	var redemptions []model.CouponRedemption
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ?", order.ID).
+		Limit(1).
+		Find(&redemptions).Error; err != nil {
+		return nil, fmt.Errorf("查询优惠券使用记录失败: %w", err)
+	}
+	if len(redemptions) == 0 {
+		return nil, nil
+	}
+	return &redemptions[0], nil
+}
+
+// confirmCouponRedemptionInTx 订单支付后确认优惠券使用
+// 订单取消后才收到支付回调时，已释放的使用次数重新占用（用户已付款，不受次数上限限制）
+func confirmCouponRedemptionInTx(tx *gorm.DB, order *model.Order) error {
+	redemption, err := lockOrderRedemption(tx, order)
+	if err != nil || redemption == nil || redemption.Status == model.CouponRedemptionRedeemed {
+		return err
+	}
+
+	if redemption.Status == model.CouponRedemptionReleased {
+		if err := tx.Model(&model.Coupon{}).Where("id = ?", redemption.CouponID).
+			Update("redeemed_count", gorm.Expr("redeemed_count + 1")).Error; err != nil {
+			return fmt.Errorf("更新优惠券使用次数失败: %w", err)
+		}
+	}
+
+	now := time.Now()
+	return tx.Model(redemption).Updates(map[string]interface{}{
+		"status":      model.CouponRedemptionRedeemed,
+		"redeemed_at": now,
+	}).Error
+}
+
+// releaseCouponRedemptionInTx 订单取消后释放优惠券，归还占用的使用次数
+func releaseCouponRedemptionInTx(tx *gorm.DB, order *model.Order) error {
+	redemption, err := lockOrderRedemption(tx, order)
+	if err != nil || redemption == nil || redemption.Status != model.CouponRedemptionPending {
+		return err
+	}
+
+	if err := tx.Model(&model.Coupon{}).Where("id = ? AND redeemed_count > 0", redemption.CouponID).
+		Update("redeemed_count", gorm.Expr("redeemed_count - 1")).Error; err != nil {
+		return fmt.Errorf("更新优惠券使用次数失败: %w", err)
+	}
+
+	now := time.Now()
+	return tx.Model(redemption).Updates(map[string]interface{}{
+		"status":      model.CouponRedemptionReleased,
+		"released_at": now,
+	}).Error
+}
@@ -0,0 +1,97 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"server/model"
+)
+
+// MockProviderName 模拟支付渠道名称
+const MockProviderName = "mock"
+
+// mockSignatureHeader 模拟回调签名请求头
+const mockSignatureHeader = "X-Mock-Signature"
+
+// MockProvider 模拟支付渠道，仅用于本地测试
+// 回调报文使用 HMAC-SHA256 签名，与真实渠道一样经过签名校验后才会处理
+type MockProvider struct {
+	Secret string
+}
+
+// mockWebhookPayload 模拟回调报文
+type mockWebhookPayload struct {
+	EventID string `json:"event_id"`
+	Type    string `json:"type"`
+	OrderID string `json:"order_id"`
+	TradeNo string `json:"trade_no"`
+	Amount  int64  `json:"amount"`
+}
+
+func (p *MockProvider) Name() string {
+	return MockProviderName
+}
+
+// CreatePayment 模拟支付无需跳转，前端调用模拟支付接口完成支付
+func (p *MockProvider) CreatePayment(order *model.Order) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		Provider: MockProviderName,
+		PayURL:   fmt.Sprintf("/api/orders/%s/mock-pay", order.ID),
+		Params: map[string]interface{}{
+			"order_id": order.ID,
+			"amount":   order.Amount,
+		},
+	}, nil
+}
+
+// VerifyWebhook 校验模拟回调签名
+func (p *MockProvider) VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error) {
+	signature := header.Get(mockSignatureHeader)
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(p.sign(body))) {
+		return nil, errors.New("回调签名错误")
+	}
+
+	var payload mockWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("回调报文格式错误: %w", err)
+	}
+
+	return &WebhookEvent{
+		EventID:         payload.EventID,
+		Type:            WebhookEventType(payload.Type),
+		OrderID:         payload.OrderID,
+		ProviderTradeNo: payload.TradeNo,
+		Amount:          payload.Amount,
+	}, nil
+}
+
+// BuildWebhook 生成一条已签名的模拟回调（请求头和报文）
+func (p *MockProvider) BuildWebhook(order *model.Order, eventType WebhookEventType) (http.Header, []byte, error) {
+	now := time.Now().UnixNano()
+	body, err := json.Marshal(mockWebhookPayload{
+		EventID: fmt.Sprintf("mock_evt_%d", now),
+		Type:    string(eventType),
+		OrderID: order.ID,
+		TradeNo: "mock_" + order.ID,
+		Amount:  order.Amount,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(mockSignatureHeader, p.sign(body))
+	return header, body, nil
+}
+
+func (p *MockProvider) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
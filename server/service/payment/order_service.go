@@ -0,0 +1,485 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"server/global"
+	"server/model"
+	"server/service/billing"
+	"server/service/eventlog"
+	"server/utils"
+)
+
+type orderService struct{}
+
+var OrderService = &orderService{}
+
+// defaultOrderExpireMinutes 未配置时未支付订单的自动取消时间
+const defaultOrderExpireMinutes = 30
+
+// CouponProviderName 优惠后金额为0、无需付款的订单使用的支付渠道标识
+const CouponProviderName = "coupon"
+
+// CreateOrder 创建套餐购买订单并发起支付
+func (s *orderService) CreateOrder(userID string, req *CreateOrderRequest) (*CreateOrderResponse, error) {
+	providerName := req.Provider
+	if providerName == "" {
+		providerName = global.CONFIG.Payment.DefaultProvider
+	}
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg model.BillingPackage
+	if err := global.DB.First(&pkg, req.PackageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("套餐不存在")
+		}
+		return nil, errors.New("查询套餐失败")
+	}
+	if !pkg.IsActive || !pkg.IsVisible {
+		return nil, errors.New("套餐已下架")
+	}
+
+	// 套餐价格以分为单位存储
+	amount := int64(math.Round(pkg.Price))
+	if amount <= 0 {
+		return nil, errors.New("免费套餐无需购买")
+	}
+
+	expireMinutes := global.CONFIG.Payment.OrderExpireMinutes
+	if expireMinutes <= 0 {
+		expireMinutes = defaultOrderExpireMinutes
+	}
+
+	order := &model.Order{
+		ID:               utils.GenerateTLID(),
+		UserID:           userID,
+		BillingPackageID: pkg.ID,
+		PackageName:      pkg.Name,
+		Amount:           amount,
+		OriginalAmount:   amount,
+		Status:           model.OrderStatusCreated,
+		Provider:         provider.Name(),
+		ExpiresAt:        time.Now().Add(time.Duration(expireMinutes) * time.Minute),
+	}
+	err = global.DB.Transaction(func(tx *gorm.DB) error {
+		if req.CouponCode != "" {
+			if err := redeemCouponInTx(tx, req.CouponCode, order); err != nil {
+				return err
+			}
+		}
+		// 优惠后无需付款的订单直接标记为已支付
+		if order.Amount == 0 {
+			now := time.Now()
+			order.Provider = CouponProviderName
+			order.Status = model.OrderStatusPaid
+			order.PaidAt = &now
+		}
+		if err := tx.Create(order).Error; err != nil {
+			return errors.New("创建订单失败")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logPaymentEvent(eventlog.EventOrderCreate, order, eventlog.StatusSuccess, "",
+		map[string]interface{}{"coupon_code": order.CouponCode, "discount_amount": order.DiscountAmount})
+
+	if order.Status == model.OrderStatusPaid {
+		if err := s.fulfillOrder(order.ID); err != nil {
+			return nil, fmt.Errorf("发放套餐失败: %w", err)
+		}
+		fulfilled, err := s.GetOrder(userID, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &CreateOrderResponse{Order: fulfilled}, nil
+	}
+
+	intent, err := provider.CreatePayment(order)
+	if err != nil {
+		return nil, fmt.Errorf("发起支付失败: %w", err)
+	}
+
+	return &CreateOrderResponse{Order: order, Payment: intent}, nil
+}
+
+// GetOrder 获取用户订单详情
+func (s *orderService) GetOrder(userID, orderID string) (*model.Order, error) {
+	var order model.Order
+	if err := global.DB.Where("id = ? AND user_id = ?", orderID, userID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("订单不存在")
+		}
+		return nil, errors.New("查询订单失败")
+	}
+	return &order, nil
+}
+
+// ListOrders 分页查询订单
+func (s *orderService) ListOrders(req *OrderQueryRequest) (*OrderQueryResponse, error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	if req.PageSize > 100 {
+		req.PageSize = 100
+	}
+
+	query := global.DB.Model(&model.Order{})
+	if req.UserID != "" {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, errors.New("统计订单失败: " + err.Error())
+	}
+
+	var orders []model.Order
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("created_at DESC").
+		Limit(req.PageSize).
+		Offset(offset).
+		Find(&orders).Error; err != nil {
+		return nil, errors.New("查询订单失败: " + err.Error())
+	}
+
+	return &OrderQueryResponse{
+		List:     orders,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, nil
+}
+
+// CancelOrder 用户取消未支付的订单
+func (s *orderService) CancelOrder(userID, orderID string) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.UserID != userID {
+			return errors.New("订单不存在")
+		}
+		if order.Status == model.OrderStatusCancelled {
+			return nil
+		}
+		if order.Status != model.OrderStatusCreated {
+			return errors.New("订单已支付，无法取消")
+		}
+		return cancelOrderInTx(tx, order)
+	})
+}
+
+// cancelOrderInTx 在给定事务中取消已锁定的未支付订单，并释放订单占用的优惠券
+func cancelOrderInTx(tx *gorm.DB, order *model.Order) error {
+	now := time.Now()
+	if err := tx.Model(order).Updates(map[string]interface{}{
+		"status":       model.OrderStatusCancelled,
+		"cancelled_at": now,
+	}).Error; err != nil {
+		return fmt.Errorf("更新订单状态失败: %w", err)
+	}
+	return releaseCouponRedemptionInTx(tx, order)
+}
+
+// HandleWebhook 处理支付渠道回调
+// 渠道可能重复投递同一回调，所有状态变更都是幂等的：订单只会被标记支付一次、履约一次
+func (s *orderService) HandleWebhook(providerName string, header http.Header, body []byte) error {
+	provider, err := GetProvider(providerName)
+	if err != nil {
+		return err
+	}
+
+	event, err := provider.VerifyWebhook(header, body)
+	if err != nil {
+		logPaymentEvent(eventlog.EventPaymentFailed, &model.Order{Provider: providerName}, eventlog.StatusFailed,
+			"回调校验失败: "+err.Error(), nil)
+		return err
+	}
+
+	switch event.Type {
+	case WebhookEventPaid:
+		if err := s.markPaid(provider.Name(), event); err != nil {
+			return err
+		}
+		return s.fulfillOrder(event.OrderID)
+	case WebhookEventRefunded:
+		return s.markRefunded(provider.Name(), event)
+	case WebhookEventFailed:
+		logPaymentEvent(eventlog.EventPaymentFailed, &model.Order{ID: event.OrderID, Provider: providerName},
+			eventlog.StatusFailed, "支付失败", map[string]interface{}{"event_id": event.EventID})
+		return nil
+	default:
+		return fmt.Errorf("未知的回调事件类型: %s", event.Type)
+	}
+}
+
+// SimulateMockPayment 通过模拟渠道完成订单支付（仅在启用模拟支付时可用）
+// 生成签名回调后走与真实渠道相同的回调处理流程
+func (s *orderService) SimulateMockPayment(userID, orderID string) (*model.Order, error) {
+	provider, err := GetProvider(MockProviderName)
+	if err != nil {
+		return nil, errors.New("模拟支付未启用")
+	}
+	mock, ok := provider.(*MockProvider)
+	if !ok {
+		return nil, errors.New("模拟支付未启用")
+	}
+
+	order, err := s.GetOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Provider != MockProviderName {
+		return nil, errors.New("该订单不是模拟支付订单")
+	}
+
+	header, body, err := mock.BuildWebhook(order, WebhookEventPaid)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.HandleWebhook(MockProviderName, header, body); err != nil {
+		return nil, err
+	}
+
+	return s.GetOrder(userID, orderID)
+}
+
+// markPaid 将订单标记为已支付
+func (s *orderService) markPaid(providerName string, event *WebhookEvent) error {
+	var paidOrder *model.Order
+	err := global.DB.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, event.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.Provider != providerName {
+			return errors.New("订单支付渠道不匹配")
+		}
+		if event.Amount != order.Amount {
+			return fmt.Errorf("支付金额不匹配: 订单 %d 分，实付 %d 分", order.Amount, event.Amount)
+		}
+
+		switch order.Status {
+		case model.OrderStatusPaid, model.OrderStatusFulfilled, model.OrderStatusRefunded:
+			// 重复回调
+			return nil
+		}
+
+		// 已取消的订单收到支付成功回调时仍按已支付处理，保证用户付款后能拿到套餐
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":  model.OrderStatusPaid,
+			"paid_at": now,
+		}
+		if event.ProviderTradeNo != "" {
+			updates["provider_trade_no"] = event.ProviderTradeNo
+		}
+		if err := tx.Model(order).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新订单状态失败: %w", err)
+		}
+		if err := confirmCouponRedemptionInTx(tx, order); err != nil {
+			return err
+		}
+		paidOrder = order
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if paidOrder != nil {
+		logPaymentEvent(eventlog.EventPaymentSuccess, paidOrder, eventlog.StatusSuccess, "",
+			map[string]interface{}{"event_id": event.EventID, "trade_no": event.ProviderTradeNo})
+	}
+	return nil
+}
+
+// fulfillOrder 为已支付订单发放套餐
+// 订单行锁保证并发回调时只有一个能完成履约，user_billing_packages.order_id 唯一索引兜底
+func (s *orderService) fulfillOrder(orderID string) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status != model.OrderStatusPaid {
+			return nil
+		}
+
+		userPackageService := &billing.UserPackageService{}
+		userPackage, err := userPackageService.AssignBillingPackageInTx(
+			tx,
+			order.UserID,
+			order.BillingPackageID,
+			billing.PackageSourcePurchase,
+			"购买订单 "+order.ID,
+			&order.ID,
+			true, // 自动激活
+		)
+		if err != nil {
+			return fmt.Errorf("发放套餐失败: %w", err)
+		}
+		if err := userPackageService.GrantBonusCreditsInTx(tx, userPackage.ID, order.BonusCredits,
+			"优惠券 "+order.CouponCode+" 赠送积分"); err != nil {
+			return fmt.Errorf("发放赠送积分失败: %w", err)
+		}
+
+		now := time.Now()
+		return tx.Model(order).Updates(map[string]interface{}{
+			"status":                  model.OrderStatusFulfilled,
+			"fulfilled_at":            now,
+			"user_billing_package_id": userPackage.ID,
+		}).Error
+	})
+}
+
+// markRefunded 将订单标记为已退款，已履约的订单同时收回套餐剩余积分
+// 已通过套餐取消部分退款的订单（已记录退款金额），回调视为该笔退款的渠道确认，不再处理
+func (s *orderService) markRefunded(providerName string, event *WebhookEvent) error {
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		order, err := lockOrder(tx, event.OrderID)
+		if err != nil {
+			return err
+		}
+		if order.Provider != providerName {
+			return errors.New("订单支付渠道不匹配")
+		}
+
+		switch order.Status {
+		case model.OrderStatusRefunded:
+			return nil
+		case model.OrderStatusCreated, model.OrderStatusCancelled:
+			return errors.New("订单未支付，无法退款")
+		}
+		if order.RefundAmount > 0 {
+			return nil
+		}
+
+		if order.UserBillingPackageID != nil {
+			userPackageService := &billing.UserPackageService{}
+			if err := userPackageService.RevokeUserPackageInTx(tx, *order.UserBillingPackageID, order.ID, order.Amount, "订单退款 "+order.ID); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		return tx.Model(order).Updates(map[string]interface{}{
+			"status":        model.OrderStatusRefunded,
+			"refunded_at":   now,
+			"refund_amount": order.Amount,
+		}).Error
+	})
+}
+
+// CancelExpiredOrders 取消超时未支付的订单，逐单处理以释放订单占用的优惠券
+func (s *orderService) CancelExpiredOrders() (int64, error) {
+	var orderIDs []string
+	if err := global.DB.Model(&model.Order{}).
+		Where("status = ? AND expires_at < ?", model.OrderStatusCreated, time.Now()).
+		Pluck("id", &orderIDs).Error; err != nil {
+		return 0, err
+	}
+
+	var cancelled int64
+	for _, orderID := range orderIDs {
+		changed := false
+		err := global.DB.Transaction(func(tx *gorm.DB) error {
+			order, err := lockOrder(tx, orderID)
+			if err != nil {
+				return err
+			}
+			// 加锁后重新确认，期间可能已收到支付回调
+			if order.Status != model.OrderStatusCreated {
+				return nil
+			}
+			changed = true
+			return cancelOrderInTx(tx, order)
+		})
+		if err != nil {
+			return cancelled, err
+		}
+		if changed {
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+// FulfillPaidOrders 补偿履约：为已支付但履约失败的订单重新发放套餐
+func (s *orderService) FulfillPaidOrders() (int, error) {
+	var orderIDs []string
+	if err := global.DB.Model(&model.Order{}).
+		Where("status = ?", model.OrderStatusPaid).
+		Pluck("id", &orderIDs).Error; err != nil {
+		return 0, err
+	}
+
+	fulfilled := 0
+	for _, orderID := range orderIDs {
+		if err := s.fulfillOrder(orderID); err != nil {
+			fmt.Printf("订单补偿履约失败: order_id=%s, err=%v\n", orderID, err)
+			continue
+		}
+		fulfilled++
+	}
+	return fulfilled, nil
+}
+
+// lockOrder 锁定订单行
+func lockOrder(tx *gorm.DB, orderID string) (*model.Order, error) {
+	var order model.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", orderID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("订单不存在")
+		}
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+	return &order, nil
+}
+
+// logPaymentEvent 记录订单支付相关事件
+func logPaymentEvent(eventType string, order *model.Order, status, errorMessage string, extra map[string]interface{}) {
+	details := map[string]interface{}{
+		"provider": order.Provider,
+		"amount":   order.Amount,
+	}
+	for k, v := range extra {
+		details[k] = v
+	}
+	detailsJSON, _ := json.Marshal(details)
+
+	global.EventLog.Log(context.Background(), &model.EventLog{
+		UserID:        order.UserID,
+		EventType:     eventType,
+		EventCategory: eventlog.CategoryPayment,
+		ResourceType:  "order",
+		ResourceID:    order.ID,
+		Status:        status,
+		ErrorMessage:  errorMessage,
+		Details:       model.JSON(detailsJSON),
+	})
+}
@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"server/model"
+)
+
+// PaymentProvider 支付渠道接口
+// 新增支付渠道时实现该接口，并在初始化时通过 RegisterProvider 注册
+type PaymentProvider interface {
+	// Name 渠道名称，用于订单记录和回调路由（/api/payment/webhook/:provider）
+	Name() string
+
+	// CreatePayment 为订单发起支付，返回前端拉起支付所需的信息
+	CreatePayment(order *model.Order) (*PaymentIntent, error)
+
+	// VerifyWebhook 校验回调签名并解析支付结果，签名无效时必须返回错误
+	VerifyWebhook(header http.Header, body []byte) (*WebhookEvent, error)
+}
+
+// PaymentIntent 发起支付的结果
+type PaymentIntent struct {
+	Provider string                 `json:"provider"`
+	PayURL   string                 `json:"pay_url,omitempty"` // 支付跳转地址或二维码内容
+	Params   map[string]interface{} `json:"params,omitempty"`  // 渠道特定的支付参数
+}
+
+// WebhookEventType 回调事件类型
+type WebhookEventType string
+
+const (
+	WebhookEventPaid     WebhookEventType = "paid"     // 支付成功
+	WebhookEventFailed   WebhookEventType = "failed"   // 支付失败
+	WebhookEventRefunded WebhookEventType = "refunded" // 已退款
+)
+
+// WebhookEvent 渠道回调解析后的统一事件
+type WebhookEvent struct {
+	EventID         string           // 渠道事件ID（仅用于日志）
+	Type            WebhookEventType // 事件类型
+	OrderID         string           // 商户订单号
+	ProviderTradeNo string           // 渠道交易号
+	Amount          int64            // 实际支付金额（分）
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]PaymentProvider)
+)
+
+// RegisterProvider 注册支付渠道
+func RegisterProvider(provider PaymentProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[provider.Name()] = provider
+}
+
+// GetProvider 获取支付渠道
+func GetProvider(name string) (PaymentProvider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的支付渠道: %s", name)
+	}
+	return provider, nil
+}
@@ -0,0 +1,152 @@
+package payment
+
+import (
+	"time"
+
+	"server/model"
+	"server/service/billing"
+)
+
+// CreateOrderRequest 创建订单请求
+type CreateOrderRequest struct {
+	PackageID  int64  `json:"package_id" binding:"required"`
+	Provider   string `json:"provider"`    // 支付渠道，为空时使用默认渠道
+	CouponCode string `json:"coupon_code"` // 优惠码（可选）
+}
+
+// CreateOrderResponse 创建订单响应
+type CreateOrderResponse struct {
+	Order   *model.Order   `json:"order"`
+	Payment *PaymentIntent `json:"payment"` // 优惠后金额为0的订单直接履约，不发起支付
+}
+
+// OrderQueryRequest 订单查询请求
+type OrderQueryRequest struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	UserID   string `form:"user_id"`
+	Status   string `form:"status"`
+}
+
+// OrderQueryResponse 订单查询响应
+type OrderQueryResponse struct {
+	List     []model.Order `json:"list"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// CouponRequest 优惠券配置（创建与更新共用）
+type CouponRequest struct {
+	Name           string     `json:"name" binding:"required,max=100"`
+	Description    string     `json:"description"`
+	DiscountType   string     `json:"discount_type" binding:"required,oneof=percent fixed none"`
+	DiscountValue  int64      `json:"discount_value" binding:"min=0"`
+	MaxDiscount    int64      `json:"max_discount" binding:"min=0"`
+	MinAmount      int64      `json:"min_amount" binding:"min=0"`
+	BonusCredits   int        `json:"bonus_credits" binding:"min=0"`
+	PackageIDs     []int64    `json:"package_ids"`
+	MaxRedemptions int        `json:"max_redemptions" binding:"min=0"`
+	PerUserLimit   int        `json:"per_user_limit" binding:"min=0"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	IsActive       bool       `json:"is_active"`
+}
+
+// CreateCouponRequest 创建优惠券请求
+type CreateCouponRequest struct {
+	Code string `json:"code" binding:"omitempty,max=32,alphanum"` // 为空时自动生成
+	CouponRequest
+}
+
+// CouponQueryRequest 优惠券查询请求
+type CouponQueryRequest struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Keyword  string `form:"keyword"`   // 按优惠码或名称搜索
+	IsActive *bool  `form:"is_active"` // 是否启用
+}
+
+// CouponQueryResponse 优惠券查询响应
+type CouponQueryResponse struct {
+	List     []model.Coupon `json:"list"`
+	Total    int64          `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// CouponRedemptionQueryRequest 优惠券使用记录查询请求
+type CouponRedemptionQueryRequest struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	CouponID int64  `form:"coupon_id"`
+	UserID   string `form:"user_id"`
+	Status   string `form:"status"`
+}
+
+// CouponRedemptionQueryResponse 优惠券使用记录查询响应
+type CouponRedemptionQueryResponse struct {
+	List     []model.CouponRedemption `json:"list"`
+	Total    int64                    `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"page_size"`
+}
+
+// CouponStatsResponse 优惠券使用统计
+type CouponStatsResponse struct {
+	Coupon            *model.Coupon `json:"coupon"`
+	PendingCount      int64         `json:"pending_count"`       // 待支付订单占用次数
+	RedeemedCount     int64         `json:"redeemed_count"`      // 已支付订单使用次数
+	ReleasedCount     int64         `json:"released_count"`      // 订单取消后释放的次数
+	UniqueUsers       int64         `json:"unique_users"`        // 已使用的用户数
+	TotalDiscount     int64         `json:"total_discount"`      // 已使用的累计减免金额（分）
+	TotalPaidAmount   int64         `json:"total_paid_amount"`   // 已使用订单的累计实付金额（分）
+	TotalBonusCredits int64         `json:"total_bonus_credits"` // 已使用的累计赠送积分
+}
+
+// PreviewCouponRequest 优惠券试算请求
+type PreviewCouponRequest struct {
+	Code      string `json:"code" binding:"required"`
+	PackageID int64  `json:"package_id" binding:"required"`
+}
+
+// CouponQuote 优惠券试算结果
+type CouponQuote struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	OriginalAmount int64  `json:"original_amount"` // 原价（分）
+	DiscountAmount int64  `json:"discount_amount"` // 减免金额（分）
+	FinalAmount    int64  `json:"final_amount"`    // 应付金额（分）
+	BonusCredits   int    `json:"bonus_credits"`   // 赠送积分
+}
+
+// CancelPackageRequest 取消用户套餐请求
+type CancelPackageRequest struct {
+	Reason       string `json:"reason" binding:"required"`
+	RefundAmount *int64 `json:"refund_amount" binding:"omitempty,min=0"` // 实际退款金额（分），为空时按折算金额退款，不能超过实付金额
+}
+
+// CancellationQuote 取消用户套餐的退款试算结果
+type CancellationQuote struct {
+	UserBillingPackageID int64   `json:"user_billing_package_id"`
+	PackageName          string  `json:"package_name"`
+	OrderID              *string `json:"order_id,omitempty"`
+	RemainingCredits     int     `json:"remaining_credits"`
+	*billing.RefundProration
+}
+
+// CancellationQueryRequest 套餐取消记录查询请求
+type CancellationQueryRequest struct {
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	UserID   string `form:"user_id"`
+	OrderID  string `form:"order_id"`
+}
+
+// CancellationQueryResponse 套餐取消记录查询响应
+type CancellationQueryResponse struct {
+	List     []model.PackageCancellation `json:"list"`
+	Total    int64                       `json:"total"`
+	Page     int                         `json:"page"`
+	PageSize int                         `json:"page_size"`
+}
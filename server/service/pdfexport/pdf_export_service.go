@@ -14,6 +14,7 @@ import (
 
 	"server/global"
 	"server/model"
+	"server/service/billing"
 	"server/utils"
 
 	"github.com/google/uuid"
@@ -22,19 +23,7 @@ import (
 // CreateExportTask 创建PDF导出任务
 // resumeDataSnapshot: 可选的简历数据快照，如果提供则使用该数据，否则从数据库查询
 func CreateExportTask(userID, resumeID string, resumeDataSnapshot map[string]interface{}) (string, error) {
-	// 1. 检查速率限制：同一用户15秒内不能创建多个任务
-	var lastTask model.PdfExportTask
-	if err := global.DB.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		First(&lastTask).Error; err == nil {
-		// 找到了最近的任务，检查时间间隔
-		timeSinceLastTask := time.Since(lastTask.CreatedAt)
-		if timeSinceLastTask < 15*time.Second {
-			remainingTime := 15*time.Second - timeSinceLastTask
-			return "", fmt.Errorf("操作过于频繁，请在 %d 秒后重试", int(remainingTime.Seconds())+1)
-		}
-	}
-	// 如果没有找到记录（err != nil），说明是首次创建，直接继续
+	// 1. 导出频率由动作使用配额（pdf_export）限制，在预扣积分时检查
 
 	// 2. 获取简历数据
 	var resumeDataBytes []byte
@@ -66,26 +55,34 @@ func CreateExportTask(userID, resumeID string, resumeDataSnapshot map[string]int
 	taskID := utils.GenerateTLID()
 	token := uuid.New().String()
 
-	// 5. 创建任务记录 (status=pending)，保存简历数据快照
+	// 5. 预扣导出积分，PDF生成成功后结算，失败时释放
+	holdID, err := billing.ReserveForAction(userID, billing.ActionPDFExport, "pdf_export_task", taskID, 10*time.Minute)
+	if err != nil {
+		return "", err
+	}
+
+	// 6. 创建任务记录 (status=pending)，保存简历数据快照
 	task := model.PdfExportTask{
-		ID:         taskID,
-		UserID:     userID,
-		ResumeID:   resumeID,
-		ResumeData: resumeDataBytes, // 保存简历数据快照
-		Status:     model.PdfExportStatusPending,
-		Token:      token,
-		TokenUsed:  false,
-		CreatedAt:  time.Now(),
+		ID:           taskID,
+		UserID:       userID,
+		ResumeID:     resumeID,
+		ResumeData:   resumeDataBytes, // 保存简历数据快照
+		Status:       model.PdfExportStatusPending,
+		Token:        token,
+		TokenUsed:    false,
+		CreatedAt:    time.Now(),
+		CreditHoldID: holdID,
 	}
 
 	if err := global.DB.Create(&task).Error; err != nil {
+		billing.ReleaseCreditHold(userID, holdID, "创建导出任务失败")
 		return "", fmt.Errorf("创建任务记录失败: %w", err)
 	}
 
 	log.Printf("PDF导出任务创建成功: task_id=%s, resume_id=%s, 数据大小=%d bytes",
 		taskID, resumeID, len(resumeDataBytes))
 
-	// 6. 异步调用PDF生成
+	// 7. 异步调用PDF生成
 	go GeneratePdfAsync(taskID)
 
 	return taskID, nil
@@ -173,6 +170,11 @@ func GeneratePdfAsync(taskID string) {
 		return
 	}
 
+	// 10. 结算预扣积分
+	if err := billing.CommitCreditHold(task.UserID, task.CreditHoldID); err != nil {
+		log.Printf("结算导出预扣失败: task_id=%s, error=%v", taskID, err)
+	}
+
 	log.Printf("PDF生成成功: task_id=%s", taskID)
 }
 
@@ -270,7 +272,7 @@ func VerifyTokenAndGetResume(taskID, token string) (map[string]interface{}, erro
 	return resumeData, nil
 }
 
-// updateTaskFailed 更新任务为失败状态
+// updateTaskFailed 更新任务为失败状态，并释放导出预扣积分
 func updateTaskFailed(taskID string, errorMessage string) {
 	log.Printf("任务失败: task_id=%s, error=%s", taskID, errorMessage)
 	global.DB.Model(&model.PdfExportTask{}).Where("id = ?", taskID).
@@ -278,4 +280,46 @@ func updateTaskFailed(taskID string, errorMessage string) {
 			"status":        model.PdfExportStatusFailed,
 			"error_message": errorMessage,
 		})
+
+	var task model.PdfExportTask
+	if err := global.DB.Select("id", "user_id", "credit_hold_id").Where("id = ?", taskID).First(&task).Error; err != nil {
+		return
+	}
+	if err := billing.ReleaseCreditHold(task.UserID, task.CreditHoldID, "PDF导出失败: "+errorMessage); err != nil {
+		log.Printf("释放导出预扣失败: task_id=%s, error=%v", taskID, err)
+	}
+}
+
+// CleanOldPdfFiles 删除超过保留天数的PDF文件目录（uploads/pdf/YYYY-MM-DD）
+// 返回删除的目录数量
+func CleanOldPdfFiles(retentionDays int) (int, error) {
+	pdfRoot := filepath.Join("uploads", "pdf")
+	entries, err := os.ReadDir(pdfRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取PDF目录失败: %w", err)
+	}
+
+	now := time.Now()
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -retentionDays)
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		// 只处理按日期命名的目录
+		date, err := time.ParseInLocation("2006-01-02", entry.Name(), time.Local)
+		if err != nil || !date.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(pdfRoot, entry.Name())); err != nil {
+			log.Printf("删除过期PDF目录失败: dir=%s, err=%v", entry.Name(), err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
 }
@@ -0,0 +1,132 @@
+package resume
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+	"server/service/jobqueue"
+)
+
+// 简历转换的异步任务类型
+const (
+	JobTypeFileToText = "resume_file_to_text"
+	JobTypeStructure  = "resume_structure"
+	JobTypeIngest     = "resume_ingest"
+)
+
+// resumeJobPayload 简历转换任务参数
+type resumeJobPayload struct {
+	ResumeID string `json:"resume_id"`
+}
+
+// SubmitFileToText 提交简历文件转文本任务，返回任务记录，可通过任务ID查询进度
+func (s *resumeService) SubmitFileToText(userID, resumeID string) (*model.WorkflowJob, error) {
+	resume, err := s.getUserResumeRecord(userID, resumeID)
+	if err != nil {
+		return nil, err
+	}
+	if resume.FileID == nil {
+		return nil, errors.New("简历没有文件")
+	}
+	return submitResumeJob(JobTypeFileToText, "doc_extract", userID, resumeID)
+}
+
+// SubmitStructure 提交简历文本结构化任务，返回任务记录，可通过任务ID查询进度
+func (s *resumeService) SubmitStructure(userID, resumeID string) (*model.WorkflowJob, error) {
+	resume, err := s.getUserResumeRecord(userID, resumeID)
+	if err != nil {
+		return nil, err
+	}
+	if resume.TextContent == "" {
+		return nil, errors.New("简历内容不能为空")
+	}
+	return submitResumeJob(JobTypeStructure, "resume_structure", userID, resumeID)
+}
+
+// SubmitIngest 提交简历解析任务：有文件时先提取文本，再结构化，返回任务记录，可通过任务ID查询进度
+func (s *resumeService) SubmitIngest(userID, resumeID string) (*model.WorkflowJob, error) {
+	resume, err := s.getUserResumeRecord(userID, resumeID)
+	if err != nil {
+		return nil, err
+	}
+	if resume.FileID == nil && resume.TextContent == "" {
+		return nil, errors.New("简历没有文件和文本内容")
+	}
+	return submitResumeJob(JobTypeIngest, "resume_structure", userID, resumeID)
+}
+
+// RunFileToTextJob 执行简历文件转文本任务
+func (s *resumeService) RunFileToTextJob(ctx context.Context, job *jobqueue.Job) (interface{}, error) {
+	var payload resumeJobPayload
+	if err := job.Bind(&payload); err != nil {
+		return nil, err
+	}
+	job.Progress(10, "正在提取简历文本")
+	if err := s.ResumeFileToText(ctx, job.UserID, payload.ResumeID); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// RunStructureJob 执行简历文本结构化任务
+func (s *resumeService) RunStructureJob(ctx context.Context, job *jobqueue.Job) (interface{}, error) {
+	var payload resumeJobPayload
+	if err := job.Bind(&payload); err != nil {
+		return nil, err
+	}
+	job.Progress(10, "正在结构化简历内容")
+	if err := s.StructureTextToJSON(ctx, job.UserID, payload.ResumeID); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// RunIngestJob 执行简历解析任务，按简历解析流水线提取文本（有文件时）并结构化
+func (s *resumeService) RunIngestJob(ctx context.Context, job *jobqueue.Job) (interface{}, error) {
+	var payload resumeJobPayload
+	if err := job.Bind(&payload); err != nil {
+		return nil, err
+	}
+
+	var resume model.ResumeRecord
+	if err := global.DB.Where("id = ?", payload.ResumeID).First(&resume).Error; err != nil {
+		return nil, errors.New("查询简历失败")
+	}
+	job.Progress(10, "正在解析简历")
+	if err := s.runIngestPipeline(ctx, &resume, resume.FileID != nil, true); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// getUserResumeRecord 查询用户自己的简历
+func (s *resumeService) getUserResumeRecord(userID, resumeID string) (*model.ResumeRecord, error) {
+	var resume model.ResumeRecord
+	if err := global.DB.Where("id = ? AND user_id = ?", resumeID, userID).First(&resume).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("简历不存在")
+		}
+		return nil, errors.New("查询简历失败")
+	}
+	return &resume, nil
+}
+
+// submitResumeJob 按工作流名称提交简历转换任务，同一简历同一类型未完成的任务不会重复提交
+func submitResumeJob(jobType, workflowName, userID, resumeID string) (*model.WorkflowJob, error) {
+	var workflow model.Workflow
+	if err := global.DB.Where("name = ?", workflowName).First(&workflow).Error; err != nil {
+		return nil, errors.New("查询工作流失败")
+	}
+
+	return jobqueue.JobQueueService.Submit(jobqueue.SubmitRequest{
+		Type:       jobType,
+		UserID:     userID,
+		WorkflowID: workflow.ID,
+		ResourceID: resumeID,
+		Payload:    resumeJobPayload{ResumeID: resumeID},
+	})
+}
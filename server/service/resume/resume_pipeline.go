@@ -0,0 +1,127 @@
+package resume
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"server/global"
+	"server/model"
+	appService "server/service/app"
+)
+
+// IngestPipelineName 简历解析流水线名称，管理员可创建同名流水线替换内置步骤
+// 流水线输入：doc_file（简历文件，可选）、text_content（已有文本）、structure（是否结构化）；
+// 输出：text_content（简历文本）、structured_data（结构化数据）
+const IngestPipelineName = "resume_ingest"
+
+// defaultIngestPipeline 内置的简历解析流水线
+// extract：有文件时用 doc_extract 提取文本；structure：需要结构化时用 resume_structure 处理提取的文本（或已有文本）
+func defaultIngestPipeline() *appService.Pipeline {
+	return &appService.Pipeline{
+		Name: IngestPipelineName,
+		Steps: []model.PipelineStep{
+			{
+				Name:     "extract",
+				Workflow: "doc_extract",
+				Inputs:   map[string]interface{}{"doc_file": "{{input.doc_file}}"},
+				Transforms: []model.PipelineTransform{
+					{Type: "strip_think", Field: "output"},
+				},
+				When: &model.PipelineCondition{Path: "input.doc_file"},
+			},
+			{
+				Name:     "structure",
+				Workflow: "resume_structure",
+				Inputs:   map[string]interface{}{"text_content": "{{steps.extract.outputs.output || input.text_content}}"},
+				Transforms: []model.PipelineTransform{
+					{Type: "strip_think", Field: "output"},
+					{Type: "extract_json", Field: "output"},
+				},
+				When: &model.PipelineCondition{Path: "input.structure", Op: "equals", Value: true},
+			},
+		},
+		Outputs: map[string]string{
+			"text_content":    "{{steps.extract.outputs.output || input.text_content}}",
+			"structured_data": "{{steps.structure.outputs.output}}",
+		},
+	}
+}
+
+// getIngestPipeline 获取简历解析流水线，未配置时使用内置定义
+func getIngestPipeline() (*appService.Pipeline, error) {
+	pipeline, err := appService.AppService.GetPipelineByName(IngestPipelineName)
+	if errors.Is(err, appService.ErrPipelineNotFound) {
+		return defaultIngestPipeline(), nil
+	}
+	return pipeline, err
+}
+
+// runIngestPipeline 运行简历解析流水线并保存结果
+// withFile 为 true 时从简历文件提取文本并保存，structure 为 true 时保存结构化数据；ctx 取消时中止流水线
+func (s *resumeService) runIngestPipeline(ctx context.Context, resume *model.ResumeRecord, withFile, structure bool) error {
+	pipeline, err := getIngestPipeline()
+	if err != nil {
+		return err
+	}
+
+	inputs := map[string]interface{}{
+		"text_content": resume.TextContent,
+		"structure":    structure,
+	}
+	if withFile {
+		file := model.File{}
+		if err := global.DB.Where("id = ?", *resume.FileID).First(&file).Error; err != nil {
+			return errors.New("查询文件失败")
+		}
+		inputs["doc_file"] = map[string]interface{}{
+			"transfer_method": "local_file",
+			"upload_file_id":  file.DifyID,
+			"type":            "document",
+		}
+	}
+
+	run, err := appService.AppService.RunPipeline(ctx, pipeline, resume.UserID, inputs)
+	if err != nil {
+		return err
+	}
+	if run.Status != model.PipelineRunStatusSucceeded {
+		return errors.New(run.ErrorMessage)
+	}
+
+	var outputs map[string]interface{}
+	if err := json.Unmarshal(run.Outputs, &outputs); err != nil {
+		return errors.New("响应格式错误")
+	}
+
+	updates := map[string]interface{}{}
+	if withFile {
+		text, ok := outputs["text_content"].(string)
+		if !ok || text == "" {
+			return errors.New("未提取到简历文本")
+		}
+		updates["text_content"] = text
+	}
+	if structure {
+		data, ok := outputs["structured_data"]
+		if !ok || data == nil {
+			return errors.New("未生成结构化数据")
+		}
+		// 流水线输出通常为 extract_json 解析后的数据，字符串结果需本身是有效的JSON
+		var structured []byte
+		if text, isString := data.(string); isString {
+			structured = []byte(text)
+		} else if structured, err = json.Marshal(data); err != nil {
+			return errors.New("结构化数据格式错误")
+		}
+		if !json.Valid(structured) {
+			return errors.New("结构化数据格式错误")
+		}
+		updates["structured_data"] = model.JSON(structured)
+	}
+
+	if err := global.DB.Model(&model.ResumeRecord{}).Where("id = ?", resume.ID).Updates(updates).Error; err != nil {
+		return errors.New("更新简历失败")
+	}
+	return nil
+}
@@ -1,21 +1,19 @@
 package resume
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"os"
-	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"gorm.io/gorm"
 
 	"server/global"
 	"server/model"
-	appService "server/service/app"
 	fileService "server/service/file"
 	"server/utils"
 )
@@ -368,13 +366,8 @@ func (s *resumeService) UploadResume(userID string, file *multipart.FileHeader)
 	return response, nil
 }
 
-func removeThinkTags(input string) string {
-	// (?s) 表示 . 匹配包括换行符在内的所有字符
-	re := regexp.MustCompile(`(?s)<think>.*?</think>`)
-	return re.ReplaceAllString(input, "")
-}
-
-func (s *resumeService) ResumeFileToText(userId string, resumeId string) error {
+// ResumeFileToText 使用简历解析流水线提取简历文件的文本
+func (s *resumeService) ResumeFileToText(ctx context.Context, userId string, resumeId string) error {
 	var resume model.ResumeRecord
 	if err := global.DB.Where("id = ?", resumeId).First(&resume).Error; err != nil {
 		return errors.New("查询简历失败")
@@ -384,72 +377,11 @@ func (s *resumeService) ResumeFileToText(userId string, resumeId string) error {
 		return errors.New("简历没有文件")
 	}
 
-	file := model.File{}
-	if err := global.DB.Where("id = ?", *resume.FileID).First(&file).Error; err != nil {
-		return errors.New("查询文件失败")
-	}
-	difyFileId := file.DifyID
-
-	workflow := model.Workflow{}
-	if err := global.DB.Where("name = ?", "doc_extract").First(&workflow).Error; err != nil {
-		return errors.New("查询工作流失败")
-	}
-
-	// doc_file is a list of map[string]any, how to fix?
-	fileInput := map[string]any{
-		"doc_file": map[string]any{
-			"transfer_method": "local_file",
-			"upload_file_id":  difyFileId,
-			"type":            "document",
-		},
-	}
-
-	// 使用新的ExecuteWorkflowAPI方法，自动处理日志记录
-	response, err := appService.AppService.ExecuteWorkflowAPI(workflow.ID, resume.UserID, fileInput)
-	if err != nil {
-		fmt.Println("[err] ", err)
-		return errors.New("工作流执行失败: " + err.Error())
-	}
-	if !response.Success {
-		fmt.Println("[response error] ", response.Message)
-		return errors.New(response.Message)
-	}
-
-	// 从响应中提取输出
-	outputs, ok := response.Data["outputs"].(map[string]interface{})
-	if !ok {
-		return errors.New("响应格式错误")
-	}
-
-	resume.TextContent = outputs["output"].(string)
-	resume.TextContent = removeThinkTags(resume.TextContent)
-	if err := global.DB.Model(&model.ResumeRecord{}).Where("id = ?", resume.ID).Updates(&resume).Error; err != nil {
-		return errors.New("更新简历表格失败")
-	}
-
-	return nil
+	return s.runIngestPipeline(ctx, &resume, true, false)
 }
 
-// 从字符串中提取可能的完整JSON子串
-func extractCompleteJSON(s string) string {
-	// 查找第一个 '{' 或 '['
-	start := strings.IndexAny(s, "{[")
-	if start == -1 {
-		return ""
-	}
-
-	// 从可能的开始位置向后扫描
-	for end := len(s); end > start; end-- {
-		candidate := s[start:end]
-		if json.Valid([]byte(candidate)) {
-			return candidate
-		}
-	}
-
-	return ""
-}
-
-func (s *resumeService) StructureTextToJSON(userId string, resumeId string) error {
+// StructureTextToJSON 使用简历解析流水线将简历文本转换为结构化数据
+func (s *resumeService) StructureTextToJSON(ctx context.Context, userId string, resumeId string) error {
 	var resume model.ResumeRecord
 	if err := global.DB.Where("id = ?", resumeId).First(&resume).Error; err != nil {
 		return errors.New("查询简历失败")
@@ -459,37 +391,7 @@ func (s *resumeService) StructureTextToJSON(userId string, resumeId string) erro
 		return errors.New("简历内容不能为空")
 	}
 
-	workflow := model.Workflow{}
-	if err := global.DB.Where("name = ?", "resume_structure").First(&workflow).Error; err != nil {
-		return errors.New("查询工作流失败")
-	}
-
-	input := map[string]any{
-		"text_content": resume.TextContent,
-	}
-
-	// 使用新的ExecuteWorkflowAPI方法，自动处理日志记录
-	response, err := appService.AppService.ExecuteWorkflowAPI(workflow.ID, resume.UserID, input)
-	if err != nil {
-		return errors.New("工作流执行失败: " + err.Error())
-	}
-	if !response.Success {
-		return errors.New(response.Message)
-	}
-
-	// 从响应中提取输出
-	outputs, ok := response.Data["outputs"].(map[string]interface{})
-	if !ok {
-		return errors.New("响应格式错误")
-	}
-
-	completeJSON := extractCompleteJSON(outputs["output"].(string))
-	resume.StructuredData = model.JSON(completeJSON)
-	if err := global.DB.Model(&model.ResumeRecord{}).Where("id = ?", resume.ID).Updates(&resume).Error; err != nil {
-		return errors.New("更新简历结构化数据失败")
-	}
-
-	return nil
+	return s.runIngestPipeline(ctx, &resume, false, true)
 }
 
 // CreateTextResume 创建纯文本简历
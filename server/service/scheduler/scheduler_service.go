@@ -0,0 +1,327 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"server/global"
+	"server/model"
+)
+
+type schedulerService struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+var SchedulerService = &schedulerService{jobs: make(map[string]*Job)}
+
+// Register 注册定时任务，需在 Start 之前调用
+func (s *schedulerService) Register(job *Job) {
+	if _, err := parseCron(job.CronExpr); err != nil {
+		panic(fmt.Sprintf("定时任务 %s 的Cron表达式无效: %v", job.Name, err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.Name] = job
+}
+
+// Start 将已注册的任务同步到数据库并启动调度循环
+// 多实例部署时每个实例都会启动调度循环，通过Postgres咨询锁保证同一任务同一时刻只在一个实例执行
+func (s *schedulerService) Start() error {
+	if err := s.syncJobs(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.runDueJobs()
+		}
+	}()
+	return nil
+}
+
+// syncJobs 为首次注册的任务创建数据库记录，已存在的任务保留数据库中的Cron表达式和暂停状态
+func (s *schedulerService) syncJobs() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, job := range s.jobs {
+		var record model.ScheduledJob
+		err := global.DB.Where("name = ?", job.Name).First(&record).Error
+		if err == nil {
+			updates := map[string]interface{}{"description": job.Description}
+			if record.NextRunAt == nil {
+				updates["next_run_at"] = nextRunAt(record.CronExpr, time.Now())
+			}
+			if err := global.DB.Model(&record).Updates(updates).Error; err != nil {
+				return fmt.Errorf("更新定时任务失败: %w", err)
+			}
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("查询定时任务失败: %w", err)
+		}
+
+		record = model.ScheduledJob{
+			Name:        job.Name,
+			Description: job.Description,
+			CronExpr:    job.CronExpr,
+			NextRunAt:   nextRunAt(job.CronExpr, time.Now()),
+		}
+		if err := global.DB.Create(&record).Error; err != nil {
+			return fmt.Errorf("创建定时任务失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// runDueJobs 执行所有到期的任务
+func (s *schedulerService) runDueJobs() {
+	var records []model.ScheduledJob
+	if err := global.DB.Where("paused = ? AND next_run_at <= ?", false, time.Now()).
+		Find(&records).Error; err != nil {
+		fmt.Printf("查询到期定时任务失败: %v\n", err)
+		return
+	}
+
+	for _, record := range records {
+		job := s.getJob(record.Name)
+		if job == nil {
+			continue
+		}
+		go func(job *Job) {
+			if err := s.execute(job, false); err != nil && !errors.Is(err, ErrJobRunning) {
+				fmt.Printf("执行定时任务失败: name=%s, err=%v\n", job.Name, err)
+			}
+		}(job)
+	}
+}
+
+// ListJobs 获取定时任务列表
+func (s *schedulerService) ListJobs() ([]JobResponse, error) {
+	var records []model.ScheduledJob
+	if err := global.DB.Order("name ASC").Find(&records).Error; err != nil {
+		return nil, errors.New("查询定时任务失败")
+	}
+
+	responses := make([]JobResponse, 0, len(records))
+	for _, record := range records {
+		responses = append(responses, JobResponse{
+			ID:             record.ID,
+			Name:           record.Name,
+			Description:    record.Description,
+			CronExpr:       record.CronExpr,
+			Paused:         record.Paused,
+			Registered:     s.getJob(record.Name) != nil,
+			NextRunAt:      record.NextRunAt,
+			LastRunAt:      record.LastRunAt,
+			LastStatus:     record.LastStatus,
+			LastError:      record.LastError,
+			LastResult:     record.LastResult,
+			LastDurationMs: record.LastDurationMs,
+			RunCount:       record.RunCount,
+		})
+	}
+	return responses, nil
+}
+
+// TriggerJob 立即执行任务（异步），不影响原有的调度时间
+// 任务正在执行时返回 ErrJobRunning
+func (s *schedulerService) TriggerJob(name string) error {
+	job := s.getJob(name)
+	if job == nil {
+		return ErrJobNotFound
+	}
+
+	lock, err := acquireJobLock(name)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer lock.release()
+		if err := s.run(job, true); err != nil {
+			fmt.Printf("手动执行定时任务失败: name=%s, err=%v\n", name, err)
+		}
+	}()
+	return nil
+}
+
+// PauseJob 暂停任务，暂停后不再按计划执行，但仍可手动触发
+func (s *schedulerService) PauseJob(name string) error {
+	result := global.DB.Model(&model.ScheduledJob{}).Where("name = ?", name).Update("paused", true)
+	if result.Error != nil {
+		return errors.New("暂停任务失败")
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// ResumeJob 恢复任务，从当前时间重新计算下次执行时间
+func (s *schedulerService) ResumeJob(name string) error {
+	var record model.ScheduledJob
+	if err := global.DB.Where("name = ?", name).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrJobNotFound
+		}
+		return errors.New("查询定时任务失败")
+	}
+
+	if err := global.DB.Model(&record).Updates(map[string]interface{}{
+		"paused":      false,
+		"next_run_at": nextRunAt(record.CronExpr, time.Now()),
+	}).Error; err != nil {
+		return errors.New("恢复任务失败")
+	}
+	return nil
+}
+
+// execute 获取任务锁后执行任务
+func (s *schedulerService) execute(job *Job, manual bool) error {
+	lock, err := acquireJobLock(job.Name)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	return s.run(job, manual)
+}
+
+// run 执行任务并记录执行结果，调用方需持有任务锁
+// 计划执行时会重新检查任务是否仍然到期，避免其他实例刚执行完后重复执行
+func (s *schedulerService) run(job *Job, manual bool) error {
+	var record model.ScheduledJob
+	if err := global.DB.Where("name = ?", job.Name).First(&record).Error; err != nil {
+		return fmt.Errorf("查询定时任务失败: %w", err)
+	}
+
+	startedAt := time.Now()
+	if !manual && (record.Paused || record.NextRunAt == nil || record.NextRunAt.After(startedAt)) {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"last_run_at": startedAt,
+		"last_status": model.ScheduledJobStatusRunning,
+	}
+	if !manual {
+		updates["next_run_at"] = nextRunAt(record.CronExpr, startedAt)
+	}
+	if err := global.DB.Model(&record).Updates(updates).Error; err != nil {
+		return fmt.Errorf("更新定时任务状态失败: %w", err)
+	}
+
+	result, runErr := invoke(job)
+
+	status, lastError := model.ScheduledJobStatusSuccess, ""
+	if runErr != nil {
+		status, lastError = model.ScheduledJobStatusFailed, runErr.Error()
+	}
+	return global.DB.Model(&record).Updates(map[string]interface{}{
+		"last_status":      status,
+		"last_error":       lastError,
+		"last_result":      result,
+		"last_duration_ms": time.Since(startedAt).Milliseconds(),
+		"run_count":        gorm.Expr("run_count + 1"),
+	}).Error
+}
+
+// invoke 调用任务执行函数，捕获panic并应用超时
+func invoke(job *Job) (result string, err error) {
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = DefaultJobTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务执行异常: %v", r)
+		}
+	}()
+	return job.Handler(ctx)
+}
+
+func (s *schedulerService) getJob(name string) *Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jobs[name]
+}
+
+// nextRunAt 计算下次执行时间，表达式无效时返回nil（任务不会被调度）
+func nextRunAt(expr string, from time.Time) *time.Time {
+	schedule, err := parseCron(expr)
+	if err != nil {
+		fmt.Printf("定时任务Cron表达式无效: expr=%s, err=%v\n", expr, err)
+		return nil
+	}
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// jobLock 基于Postgres会话级咨询锁的任务锁
+// 咨询锁与数据库连接绑定，因此加锁和解锁必须使用同一个连接
+type jobLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// acquireJobLock 尝试获取任务锁，已被占用时返回 ErrJobRunning
+func acquireJobLock(name string) (*jobLock, error) {
+	sqlDB, err := global.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+
+	key := lockKey(name)
+	var locked bool
+	if err := conn.QueryRowContext(context.Background(), "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("获取任务锁失败: %w", err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrJobRunning
+	}
+
+	return &jobLock{conn: conn, key: key}, nil
+}
+
+// release 释放任务锁并归还连接
+func (l *jobLock) release() {
+	if _, err := l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		fmt.Printf("释放任务锁失败: %v\n", err)
+		// 解锁失败时丢弃该连接，连接关闭后会话级锁随之释放，避免带锁的连接回到连接池
+		l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+}
+
+// lockKey 根据任务名称生成咨询锁的键
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("scheduled_job:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// JobHandler 定时任务执行函数，返回的字符串作为执行结果摘要记录
+type JobHandler func(ctx context.Context) (string, error)
+
+// Job 定时任务定义
+type Job struct {
+	Name        string        // 任务名称（唯一）
+	Description string        // 任务描述
+	CronExpr    string        // 默认Cron表达式，仅在任务首次写入数据库时使用
+	Timeout     time.Duration // 单次执行超时，0表示使用默认值
+	Handler     JobHandler
+}
+
+// DefaultJobTimeout 任务默认执行超时
+const DefaultJobTimeout = 30 * time.Minute
+
+// pollInterval 调度器检查到期任务的间隔
+const pollInterval = 30 * time.Second
+
+var (
+	// ErrJobNotFound 任务未注册
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrJobRunning 任务正在其他实例或协程中执行
+	ErrJobRunning = errors.New("任务正在执行中")
+)
+
+// JobResponse 定时任务信息
+type JobResponse struct {
+	ID             int64      `json:"id"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	CronExpr       string     `json:"cron_expr"`
+	Paused         bool       `json:"paused"`
+	Registered     bool       `json:"registered"` // 当前进程是否注册了该任务的执行逻辑
+	NextRunAt      *time.Time `json:"next_run_at"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastStatus     string     `json:"last_status"`
+	LastError      string     `json:"last_error"`
+	LastResult     string     `json:"last_result"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	RunCount       int64      `json:"run_count"`
+}
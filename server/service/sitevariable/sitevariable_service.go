@@ -132,3 +132,12 @@ func (s *siteVariableService) GetSiteVariableByID(id int64) (*model.SiteVariable
 
 	return &variable, nil
 }
+
+// GetValue 读取网站变量的值，变量不存在时返回默认值
+func (s *siteVariableService) GetValue(key, defaultValue string) string {
+	var variables []model.SiteVariable
+	if err := global.DB.Where("key = ?", key).Limit(1).Find(&variables).Error; err != nil || len(variables) == 0 {
+		return defaultValue
+	}
+	return variables[0].Value
+}
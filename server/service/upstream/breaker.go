@@ -0,0 +1,175 @@
+package upstream
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"server/global"
+)
+
+// breaker 单个上游的熔断器
+// 连续失败达到阈值后熔断，冷却期内请求直接失败；冷却期后放行一个试探请求，成功则恢复，失败则重新熔断
+// 熔断状态保存在实例内存中，多实例部署时各实例独立判断
+type breaker struct {
+	mu                  sync.Mutex
+	state               string
+	consecutiveFailures int
+	totalSuccesses      int64
+	totalFailures       int64
+	lastError           string
+	lastFailureAt       time.Time
+	openedAt            time.Time
+	probing             bool // 半开状态下是否已有试探请求在执行
+}
+
+var (
+	breakers   = make(map[string]*breaker)
+	breakersMu sync.Mutex
+)
+
+// getBreaker 获取或创建熔断器
+func getBreaker(key string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[key]
+	if !ok {
+		b = &breaker{state: BreakerClosed}
+		breakers[key] = b
+	}
+	return b
+}
+
+// allow 判断是否放行请求，熔断中返回 CircuitOpenError
+func allow(key string) error {
+	b := getBreaker(key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		retryAt := b.openedAt.Add(breakerCooldown())
+		if time.Now().Before(retryAt) {
+			return &CircuitOpenError{Key: key, RetryAt: retryAt}
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{Key: key, RetryAt: time.Now().Add(breakerCooldown())}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record 记录一次请求结果，failure 为空表示成功
+func record(key, failure string) {
+	b := getBreaker(key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if failure == "" {
+		b.state = BreakerClosed
+		b.consecutiveFailures = 0
+		b.totalSuccesses++
+		return
+	}
+
+	now := time.Now()
+	b.consecutiveFailures++
+	b.totalFailures++
+	b.lastError = failure
+	b.lastFailureAt = now
+	if b.state == BreakerHalfOpen || b.consecutiveFailures >= breakerThreshold() {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+// releaseProbe 结束半开状态下的试探请求但不记录结果，下一个请求重新试探
+func releaseProbe(key string) {
+	b := getBreaker(key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// ListBreakers 获取当前实例所有熔断器的状态，按键排序
+func ListBreakers() []BreakerStatus {
+	breakersMu.Lock()
+	keys := make([]string, 0, len(breakers))
+	for key := range breakers {
+		keys = append(keys, key)
+	}
+	breakersMu.Unlock()
+	sort.Strings(keys)
+
+	statuses := make([]BreakerStatus, 0, len(keys))
+	for _, key := range keys {
+		statuses = append(statuses, getBreaker(key).status(key))
+	}
+	return statuses
+}
+
+// ResetBreaker 手动恢复熔断器（如确认上游已恢复）
+func ResetBreaker(key string) error {
+	breakersMu.Lock()
+	b, ok := breakers[key]
+	breakersMu.Unlock()
+	if !ok {
+		return ErrBreakerNotFound
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.consecutiveFailures = 0
+	b.probing = false
+	return nil
+}
+
+// status 返回熔断器状态快照
+func (b *breaker) status(key string) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Key:                 key,
+		State:               b.state,
+		ConsecutiveFailures: b.consecutiveFailures,
+		TotalSuccesses:      b.totalSuccesses,
+		TotalFailures:       b.totalFailures,
+		LastError:           b.lastError,
+	}
+	if !b.lastFailureAt.IsZero() {
+		lastFailureAt := b.lastFailureAt
+		status.LastFailureAt = &lastFailureAt
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(breakerCooldown())
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// breakerThreshold 连续失败多少次后熔断
+func breakerThreshold() int {
+	if global.CONFIG.Upstream.BreakerThreshold > 0 {
+		return global.CONFIG.Upstream.BreakerThreshold
+	}
+	return defaultBreakerFailures
+}
+
+// breakerCooldown 熔断冷却时长
+func breakerCooldown() time.Duration {
+	if global.CONFIG.Upstream.BreakerCooldownSeconds > 0 {
+		return time.Duration(global.CONFIG.Upstream.BreakerCooldownSeconds) * time.Second
+	}
+	return defaultBreakerCooldown
+}
@@ -0,0 +1,185 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"server/global"
+)
+
+// Client 带重试和熔断的上游 HTTP 客户端，可被多个协程共享
+type Client struct {
+	http   *http.Client
+	policy RetryPolicy
+}
+
+// NewClient 创建上游客户端，httpClient 为空时使用默认客户端
+func NewClient(httpClient *http.Client, policy RetryPolicy) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{http: httpClient, policy: policy}
+}
+
+// Do 发送请求，按重试策略重试并记录熔断器状态
+// 成功返回 2xx 响应；重试用尽后返回最后一次非 2xx 响应（由调用方读取响应体并处理），或最后一次网络错误；
+// 熔断中直接返回 CircuitOpenError
+func (c *Client) Do(ctx context.Context, req *Request) (*http.Response, error) {
+	maxAttempts := c.maxAttempts()
+
+	for attempt := 1; ; attempt++ {
+		if req.Breaker != "" {
+			if err := allow(req.Breaker); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.send(ctx, req)
+		if req.Breaker != "" {
+			if errors.Is(err, context.Canceled) {
+				// 调用方取消请求无法说明上游是否可用，只结束试探，不改变熔断器状态
+				releaseProbe(req.Breaker)
+			} else {
+				record(req.Breaker, failureReason(resp, err))
+			}
+		}
+
+		if attempt >= maxAttempts || ctx.Err() != nil || !c.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := c.backoff(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		fmt.Printf("上游请求失败，%v 后进行第 %d 次重试: %s %s: %s\n", delay, attempt+1, req.Method, req.URL, failureReason(resp, err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send 发送单次请求
+func (c *Client) send(ctx context.Context, req *Request) (*http.Response, error) {
+	var body io.Reader
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	for key, values := range req.Header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
+	}
+	return c.http.Do(httpReq)
+}
+
+// shouldRetry 判断是否需要重试
+func (c *Client) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		// 连接未建立时请求一定没有被上游处理，可以安全重试
+		if isDialError(err) {
+			return true
+		}
+		return c.policy.Idempotent
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusGatewayTimeout:
+		return c.policy.Idempotent
+	}
+	return false
+}
+
+// backoff 计算第 attempt 次失败后的等待时间：指数退避加随机抖动，上游返回 Retry-After 时优先使用
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	maxDelay := c.maxDelay()
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			delay := time.Duration(seconds) * time.Second
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+			return delay
+		}
+	}
+
+	delay := c.baseDelay() << (attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	// 在 [delay/2, delay) 之间随机，避免多个请求同时重试
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (c *Client) maxAttempts() int {
+	if c.policy.MaxAttempts > 0 {
+		return c.policy.MaxAttempts
+	}
+	if global.CONFIG.Upstream.MaxAttempts > 0 {
+		return global.CONFIG.Upstream.MaxAttempts
+	}
+	return defaultMaxAttempts
+}
+
+func (c *Client) baseDelay() time.Duration {
+	if c.policy.BaseDelay > 0 {
+		return c.policy.BaseDelay
+	}
+	if global.CONFIG.Upstream.BaseDelayMs > 0 {
+		return time.Duration(global.CONFIG.Upstream.BaseDelayMs) * time.Millisecond
+	}
+	return defaultBaseDelay
+}
+
+func (c *Client) maxDelay() time.Duration {
+	if c.policy.MaxDelay > 0 {
+		return c.policy.MaxDelay
+	}
+	if global.CONFIG.Upstream.MaxDelayMs > 0 {
+		return time.Duration(global.CONFIG.Upstream.MaxDelayMs) * time.Millisecond
+	}
+	return defaultMaxDelay
+}
+
+// failureReason 返回计入熔断器的失败原因，成功或客户端错误（4xx，429 除外）返回空
+func failureReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if isFailureStatus(resp.StatusCode) {
+		return fmt.Sprintf("状态码: %d", resp.StatusCode)
+	}
+	return ""
+}
+
+// isFailureStatus 状态码是否表示上游不可用
+func isFailureStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// isDialError 是否为建立连接阶段的错误
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
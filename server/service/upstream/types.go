@@ -0,0 +1,98 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Request 上游请求，重试时重新发送 Body
+type Request struct {
+	Method  string
+	URL     string
+	Header  http.Header
+	Body    []byte
+	Breaker string // 熔断器键（如 workflow:<工作流ID>），为空表示不经过熔断器
+}
+
+// RetryPolicy 重试策略，零值字段使用 upstream 配置中的默认值
+// 429、502、503 表示请求未被处理，总是重试；500、504 和请求已发出后的网络错误只在幂等请求上重试，
+// 避免上游已经执行（并消耗用量）的请求被重复执行；其余 4xx 不重试
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（含首次）
+	BaseDelay   time.Duration // 首次重试前的等待时间，之后按指数增长
+	MaxDelay    time.Duration // 单次等待上限
+	Idempotent  bool          // 请求是否可以安全地重复执行
+}
+
+// BreakerStatus 熔断器状态
+type BreakerStatus struct {
+	Key                 string     `json:"key"`
+	State               string     `json:"state"` // closed/open/half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalSuccesses      int64      `json:"total_successes"`
+	TotalFailures       int64      `json:"total_failures"`
+	LastError           string     `json:"last_error"`
+	LastFailureAt       *time.Time `json:"last_failure_at"`
+	OpenedAt            *time.Time `json:"opened_at"`
+	RetryAt             *time.Time `json:"retry_at"` // 熔断中时下次放行试探请求的时间
+}
+
+// ResetBreakerRequest 重置熔断器请求
+type ResetBreakerRequest struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+const (
+	defaultMaxAttempts     = 3
+	defaultBaseDelay       = 500 * time.Millisecond
+	defaultMaxDelay        = 5 * time.Second
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// ErrBreakerNotFound 熔断器不存在
+var ErrBreakerNotFound = errors.New("熔断器不存在")
+
+// CircuitOpenError 上游处于熔断状态，请求未发出
+type CircuitOpenError struct {
+	Key     string
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("上游服务暂时不可用（%s 熔断中），请在 %s 后重试", e.Key, e.RetryAt.Format("15:04:05"))
+}
+
+// StatusError 上游返回非2xx状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API请求失败，状态码: %d, 响应: %s", e.StatusCode, e.Body)
+}
+
+// IsUnavailable 判断错误是否表示上游不可用（熔断、网络错误、429 或 5xx），可用于切换到备用上游
+func IsUnavailable(err error) bool {
+	var circuitOpen *CircuitOpenError
+	if errors.As(err, &circuitOpen) {
+		return true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return isFailureStatus(statusErr.StatusCode)
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
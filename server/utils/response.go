@@ -16,7 +16,9 @@ type Response struct {
 const (
 	SUCCESS           = 0
 	ERROR             = 500
+	BAD_REQUEST       = 400
 	UNAUTHORIZED      = 401
+	PAYMENT_REQUIRED  = 402
 	FORBIDDEN         = 403
 	NOT_FOUND         = 404
 	TOO_MANY_REQUESTS = 429
@@ -29,8 +31,12 @@ func Result(code int, data interface{}, msg string, c *gin.Context) {
 	switch code {
 	case SUCCESS:
 		httpStatus = http.StatusOK
+	case BAD_REQUEST:
+		httpStatus = http.StatusBadRequest
 	case UNAUTHORIZED:
 		httpStatus = http.StatusUnauthorized
+	case PAYMENT_REQUIRED:
+		httpStatus = http.StatusPaymentRequired
 	case FORBIDDEN:
 		httpStatus = http.StatusForbidden
 	case NOT_FOUND:
@@ -83,11 +89,21 @@ func FailWithDetailed(data interface{}, message string, c *gin.Context) {
 	Result(ERROR, data, message, c)
 }
 
+// FailWithBadRequest 请求参数校验失败返回
+func FailWithBadRequest(data interface{}, message string, c *gin.Context) {
+	Result(BAD_REQUEST, data, message, c)
+}
+
 // FailWithUnauthorized 未授权返回
 func FailWithUnauthorized(message string, c *gin.Context) {
 	Result(UNAUTHORIZED, map[string]interface{}{}, message, c)
 }
 
+// FailWithPaymentRequired 积分不足返回
+func FailWithPaymentRequired(data interface{}, message string, c *gin.Context) {
+	Result(PAYMENT_REQUIRED, data, message, c)
+}
+
 // FailWithForbidden 禁止访问返回
 func FailWithForbidden(message string, c *gin.Context) {
 	Result(FORBIDDEN, map[string]interface{}{}, message, c)
@@ -97,3 +113,19 @@ func FailWithForbidden(message string, c *gin.Context) {
 func FailWithNotFound(message string, c *gin.Context) {
 	Result(NOT_FOUND, map[string]interface{}{}, message, c)
 }
+
+// FailWithTooManyRequests 超出频率或使用配额限制返回
+func FailWithTooManyRequests(data interface{}, message string, c *gin.Context) {
+	c.JSON(http.StatusTooManyRequests, Response{
+		Code: TOO_MANY_REQUESTS,
+		Data: data,
+		Msg:  message,
+	})
+}
+
+// SetHeaders 批量设置响应头
+func SetHeaders(headers map[string]string, c *gin.Context) {
+	for key, value := range headers {
+		c.Header(key, value)
+	}
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"server/global"
+)
+
+// 加密字段格式：enc:v1:<主密钥ID>:<主密钥加密的数据密钥>:<数据密钥加密的明文>
+// 每个值使用独立的随机数据密钥（信封加密），轮换主密钥时只需重新加密数据密钥
+const (
+	secretPrefix  = "enc:v1:"
+	secretMask    = "****"
+	dataKeyLength = 32
+)
+
+// secretEncoding 密文编码，不含冒号
+var secretEncoding = base64.RawStdEncoding
+
+// masterKey 按ID查找并解码主密钥
+func masterKey(keyID string) ([]byte, error) {
+	for _, key := range global.CONFIG.SecretEncryption.Keys {
+		if key.ID != keyID {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("主密钥 %s 格式错误: %w", keyID, err)
+		}
+		if len(raw) != dataKeyLength {
+			return nil, fmt.Errorf("主密钥 %s 长度必须为%d字节", keyID, dataKeyLength)
+		}
+		return raw, nil
+	}
+	return nil, fmt.Errorf("未配置主密钥: %s", keyID)
+}
+
+// ActiveSecretKeyID 返回当前用于加密的主密钥ID
+func ActiveSecretKeyID() (string, error) {
+	keyID := global.CONFIG.SecretEncryption.ActiveKeyID
+	if keyID == "" {
+		return "", errors.New("未配置敏感字段加密主密钥（secret_encryption.active_key_id）")
+	}
+	if strings.Contains(keyID, ":") {
+		return "", fmt.Errorf("主密钥ID不能包含冒号: %s", keyID)
+	}
+	return keyID, nil
+}
+
+// seal 使用 AES-256-GCM 加密，返回 nonce + 密文
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open 解密 seal 的输出
+func open(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("密文长度错误")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// IsEncryptedSecret 判断值是否为加密后的格式
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+// splitSecret 拆分加密值为主密钥ID、加密的数据密钥、密文
+func splitSecret(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, secretPrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.New("加密值格式错误")
+	}
+	wrappedKey, err := secretEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, errors.New("加密值格式错误")
+	}
+	ciphertext, err := secretEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, errors.New("加密值格式错误")
+	}
+	return parts[0], wrappedKey, ciphertext, nil
+}
+
+// wrapDataKey 使用主密钥加密数据密钥，返回加密值
+func wrapDataKey(keyID string, dataKey, ciphertext []byte) (string, error) {
+	key, err := masterKey(keyID)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(key, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("加密数据密钥失败: %w", err)
+	}
+	return secretPrefix + keyID + ":" + secretEncoding.EncodeToString(wrappedKey) + ":" + secretEncoding.EncodeToString(ciphertext), nil
+}
+
+// unwrapDataKey 解密加密值中的数据密钥
+func unwrapDataKey(value string) ([]byte, []byte, error) {
+	keyID, wrappedKey, ciphertext, err := splitSecret(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := masterKey(keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataKey, err := open(key, wrappedKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解密数据密钥失败（主密钥 %s）: %w", keyID, err)
+	}
+	return dataKey, ciphertext, nil
+}
+
+// EncryptSecret 使用当前主密钥以信封加密方式加密敏感字段
+func EncryptSecret(plaintext string) (string, error) {
+	keyID, err := ActiveSecretKeyID()
+	if err != nil {
+		return "", err
+	}
+
+	dataKey := make([]byte, dataKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("生成数据密钥失败: %w", err)
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("加密失败: %w", err)
+	}
+	return wrapDataKey(keyID, dataKey, ciphertext)
+}
+
+// DecryptSecret 解密敏感字段，未加密的历史明文原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	dataKey, ciphertext, err := unwrapDataKey(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// SecretKeyPrefix 返回使用指定主密钥加密的值的前缀，用于按主密钥筛选数据
+func SecretKeyPrefix(keyID string) string {
+	return secretPrefix + keyID + ":"
+}
+
+// SecretKeyID 返回加密值使用的主密钥ID，未加密时返回空字符串
+func SecretKeyID(value string) string {
+	if !IsEncryptedSecret(value) {
+		return ""
+	}
+	keyID, _, _, err := splitSecret(value)
+	if err != nil {
+		return ""
+	}
+	return keyID
+}
+
+// RewrapSecret 使用当前主密钥重新加密敏感字段
+// 已加密的值只重新加密数据密钥，明文直接加密；已使用当前主密钥的值原样返回，changed 为 false
+func RewrapSecret(value string) (rewrapped string, changed bool, err error) {
+	keyID, err := ActiveSecretKeyID()
+	if err != nil {
+		return "", false, err
+	}
+	if !IsEncryptedSecret(value) {
+		encrypted, err := EncryptSecret(value)
+		return encrypted, err == nil, err
+	}
+	if SecretKeyID(value) == keyID {
+		return value, false, nil
+	}
+
+	dataKey, ciphertext, err := unwrapDataKey(value)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err = wrapDataKey(keyID, dataKey, ciphertext)
+	return rewrapped, err == nil, err
+}
+
+// MaskSecret 脱敏显示敏感字段明文，保留前缀和末4位，如 app-****abcd
+func MaskSecret(plaintext string) string {
+	if plaintext == "" {
+		return ""
+	}
+	if len(plaintext) <= 8 {
+		return secretMask
+	}
+	prefix := ""
+	if i := strings.Index(plaintext, "-"); i > 0 && i <= 4 {
+		prefix = plaintext[:i+1]
+	}
+	return prefix + secretMask + plaintext[len(plaintext)-4:]
+}
+
+// IsMaskedSecret 判断值是否为脱敏后的显示值（客户端回传脱敏值表示不修改）
+func IsMaskedSecret(value string) bool {
+	return strings.Contains(value, secretMask)
+}
@@ -1,6 +1,6 @@
 import apiClient from './client';
 import type { User } from '@/types/user';
-import type { Workflow, CreateWorkflowRequest, UpdateWorkflowRequest } from '@/types/workflow';
+import type { Workflow, CreateWorkflowRequest, UpdateWorkflowRequest, WorkflowVersion, WorkflowVersionDiff, WorkflowCacheStats, WorkflowPipeline, PipelineRequest } from '@/types/workflow';
 import type { ApiResponse, PaginationParams, PaginationResponse } from '@/types/global';
 
 export const adminAPI = {
@@ -64,6 +64,50 @@ export const adminAPI = {
     return apiClient.delete(`/api/workflow/${id}`);
   },
 
+  // 工作流配置版本
+  getWorkflowVersions: (id: string): Promise<ApiResponse<WorkflowVersion[]>> => {
+    return apiClient.get(`/api/workflow/${id}/versions`);
+  },
+
+  diffWorkflowVersions: (id: string, from: number, to: number): Promise<ApiResponse<WorkflowVersionDiff>> => {
+    return apiClient.get(`/api/workflow/${id}/versions/diff`, { params: { from, to } });
+  },
+
+  rollbackWorkflow: (id: string, version: number, notes?: string): Promise<ApiResponse<WorkflowVersion>> => {
+    return apiClient.post(`/api/workflow/${id}/versions/${version}/rollback`, { notes });
+  },
+
+  // 使用当前主密钥重新加密所有工作流API密钥
+  rotateWorkflowKeys: (): Promise<ApiResponse<{ key_id: string; workflows: number; versions: number }>> => {
+    return apiClient.post('/api/workflow/keys/rotate');
+  },
+
+  // 工作流响应缓存
+  getWorkflowCacheStats: (days: number = 7): Promise<ApiResponse<WorkflowCacheStats[]>> => {
+    return apiClient.get('/api/workflow/cache/stats', { params: { days } });
+  },
+
+  purgeWorkflowCache: (id: string, userId?: string): Promise<ApiResponse<{ removed: number }>> => {
+    return apiClient.delete(`/api/workflow/${id}/cache`, { params: { user_id: userId } });
+  },
+
+  // 工作流流水线
+  getPipelines: (): Promise<ApiResponse<WorkflowPipeline[]>> => {
+    return apiClient.get('/api/workflow/pipelines');
+  },
+
+  createPipeline: (data: PipelineRequest): Promise<ApiResponse<WorkflowPipeline>> => {
+    return apiClient.post('/api/workflow/pipelines', data);
+  },
+
+  updatePipeline: (id: string, data: PipelineRequest): Promise<ApiResponse> => {
+    return apiClient.put(`/api/workflow/pipelines/${id}`, data);
+  },
+
+  deletePipeline: (id: string): Promise<ApiResponse> => {
+    return apiClient.delete(`/api/workflow/pipelines/${id}`);
+  },
+
   // 文件管理
   getFileStats: (): Promise<ApiResponse<{ 
     total_files: number; 
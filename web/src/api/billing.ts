@@ -7,15 +7,33 @@ import apiClient from './client';
 import type { ApiResponse } from '@/types/global';
 import type {
   BillingActionPrice,
+  BillingActionPriceVersion,
+  SchedulePriceRequest,
+  UpdateActionPriceRequest,
   BillingPackage,
   UserBillingPackage,
   CreateBillingPackageRequest,
   AssignBillingPackageRequest,
-  CheckCreditsRequest,
-  CheckCreditsResponse,
-  DeductCreditsRequest,
-  DeductCreditsResponse,
   MyCreditsResponse,
+  Order,
+  CreateOrderResponse,
+  AdjustCreditsRequest,
+  CreditAdjustment,
+  CreditAdjustmentListResponse,
+  BillingReportType,
+  BillingReportParams,
+  Coupon,
+  CouponRequest,
+  CouponStats,
+  CouponQuote,
+  CouponListResponse,
+  CouponRedemptionListResponse,
+  ActionQuota,
+  QuotaRuleRequest,
+  ActionQuotaOverride,
+  QuotaOverrideRequest,
+  QuotaStatus,
+  UserQuotasResponse,
 } from '@/types/billing';
 
 /**
@@ -109,6 +127,28 @@ export const getUserBillingPackages = (userId: string): Promise<ApiResponse<User
   return apiClient.get<ApiResponse<UserBillingPackage[]>>(`/api/admin/billing/users/${userId}/billing-packages`) as any;
 };
 
+/**
+ * 手动调整用户积分（正数发放，负数扣回）
+ */
+export const adjustUserCredits = (data: AdjustCreditsRequest): Promise<ApiResponse<CreditAdjustment>> => {
+  return apiClient.post<ApiResponse<CreditAdjustment>>('/api/admin/billing/credit-adjustments', data) as any;
+};
+
+/**
+ * 查询积分调整记录，可按用户、操作人或原因筛选
+ */
+export const listCreditAdjustments = (params?: {
+  page?: number;
+  page_size?: number;
+  user_id?: string;
+  operator_id?: string;
+  reason_code?: string;
+}): Promise<ApiResponse<CreditAdjustmentListResponse>> => {
+  return apiClient.get<ApiResponse<CreditAdjustmentListResponse>>('/api/admin/billing/credit-adjustments', {
+    params,
+  }) as any;
+};
+
 /**
  * 激活套餐
  */
@@ -117,20 +157,218 @@ export const activateBillingPackage = (id: number): Promise<ApiResponse> => {
 };
 
 /**
- * ==================== 内部API ====================
+ * 更新动作展示信息与计费方式
  */
+export const updateActionPrice = (actionKey: string, data: UpdateActionPriceRequest): Promise<ApiResponse<BillingActionPrice>> => {
+  return apiClient.put<ApiResponse<BillingActionPrice>>(`/api/admin/billing/action-prices/${actionKey}`, data) as any;
+};
+
+/**
+ * 查询动作价格版本历史
+ */
+export const listActionPriceVersions = (actionKey: string): Promise<ApiResponse<BillingActionPriceVersion[]>> => {
+  return apiClient.get<ApiResponse<BillingActionPriceVersion[]>>(`/api/admin/billing/action-prices/${actionKey}/versions`) as any;
+};
 
 /**
- * 检查积分
+ * 排期动作价格版本（调价、促销、限时折扣）
  */
-export const checkCredits = (data: CheckCreditsRequest): Promise<ApiResponse<CheckCreditsResponse>> => {
-  return apiClient.post<ApiResponse<CheckCreditsResponse>>('/api/internal/billing/credits/check', data) as any;
+export const scheduleActionPrice = (actionKey: string, data: SchedulePriceRequest): Promise<ApiResponse<BillingActionPriceVersion>> => {
+  return apiClient.post<ApiResponse<BillingActionPriceVersion>>(`/api/admin/billing/action-prices/${actionKey}/versions`, data) as any;
 };
 
 /**
- * 扣减积分
+ * 取消价格版本（未生效的删除，生效中的立即结束）
  */
-export const deductCredits = (data: DeductCreditsRequest): Promise<ApiResponse<DeductCreditsResponse>> => {
-  return apiClient.post<ApiResponse<DeductCreditsResponse>>('/api/internal/billing/credits/deduct', data) as any;
+export const cancelActionPriceVersion = (id: number): Promise<ApiResponse> => {
+  return apiClient.delete<ApiResponse>(`/api/admin/billing/action-price-versions/${id}`) as any;
 };
 
+/**
+ * ==================== 订单API ====================
+ */
+
+/**
+ * 创建套餐购买订单
+ */
+export const createOrder = (
+  packageId: number,
+  provider?: string,
+  couponCode?: string
+): Promise<ApiResponse<CreateOrderResponse>> => {
+  return apiClient.post<ApiResponse<CreateOrderResponse>>('/api/orders', {
+    package_id: packageId,
+    provider,
+    coupon_code: couponCode,
+  }) as any;
+};
+
+/**
+ * 取消未支付的订单
+ */
+export const cancelOrder = (orderId: string): Promise<ApiResponse> => {
+  return apiClient.post<ApiResponse>(`/api/orders/${orderId}/cancel`) as any;
+};
+
+/**
+ * 模拟支付订单（仅测试环境）
+ */
+export const mockPayOrder = (orderId: string): Promise<ApiResponse<Order>> => {
+  return apiClient.post<ApiResponse<Order>>(`/api/orders/${orderId}/mock-pay`) as any;
+};
+
+/**
+ * ==================== 优惠券API ====================
+ */
+
+/**
+ * 试算优惠券在套餐上的优惠
+ */
+export const previewCoupon = (code: string, packageId: number): Promise<ApiResponse<CouponQuote>> => {
+  return apiClient.post<ApiResponse<CouponQuote>>('/api/coupons/preview', {
+    code,
+    package_id: packageId,
+  }) as any;
+};
+
+/**
+ * 查询优惠券（管理员）
+ */
+export const listCoupons = (params?: {
+  page?: number;
+  page_size?: number;
+  keyword?: string;
+  is_active?: boolean;
+}): Promise<ApiResponse<CouponListResponse>> => {
+  return apiClient.get<ApiResponse<CouponListResponse>>('/api/admin/coupons', { params }) as any;
+};
+
+/**
+ * 创建优惠券（管理员）
+ */
+export const createCoupon = (data: CouponRequest): Promise<ApiResponse<Coupon>> => {
+  return apiClient.post<ApiResponse<Coupon>>('/api/admin/coupons', data) as any;
+};
+
+/**
+ * 更新优惠券（管理员）
+ */
+export const updateCoupon = (id: number, data: CouponRequest): Promise<ApiResponse<Coupon>> => {
+  return apiClient.put<ApiResponse<Coupon>>(`/api/admin/coupons/${id}`, data) as any;
+};
+
+/**
+ * 删除未被使用过的优惠券（管理员）
+ */
+export const deleteCoupon = (id: number): Promise<ApiResponse> => {
+  return apiClient.delete<ApiResponse>(`/api/admin/coupons/${id}`) as any;
+};
+
+/**
+ * 查询优惠券使用统计（管理员）
+ */
+export const getCouponStats = (id: number): Promise<ApiResponse<CouponStats>> => {
+  return apiClient.get<ApiResponse<CouponStats>>(`/api/admin/coupons/${id}/stats`) as any;
+};
+
+/**
+ * 查询优惠券使用记录（管理员）
+ */
+export const listCouponRedemptions = (
+  id: number,
+  params?: { page?: number; page_size?: number; user_id?: string; status?: string }
+): Promise<ApiResponse<CouponRedemptionListResponse>> => {
+  return apiClient.get<ApiResponse<CouponRedemptionListResponse>>(`/api/admin/coupons/${id}/redemptions`, {
+    params,
+  }) as any;
+};
+
+/**
+ * ==================== 计费报表API ====================
+ */
+
+/**
+ * 查询计费报表（管理员）
+ */
+export const getBillingReport = <T>(type: BillingReportType, params?: BillingReportParams): Promise<ApiResponse<T>> => {
+  return apiClient.get<ApiResponse<T>>(`/api/admin/billing/reports/${type}`, { params }) as any;
+};
+
+/**
+ * 导出计费报表CSV（触发浏览器下载）
+ */
+export const downloadBillingReportCsv = async (type: BillingReportType, params?: BillingReportParams) => {
+  const response = await apiClient.get(`/api/admin/billing/reports/${type}`, {
+    params: { ...params, format: 'csv' },
+    responseType: 'blob',
+  });
+
+  const blob = new Blob([response as any], { type: 'text/csv;charset=utf-8' });
+  const url = window.URL.createObjectURL(blob);
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = `${type}_${new Date().toISOString().slice(0, 10)}.csv`;
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+  window.URL.revokeObjectURL(url);
+};
+
+/**
+ * ==================== 使用配额API ====================
+ */
+
+/**
+ * 查询我的动作使用配额
+ */
+export const getMyQuotas = (): Promise<ApiResponse<QuotaStatus[]>> => {
+  return apiClient.get<ApiResponse<QuotaStatus[]>>('/api/user/billing/quotas') as any;
+};
+
+/**
+ * 查询配额规则（管理员）
+ */
+export const listQuotaRules = (params?: {
+  action_key?: string;
+  billing_package_id?: number;
+}): Promise<ApiResponse<ActionQuota[]>> => {
+  return apiClient.get<ApiResponse<ActionQuota[]>>('/api/admin/billing/quotas', { params }) as any;
+};
+
+/**
+ * 创建或更新配额规则（管理员）
+ */
+export const saveQuotaRule = (data: QuotaRuleRequest): Promise<ApiResponse<ActionQuota>> => {
+  return apiClient.put<ApiResponse<ActionQuota>>('/api/admin/billing/quotas', data) as any;
+};
+
+/**
+ * 删除配额规则（管理员）
+ */
+export const deleteQuotaRule = (id: number): Promise<ApiResponse> => {
+  return apiClient.delete<ApiResponse>(`/api/admin/billing/quotas/${id}`) as any;
+};
+
+/**
+ * 查询用户配额覆盖与使用情况（管理员）
+ */
+export const getUserQuotas = (userId: string): Promise<ApiResponse<UserQuotasResponse>> => {
+  return apiClient.get<ApiResponse<UserQuotasResponse>>(`/api/admin/billing/users/${userId}/quotas`) as any;
+};
+
+/**
+ * 设置用户配额覆盖（管理员）
+ */
+export const setUserQuotaOverride = (
+  userId: string,
+  data: QuotaOverrideRequest
+): Promise<ApiResponse<ActionQuotaOverride>> => {
+  return apiClient.put<ApiResponse<ActionQuotaOverride>>(`/api/admin/billing/users/${userId}/quotas`, data) as any;
+};
+
+/**
+ * 删除用户配额覆盖（管理员）
+ */
+export const deleteUserQuotaOverride = (userId: string, actionKey: string): Promise<ApiResponse> => {
+  return apiClient.delete<ApiResponse>(`/api/admin/billing/users/${userId}/quotas/${actionKey}`) as any;
+};
@@ -1,6 +1,7 @@
 // API 统一导出
 export { tosAPI } from './tos';
 export { asrAPI } from './asr';
+export { jobAPI } from './jobs';
 export { resumeAPI } from './resume';
 export { authAPI } from './auth';
 export { userAPI } from './user';
@@ -19,5 +20,6 @@ export { eventLogAPI } from './eventlog';
 // 类型导出
 export type * from './tos';
 export type * from './asr';
+export type * from './jobs';
 export type * from './interview';
 
@@ -0,0 +1,88 @@
+import apiClient from './client';
+import type { ApiResponse } from '@/types/global';
+
+// 异步任务相关类型定义
+export interface WorkflowJob<T = any> {
+  id: string;
+  created_at: string;
+  updated_at: string;
+  type: string;
+  user_id: string;
+  workflow_id: string;
+  resource_id: string;
+  status: 'pending' | 'running' | 'succeeded' | 'failed';
+  progress: number; // 0-100
+  message: string;
+  result: T | null;
+  error_message: string;
+  attempts: number;
+  max_attempts: number;
+  started_at: string | null;
+  finished_at: string | null;
+}
+
+export interface WorkflowJobListResponse {
+  total: number;
+  page: number;
+  page_size: number;
+  items: WorkflowJob[];
+}
+
+export interface ListJobsParams {
+  type?: string;
+  resource_id?: string;
+  page?: number;
+  page_size?: number;
+}
+
+export const jobAPI = {
+  // 查询任务进度和结果
+  getJob: <T = any>(id: string): Promise<ApiResponse<WorkflowJob<T>>> => {
+    return apiClient.get(`/api/jobs/${id}`);
+  },
+
+  // 查询当前用户的任务列表
+  listJobs: (params?: ListJobsParams): Promise<ApiResponse<WorkflowJobListResponse>> => {
+    return apiClient.get('/api/jobs', { params });
+  },
+
+  /**
+   * 轮询任务直到成功或失败
+   * @param jobId 任务ID
+   * @param onProgress 进度回调
+   * @param maxAttempts 最大轮询次数
+   * @param intervalMs 轮询间隔(毫秒)
+   */
+  pollUntilComplete: async <T = any>(
+    jobId: string,
+    onProgress?: (job: WorkflowJob<T>) => void,
+    maxAttempts = 200,
+    intervalMs = 3000
+  ): Promise<WorkflowJob<T>> => {
+    let attempts = 0;
+
+    while (attempts < maxAttempts) {
+      const response = await jobAPI.getJob<T>(jobId);
+
+      if (response.code !== 0) {
+        throw new Error(response.msg || '查询任务失败');
+      }
+
+      const job = response.data;
+
+      if (onProgress) {
+        onProgress(job);
+      }
+
+      if (job.status === 'succeeded' || job.status === 'failed') {
+        return job;
+      }
+
+      // 等待一段时间后再次轮询
+      await new Promise((resolve) => setTimeout(resolve, intervalMs));
+      attempts++;
+    }
+
+    throw new Error('轮询超时');
+  },
+};
@@ -0,0 +1,49 @@
+/**
+ * Notification API Client
+ * 用户通知API封装
+ */
+
+import apiClient from './client';
+import type { ApiResponse } from '@/types/global';
+import type { NotificationListResponse, NotificationPreference } from '@/types/notification';
+
+/**
+ * 查询我的通知
+ */
+export const getMyNotifications = (params?: {
+  page?: number;
+  page_size?: number;
+  unread_only?: boolean;
+}): Promise<ApiResponse<NotificationListResponse>> => {
+  return apiClient.get<ApiResponse<NotificationListResponse>>('/api/user/notifications', { params }) as any;
+};
+
+/**
+ * 标记通知为已读
+ */
+export const markNotificationRead = (id: number): Promise<ApiResponse<null>> => {
+  return apiClient.post<ApiResponse<null>>(`/api/user/notifications/${id}/read`) as any;
+};
+
+/**
+ * 标记全部通知为已读
+ */
+export const markAllNotificationsRead = (): Promise<ApiResponse<null>> => {
+  return apiClient.post<ApiResponse<null>>('/api/user/notifications/read-all') as any;
+};
+
+/**
+ * 获取通知偏好
+ */
+export const getNotificationPreference = (): Promise<ApiResponse<NotificationPreference>> => {
+  return apiClient.get<ApiResponse<NotificationPreference>>('/api/user/notifications/preferences') as any;
+};
+
+/**
+ * 更新通知偏好
+ */
+export const updateNotificationPreference = (
+  data: NotificationPreference
+): Promise<ApiResponse<NotificationPreference>> => {
+  return apiClient.put<ApiResponse<NotificationPreference>>('/api/user/notifications/preferences', data) as any;
+};
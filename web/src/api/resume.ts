@@ -10,6 +10,7 @@ import type {
   CreateTextResumeData
 } from '@/types/resume';
 import type { ApiResponse, PaginationParams } from '@/types/global';
+import type { WorkflowJob } from './jobs';
 
 export const resumeAPI = {
   // 获取简历列表
@@ -67,12 +68,12 @@ export const resumeAPI = {
     return apiClient.put(`/api/resume/${id}/content`, { content });
   },
 
-  // 简历文件转文本
-  resumeFileToText: (id: string): Promise<ApiResponse> => {
+  // 简历文件转文本（提交异步任务，使用 jobAPI.pollUntilComplete 等待完成）
+  resumeFileToText: (id: string): Promise<ApiResponse<WorkflowJob>> => {
     return apiClient.post(`/api/user/resumes/file_to_text/${id}`);
   },
 
-  // 简历文本转JSON
+  // 简历文本转JSON（v1 提交异步任务，使用 jobAPI.pollUntilComplete 等待完成）
   structureTextToJSON: (id: string, v1?: boolean): Promise<ApiResponse> => {
     if (v1) {
       return apiClient.post(`/api/user/resumes/structure_data/${id}`);
@@ -80,6 +81,11 @@ export const resumeAPI = {
     return apiClient.post(`/api/user/resumes/structure_data/v2/${id}`);
   },
 
+  // 解析简历：有文件时提取文本后结构化（提交异步任务，使用 jobAPI.pollUntilComplete 等待完成）
+  ingestResume: (id: string): Promise<ApiResponse<WorkflowJob>> => {
+    return apiClient.post(`/api/user/resumes/ingest/${id}`);
+  },
+
   // 保存待处理内容（AI生成内容未接收时临时保存）
   savePendingContent: (id: string, pendingContent: any): Promise<ApiResponse> => {
     return apiClient.post(`/api/user/resumes/${id}/pending`, { pending_content: pendingContent });
@@ -1,7 +1,8 @@
 import apiClient from './client';
-import type { Workflow, WorkflowExecution } from '@/types/workflow';
+import type { Workflow, WorkflowExecution, WorkflowPipelineRun } from '@/types/workflow';
 import type { ApiResponse, PaginationParams, PaginationResponse } from '@/types/global';
 import { TOKEN_KEY } from '@/utils/constants';
+import type { WorkflowJob } from './jobs';
 
 type ExecuteWorkflowStreamParams = {
   id?: string,
@@ -19,6 +20,75 @@ type ExecuteWorkflowV2Params = {
   onNodeEvent?: (event: { type: string; nodeName?: string; nodeId?: string }) => void,
 }
 
+// 流式连接断开后的最大重连次数
+const STREAM_MAX_RECONNECTS = 5;
+
+// 读取工作流 SSE 流，逐条回调 data 内容
+// 连接中断时使用响应头 X-Stream-ID 和最后收到的事件 id 重连，服务端补发遗漏的事件后继续推送
+const readWorkflowStream = async (response: Response, onData: (raw: string) => void): Promise<void> => {
+  const streamId = response.headers.get('X-Stream-ID');
+  let lastEventId = 0;
+  let reconnects = 0;
+  let current = response;
+
+  while (true) {
+    const reader = current.body?.getReader();
+    if (!reader) {
+      throw new Error('无法获取响应流');
+    }
+
+    const decoder = new TextDecoder();
+    let buffer = '';
+    let pendingId: number | null = null;
+
+    while (true) {
+      let result: ReadableStreamReadResult<Uint8Array>;
+      try {
+        result = await reader.read();
+      } catch (error) {
+        if (!streamId || reconnects >= STREAM_MAX_RECONNECTS) {
+          throw error;
+        }
+        break;
+      }
+      if (result.done) return;
+
+      buffer += decoder.decode(result.value, { stream: true });
+      const lines = buffer.split('\n');
+      // 保留最后一个可能不完整的行
+      buffer = lines.pop() || '';
+
+      for (const line of lines) {
+        if (line.startsWith('id: ')) {
+          pendingId = Number(line.substring(4).trim());
+        } else if (line.startsWith('data: ')) {
+          const raw = line.substring(6).trim();
+          if (pendingId !== null) {
+            lastEventId = pendingId;
+            pendingId = null;
+          }
+          if (raw) onData(raw);
+        }
+      }
+    }
+
+    // 连接中断，按递增间隔重连
+    reconnects++;
+    await new Promise((resolve) => setTimeout(resolve, 1000 * reconnects));
+    const token = localStorage.getItem(TOKEN_KEY);
+    current = await fetch(`/api/workflow/streams/${streamId}`, {
+      headers: {
+        'Authorization': `Bearer ${token}`,
+        'Accept': 'text/event-stream',
+        'Last-Event-ID': String(lastEventId),
+      },
+    });
+    if (!current.ok) {
+      throw new Error(`流式连接中断，重连失败: HTTP ${current.status}`);
+    }
+  }
+};
+
 export const workflowAPI = {
   // 获取工作流列表
   getWorkflows: (): Promise<ApiResponse<Workflow[]>> => {
@@ -51,6 +121,12 @@ export const workflowAPI = {
     return apiClient.post(url, { inputs, response_mode: 'blocking' });
   },
 
+  // 异步执行工作流，返回任务信息，使用 jobAPI.pollUntilComplete 等待结果（任务结果与 executeWorkflow 的 data 相同）
+  submitWorkflowJob: (id: string, inputs: any, idAsName: boolean = false): Promise<ApiResponse<WorkflowJob>> => {
+    const url = idAsName ? `/api/workflow/v2/${id}/execute` : `/api/workflow/v1/${id}/execute`;
+    return apiClient.post(url, { inputs, response_mode: 'async' });
+  },
+
   // 执行工作流 v2（流式传输，Promise 包装返回最终结果）
   // 底层使用 SSE 流式连接，避免长时间阻塞导致连接被掐断
   // 返回格式与 executeWorkflow 兼容：{ code: 0, data: { data: { outputs: ... } } }
@@ -80,71 +156,55 @@ export const workflowAPI = {
       throw new Error(`HTTP ${response.status}: ${response.statusText}`);
     }
 
-    const reader = response.body?.getReader();
-    if (!reader) {
-      throw new Error('无法获取响应流');
-    }
-
-    const decoder = new TextDecoder();
     let finalOutputs: Record<string, unknown> | null = null;
-    let buffer = '';
 
-    while (true) {
-      const { done, value } = await reader.read();
-      if (done) break;
+    await readWorkflowStream(response, (raw) => {
+      if (raw === '[DONE]') return;
 
-      buffer += decoder.decode(value, { stream: true });
-      const lines = buffer.split('\n');
-      // 保留最后一个可能不完整的行
-      buffer = lines.pop() || '';
-
-      for (const line of lines) {
-        if (!line.startsWith('data: ')) continue;
-        const raw = line.substring(6).trim();
-        if (!raw || raw === '[DONE]') continue;
+      try {
+        const event = JSON.parse(raw);
 
-        try {
-          const event = JSON.parse(raw);
-
-          switch (event.event) {
-            case 'workflow_started':
-              console.log(`${tag} 工作流开始`);
-              onNodeEvent?.({ type: 'workflow_started' });
-              break;
-            case 'node_started':
-              console.log(`${tag} 节点开始: ${event.data?.node_name || ''}`);
-              onNodeEvent?.({
-                type: 'node_started',
-                nodeName: event.data?.node_name,
-                nodeId: event.data?.node_id,
-              });
-              break;
-            case 'node_finished':
-              console.log(`${tag} 节点完成: ${event.data?.node_name || ''}`);
-              onNodeEvent?.({
-                type: 'node_finished',
-                nodeName: event.data?.node_name,
-                nodeId: event.data?.node_id,
-              });
-              break;
-            case 'workflow_finished':
-              console.log(`${tag} 工作流完成`);
-              finalOutputs = event.data?.outputs ?? null;
-              onNodeEvent?.({ type: 'workflow_finished' });
-              break;
-            case 'error':
-              console.error(`${tag} 工作流错误:`, event.data?.message);
-              throw new Error(event.data?.message || '工作流执行失败');
-          }
-        } catch (e) {
-          if (e instanceof SyntaxError) {
-            console.warn(`${tag} SSE 数据解析失败:`, raw);
-          } else {
-            throw e;
-          }
+        switch (event.event) {
+          case 'workflow_started':
+            console.log(`${tag} 工作流开始`);
+            onNodeEvent?.({ type: 'workflow_started' });
+            break;
+          case 'node_started':
+            console.log(`${tag} 节点开始: ${event.data?.node_name || ''}`);
+            onNodeEvent?.({
+              type: 'node_started',
+              nodeName: event.data?.node_name,
+              nodeId: event.data?.node_id,
+            });
+            break;
+          case 'node_finished':
+            console.log(`${tag} 节点完成: ${event.data?.node_name || ''}`);
+            onNodeEvent?.({
+              type: 'node_finished',
+              nodeName: event.data?.node_name,
+              nodeId: event.data?.node_id,
+            });
+            break;
+          case 'workflow_finished':
+            console.log(`${tag} 工作流完成`);
+            finalOutputs = event.data?.outputs ?? null;
+            onNodeEvent?.({ type: 'workflow_finished' });
+            break;
+          case 'stream_cancelled':
+            console.log(`${tag} 工作流已取消: ${event.data?.message || ''}`);
+            throw new Error(event.data?.message || '工作流已取消');
+          case 'error':
+            console.error(`${tag} 工作流错误:`, event.data?.message);
+            throw new Error(event.data?.message || '工作流执行失败');
+        }
+      } catch (e) {
+        if (e instanceof SyntaxError) {
+          console.warn(`${tag} SSE 数据解析失败:`, raw);
+        } else {
+          throw e;
         }
       }
-    }
+    });
 
     if (!finalOutputs) {
       throw new Error(`${tag} 工作流未返回结果（流意外结束）`);
@@ -192,39 +252,15 @@ export const workflowAPI = {
         throw new Error(`HTTP ${response.status}: ${response.statusText}`);
       }
 
-      const reader = response.body?.getReader();
-      if (!reader) {
-        throw new Error('无法获取响应流');
-      }
-
-      const decoder = new TextDecoder();
-      
-      while (true) {
-        const { done, value } = await reader.read();
-        if (done) break;
-        
-        const chunk = decoder.decode(value);
-        const lines = chunk.split('\n');
-        
-        for (const line of lines) {
-          if (line.startsWith('data: ')) {
-            const data = line.substring(6).trim();
-            
-            if (data === '[DONE]') {
-              return;
-            }
-            
-            if (data) {
-              try {
-                const parsedData = JSON.parse(data);
-                onMessage?.(parsedData);
-              } catch (e) {
-                console.warn('Failed to parse SSE data:', data);
-              }
-            }
-          }
+      await readWorkflowStream(response, (data) => {
+        if (data === '[DONE]') return;
+        try {
+          const parsedData = JSON.parse(data);
+          onMessage?.(parsedData);
+        } catch (e) {
+          console.warn('Failed to parse SSE data:', data);
         }
-      }
+      });
     } catch (error) {
       onError?.(error);
       throw error;
@@ -246,6 +282,21 @@ export const workflowAPI = {
     return apiClient.get(`/api/workflow/${id}/stats`);
   },
 
+  // 取消流式执行，streamId 来自 stream_started 事件或响应头 X-Stream-ID
+  cancelWorkflowStream: (streamId: string): Promise<ApiResponse<null>> => {
+    return apiClient.post(`/api/workflow/streams/${streamId}/cancel`);
+  },
+
+  // 阻塞运行流水线，返回运行记录（步骤失败时 code 非0，data 仍为运行记录）
+  runPipeline: (name: string, inputs: Record<string, unknown>): Promise<ApiResponse<WorkflowPipelineRun>> => {
+    return apiClient.post(`/api/workflow/pipelines/${name}/run`, { inputs, response_mode: 'blocking' });
+  },
+
+  // 查询流水线运行记录
+  getPipelineRun: (runId: string): Promise<ApiResponse<WorkflowPipelineRun>> => {
+    return apiClient.get(`/api/workflow/pipelines/runs/${runId}`);
+  },
+
   // 获取执行详情
   getExecutionDetail: (executionId: string): Promise<ApiResponse<WorkflowExecution>> => {
     return apiClient.get(`/api/execution/${executionId}`);
@@ -18,6 +18,8 @@ export const StreamWorkflowExecutor: React.FC<StreamWorkflowExecutorProps> = ({
   const [isExecuting, setIsExecuting] = useState(false);
   const [progress, setProgress] = useState<string>('');
   const [events, setEvents] = useState<any[]>([]);
+  const [streamId, setStreamId] = useState<string | null>(null);
+  const [isCancelling, setIsCancelling] = useState(false);
 
   const executeWorkflow = useCallback(async () => {
     if (isExecuting) return;
@@ -25,6 +27,7 @@ export const StreamWorkflowExecutor: React.FC<StreamWorkflowExecutorProps> = ({
     setIsExecuting(true);
     setProgress('开始执行工作流...');
     setEvents([]);
+    setStreamId(null);
 
     try {
       await workflowAPI.executeWorkflowStream({
@@ -37,6 +40,15 @@ export const StreamWorkflowExecutor: React.FC<StreamWorkflowExecutorProps> = ({
 
           // 处理不同类型的事件
           switch (data.event) {
+            case 'stream_started':
+              setStreamId(data.data?.stream_id || null);
+              break;
+
+            case 'stream_cancelled':
+              setProgress(`已取消: ${data.data?.message || ''}`);
+              showInfo('工作流已取消');
+              break;
+
             case 'workflow_started':
               setProgress('工作流已启动');
               showInfo('工作流开始执行');
@@ -79,9 +91,24 @@ export const StreamWorkflowExecutor: React.FC<StreamWorkflowExecutorProps> = ({
       showError(`工作流执行失败: ${error.message}`);
     } finally {
       setIsExecuting(false);
+      setStreamId(null);
     }
   }, [workflowId, inputs, isExecuting, onComplete, onProgress]);
 
+  const cancelWorkflow = useCallback(async () => {
+    if (!streamId || isCancelling) return;
+
+    setIsCancelling(true);
+    try {
+      await workflowAPI.cancelWorkflowStream(streamId);
+      setProgress('正在取消...');
+    } catch (error: any) {
+      showError(`取消失败: ${error.message}`);
+    } finally {
+      setIsCancelling(false);
+    }
+  }, [streamId, isCancelling]);
+
   return (
     <div className="stream-workflow-executor">
       <div className="mb-4">
@@ -96,6 +123,19 @@ export const StreamWorkflowExecutor: React.FC<StreamWorkflowExecutorProps> = ({
         >
           {isExecuting ? '执行中...' : '执行工作流'}
         </button>
+        {isExecuting && streamId && (
+          <button
+            onClick={cancelWorkflow}
+            disabled={isCancelling}
+            className={`ml-2 px-4 py-2 rounded ${
+              isCancelling
+                ? 'bg-gray-400 cursor-not-allowed'
+                : 'bg-red-500 hover:bg-red-600 text-white'
+            }`}
+          >
+            {isCancelling ? '取消中...' : '取消'}
+          </button>
+        )}
       </div>
 
       {progress && (
@@ -1,6 +1,6 @@
 import React, { useState, useEffect } from 'react';
 import { useLocation, useNavigate } from 'react-router-dom';
-import { FiSettings, FiUsers, FiFolder, FiGift, FiDatabase, FiActivity, FiPackage } from 'react-icons/fi';
+import { FiSettings, FiUsers, FiFolder, FiGift, FiDatabase, FiActivity, FiPackage, FiBarChart2, FiSliders } from 'react-icons/fi';
 import { 
   WorkflowManagement, 
   UserManagement, 
@@ -9,11 +9,13 @@ import {
   SiteVariableManagement, 
   EventLogManagement,
   BillingPackageManagement,
-  UserBillingPackageList
+  UserBillingPackageList,
+  BillingReports,
+  ActionQuotaManagement
 } from './components';
 import { Button } from '@/components/ui/Button';
 
-type TabType = 'workflows' | 'users' | 'files' | 'invitations' | 'variables' | 'eventlogs' | 'billing' | 'user-billing-packages';
+type TabType = 'workflows' | 'users' | 'files' | 'invitations' | 'variables' | 'eventlogs' | 'billing' | 'user-billing-packages' | 'billing-reports' | 'quotas';
 
 const Administrator: React.FC = () => {
   const location = useLocation();
@@ -22,7 +24,7 @@ const Administrator: React.FC = () => {
   // 从 URL hash 中获取初始 tab，如果没有则默认为 'users'
   const getInitialTab = (): TabType => {
     const hash = location.hash.replace('#', '');
-    const validTabs: TabType[] = ['workflows', 'users', 'files', 'invitations', 'variables', 'eventlogs', 'billing'];
+    const validTabs: TabType[] = ['workflows', 'users', 'files', 'invitations', 'variables', 'eventlogs', 'billing', 'billing-reports', 'quotas'];
     return validTabs.includes(hash as TabType) ? (hash as TabType) : 'users';
   };
 
@@ -37,12 +39,14 @@ const Administrator: React.FC = () => {
     { id: 'eventlogs' as TabType, name: '事件日志', icon: FiActivity },
     { id: 'billing' as TabType, name: '套餐管理', icon: FiPackage },
     { id: 'user-billing-packages' as TabType, name: '用户套餐管理', icon: FiPackage },
+    { id: 'billing-reports' as TabType, name: '计费报表', icon: FiBarChart2 },
+    { id: 'quotas' as TabType, name: '使用配额', icon: FiSliders },
   ];
 
   // 监听 URL hash 变化
   useEffect(() => {
     const hash = location.hash.replace('#', '');
-    const validTabs: TabType[] = ['workflows', 'users', 'files', 'invitations', 'variables', 'eventlogs', 'billing'];
+    const validTabs: TabType[] = ['workflows', 'users', 'files', 'invitations', 'variables', 'eventlogs', 'billing', 'billing-reports', 'quotas'];
     if (hash && validTabs.includes(hash as TabType)) {
       setActiveTab(hash as TabType);
     }
@@ -72,6 +76,10 @@ const Administrator: React.FC = () => {
         return <BillingPackageManagement />;
       case 'user-billing-packages':
         return <UserBillingPackageList />;
+      case 'billing-reports':
+        return <BillingReports />;
+      case 'quotas':
+        return <ActionQuotaManagement />;
       default:
         return null;
     }
@@ -0,0 +1,408 @@
+import React, { useState, useEffect } from 'react';
+import { Button, Input } from '@/components/ui';
+import { showError, showSuccess } from '@/utils/toast';
+import {
+  listQuotaRules,
+  saveQuotaRule,
+  deleteQuotaRule,
+  getUserQuotas,
+  setUserQuotaOverride,
+  deleteUserQuotaOverride,
+  listActionPrices,
+  listBillingPackages,
+} from '@/api/billing';
+import type {
+  ActionQuota,
+  ActionKey,
+  BillingActionPrice,
+  BillingPackage,
+  QuotaRuleRequest,
+  QuotaOverrideRequest,
+  UserQuotasResponse,
+} from '@/types/billing';
+import { ACTION_NAME_MAP, QUOTA_WINDOW_NAME_MAP, QUOTA_SOURCE_NAME_MAP } from '@/types/billing';
+
+// 上限为 0 时显示为不限
+const limitText = (limit: number) => (limit > 0 ? limit : '不限');
+
+const emptyRuleForm: QuotaRuleRequest = {
+  billing_package_id: 0,
+  action_key: '',
+  per_minute: 0,
+  per_day: 0,
+  per_month: 0,
+  notes: '',
+};
+
+const emptyOverrideForm: QuotaOverrideRequest = {
+  action_key: '',
+  per_minute: 0,
+  per_day: 0,
+  per_month: 0,
+  expires_at: '',
+  notes: '',
+};
+
+const LimitInputs: React.FC<{
+  value: { per_minute: number; per_day: number; per_month: number };
+  onChange: (value: { per_minute: number; per_day: number; per_month: number }) => void;
+}> = ({ value, onChange }) => (
+  <>
+    {(['per_minute', 'per_day', 'per_month'] as const).map((field) => (
+      <div key={field}>
+        <label className="block text-sm font-medium mb-1">
+          {field === 'per_minute' ? '每分钟' : field === 'per_day' ? '每天' : '每30天'}
+        </label>
+        <Input
+          type="number"
+          min={0}
+          className="w-24"
+          value={value[field]}
+          onChange={(e) => onChange({ ...value, [field]: parseInt(e.target.value) || 0 })}
+        />
+      </div>
+    ))}
+  </>
+);
+
+const ActionQuotaManagement: React.FC = () => {
+  const [loading, setLoading] = useState(false);
+  const [rules, setRules] = useState<ActionQuota[]>([]);
+  const [actions, setActions] = useState<BillingActionPrice[]>([]);
+  const [packages, setPackages] = useState<BillingPackage[]>([]);
+  const [ruleForm, setRuleForm] = useState<QuotaRuleRequest>(emptyRuleForm);
+
+  const [userId, setUserId] = useState('');
+  const [userQuotas, setUserQuotas] = useState<UserQuotasResponse | null>(null);
+  const [overrideForm, setOverrideForm] = useState<QuotaOverrideRequest>(emptyOverrideForm);
+
+  useEffect(() => {
+    loadRules();
+    listActionPrices(false)
+      .then((response) => {
+        if (response.code === 0) setActions(response.data || []);
+      })
+      .catch((error) => console.error('加载动作列表失败:', error));
+    listBillingPackages()
+      .then((response) => {
+        if (response.code === 0) setPackages(response.data || []);
+      })
+      .catch((error) => console.error('加载套餐列表失败:', error));
+  }, []);
+
+  const actionName = (key: string) => ACTION_NAME_MAP[key as ActionKey] || actions.find((a) => a.action_key === key)?.action_name || key;
+
+  const packageName = (id: number) => (id === 0 ? '默认（无匹配档位）' : packages.find((p) => p.id === id)?.name || `套餐#${id}`);
+
+  const loadRules = async () => {
+    try {
+      setLoading(true);
+      const response = await listQuotaRules();
+      if (response.code === 0) {
+        setRules(response.data || []);
+      } else {
+        showError(response.msg || '加载配额规则失败');
+      }
+    } catch (error) {
+      console.error('加载配额规则失败:', error);
+      showError('加载配额规则失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleSaveRule = async () => {
+    if (!ruleForm.action_key) {
+      showError('请选择动作');
+      return;
+    }
+    try {
+      const response = await saveQuotaRule(ruleForm);
+      if (response.code === 0) {
+        showSuccess('配额规则已保存');
+        setRuleForm(emptyRuleForm);
+        loadRules();
+      } else {
+        showError(response.msg || '保存配额规则失败');
+      }
+    } catch (error: any) {
+      showError(error?.message || '保存配额规则失败');
+    }
+  };
+
+  const handleDeleteRule = async (rule: ActionQuota) => {
+    if (!window.confirm(`确定删除「${packageName(rule.billing_package_id)}」的「${actionName(rule.action_key)}」配额规则？`)) return;
+    try {
+      const response = await deleteQuotaRule(rule.id);
+      if (response.code === 0) {
+        showSuccess('配额规则已删除');
+        loadRules();
+      } else {
+        showError(response.msg || '删除配额规则失败');
+      }
+    } catch (error: any) {
+      showError(error?.message || '删除配额规则失败');
+    }
+  };
+
+  const loadUserQuotas = async (id: string = userId) => {
+    if (!id.trim()) {
+      showError('请输入用户ID');
+      return;
+    }
+    try {
+      const response = await getUserQuotas(id.trim());
+      if (response.code === 0) {
+        setUserQuotas(response.data);
+      } else {
+        showError(response.msg || '加载用户配额失败');
+      }
+    } catch (error: any) {
+      showError(error?.message || '加载用户配额失败');
+    }
+  };
+
+  const handleSaveOverride = async () => {
+    if (!overrideForm.action_key) {
+      showError('请选择动作');
+      return;
+    }
+    try {
+      const response = await setUserQuotaOverride(userId.trim(), {
+        ...overrideForm,
+        expires_at: overrideForm.expires_at ? new Date(overrideForm.expires_at).toISOString() : undefined,
+      });
+      if (response.code === 0) {
+        showSuccess('配额覆盖已保存');
+        setOverrideForm(emptyOverrideForm);
+        loadUserQuotas();
+      } else {
+        showError(response.msg || '保存配额覆盖失败');
+      }
+    } catch (error: any) {
+      showError(error?.message || '保存配额覆盖失败');
+    }
+  };
+
+  const handleDeleteOverride = async (actionKey: string) => {
+    if (!window.confirm(`确定删除该用户「${actionName(actionKey)}」的配额覆盖？`)) return;
+    try {
+      const response = await deleteUserQuotaOverride(userId.trim(), actionKey);
+      if (response.code === 0) {
+        showSuccess('配额覆盖已删除');
+        loadUserQuotas();
+      } else {
+        showError(response.msg || '删除配额覆盖失败');
+      }
+    } catch (error: any) {
+      showError(error?.message || '删除配额覆盖失败');
+    }
+  };
+
+  const actionSelect = (value: string, onChange: (value: string) => void) => (
+    <div>
+      <label className="block text-sm font-medium mb-1">动作</label>
+      <select className="border rounded px-3 py-2" value={value} onChange={(e) => onChange(e.target.value)}>
+        <option value="">请选择</option>
+        {actions.map((action) => (
+          <option key={action.action_key} value={action.action_key}>
+            {action.action_name}
+          </option>
+        ))}
+      </select>
+    </div>
+  );
+
+  return (
+    <div className="space-y-8">
+      <section className="space-y-4">
+        <div>
+          <h2 className="text-xl font-semibold">使用配额规则</h2>
+          <p className="text-sm text-gray-500">
+            按套餐档位限制动作的使用频率，与积分无关。用户有多个有效套餐时取最宽松的上限，没有匹配档位时使用默认规则；上限为 0 表示不限。
+          </p>
+        </div>
+
+        <div className="flex flex-wrap gap-4 items-end">
+          <div>
+            <label className="block text-sm font-medium mb-1">套餐档位</label>
+            <select
+              className="border rounded px-3 py-2"
+              value={ruleForm.billing_package_id}
+              onChange={(e) => setRuleForm({ ...ruleForm, billing_package_id: parseInt(e.target.value) || 0 })}
+            >
+              <option value={0}>默认（无匹配档位）</option>
+              {packages.map((pkg) => (
+                <option key={pkg.id} value={pkg.id}>
+                  {pkg.name}
+                </option>
+              ))}
+            </select>
+          </div>
+          {actionSelect(ruleForm.action_key, (action_key) => setRuleForm({ ...ruleForm, action_key }))}
+          <LimitInputs value={ruleForm} onChange={(limits) => setRuleForm({ ...ruleForm, ...limits })} />
+          <div>
+            <label className="block text-sm font-medium mb-1">备注</label>
+            <Input value={ruleForm.notes} onChange={(e) => setRuleForm({ ...ruleForm, notes: e.target.value })} />
+          </div>
+          <Button onClick={handleSaveRule}>保存规则</Button>
+        </div>
+
+        {loading ? (
+          <div>加载中...</div>
+        ) : (
+          <div className="overflow-x-auto">
+            <table className="min-w-full bg-white border">
+              <thead className="bg-gray-50">
+                <tr>
+                  {['套餐档位', '动作', '每分钟', '每天', '每30天', '备注', '操作'].map((header) => (
+                    <th key={header} className="px-4 py-2 border text-sm">
+                      {header}
+                    </th>
+                  ))}
+                </tr>
+              </thead>
+              <tbody>
+                {rules.length === 0 && (
+                  <tr>
+                    <td colSpan={7} className="px-4 py-6 text-center text-gray-500">
+                      暂无配额规则
+                    </td>
+                  </tr>
+                )}
+                {rules.map((rule) => (
+                  <tr key={rule.id} className="hover:bg-gray-50 text-center text-sm">
+                    <td className="px-4 py-2 border">{packageName(rule.billing_package_id)}</td>
+                    <td className="px-4 py-2 border">{actionName(rule.action_key)}</td>
+                    <td className="px-4 py-2 border">{limitText(rule.per_minute)}</td>
+                    <td className="px-4 py-2 border">{limitText(rule.per_day)}</td>
+                    <td className="px-4 py-2 border">{limitText(rule.per_month)}</td>
+                    <td className="px-4 py-2 border">{rule.notes || '-'}</td>
+                    <td className="px-4 py-2 border space-x-2">
+                      <button
+                        className="text-blue-600 hover:text-blue-800"
+                        onClick={() =>
+                          setRuleForm({
+                            billing_package_id: rule.billing_package_id,
+                            action_key: rule.action_key,
+                            per_minute: rule.per_minute,
+                            per_day: rule.per_day,
+                            per_month: rule.per_month,
+                            notes: rule.notes,
+                          })
+                        }
+                      >
+                        编辑
+                      </button>
+                      <button className="text-red-600 hover:text-red-800" onClick={() => handleDeleteRule(rule)}>
+                        删除
+                      </button>
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+      </section>
+
+      <section className="space-y-4">
+        <h2 className="text-xl font-semibold">用户配额覆盖</h2>
+        <div className="flex gap-2 items-end">
+          <div>
+            <label className="block text-sm font-medium mb-1">用户ID</label>
+            <Input value={userId} onChange={(e) => setUserId(e.target.value)} placeholder="输入用户ID" />
+          </div>
+          <Button onClick={() => loadUserQuotas()}>查询</Button>
+        </div>
+
+        {userQuotas && (
+          <>
+            <div className="overflow-x-auto">
+              <table className="min-w-full bg-white border">
+                <thead className="bg-gray-50">
+                  <tr>
+                    {['动作', '规则来源', '窗口', '上限', '已用', '剩余', '恢复时间'].map((header) => (
+                      <th key={header} className="px-4 py-2 border text-sm">
+                        {header}
+                      </th>
+                    ))}
+                  </tr>
+                </thead>
+                <tbody>
+                  {userQuotas.statuses.length === 0 && (
+                    <tr>
+                      <td colSpan={7} className="px-4 py-6 text-center text-gray-500">
+                        该用户没有受限的动作
+                      </td>
+                    </tr>
+                  )}
+                  {userQuotas.statuses.flatMap((status) =>
+                    status.windows.map((w) => (
+                      <tr key={`${status.action_key}-${w.window}`} className="text-center text-sm">
+                        <td className="px-4 py-2 border">{actionName(status.action_key)}</td>
+                        <td className="px-4 py-2 border">{QUOTA_SOURCE_NAME_MAP[status.source] || status.source}</td>
+                        <td className="px-4 py-2 border">{QUOTA_WINDOW_NAME_MAP[w.window]}</td>
+                        <td className="px-4 py-2 border">{w.limit}</td>
+                        <td className="px-4 py-2 border">{w.used}</td>
+                        <td className="px-4 py-2 border">{w.remaining}</td>
+                        <td className="px-4 py-2 border">{w.reset_at ? new Date(w.reset_at).toLocaleString() : '-'}</td>
+                      </tr>
+                    ))
+                  )}
+                </tbody>
+              </table>
+            </div>
+
+            {userQuotas.overrides.length > 0 && (
+              <div className="space-y-2">
+                <h3 className="font-medium">当前覆盖</h3>
+                {userQuotas.overrides.map((override) => (
+                  <div key={override.id} className="flex items-center gap-4 text-sm border rounded p-2">
+                    <span className="font-medium">{actionName(override.action_key)}</span>
+                    <span>每分钟 {limitText(override.per_minute)}</span>
+                    <span>每天 {limitText(override.per_day)}</span>
+                    <span>每30天 {limitText(override.per_month)}</span>
+                    <span className="text-gray-500">
+                      {override.expires_at ? `至 ${new Date(override.expires_at).toLocaleString()}` : '长期有效'}
+                    </span>
+                    {override.notes && <span className="text-gray-500">{override.notes}</span>}
+                    <button
+                      className="ml-auto text-red-600 hover:text-red-800"
+                      onClick={() => handleDeleteOverride(override.action_key)}
+                    >
+                      删除
+                    </button>
+                  </div>
+                ))}
+              </div>
+            )}
+
+            <div className="flex flex-wrap gap-4 items-end">
+              {actionSelect(overrideForm.action_key, (action_key) => setOverrideForm({ ...overrideForm, action_key }))}
+              <LimitInputs value={overrideForm} onChange={(limits) => setOverrideForm({ ...overrideForm, ...limits })} />
+              <div>
+                <label className="block text-sm font-medium mb-1">过期时间（可选）</label>
+                <Input
+                  type="datetime-local"
+                  value={overrideForm.expires_at}
+                  onChange={(e) => setOverrideForm({ ...overrideForm, expires_at: e.target.value })}
+                />
+              </div>
+              <div>
+                <label className="block text-sm font-medium mb-1">备注</label>
+                <Input
+                  value={overrideForm.notes}
+                  onChange={(e) => setOverrideForm({ ...overrideForm, notes: e.target.value })}
+                />
+              </div>
+              <Button onClick={handleSaveOverride}>设置覆盖</Button>
+            </div>
+          </>
+        )}
+      </section>
+    </div>
+  );
+};
+
+export default ActionQuotaManagement;
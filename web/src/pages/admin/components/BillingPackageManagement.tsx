@@ -6,9 +6,11 @@ import {
   listBillingPackages,
   createBillingPackage,
   updateBillingPackage,
+  listActionPrices,
 } from '@/api/billing';
 import type {
   BillingPackage,
+  BillingActionPrice,
   CreateBillingPackageRequest,
   PackageType,
 } from '@/types/billing';
@@ -20,6 +22,7 @@ const BillingPackageManagement: React.FC = () => {
   const [packages, setPackages] = useState<BillingPackage[]>([]);
   const [modalOpen, setModalOpen] = useState(false);
   const [editingPackage, setEditingPackage] = useState<BillingPackage | null>(null);
+  const [actionPrices, setActionPrices] = useState<BillingActionPrice[]>([]);
   const [formData, setFormData] = useState<CreateBillingPackageRequest>({
     name: '',
     description: '',
@@ -32,10 +35,21 @@ const BillingPackageManagement: React.FC = () => {
     is_visible: false,
     sort_order: 0,
     display_order: 100,
+    covered_actions: [],
+    daily_action_limit: 0,
   });
 
   useEffect(() => {
     loadPackages();
+    listActionPrices(false)
+      .then((response) => {
+        if (response.code === 0) {
+          setActionPrices(response.data || []);
+        }
+      })
+      .catch((error) => {
+        console.error('加载计费动作失败:', error);
+      });
   }, []);
 
   const loadPackages = async () => {
@@ -70,6 +84,8 @@ const BillingPackageManagement: React.FC = () => {
       is_visible: false,
       sort_order: 0,
       display_order: 100,
+      covered_actions: [],
+      daily_action_limit: 0,
     });
     setModalOpen(true);
   };
@@ -88,10 +104,35 @@ const BillingPackageManagement: React.FC = () => {
       is_visible: pkg.is_visible,
       sort_order: pkg.sort_order,
       display_order: pkg.display_order,
+      covered_actions: pkg.covered_actions || [],
+      daily_action_limit: pkg.daily_action_limit || 0,
     });
     setModalOpen(true);
   };
 
+  // 切换套餐类型：积分包、永久型不设有效期，时长型不发放积分
+  const handlePackageTypeChange = (packageType: PackageType) => {
+    const hasValidity = packageType === 'duration' || packageType === 'hybrid';
+    setFormData({
+      ...formData,
+      package_type: packageType,
+      validity_days: hasValidity ? (formData.validity_days || 30) : 0,
+      credits_amount: packageType === 'duration' ? 0 : (formData.credits_amount || 10),
+    });
+  };
+
+  const toggleCoveredAction = (actionKey: string) => {
+    const covered = formData.covered_actions || [];
+    setFormData({
+      ...formData,
+      covered_actions: covered.includes(actionKey)
+        ? covered.filter((key) => key !== actionKey)
+        : [...covered, actionKey],
+    });
+  };
+
+  const hasEntitlements = formData.package_type !== 'credits';
+
   const handleSubmit = async () => {
     try {
       setLoading(true);
@@ -191,11 +232,17 @@ const BillingPackageManagement: React.FC = () => {
                       <td className="px-6 py-4 whitespace-nowrap">
                         <div className="flex flex-col gap-1">
                           <div className="text-sm font-medium text-gray-900">
-                            {pkg.credits_amount} 积分
+                            {pkg.package_type === 'duration' ? '不发放积分' : `${pkg.credits_amount} 积分`}
                           </div>
                           <div className="text-xs text-gray-500">
-                            {pkg.validity_days === 0 ? '永久有效' : `${pkg.validity_days} 天有效`}
+                            {pkg.validity_days === 0 || pkg.package_type === 'permanent' ? '永久有效' : `${pkg.validity_days} 天有效`}
                           </div>
+                          {pkg.package_type !== 'credits' && pkg.covered_actions && pkg.covered_actions.length > 0 && (
+                            <div className="text-xs text-blue-600">
+                              不限次：{pkg.covered_actions.length} 个动作
+                              {pkg.daily_action_limit > 0 && `（每日 ${pkg.daily_action_limit} 次）`}
+                            </div>
+                          )}
                         </div>
                       </td>
                       <td className="px-6 py-4 whitespace-nowrap">
@@ -303,16 +350,13 @@ const BillingPackageManagement: React.FC = () => {
             <div className="grid grid-cols-2 gap-3">
               {[
                 { value: 'credits', label: '积分包', desc: '按积分消耗' },
-                { value: 'duration', label: '时长型', desc: '按时间有效' },
+                { value: 'duration', label: '时长型', desc: '有效期内指定动作不限次' },
+                { value: 'hybrid', label: '混合型', desc: '积分 + 限时不限次动作' },
+                { value: 'permanent', label: '永久型', desc: '积分与动作权益永久有效' },
               ].map((option) => (
                 <div
                   key={option.value}
-                  onClick={() => setFormData({ 
-                    ...formData, 
-                    package_type: option.value as PackageType,
-                    // 积分包时有效期为0，时长型默认30天
-                    validity_days: option.value === 'credits' ? 0 : (formData.package_type === 'credits' ? 30 : formData.validity_days)
-                  })}
+                  onClick={() => handlePackageTypeChange(option.value as PackageType)}
                   className={cn(
                     'relative flex items-start p-3 border rounded-lg cursor-pointer transition-all',
                     formData.package_type === option.value
@@ -349,24 +393,66 @@ const BillingPackageManagement: React.FC = () => {
                 setFormData({ ...formData, credits_amount: parseInt(e.target.value) || 0 })
               }
               min="1"
-              placeholder="10"
+              placeholder={formData.package_type === 'duration' ? '时长型不发放积分' : '10'}
               className="w-full"
+              disabled={formData.package_type === 'duration'}
             />
             <Input
-              label={formData.package_type === 'credits' ? '有效期（无限制）' : '有效期（天）'}
-              required={formData.package_type !== 'credits'}
+              label={formData.package_type === 'credits' || formData.package_type === 'permanent' ? '有效期（无限制）' : '有效期（天）'}
+              required={formData.package_type === 'duration' || formData.package_type === 'hybrid'}
               type="number"
               value={formData.validity_days}
               onChange={(e) =>
                 setFormData({ ...formData, validity_days: parseInt(e.target.value) || 0 })
               }
               min="0"
-              placeholder={formData.package_type === 'credits' ? '积分包无需设置有效期' : '30'}
+              placeholder={formData.package_type === 'credits' || formData.package_type === 'permanent' ? '无需设置有效期' : '30'}
               className="w-full"
-              disabled={formData.package_type === 'credits'}
+              disabled={formData.package_type === 'credits' || formData.package_type === 'permanent'}
             />
           </div>
 
+          {hasEntitlements && (
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                不限次使用的动作
+                {formData.package_type === 'duration' && <span className="text-red-500"> *</span>}
+              </label>
+              {actionPrices.length === 0 ? (
+                <p className="text-xs text-gray-500">暂无计费动作</p>
+              ) : (
+                <div className="grid grid-cols-2 gap-2">
+                  {actionPrices.map((price) => (
+                    <label key={price.action_key} className="flex items-center text-sm cursor-pointer">
+                      <input
+                        type="checkbox"
+                        checked={(formData.covered_actions || []).includes(price.action_key)}
+                        onChange={() => toggleCoveredAction(price.action_key)}
+                        className="mr-2 h-4 w-4 rounded border-gray-300 text-blue-600 focus:ring-blue-500"
+                      />
+                      {price.action_name}
+                      <span className="ml-1 text-xs text-gray-400">({price.credits_cost} 积分/次)</span>
+                    </label>
+                  ))}
+                </div>
+              )}
+              <div className="mt-2">
+                <Input
+                  label="每个动作每日上限（0 为不限）"
+                  type="number"
+                  value={formData.daily_action_limit}
+                  onChange={(e) =>
+                    setFormData({ ...formData, daily_action_limit: parseInt(e.target.value) || 0 })
+                  }
+                  min="0"
+                  placeholder="0"
+                  className="w-full"
+                />
+                <p className="text-xs text-gray-500 mt-1">超出每日上限后按积分扣费</p>
+              </div>
+            </div>
+          )}
+
           <div className="grid grid-cols-2 gap-4">
             <div>
               <Input
@@ -0,0 +1,270 @@
+import React, { useState, useEffect } from 'react';
+import { Button, Input } from '@/components/ui';
+import { showError } from '@/utils/toast';
+import { getBillingReport, downloadBillingReportCsv } from '@/api/billing';
+import type {
+  BillingReportType,
+  BillingReportParams,
+  RevenueReport,
+  CreditsReport,
+  BreakageReport,
+  TopConsumersReport,
+  ConversionReport,
+} from '@/types/billing';
+import { PACKAGE_SOURCE_NAME_MAP } from '@/types/billing';
+
+const REPORT_TYPES: { id: BillingReportType; name: string }[] = [
+  { id: 'revenue', name: '收入' },
+  { id: 'credits', name: '积分发放与消耗' },
+  { id: 'breakage', name: '积分沉淀' },
+  { id: 'top-consumers', name: '消耗排行' },
+  { id: 'conversion', name: '赠送转化' },
+];
+
+type AnyReport = RevenueReport | CreditsReport | BreakageReport | TopConsumersReport | ConversionReport;
+
+// 金额（分）格式化为元
+const yuan = (fen: number) => `¥${(fen / 100).toFixed(2)}`;
+
+const Table: React.FC<{ headers: string[]; rows: React.ReactNode[][] }> = ({ headers, rows }) => (
+  <div className="overflow-x-auto">
+    <table className="min-w-full bg-white border">
+      <thead className="bg-gray-50">
+        <tr>
+          {headers.map((header) => (
+            <th key={header} className="px-4 py-2 border text-sm">
+              {header}
+            </th>
+          ))}
+        </tr>
+      </thead>
+      <tbody>
+        {rows.length === 0 && (
+          <tr>
+            <td colSpan={headers.length} className="px-4 py-6 text-center text-gray-500">
+              暂无数据
+            </td>
+          </tr>
+        )}
+        {rows.map((row, i) => (
+          <tr key={i} className="hover:bg-gray-50">
+            {row.map((cell, j) => (
+              <td key={j} className="px-4 py-2 border text-center text-sm">
+                {cell}
+              </td>
+            ))}
+          </tr>
+        ))}
+      </tbody>
+    </table>
+  </div>
+);
+
+const Summary: React.FC<{ items: [string, React.ReactNode][] }> = ({ items }) => (
+  <div className="grid grid-cols-2 md:grid-cols-4 gap-2">
+    {items.map(([label, value]) => (
+      <div key={label} className="border rounded p-3 bg-gray-50">
+        <div className="text-xs text-gray-500">{label}</div>
+        <div className="text-lg font-semibold">{value}</div>
+      </div>
+    ))}
+  </div>
+);
+
+const BillingReports: React.FC = () => {
+  const [reportType, setReportType] = useState<BillingReportType>('revenue');
+  const [startDate, setStartDate] = useState('');
+  const [endDate, setEndDate] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [report, setReport] = useState<AnyReport | null>(null);
+
+  const buildParams = (): BillingReportParams => {
+    if (startDate && endDate) {
+      return { start_date: startDate, end_date: endDate };
+    }
+    return { days: 30 };
+  };
+
+  const loadReport = async (type: BillingReportType = reportType) => {
+    try {
+      setLoading(true);
+      setReport(null);
+      const response = await getBillingReport<AnyReport>(type, buildParams());
+      if (response.code === 0) {
+        setReport(response.data);
+      } else {
+        showError(response.msg || '加载报表失败');
+      }
+    } catch (error) {
+      console.error('加载报表失败:', error);
+      showError('加载报表失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    loadReport(reportType);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [reportType]);
+
+  const handleExport = async () => {
+    try {
+      await downloadBillingReportCsv(reportType, buildParams());
+    } catch (error) {
+      console.error('导出报表失败:', error);
+      showError('导出报表失败');
+    }
+  };
+
+  const renderReport = () => {
+    if (!report) return null;
+
+    switch (reportType) {
+      case 'revenue': {
+        const data = report as RevenueReport;
+        return (
+          <>
+            <Summary
+              items={[
+                ['订单数', data.total_orders],
+                ['实收', yuan(data.total_gross)],
+                ['退款', yuan(data.total_refunded)],
+                ['净收入', yuan(data.total_net_amount)],
+              ]}
+            />
+            <Table
+              headers={['日期', '套餐', '订单数', '原价', '优惠', '实收', '退款', '净收入']}
+              rows={data.items.map((item) => [
+                item.date,
+                item.package_name,
+                item.orders,
+                yuan(item.original_amount),
+                yuan(item.discount_amount),
+                yuan(item.gross_amount),
+                yuan(item.refunded_amount),
+                yuan(item.net_amount),
+              ])}
+            />
+          </>
+        );
+      }
+      case 'credits': {
+        const data = report as CreditsReport;
+        return (
+          <>
+            <Summary
+              items={[
+                ['套餐发放', data.issued_credits],
+                ['管理员调整', data.adjusted_credits],
+                ['净消耗', data.consumed_credits],
+                ['过期作废', data.expired_credits],
+              ]}
+            />
+            <Table
+              headers={['动作', '扣减次数', '扣减积分', '退还积分', '净消耗积分']}
+              rows={data.actions.map((item) => [
+                item.action_name,
+                item.deductions,
+                item.consumed_credits,
+                item.refunded_credits,
+                item.net_credits,
+              ])}
+            />
+          </>
+        );
+      }
+      case 'breakage': {
+        const data = report as BreakageReport;
+        return (
+          <>
+            <Summary
+              items={[
+                ['期间发放积分', data.issued_credits],
+                ['过期作废积分', data.expired_credits],
+                ['沉淀率', `${data.breakage_rate}%`],
+              ]}
+            />
+            <Table
+              headers={['日期', '过期套餐数', '过期作废积分']}
+              rows={data.items.map((item) => [item.date, item.packages, item.expired_credits])}
+            />
+          </>
+        );
+      }
+      case 'top-consumers': {
+        const data = report as TopConsumersReport;
+        return (
+          <Table
+            headers={['用户', '扣减次数', '净消耗积分', '支付金额']}
+            rows={data.items.map((item) => [
+              item.user_name || item.user_id,
+              item.deductions,
+              item.consumed_credits,
+              yuan(item.paid_amount),
+            ])}
+          />
+        );
+      }
+      case 'conversion': {
+        const data = report as ConversionReport;
+        return (
+          <Table
+            headers={['赠送套餐', '来源', '获赠用户', '转化用户', '转化率', '转化支付金额']}
+            rows={data.items.map((item) => [
+              item.package_name,
+              PACKAGE_SOURCE_NAME_MAP[item.source] || item.source,
+              item.gifted_users,
+              item.converted_users,
+              `${item.conversion_rate}%`,
+              yuan(item.converted_amount),
+            ])}
+          />
+        );
+      }
+    }
+  };
+
+  return (
+    <div className="space-y-4">
+      <div className="flex justify-between items-center">
+        <h2 className="text-xl font-semibold">计费报表</h2>
+      </div>
+
+      <div className="flex flex-wrap gap-2">
+        {REPORT_TYPES.map((type) => (
+          <Button
+            key={type.id}
+            size="sm"
+            variant={reportType === type.id ? 'primary' : 'outline'}
+            onClick={() => setReportType(type.id)}
+          >
+            {type.name}
+          </Button>
+        ))}
+      </div>
+
+      <div className="flex flex-wrap gap-4 items-end">
+        <div>
+          <label className="block text-sm font-medium mb-1">开始日期</label>
+          <Input type="date" value={startDate} onChange={(e) => setStartDate(e.target.value)} />
+        </div>
+        <div>
+          <label className="block text-sm font-medium mb-1">结束日期</label>
+          <Input type="date" value={endDate} onChange={(e) => setEndDate(e.target.value)} />
+        </div>
+        <Button onClick={() => loadReport()} disabled={loading}>
+          查询
+        </Button>
+        <Button variant="outline" onClick={handleExport} disabled={loading}>
+          导出CSV
+        </Button>
+        <span className="text-sm text-gray-500">未选择日期时统计最近30天</span>
+      </div>
+
+      {loading ? <div>加载中...</div> : renderReport()}
+    </div>
+  );
+};
+
+export default BillingReports;
@@ -5,10 +5,12 @@ import {
   getUserBillingPackages,
   assignBillingPackage,
   listBillingPackages,
+  adjustUserCredits,
+  listCreditAdjustments,
 } from '@/api/billing';
 import { adminAPI } from '@/api/admin';
-import type { UserBillingPackage, BillingPackage } from '@/types/billing';
-import { PACKAGE_STATUS_NAME_MAP, PACKAGE_SOURCE_NAME_MAP } from '@/types/billing';
+import type { UserBillingPackage, BillingPackage, CreditAdjustment, AdjustmentReason } from '@/types/billing';
+import { PACKAGE_STATUS_NAME_MAP, PACKAGE_SOURCE_NAME_MAP, ADJUSTMENT_REASON_NAME_MAP } from '@/types/billing';
 import type { User } from '@/types/user';
 
 const UserBillingPackageList: React.FC = () => {
@@ -23,6 +25,15 @@ const UserBillingPackageList: React.FC = () => {
     notes: '',
     auto_activate: true,
   });
+  const [adjustments, setAdjustments] = useState<CreditAdjustment[]>([]);
+  const [adjustModalOpen, setAdjustModalOpen] = useState(false);
+  const [adjustForm, setAdjustForm] = useState({
+    direction: 'grant' as 'grant' | 'revoke',
+    credits: 0,
+    reason_code: 'compensation' as AdjustmentReason,
+    notes: '',
+    validity_days: 0,
+  });
 
   useEffect(() => {
     loadUsers();
@@ -70,12 +81,76 @@ const UserBillingPackageList: React.FC = () => {
     }
   };
 
+  const loadAdjustments = async (userId: string) => {
+    try {
+      const response = await listCreditAdjustments({ user_id: userId, page: 1, page_size: 20 });
+      if (response.code === 0) {
+        setAdjustments(response.data.list || []);
+      }
+    } catch (error) {
+      console.error('加载积分调整记录失败:', error);
+    }
+  };
+
   const handleUserChange = (userId: string) => {
     setSelectedUserId(userId);
     if (userId) {
       loadUserPackages(userId);
+      loadAdjustments(userId);
     } else {
       setUserPackages([]);
+      setAdjustments([]);
+    }
+  };
+
+  const handleOpenAdjustModal = () => {
+    if (!selectedUserId) {
+      showError('请先选择用户');
+      return;
+    }
+    setAdjustForm({
+      direction: 'grant',
+      credits: 0,
+      reason_code: 'compensation',
+      notes: '',
+      validity_days: 0,
+    });
+    setAdjustModalOpen(true);
+  };
+
+  const handleAdjustCredits = async () => {
+    if (!selectedUserId || adjustForm.credits <= 0) {
+      showError('请输入大于0的积分数量');
+      return;
+    }
+    if (!adjustForm.notes.trim()) {
+      showError('请填写调整说明');
+      return;
+    }
+
+    try {
+      setLoading(true);
+      const response = await adjustUserCredits({
+        user_id: selectedUserId,
+        credits: adjustForm.direction === 'grant' ? adjustForm.credits : -adjustForm.credits,
+        reason_code: adjustForm.reason_code,
+        notes: adjustForm.notes.trim(),
+        validity_days: adjustForm.direction === 'grant' ? adjustForm.validity_days : 0,
+      });
+
+      if (response.code === 0) {
+        showSuccess('积分调整成功');
+        setAdjustModalOpen(false);
+        loadUserPackages(selectedUserId);
+        loadAdjustments(selectedUserId);
+      } else {
+        showError(response.msg || '积分调整失败');
+      }
+    } catch (error) {
+      console.error('积分调整失败:', error);
+      showError('积分调整失败');
+    } finally {
+      setLoading(false);
     }
   };
 
@@ -155,6 +230,11 @@ const UserBillingPackageList: React.FC = () => {
             分配套餐
           </Button>
         </div>
+        <div className="pt-6">
+          <Button variant="outline" onClick={handleOpenAdjustModal} disabled={!selectedUserId}>
+            调整积分
+          </Button>
+        </div>
       </div>
 
       {selectedUserId && (
@@ -219,6 +299,44 @@ const UserBillingPackageList: React.FC = () => {
               </table>
             </div>
           )}
+
+          {adjustments.length > 0 && (
+            <div className="overflow-x-auto">
+              <h3 className="text-lg font-semibold mb-2">积分调整记录</h3>
+              <table className="min-w-full bg-white border">
+                <thead className="bg-gray-50">
+                  <tr>
+                    <th className="px-4 py-2 border">时间</th>
+                    <th className="px-4 py-2 border">积分</th>
+                    <th className="px-4 py-2 border">调整后余额</th>
+                    <th className="px-4 py-2 border">原因</th>
+                    <th className="px-4 py-2 border">说明</th>
+                    <th className="px-4 py-2 border">操作人</th>
+                  </tr>
+                </thead>
+                <tbody>
+                  {adjustments.map((item) => (
+                    <tr key={item.id} className="hover:bg-gray-50">
+                      <td className="px-4 py-2 border text-center text-sm">{formatDate(item.created_at)}</td>
+                      <td
+                        className={`px-4 py-2 border text-center font-semibold ${
+                          item.credits > 0 ? 'text-green-600' : 'text-red-600'
+                        }`}
+                      >
+                        {item.credits > 0 ? `+${item.credits}` : item.credits}
+                      </td>
+                      <td className="px-4 py-2 border text-center">{item.balance_after}</td>
+                      <td className="px-4 py-2 border text-center">
+                        {ADJUSTMENT_REASON_NAME_MAP[item.reason_code] || item.reason_code}
+                      </td>
+                      <td className="px-4 py-2 border text-sm">{item.notes}</td>
+                      <td className="px-4 py-2 border text-center text-sm">{item.operator_id}</td>
+                    </tr>
+                  ))}
+                </tbody>
+              </table>
+            </div>
+          )}
         </>
       )}
 
@@ -277,6 +395,92 @@ const UserBillingPackageList: React.FC = () => {
           </div>
         </div>
       </Modal>
+
+      <Modal open={adjustModalOpen} onClose={() => setAdjustModalOpen(false)}>
+        <div className="p-6 space-y-4">
+          <h3 className="text-lg font-semibold">调整用户积分</h3>
+
+          <div className="flex gap-4">
+            <label className="flex items-center">
+              <input
+                type="radio"
+                checked={adjustForm.direction === 'grant'}
+                onChange={() => setAdjustForm({ ...adjustForm, direction: 'grant' })}
+                className="mr-2"
+              />
+              发放积分
+            </label>
+            <label className="flex items-center">
+              <input
+                type="radio"
+                checked={adjustForm.direction === 'revoke'}
+                onChange={() => setAdjustForm({ ...adjustForm, direction: 'revoke' })}
+                className="mr-2"
+              />
+              扣回积分
+            </label>
+          </div>
+
+          <div>
+            <label className="block text-sm font-medium mb-1">积分数量</label>
+            <Input
+              type="number"
+              min={1}
+              value={adjustForm.credits}
+              onChange={(e) => setAdjustForm({ ...adjustForm, credits: parseInt(e.target.value) || 0 })}
+            />
+          </div>
+
+          {adjustForm.direction === 'grant' && (
+            <div>
+              <label className="block text-sm font-medium mb-1">有效天数（0 表示永久有效）</label>
+              <Input
+                type="number"
+                min={0}
+                value={adjustForm.validity_days}
+                onChange={(e) =>
+                  setAdjustForm({ ...adjustForm, validity_days: parseInt(e.target.value) || 0 })
+                }
+              />
+            </div>
+          )}
+
+          <div>
+            <label className="block text-sm font-medium mb-1">调整原因</label>
+            <select
+              className="w-full border rounded px-3 py-2"
+              value={adjustForm.reason_code}
+              onChange={(e) =>
+                setAdjustForm({ ...adjustForm, reason_code: e.target.value as AdjustmentReason })
+              }
+            >
+              {(Object.keys(ADJUSTMENT_REASON_NAME_MAP) as AdjustmentReason[]).map((reason) => (
+                <option key={reason} value={reason}>
+                  {ADJUSTMENT_REASON_NAME_MAP[reason]}
+                </option>
+              ))}
+            </select>
+          </div>
+
+          <div>
+            <label className="block text-sm font-medium mb-1">调整说明</label>
+            <Input
+              value={adjustForm.notes}
+              onChange={(e) => setAdjustForm({ ...adjustForm, notes: e.target.value })}
+              placeholder="必填，如关联的工单号或失败的导出任务"
+            />
+          </div>
+
+          <div className="flex gap-2 justify-end">
+            <Button variant="outline" onClick={() => setAdjustModalOpen(false)}>
+              取消
+            </Button>
+            <Button onClick={handleAdjustCredits} disabled={loading}>
+              {loading ? '提交中...' : '确定'}
+            </Button>
+          </div>
+        </div>
+      </Modal>
     </div>
   );
 };
@@ -1,9 +1,11 @@
 import React, { useState, useEffect } from 'react';
 import { adminAPI } from '@/api/admin';
+import { listActionPrices } from '@/api/billing';
 import { showSuccess, showError } from '@/utils/toast';
 import { Modal, Button } from '@/components/ui';
 import { WorkflowStreamTester } from '@/components/workflow/WorkflowStreamTester';
 import type { Workflow, CreateWorkflowRequest, UpdateWorkflowRequest } from '@/types/workflow';
+import type { BillingActionPrice } from '@/types/billing';
 
 interface WorkflowModalProps {
   mode: 'create' | 'edit';
@@ -21,11 +23,13 @@ const WorkflowModal: React.FC<WorkflowModalProps> = ({ mode, workflow, onClose }
     outputs: {},
     is_public: false,
     enabled: true,
+    billing_action_key: '',
   });
   const [loading, setLoading] = useState(false);
   const [inputsJson, setInputsJson] = useState('{}');
   const [outputsJson, setOutputsJson] = useState('{}');
   const [jsonErrors, setJsonErrors] = useState({ inputs: '', outputs: '' });
+  const [actionPrices, setActionPrices] = useState<BillingActionPrice[]>([]);
 
   const title = mode === 'create' ? '创建工作流' : mode === 'edit' ? '编辑工作流' : '工作流详情';
 
@@ -40,12 +44,26 @@ const WorkflowModal: React.FC<WorkflowModalProps> = ({ mode, workflow, onClose }
         outputs: workflow.outputs,
         is_public: workflow.is_public,
         enabled: workflow.enabled,
+        billing_action_key: workflow.billing_action_key || '',
       });
       setInputsJson(JSON.stringify(workflow.inputs || {}, null, 2));
       setOutputsJson(JSON.stringify(workflow.outputs || {}, null, 2));
     }
   }, [workflow, mode]);
 
+  // 加载计费动作列表（包含已停用的动作，停用后该工作流按免费处理）
+  useEffect(() => {
+    listActionPrices(false)
+      .then((response) => {
+        if (response.code === 0) {
+          setActionPrices(response.data || []);
+        }
+      })
+      .catch((error) => {
+        console.error('加载计费动作失败:', error);
+      });
+  }, []);
+
   // 处理表单字段变化
   const handleFieldChange = (field: keyof CreateWorkflowRequest, value: any) => {
     setFormData(prev => ({ ...prev, [field]: value }));
@@ -83,6 +101,10 @@ const WorkflowModal: React.FC<WorkflowModalProps> = ({ mode, workflow, onClose }
       let response;
       if (mode === 'create') {
         response = await adminAPI.createWorkflow(formData);
+        // 创建接口不设置计费动作，需通过管理员更新接口补充
+        if (response.code === 0 && formData.billing_action_key && response.data?.id) {
+          response = await adminAPI.updateWorkflowAsAdmin(response.data.id, { ...formData });
+        }
       } else {
         const updateData: UpdateWorkflowRequest = { ...formData };
         response = await adminAPI.updateWorkflowAsAdmin(workflow!.id, updateData);
@@ -183,9 +205,32 @@ const WorkflowModal: React.FC<WorkflowModalProps> = ({ mode, workflow, onClose }
                 autoCapitalize="off"
                 spellCheck="false"
                 className={`w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500`}
-                placeholder="请输入API密钥"
+                placeholder={mode === 'edit' ? '保持脱敏值表示不修改' : '请输入API密钥'}
               />
             </div>
+
+            <div>
+              <label className="block text-sm font-medium text-gray-700 mb-1">
+                计费动作
+              </label>
+              <select
+                value={formData.billing_action_key || ''}
+                onChange={(e) => handleFieldChange('billing_action_key', e.target.value)}
+                className={`w-full px-3 py-2 border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500`}
+              >
+                <option value="">免费（不扣费）</option>
+                {actionPrices.map((price) => (
+                  <option key={price.action_key} value={price.action_key}>
+                    {price.action_name}
+                    {price.pricing_mode === 'metered'
+                      ? `（${price.credits_cost} 积分 + ${price.credits_per_1k_tokens} 积分/千token，上限 ${price.max_credits_per_call}）`
+                      : `（${price.credits_cost} 积分）`}
+                    {price.is_active ? '' : ' - 已停用'}
+                  </option>
+                ))}
+              </select>
+              <p className="mt-1 text-xs text-gray-500">执行该工作流时按所选动作扣减积分，动作停用后视为免费</p>
+            </div>
           </div>
 
           {/* 配置选项 */}
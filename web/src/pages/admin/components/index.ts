@@ -7,3 +7,5 @@ export { default as SiteVariableManagement } from './SiteVariableManagement';
 export { default as EventLogManagement } from './EventLogManagement';
 export { default as BillingPackageManagement } from './BillingPackageManagement';
 export { default as UserBillingPackageList } from './UserBillingPackageList';
+export { default as BillingReports } from './BillingReports';
+export { default as ActionQuotaManagement } from './ActionQuotaManagement';
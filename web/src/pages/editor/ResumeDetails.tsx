@@ -14,6 +14,7 @@ import { type FontSettings } from './components/FontSettingsPanel';
 import LoadingIndicator, { type LoadingStage } from '@/components/LoadingIndicator';
 import { defaultResumeData, type ResumeData } from '@/types/resume';
 import { resumeAPI } from '@/api/resume';
+import { jobAPI } from '@/api/jobs';
 import { showError, showSuccess, showInfo } from '@/utils/toast';
 import { exportResumeToPDF, exportResumeToPDFViaCanvas } from '@/utils/pdfExport';
 import { workflowAPI } from '@/api/workflow';
@@ -206,6 +207,11 @@ export default function ResumeDetails() {
 
       const response = await resumeAPI.resumeFileToText(resumeId);
       if (response.code === 0) {
+        // 文本提取在后台任务中执行，等待任务完成
+        const job = await jobAPI.pollUntilComplete(response.data.id);
+        if (job.status === 'failed') {
+          return { success: false, error: job.error_message || '文件解析失败' };
+        }
         updater.completeCurrentStep();
         // 更新处理阶段到 metadata
         await updateMetadata({ processingStage: 'parsed' }, true);
@@ -1,10 +1,10 @@
 import React, { useState } from 'react';
-import { FiUser, FiPackage } from 'react-icons/fi';
-import { ProfileInfo, PackagesList } from './components';
+import { FiUser, FiPackage, FiBell } from 'react-icons/fi';
+import { ProfileInfo, PackagesList, NotificationsList } from './components';
 import { Button } from '@/components/ui/Button';
 import { useAuthStore } from '@/store';
 
-type TabType = 'account' | 'packages';
+type TabType = 'account' | 'packages' | 'notifications';
 
 const Profile: React.FC = () => {
   const [loading, setLoading] = useState(false);
@@ -15,6 +15,7 @@ const Profile: React.FC = () => {
   const tabs = [
     { id: 'account' as TabType, name: '账号设置', icon: FiUser, adminOnly: false },
     { id: 'packages' as TabType, name: '升级计划(Pro)', icon: FiPackage, adminOnly: false },
+    { id: 'notifications' as TabType, name: '消息通知', icon: FiBell, adminOnly: false },
   ];
 
   const renderContent = () => {
@@ -23,6 +24,8 @@ const Profile: React.FC = () => {
         return <ProfileInfo loading={loading} setLoading={setLoading} />;
       case 'packages':
         return <PackagesList />;
+      case 'notifications':
+        return <NotificationsList />;
       default:
         return null;
     }
@@ -0,0 +1,190 @@
+import React, { useState, useEffect } from 'react';
+import { showError, showSuccess } from '@/utils/toast';
+import {
+  getMyNotifications,
+  markNotificationRead,
+  markAllNotificationsRead,
+  getNotificationPreference,
+  updateNotificationPreference,
+} from '@/api/notification';
+import type { Notification, NotificationPreference } from '@/types/notification';
+
+const PREFERENCE_OPTIONS: { key: keyof NotificationPreference; label: string }[] = [
+  { key: 'package_expiry', label: '套餐即将到期提醒' },
+  { key: 'low_balance', label: '积分余额不足提醒' },
+  { key: 'external_channels', label: '同时通过短信/邮件提醒' },
+];
+
+const PAGE_SIZE = 20;
+
+const NotificationsList: React.FC = () => {
+  const [loading, setLoading] = useState(false);
+  const [notifications, setNotifications] = useState<Notification[]>([]);
+  const [total, setTotal] = useState(0);
+  const [unreadCount, setUnreadCount] = useState(0);
+  const [page, setPage] = useState(1);
+  const [preference, setPreference] = useState<NotificationPreference | null>(null);
+  const [savingPreference, setSavingPreference] = useState(false);
+
+  useEffect(() => {
+    getNotificationPreference()
+      .then((response) => {
+        if (response.code === 0) {
+          setPreference(response.data);
+        }
+      })
+      .catch((error) => {
+        console.error('加载通知偏好失败:', error);
+      });
+  }, []);
+
+  useEffect(() => {
+    loadNotifications();
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [page]);
+
+  const loadNotifications = async () => {
+    try {
+      setLoading(true);
+      const response = await getMyNotifications({ page, page_size: PAGE_SIZE });
+      if (response.code === 0) {
+        setNotifications(response.data.list || []);
+        setTotal(response.data.total);
+        setUnreadCount(response.data.unread_count);
+      } else {
+        showError(response.msg || '加载通知失败');
+      }
+    } catch (error) {
+      console.error('加载通知失败:', error);
+      showError('加载通知失败');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const handleMarkRead = async (notification: Notification) => {
+    if (notification.read_at) return;
+    try {
+      const response = await markNotificationRead(notification.id);
+      if (response.code === 0) {
+        setNotifications((list) =>
+          list.map((item) => (item.id === notification.id ? { ...item, read_at: new Date().toISOString() } : item))
+        );
+        setUnreadCount((count) => Math.max(0, count - 1));
+      }
+    } catch (error) {
+      console.error('标记已读失败:', error);
+    }
+  };
+
+  const handleMarkAllRead = async () => {
+    try {
+      const response = await markAllNotificationsRead();
+      if (response.code === 0) {
+        loadNotifications();
+      } else {
+        showError(response.msg || '标记已读失败');
+      }
+    } catch (error) {
+      console.error('标记已读失败:', error);
+      showError('标记已读失败');
+    }
+  };
+
+  const handleTogglePreference = async (key: keyof NotificationPreference) => {
+    if (!preference) return;
+    const next = { ...preference, [key]: !preference[key] };
+    try {
+      setSavingPreference(true);
+      const response = await updateNotificationPreference(next);
+      if (response.code === 0) {
+        setPreference(response.data);
+        showSuccess('通知偏好已更新');
+      } else {
+        showError(response.msg || '更新通知偏好失败');
+      }
+    } catch (error) {
+      console.error('更新通知偏好失败:', error);
+      showError('更新通知偏好失败');
+    } finally {
+      setSavingPreference(false);
+    }
+  };
+
+  const totalPages = Math.max(1, Math.ceil(total / PAGE_SIZE));
+
+  return (
+    <div className="bg-white space-y-6">
+      <div className="flex justify-between items-center">
+        <h2 className="text-xl font-semibold">
+          消息通知
+          {unreadCount > 0 && <span className="ml-2 text-sm text-blue-600">{unreadCount} 条未读</span>}
+        </h2>
+        {unreadCount > 0 && (
+          <button className="text-sm text-blue-600 hover:text-blue-800" onClick={handleMarkAllRead}>
+            全部标记为已读
+          </button>
+        )}
+      </div>
+
+      {/* 通知偏好 */}
+      {preference && (
+        <div className="p-4 border border-gray-200 rounded-lg space-y-2">
+          <div className="text-sm font-medium text-gray-900">提醒设置</div>
+          {PREFERENCE_OPTIONS.map((option) => (
+            <label key={option.key} className="flex items-center gap-2 text-sm text-gray-700">
+              <input
+                type="checkbox"
+                checked={preference[option.key]}
+                disabled={savingPreference}
+                onChange={() => handleTogglePreference(option.key)}
+              />
+              {option.label}
+            </label>
+          ))}
+        </div>
+      )}
+
+      {/* 通知列表 */}
+      {loading ? (
+        <div className="text-gray-500 py-6 text-center">加载中...</div>
+      ) : notifications.length === 0 ? (
+        <div className="text-gray-500 py-6 text-center">暂无通知</div>
+      ) : (
+        <div className="divide-y divide-gray-200 border border-gray-200 rounded-lg">
+          {notifications.map((notification) => (
+            <div
+              key={notification.id}
+              className={`p-4 cursor-pointer ${notification.read_at ? '' : 'bg-blue-50'}`}
+              onClick={() => handleMarkRead(notification)}
+            >
+              <div className="flex justify-between items-center">
+                <span className={`text-sm ${notification.read_at ? 'text-gray-700' : 'font-semibold text-gray-900'}`}>
+                  {notification.title}
+                </span>
+                <span className="text-xs text-gray-500">{new Date(notification.created_at).toLocaleString()}</span>
+              </div>
+              <p className="mt-1 text-sm text-gray-600">{notification.content}</p>
+            </div>
+          ))}
+        </div>
+      )}
+
+      {totalPages > 1 && (
+        <div className="flex justify-center items-center gap-4 text-sm">
+          <button disabled={page <= 1} onClick={() => setPage(page - 1)} className="disabled:text-gray-400">
+            上一页
+          </button>
+          <span>
+            {page} / {totalPages}
+          </span>
+          <button disabled={page >= totalPages} onClick={() => setPage(page + 1)} className="disabled:text-gray-400">
+            下一页
+          </button>
+        </div>
+      )}
+    </div>
+  );
+};
+
+export default NotificationsList;
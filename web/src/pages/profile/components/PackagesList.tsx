@@ -1,15 +1,27 @@
 import React, { useState, useEffect } from 'react';
-import { showError } from '@/utils/toast';
-import { getPublicBillingPackages } from '@/api/billing';
-import type { BillingPackage } from '@/types/billing';
+import { showError, showSuccess } from '@/utils/toast';
+import { getPublicBillingPackages, getActiveActionPrices, createOrder, cancelOrder, mockPayOrder, previewCoupon } from '@/api/billing';
+import type { BillingActionPrice, BillingPackage } from '@/types/billing';
 import { PACKAGE_TYPE_NAME_MAP } from '@/types/billing';
 
 const PackagesList: React.FC = () => {
   const [loading, setLoading] = useState(false);
   const [packages, setPackages] = useState<BillingPackage[]>([]);
+  const [purchasingId, setPurchasingId] = useState<number | null>(null);
+  const [priceChanges, setPriceChanges] = useState<BillingActionPrice[]>([]);
+  const [couponCode, setCouponCode] = useState('');
 
   useEffect(() => {
     loadPackages();
+    getActiveActionPrices()
+      .then((response) => {
+        if (response.code === 0) {
+          setPriceChanges((response.data || []).filter((price) => price.upcoming_change));
+        }
+      })
+      .catch((error) => {
+        console.error('加载动作价格失败:', error);
+      });
   }, []);
 
   const loadPackages = async () => {
@@ -30,6 +42,61 @@ const PackagesList: React.FC = () => {
     }
   };
 
+  const handlePurchase = async (pkg: BillingPackage) => {
+    try {
+      setPurchasingId(pkg.id);
+      const code = couponCode.trim();
+      if (code) {
+        // 下单前先试算，优惠券不可用时给出原因
+        const quoteResponse = await previewCoupon(code, pkg.id);
+        if (quoteResponse.code !== 0) {
+          showError(quoteResponse.msg || '优惠券不可用');
+          return;
+        }
+      }
+
+      const response = await createOrder(pkg.id, undefined, code || undefined);
+      if (response.code !== 0) {
+        showError(response.msg || '创建订单失败');
+        return;
+      }
+
+      const { order, payment } = response.data;
+      if (!payment) {
+        // 优惠后无需付款，订单已直接履约
+        showSuccess('兑换成功，套餐已到账');
+        setCouponCode('');
+        return;
+      }
+      if (payment.provider !== 'mock') {
+        // 真实支付渠道跳转到渠道支付页面，支付结果通过回调通知
+        if (payment.pay_url) {
+          window.location.href = payment.pay_url;
+        }
+        return;
+      }
+
+      // 模拟支付：确认后直接完成支付
+      const discountText = order.discount_amount > 0 ? `（已优惠 ¥${(order.discount_amount / 100).toFixed(2)}）` : '';
+      if (!window.confirm(`模拟支付 ¥${(order.amount / 100).toFixed(2)}${discountText} 购买「${order.package_name}」？`)) {
+        await cancelOrder(order.id);
+        return;
+      }
+      const payResponse = await mockPayOrder(order.id);
+      if (payResponse.code === 0) {
+        showSuccess('购买成功，套餐已到账');
+        setCouponCode('');
+      } else {
+        showError(payResponse.msg || '支付失败');
+      }
+    } catch (error: any) {
+      console.error('购买套餐失败:', error);
+      showError(error?.message || '购买套餐失败');
+    } finally {
+      setPurchasingId(null);
+    }
+  };
+
   if (loading) {
     return (
       <div className="flex justify-center items-center py-12">
@@ -104,26 +171,53 @@ const PackagesList: React.FC = () => {
             </span>
 
             {/* 购买按钮 */}
-            {/* <button
-              className="w-full bg-blue-600 hover:bg-blue-700 text-white font-medium py-2 px-4 rounded-lg transition-colors cursor-not-allowed"
-              onClick={() => {
-                // TODO: 实现购买逻辑
-                console.log('购买套餐:', pkg.id);
-              }}
-              disabled={false}
-            >
-              选择套餐
-            </button> */}
+            {pkg.price > 0 && (
+              <button
+                className="w-full mt-4 bg-blue-600 hover:bg-blue-700 text-white font-medium py-2 px-4 rounded-lg transition-colors disabled:opacity-50 disabled:cursor-not-allowed"
+                onClick={() => handlePurchase(pkg)}
+                disabled={purchasingId !== null}
+              >
+                {purchasingId === pkg.id ? '处理中...' : '选择套餐'}
+              </button>
+            )}
           </div>
         ))}
       </div>
 
+      {/* 优惠码 */}
+      <div className="mt-4 flex items-center gap-2">
+        <label htmlFor="coupon-code" className="text-sm text-gray-600">
+          优惠码
+        </label>
+        <input
+          id="coupon-code"
+          type="text"
+          value={couponCode}
+          onChange={(e) => setCouponCode(e.target.value.toUpperCase())}
+          placeholder="选填，购买时自动使用"
+          className="w-56 px-3 py-1.5 text-sm border border-gray-300 rounded-md focus:outline-none focus:ring-2 focus:ring-blue-500"
+        />
+      </div>
+
       {/* 提示信息 */}
       <div className="mt-8 p-4 bg-blue-50 border border-blue-200 rounded-lg">
         <p className="text-sm text-blue-800">
           积分可用于简历优化、AI对话等服务
         </p>
       </div>
+
+      {/* 价格调整预告 */}
+      {priceChanges.length > 0 && (
+        <div className="mt-4 p-4 bg-amber-50 border border-amber-200 rounded-lg space-y-1">
+          {priceChanges.map((price) => (
+            <p key={price.action_key} className="text-sm text-amber-800">
+              {price.action_name}将于 {new Date(price.upcoming_change!.effective_at).toLocaleString()} 起
+              由 {price.credits_cost} 积分/次调整为 {price.upcoming_change!.credits_cost} 积分/次
+              {price.upcoming_change!.notes ? `（${price.upcoming_change!.notes}）` : ''}
+            </p>
+          ))}
+        </div>
+      )}
     </div>
   );
 };
@@ -1,2 +1,3 @@
 export { default as ProfileInfo } from './ProfileInfo';
 export { default as PackagesList } from './PackagesList';
+export { default as NotificationsList } from './NotificationsList';
@@ -4,6 +4,7 @@ import { FiEdit, FiShare2, FiTrash2, FiFileText, FiCalendar, FiUser } from 'reac
 // import { Sparkles } from 'lucide-react';
 import { Button } from '@/components/ui';
 import { resumeAPI } from '@/api/resume';
+import { jobAPI } from '@/api/jobs';
 import { fileAPI } from '@/api/file';
 import type { ResumeDetail as ResumeDetailType } from '@/types/resume';
 import { showSuccess, showError } from '@/utils/toast';
@@ -92,6 +93,12 @@ const ResumeDetail: React.FC = () => {
       setProcessingText(true);
       const response = await resumeAPI.resumeFileToText(id);
       if (response.code === 0) {
+        // 文本提取在后台任务中执行，等待任务完成
+        const job = await jobAPI.pollUntilComplete(response.data.id);
+        if (job.status === 'failed') {
+          showError(job.error_message || '文本提取失败');
+          return;
+        }
         showSuccess('文本提取成功');
         // 刷新简历详情
         await loadResumeDetail();
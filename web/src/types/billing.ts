@@ -24,9 +24,57 @@ export interface BillingActionPrice {
   action_name: string;
   description: string;
   credits_cost: number;
+  pricing_mode: PricingMode;
+  credits_per_1k_tokens: number; // 按量计费：每千token积分
+  max_credits_per_call: number; // 按量计费：单次上限
   is_active: boolean;
   sort_order: number;
   metadata?: Record<string, any>;
+  price_version_id?: number; // 当前生效的价格版本
+  upcoming_change?: ActionPriceChange; // 即将发生的价格变动
+}
+
+// 计费方式
+export type PricingMode = 'fixed' | 'metered';
+
+// 更新动作配置请求（基础价格通过价格版本调整）
+export interface UpdateActionPriceRequest {
+  action_name?: string;
+  description?: string;
+  is_active?: boolean;
+  sort_order?: number;
+  pricing_mode?: PricingMode;
+  credits_per_1k_tokens?: number;
+  max_credits_per_call?: number;
+}
+
+// 动作价格变动预告
+export interface ActionPriceChange {
+  credits_cost: number;
+  effective_at: string;
+  price_version_id?: number; // 为空表示恢复基础价格
+  notes?: string;
+}
+
+// 动作价格版本
+export interface BillingActionPriceVersion {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  action_key: string;
+  credits_cost: number;
+  effective_from: string;
+  effective_to?: string;
+  notes?: string;
+  created_by?: string;
+}
+
+// 排期价格版本请求
+export interface SchedulePriceRequest {
+  credits_cost: number;
+  effective_from?: string; // 为空表示立即生效
+  effective_to?: string; // 为空表示长期有效
+  notes?: string;
 }
 
 // 套餐定义
@@ -45,6 +93,8 @@ export interface BillingPackage {
   is_visible: boolean;
   sort_order: number;
   display_order: number;
+  covered_actions?: string[]; // 不限次使用的动作（时长型/混合型/永久型）
+  daily_action_limit: number; // 每个动作每日合理使用上限，0=不限
   metadata?: Record<string, any>;
 }
 
@@ -60,6 +110,8 @@ export interface UserBillingPackage {
   total_credits: number;
   used_credits: number;
   remaining_credits: number;
+  covered_actions?: string[];
+  daily_action_limit: number;
   activated_at?: string;
   expires_at?: string;
   status: PackageStatus;
@@ -82,6 +134,8 @@ export interface CreateBillingPackageRequest {
   is_visible: boolean;
   sort_order?: number;
   display_order?: number;
+  covered_actions?: string[];
+  daily_action_limit?: number;
 }
 
 // 分配套餐请求
@@ -99,11 +153,23 @@ export interface CheckCreditsRequest {
   action_key: ActionKey;
 }
 
+// 套餐提供的动作权益
+export interface ActionEntitlement {
+  action_key: string;
+  user_billing_package_id: number;
+  package_name: string;
+  package_type: PackageType;
+  daily_limit: number; // 0=不限
+  used_today: number;
+  expires_at?: string;
+}
+
 // 检查积分响应
 export interface CheckCreditsResponse {
   has_enough: boolean;
   total_credits: number;
   required_credits: number;
+  entitlement?: ActionEntitlement;
 }
 
 // 扣减积分请求
@@ -120,12 +186,297 @@ export interface DeductCreditsResponse {
   deducted_credits: number;
   remaining_credits: number;
   message?: string;
+  entitlement_package_id?: number;
 }
 
 // 我的积分响应
 export interface MyCreditsResponse {
   total_credits: number;
   user_id: string;
+  entitlements: ActionEntitlement[];
+}
+
+// 订单状态枚举
+export type OrderStatus = 'created' | 'paid' | 'fulfilled' | 'cancelled' | 'refunded';
+
+// 套餐购买订单
+export interface Order {
+  id: string;
+  created_at: string;
+  updated_at: string;
+  user_id: string;
+  billing_package_id: number;
+  package_name: string;
+  amount: number; // 订单金额（分）
+  status: OrderStatus;
+  provider: string;
+  provider_trade_no?: string;
+  user_billing_package_id?: number;
+  expires_at: string;
+  paid_at?: string;
+  fulfilled_at?: string;
+  cancelled_at?: string;
+  refunded_at?: string;
+  coupon_code?: string; // 使用的优惠码
+  original_amount: number; // 优惠前金额（分）
+  discount_amount: number; // 优惠减免金额（分）
+  bonus_credits: number; // 优惠券赠送积分
+}
+
+// 发起支付结果
+export interface PaymentIntent {
+  provider: string;
+  pay_url?: string;
+  params?: Record<string, any>;
+}
+
+// 创建订单响应
+export interface CreateOrderResponse {
+  order: Order;
+  payment: PaymentIntent | null; // 优惠后无需付款的订单直接履约，不发起支付
+}
+
+// 优惠券折扣类型
+export type CouponDiscountType = 'percent' | 'fixed' | 'none';
+
+// 优惠券
+export interface Coupon {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  code: string;
+  name: string;
+  description?: string;
+  discount_type: CouponDiscountType;
+  discount_value: number; // percent 为折扣百分比，fixed 为减免金额（分）
+  max_discount: number; // 百分比折扣的最高减免金额（分），0=不限
+  min_amount: number; // 订单最低金额（分），0=不限
+  bonus_credits: number;
+  package_ids?: number[]; // 为空表示全部套餐
+  max_redemptions: number; // 0=不限
+  per_user_limit: number; // 0=不限
+  redeemed_count: number;
+  starts_at?: string;
+  ends_at?: string;
+  is_active: boolean;
+  created_by?: string;
+}
+
+// 创建/更新优惠券请求
+export interface CouponRequest {
+  code?: string; // 仅创建时有效，为空时自动生成
+  name: string;
+  description?: string;
+  discount_type: CouponDiscountType;
+  discount_value: number;
+  max_discount?: number;
+  min_amount?: number;
+  bonus_credits?: number;
+  package_ids?: number[];
+  max_redemptions?: number;
+  per_user_limit?: number;
+  starts_at?: string;
+  ends_at?: string;
+  is_active: boolean;
+}
+
+// 优惠券使用记录状态
+export type CouponRedemptionStatus = 'pending' | 'redeemed' | 'released';
+
+// 优惠券使用记录
+export interface CouponRedemption {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  coupon_id: number;
+  code: string;
+  user_id: string;
+  order_id: string;
+  original_amount: number;
+  discount_amount: number;
+  bonus_credits: number;
+  status: CouponRedemptionStatus;
+  redeemed_at?: string;
+  released_at?: string;
+}
+
+// 优惠券使用统计
+export interface CouponStats {
+  coupon: Coupon;
+  pending_count: number;
+  redeemed_count: number;
+  released_count: number;
+  unique_users: number;
+  total_discount: number;
+  total_paid_amount: number;
+  total_bonus_credits: number;
+}
+
+// 优惠券试算结果
+export interface CouponQuote {
+  code: string;
+  name: string;
+  original_amount: number;
+  discount_amount: number;
+  final_amount: number;
+  bonus_credits: number;
+}
+
+// 优惠券查询响应
+export interface CouponListResponse {
+  list: Coupon[];
+  total: number;
+  page: number;
+  page_size: number;
+}
+
+// 优惠券使用记录查询响应
+export interface CouponRedemptionListResponse {
+  list: CouponRedemption[];
+  total: number;
+  page: number;
+  page_size: number;
+}
+
+// 积分调整原因代码
+export type AdjustmentReason = 'compensation' | 'goodwill' | 'promotion' | 'correction' | 'abuse' | 'other';
+
+// 管理员积分调整记录
+export interface CreditAdjustment {
+  id: number;
+  created_at: string;
+  user_id: string;
+  operator_id: string;
+  credits: number; // 发放为正，扣回为负
+  balance_after: number;
+  reason_code: AdjustmentReason;
+  notes: string;
+  user_billing_package_id?: number;
+  expires_at?: string;
+}
+
+// 积分调整请求
+export interface AdjustCreditsRequest {
+  user_id: string;
+  credits: number; // 正数发放，负数扣回
+  reason_code: AdjustmentReason;
+  notes: string;
+  validity_days?: number; // 发放积分的有效天数，0=永久有效
+}
+
+// 积分调整记录查询响应
+export interface CreditAdjustmentListResponse {
+  list: CreditAdjustment[];
+  total: number;
+  page: number;
+  page_size: number;
+}
+
+// 计费报表类型
+export type BillingReportType = 'revenue' | 'credits' | 'breakage' | 'top-consumers' | 'conversion';
+
+// 计费报表查询条件
+export interface BillingReportParams {
+  start_date?: string; // YYYY-MM-DD
+  end_date?: string; // YYYY-MM-DD
+  days?: number; // 最近N天，与 start_date/end_date 二选一
+  limit?: number; // 排行类报表的条数
+}
+
+// 报表时间范围
+export interface ReportRange {
+  start_time: string;
+  end_time: string;
+}
+
+// 每日每套餐收入
+export interface RevenueReportItem {
+  date: string;
+  package_id: number;
+  package_name: string;
+  orders: number;
+  original_amount: number;
+  discount_amount: number;
+  gross_amount: number;
+  refunded_amount: number;
+  net_amount: number;
+}
+
+// 收入报表
+export interface RevenueReport {
+  range: ReportRange;
+  items: RevenueReportItem[];
+  total_orders: number;
+  total_gross: number;
+  total_refunded: number;
+  total_net_amount: number;
+}
+
+// 单个动作的积分消耗
+export interface ActionCreditsItem {
+  action_key: string;
+  action_name: string;
+  deductions: number;
+  consumed_credits: number;
+  refunded_credits: number;
+  net_credits: number;
+}
+
+// 积分发放与消耗报表
+export interface CreditsReport {
+  range: ReportRange;
+  actions: ActionCreditsItem[];
+  issued_credits: number;
+  adjusted_credits: number;
+  consumed_credits: number;
+  expired_credits: number;
+}
+
+// 每日过期作废积分
+export interface BreakageReportItem {
+  date: string;
+  packages: number;
+  expired_credits: number;
+}
+
+// 积分沉淀报表
+export interface BreakageReport {
+  range: ReportRange;
+  items: BreakageReportItem[];
+  issued_credits: number;
+  expired_credits: number;
+  breakage_rate: number;
+}
+
+// 积分消耗排行
+export interface TopConsumerItem {
+  user_id: string;
+  user_name: string;
+  deductions: number;
+  consumed_credits: number;
+  paid_amount: number;
+}
+
+// 积分消耗排行报表
+export interface TopConsumersReport {
+  range: ReportRange;
+  items: TopConsumerItem[];
+}
+
+// 赠送套餐转化情况
+export interface ConversionReportItem {
+  package_name: string;
+  source: PackageSource;
+  gifted_users: number;
+  converted_users: number;
+  conversion_rate: number;
+  converted_amount: number;
+}
+
+// 赠送→购买转化报表
+export interface ConversionReport {
+  range: ReportRange;
+  items: ConversionReportItem[];
 }
 
 // 动作名称映射
@@ -160,3 +511,109 @@ export const PACKAGE_SOURCE_NAME_MAP: Record<PackageSource, string> = {
   system: '系统',
 };
 
+// 订单状态名称映射
+export const ORDER_STATUS_NAME_MAP: Record<OrderStatus, string> = {
+  created: '待支付',
+  paid: '已支付',
+  fulfilled: '已完成',
+  cancelled: '已取消',
+  refunded: '已退款',
+};
+
+// 积分调整原因名称映射
+export const ADJUSTMENT_REASON_NAME_MAP: Record<AdjustmentReason, string> = {
+  compensation: '服务故障补偿',
+  goodwill: '客服关怀',
+  promotion: '活动奖励',
+  correction: '账务更正',
+  abuse: '违规扣回',
+  other: '其他',
+};
+
+// 配额滑动窗口
+export type QuotaWindow = 'minute' | 'day' | 'month';
+
+// 配额窗口名称映射
+export const QUOTA_WINDOW_NAME_MAP: Record<QuotaWindow, string> = {
+  minute: '每分钟',
+  day: '每天',
+  month: '每30天',
+};
+
+// 配额规则来源名称映射
+export const QUOTA_SOURCE_NAME_MAP: Record<string, string> = {
+  override: '用户覆盖',
+  package: '套餐档位',
+  default: '默认规则',
+  builtin: '内置限制',
+};
+
+// 动作使用配额规则（billing_package_id 为 0 表示默认规则，各上限为 0 表示不限）
+export interface ActionQuota {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  billing_package_id: number;
+  action_key: string;
+  per_minute: number;
+  per_day: number;
+  per_month: number;
+  notes: string;
+}
+
+// 保存配额规则请求
+export interface QuotaRuleRequest {
+  billing_package_id: number;
+  action_key: string;
+  per_minute: number;
+  per_day: number;
+  per_month: number;
+  notes?: string;
+}
+
+// 用户配额覆盖
+export interface ActionQuotaOverride {
+  id: number;
+  created_at: string;
+  updated_at: string;
+  user_id: string;
+  action_key: string;
+  per_minute: number;
+  per_day: number;
+  per_month: number;
+  expires_at?: string;
+  operator_id: string;
+  notes: string;
+}
+
+// 设置用户配额覆盖请求
+export interface QuotaOverrideRequest {
+  action_key: string;
+  per_minute: number;
+  per_day: number;
+  per_month: number;
+  expires_at?: string;
+  notes?: string;
+}
+
+// 单个窗口的配额使用情况
+export interface QuotaWindowStatus {
+  window: QuotaWindow;
+  limit: number;
+  used: number;
+  remaining: number;
+  reset_at?: string;
+}
+
+// 动作配额使用情况
+export interface QuotaStatus {
+  action_key: string;
+  source: string;
+  windows: QuotaWindowStatus[];
+}
+
+// 用户配额覆盖与使用情况
+export interface UserQuotasResponse {
+  overrides: ActionQuotaOverride[];
+  statuses: QuotaStatus[];
+}
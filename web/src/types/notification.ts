@@ -0,0 +1,31 @@
+// 通知类型
+export type NotificationType = 'package_expiring' | 'low_balance';
+
+// 站内通知
+export interface Notification {
+  id: number;
+  created_at: string;
+  user_id: string;
+  type: NotificationType;
+  title: string;
+  content: string;
+  resource_type?: string;
+  resource_id?: string;
+  read_at?: string;
+}
+
+// 通知列表响应
+export interface NotificationListResponse {
+  list: Notification[];
+  total: number;
+  unread_count: number;
+  page: number;
+  page_size: number;
+}
+
+// 通知偏好
+export interface NotificationPreference {
+  package_expiry: boolean;
+  low_balance: boolean;
+  external_channels: boolean;
+}
@@ -2,7 +2,7 @@
 export interface Workflow {
   id: string;
   api_url: string;
-  api_key: string;
+  api_key: string; // 管理员接口返回脱敏值，如 app-****abcd
   name: string;
   description: string;
   creator_id?: string;
@@ -11,6 +11,13 @@ export interface Workflow {
   used: number;
   is_public: boolean;
   enabled: boolean;
+  billing_action_key?: string; // 计费动作，为空表示免费
+  config_version?: number; // 当前配置版本
+  max_concurrency?: number; // 异步任务最大并发数，0 表示使用全局默认值
+  fallback_workflow_id?: string; // 备用工作流ID，上游不可用时切换执行，为空表示不切换
+  cache_enabled?: boolean; // 是否开启响应缓存
+  cache_ttl_seconds?: number; // 缓存有效期，0 表示使用全局默认值
+  cache_action_key?: string; // 命中缓存时的计费动作，为空表示命中缓存免费
   created_at: string;
   updated_at: string;
 }
@@ -20,6 +27,16 @@ export interface WorkflowField {
   field_name: string;
   field_type: 'string' | 'number' | 'boolean' | 'file';
   required: boolean;
+  max_length?: number; // string 的最大字符数，file 的最大文件数
+  min?: number; // number 的最小值
+  max?: number; // number 的最大值
+  enum?: Array<string | number | boolean>; // 可选值
+}
+
+// 字段级校验错误（输入校验失败时接口返回 400，data.errors 为错误列表）
+export interface WorkflowFieldError {
+  field: string;
+  message: string;
 }
 
 // 创建工作流请求
@@ -32,6 +49,7 @@ export interface CreateWorkflowRequest {
   outputs?: any;
   is_public?: boolean;
   enabled?: boolean;
+  billing_action_key?: string;
 }
 
 // 更新工作流请求
@@ -44,6 +62,43 @@ export interface UpdateWorkflowRequest {
   outputs?: any;
   is_public?: boolean;
   enabled?: boolean;
+  billing_action_key?: string;
+  notes?: string; // 配置变更说明
+  max_concurrency?: number; // 异步任务最大并发数，0 表示使用全局默认值
+  fallback_workflow_id?: string; // 备用工作流ID，上游不可用时切换执行，为空表示不切换
+  cache_enabled?: boolean; // 是否开启响应缓存
+  cache_ttl_seconds?: number; // 缓存有效期，0 表示使用全局默认值
+  cache_action_key?: string; // 命中缓存时的计费动作，为空表示命中缓存免费
+}
+
+// 工作流配置版本
+export interface WorkflowVersion {
+  id: number;
+  workflow_id: string;
+  version: number;
+  api_url: string;
+  api_key: string;
+  inputs: any;
+  outputs: any;
+  billing_action_key: string;
+  author_id: string;
+  rolled_back_from?: number; // 回滚来源版本
+  notes?: string;
+  created_at: string;
+}
+
+// 工作流配置项变更，新增字段无 from，删除字段无 to
+export interface WorkflowConfigChange {
+  field: string;
+  from?: any;
+  to?: any;
+}
+
+export interface WorkflowVersionDiff {
+  workflow_id: string;
+  from_version: number;
+  to_version: number;
+  changes: WorkflowConfigChange[];
 }
 
 export interface WorkflowExecution {
@@ -57,6 +112,90 @@ export interface WorkflowExecution {
   started_at: string;
   completed_at?: string;
   execution_time?: number;
+  total_tokens?: number; // 上游报告的token用量
+  total_steps?: number;
+  elapsed_time?: number; // 上游执行耗时（秒）
+  deduction_id?: number; // 关联的积分扣减记录
+  config_version?: number; // 实际执行的配置版本
+  schema_drift?: WorkflowFieldError[]; // 输出与声明字段不一致的字段
+  has_drift?: boolean;
+  cache_hit?: boolean; // 是否命中响应缓存
+}
+
+// 工作流响应缓存统计
+export interface WorkflowCacheStats {
+  workflow_id: string;
+  workflow_name: string;
+  cache_enabled: boolean;
+  ttl_seconds: number; // 实际生效的缓存有效期
+  cache_action_key: string;
+  entries: number;
+  active_entries: number;
+  stored_hits: number; // 现存缓存记录的累计命中次数
+  days: number;
+  executions: number; // 统计期内的执行次数
+  cache_hits: number; // 统计期内命中缓存的执行次数
+  hit_rate: number;
+}
+
+// 流水线步骤，inputs 中的 {{input.字段}}、{{steps.步骤名.outputs.字段}} 引用流水线输入和之前步骤的输出
+export interface PipelineStep {
+  name: string;
+  workflow: string; // 工作流名称
+  inputs: Record<string, unknown>;
+  transforms?: { type: 'strip_think' | 'extract_json' | 'trim'; field?: string }[];
+  when?: { path: string; op?: 'not_empty' | 'empty' | 'equals' | 'not_equals'; value?: unknown };
+  on_error?: 'fail' | 'continue';
+}
+
+export interface WorkflowPipeline {
+  id: string;
+  name: string;
+  description: string;
+  steps: PipelineStep[];
+  outputs: Record<string, string> | null; // 输出字段 -> 取值表达式
+  enabled: boolean;
+  author_id: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface PipelineRequest {
+  name?: string;
+  description?: string;
+  steps?: PipelineStep[];
+  outputs?: Record<string, string>;
+  enabled?: boolean;
+}
+
+export interface PipelineStepResult {
+  name: string;
+  workflow: string;
+  workflow_id?: string;
+  status: 'succeeded' | 'failed' | 'skipped';
+  outputs?: Record<string, unknown>;
+  error_message?: string;
+  workflow_run_id?: string;
+  cache_hit?: boolean;
+  execution_time: number;
+}
+
+export interface WorkflowPipelineRun {
+  id: string;
+  pipeline_id: string;
+  pipeline_name: string;
+  user_id: string;
+  response_mode: 'blocking' | 'streaming';
+  status: 'running' | 'succeeded' | 'failed' | 'cancelled';
+  inputs: Record<string, unknown>;
+  outputs: Record<string, unknown> | null;
+  steps: PipelineStepResult[] | null;
+  failed_step?: string;
+  error_message?: string;
+  execution_time: number;
+  finished_at: string | null;
+  created_at: string;
+  updated_at: string;
 }
 
 export interface WorkflowResult {
@@ -76,6 +215,7 @@ export interface WorkflowBackupData {
   outputs?: any;
   is_public?: boolean;
   enabled?: boolean;
+  billing_action_key?: string;
 }
 
 export interface WorkflowBackupFile {